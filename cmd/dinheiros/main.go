@@ -2,6 +2,8 @@ package main
 
 import (
 	"log"
+	// Embed the timezone database so user timezones load on hosts without one
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 
@@ -21,7 +21,7 @@ import CategoryManager from './pages/CategoryManager';
 import CategorizationRules from './pages/CategorizationRules';
 import SharedAccounts from './pages/SharedAccounts';
 import AcceptInvitation from './pages/AcceptInvitation';
-import api from './services/api';
+import api, { clearSession } from './services/api';
 
 function App() {
   const [isAuthenticated, setIsAuthenticated] = useState(false);
@@ -45,7 +45,7 @@ function App() {
         setIsAuthenticated(true);
       } catch (error) {
         // Token is invalid, remove it
-        localStorage.removeItem('token');
+        clearSession();
         setIsAuthenticated(false);
       } finally {
         setIsLoading(false);
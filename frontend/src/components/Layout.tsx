@@ -6,14 +6,15 @@ import LanguageSwitcher from './LanguageSwitcher';
 import CategoryIcon from './CategoryIcon';
 import ThemeToggle from './ThemeToggle';
 import { Button, NavLink } from '@/components/ui';
+import { logout } from '../services/api';
 
 const Layout = () => {
   const { t } = useTranslation();
   const navigate = useNavigate();
   const [isMobileMenuOpen, setIsMobileMenuOpen] = useState(false);
 
-  const handleLogout = () => {
-    localStorage.removeItem('token');
+  const handleLogout = async () => {
+    await logout();
     navigate('/login');
   };
 
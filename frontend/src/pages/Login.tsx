@@ -4,7 +4,7 @@ import { useForm } from 'react-hook-form';
 import { z } from 'zod';
 import { zodResolver } from '@hookform/resolvers/zod';
 import { useTranslation } from 'react-i18next';
-import api from '../services/api';
+import api, { storeSession } from '../services/api';
 import { toast } from 'react-hot-toast';
 import { GoogleLogin } from '@react-oauth/google';
 import { trackUserAction } from '../lib/analytics';
@@ -35,7 +35,7 @@ export default function Login() {
       setIsLoading(true);
       const response = await api.post('/api/auth/login', data);
       
-      localStorage.setItem('token', response.data.token);
+      storeSession(response.data);
       toast.success(t('login.loggedInSuccess'));
       trackUserAction.login();
 
@@ -59,7 +59,7 @@ export default function Login() {
       const response = await api.post('/api/auth/google', {
         credential: credentialResponse.credential,
       });
-      localStorage.setItem('token', response.data.token);
+      storeSession(response.data);
       toast.success(t('login.googleLoginSuccess'));
       trackUserAction.login();
       window.location.href = '/';
@@ -25,19 +25,78 @@ api.interceptors.request.use(
   }
 );
 
-// Add a response interceptor to handle errors
+// Stores the tokens of a login, registration or refresh response
+export const storeSession = (data: { token: string; refresh_token: string }) => {
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refreshToken', data.refresh_token);
+};
+
+export const clearSession = () => {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refreshToken');
+};
+
+// Requests failing at the same time share one refresh, since a refresh token can only be used once
+let refreshing: Promise<string> | null = null;
+
+const refreshAccessToken = () => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refreshToken');
+    refreshing = (refreshToken
+      ? axios.post(`${API_URL}/api/auth/refresh`, { refresh_token: refreshToken }).then((response) => {
+          storeSession(response.data);
+          return response.data.token as string;
+        })
+      : Promise.reject(new Error('No refresh token'))
+    ).finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
+// Add a response interceptor to refresh expired access tokens and handle errors
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
-    if (error.response?.status === 401) {
+  async (error) => {
+    const original = error.config;
+    const isAuthRequest = original?.url?.startsWith('/api/auth/');
+    if (error.response?.status === 401 && original && !original._retry && !isAuthRequest) {
+      original._retry = true;
+      try {
+        await refreshAccessToken();
+        return api(original);
+      } catch {
+        // The session expired or was logged out, fall through to the login page
+      }
+    }
+    if (error.response?.status === 401 && !isAuthRequest) {
       // Handle unauthorized access (e.g., redirect to login)
-      localStorage.removeItem('token');
+      clearSession();
       window.location.href = '/login';
     }
     return Promise.reject(error);
   }
 );
 
+// Logs out the current session on the server before forgetting its tokens
+export const logout = async () => {
+  try {
+    await api.post('/api/auth/logout');
+  } catch (error) {
+    console.error('Logout error:', error);
+  } finally {
+    clearSession();
+  }
+};
+
+// Sessions API
+export const sessionsApi = {
+  list: () => api.get('/api/users/me/sessions'),
+  revoke: (id: number) => api.delete(`/api/users/me/sessions/${id}`),
+  revokeAll: (keepCurrent = false) => api.delete('/api/users/me/sessions', { params: { keep_current: keepCurrent } }),
+};
+
 export default api;
 
 // Categorization Rules API
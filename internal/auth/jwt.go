@@ -4,7 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
-	"os"
 	"strconv"
 	"time"
 
@@ -31,6 +30,54 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	// SessionID is the session the token was issued for, which must still be active
+	SessionID uint `json:"sid"`
+	// Purpose is empty for access tokens. Tokens with a purpose are only accepted by the
+	// endpoint they were issued for.
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// PurposeTwoFactor marks the token a password login returns to users with two-factor
+// authentication, which is exchanged for a session along with a code
+const PurposeTwoFactor = "2fa"
+
+// PurposeLinkIdentity marks the token a login with an identity provider returns when the
+// provider's email belongs to a user the identity isn't linked to. It links the identity once
+// the user confirms their password.
+const PurposeLinkIdentity = "link"
+
+// LinkClaims are the claims of link tokens: the user and the identity to link to them
+type LinkClaims struct {
+	UserClaims
+	Provider        string `json:"idp"`
+	ProviderSubject string `json:"idp_sub"`
+	ProviderEmail   string `json:"idp_email"`
+}
+
+// PurposeOIDCState marks the token that keeps the state of a login with an OpenID Connect
+// provider in the browser, from redirecting to the provider until its callback
+const PurposeOIDCState = "oidc_state"
+
+// OIDCStateClaims are the claims of OIDC state tokens: the provider logged in with and the
+// values the callback must match
+type OIDCStateClaims struct {
+	jwt.RegisteredClaims
+	Purpose      string `json:"purpose"`
+	Provider     string `json:"idp"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// CategoryTemplate seeds the categories of a user the login registers
+	CategoryTemplate string `json:"category_template,omitempty"`
+}
+
+// TokenPair is the short-lived access token sent with each request and the refresh token
+// that is exchanged for new tokens when it expires
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
 }
 
 // NewJWTManager creates a new JWTManager with the given secret key and token duration
@@ -41,29 +88,171 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	}
 }
 
-// GenerateToken generates a new JWT token for the given user
-func (m *JWTManager) GenerateToken(user *models.User) (string, error) {
+// GenerateToken generates a new JWT access token for the given user and session, returning
+// it with its expiration time
+func (m *JWTManager) GenerateToken(user *models.User, sessionID uint) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.tokenDuration)
 	claims := UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "dinheiros-api",
 			Subject:   strconv.FormatUint(uint64(user.ID), 10),
 		},
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: sessionID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secretKey)
+	signed, err := token.SignedString(m.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// GeneratePurposeToken generates a JWT token only accepted by VerifyPurposeToken with the
+// same purpose, returning it with its expiration time
+func (m *JWTManager) GeneratePurposeToken(user *models.User, purpose string, duration time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	claims := UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "dinheiros-api",
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		},
+		UserID:  user.ID,
+		Email:   user.Email,
+		Purpose: purpose,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(m.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// GenerateLinkToken generates a token that links the provider's identity to the user,
+// returning it with its expiration time
+func (m *JWTManager) GenerateLinkToken(user *models.User, provider, subject, email string, duration time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	claims := LinkClaims{
+		UserClaims: UserClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+				IssuedAt:  jwt.NewNumericDate(now),
+				NotBefore: jwt.NewNumericDate(now),
+				Issuer:    "dinheiros-api",
+				Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			},
+			UserID:  user.ID,
+			Email:   user.Email,
+			Purpose: PurposeLinkIdentity,
+		},
+		Provider:        provider,
+		ProviderSubject: subject,
+		ProviderEmail:   email,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(m.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// GenerateOIDCStateToken generates a token keeping the state of a login with an OpenID
+// Connect provider, returning it with its expiration time
+func (m *JWTManager) GenerateOIDCStateToken(claims OIDCStateClaims, duration time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "dinheiros-api",
+	}
+	claims.Purpose = PurposeOIDCState
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(m.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
-// VerifyToken verifies the given JWT token and returns the user claims if valid
+// VerifyToken verifies the given JWT access token and returns the user claims if valid
 func (m *JWTManager) VerifyToken(tokenString string) (*UserClaims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// VerifyPurposeToken verifies a token generated by GeneratePurposeToken for the purpose
+func (m *JWTManager) VerifyPurposeToken(tokenString, purpose string) (*UserClaims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// VerifyLinkToken verifies a token generated by GenerateLinkToken
+func (m *JWTManager) VerifyLinkToken(tokenString string) (*LinkClaims, error) {
+	var claims LinkClaims
+	if err := m.parseInto(tokenString, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeLinkIdentity || claims.Provider == "" || claims.ProviderSubject == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// VerifyOIDCStateToken verifies a token generated by GenerateOIDCStateToken
+func (m *JWTManager) VerifyOIDCStateToken(tokenString string) (*OIDCStateClaims, error) {
+	var claims OIDCStateClaims
+	if err := m.parseInto(tokenString, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeOIDCState || claims.Provider == "" || claims.State == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func (m *JWTManager) parse(tokenString string) (*UserClaims, error) {
+	var claims UserClaims
+	if err := m.parseInto(tokenString, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// parseInto verifies the token's signature and expiration and reads its claims
+func (m *JWTManager) parseInto(tokenString string, claims jwt.Claims) error {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&UserClaims{},
+		claims,
 		func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, ErrInvalidToken
@@ -74,17 +263,16 @@ func (m *JWTManager) VerifyToken(tokenString string) (*UserClaims, error) {
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrTokenExpired
+			return ErrTokenExpired
 		}
-		return nil, ErrInvalidToken
+		return ErrInvalidToken
 	}
 
-	claims, ok := token.Claims.(*UserClaims)
-	if !ok || !token.Valid {
-		return nil, ErrInvalidToken
+	if !token.Valid {
+		return ErrInvalidToken
 	}
 
-	return claims, nil
+	return nil
 }
 
 // GenerateRandomKey generates a random 32-byte key for JWT signing
@@ -96,23 +284,3 @@ func GenerateRandomKey() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(key), nil
 }
-
-// GetJWTConfig returns the JWT configuration from environment variables
-func GetJWTConfig() (string, time.Duration, error) {
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		return "", 0, errors.New("JWT_SECRET_KEY environment variable not set")
-	}
-
-	tokenDurationStr := os.Getenv("JWT_TOKEN_DURATION_HOURS")
-	if tokenDurationStr == "" {
-		tokenDurationStr = "24" // Default to 24 hours
-	}
-
-	tokenDurationHours, err := strconv.Atoi(tokenDurationStr)
-	if err != nil {
-		return "", 0, err
-	}
-
-	return secretKey, time.Duration(tokenDurationHours) * time.Hour, nil
-}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateOpaqueToken generates a random token for the client to keep, such as a refresh
+// token. Only its hash is stored, so a leaked database doesn't leak usable tokens.
+func GenerateOpaqueToken() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 hash an opaque token is stored and looked up by.
+// The tokens are random, so they don't need a slow, salted hash like passwords.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
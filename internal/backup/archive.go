@@ -0,0 +1,290 @@
+// Package backup defines the archive users download to move their data between instances
+// and the copy of their personal data. Archive records reference each other by the IDs they
+// had in the instance that wrote the archive, which are replaced when it is restored.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// Version is the archive format written by this version of the application. Older formats
+// are restored as long as they are listed in supportedVersions.
+const Version = 1
+
+var supportedVersions = map[int]bool{1: true}
+
+type Archive struct {
+	Version       int            `json:"version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Accounts      []Account      `json:"accounts"`
+	Categories    []Category     `json:"categories"`
+	Tags          []Tag          `json:"tags"`
+	Merchants     []Merchant     `json:"merchants"`
+	Transactions  []Transaction  `json:"transactions"`
+	Rules         []Rule         `json:"rules"`
+	Subscriptions []Subscription `json:"subscriptions"`
+	Shares        []Share        `json:"shares"`
+}
+
+type Account struct {
+	ID                  uint               `json:"id"`
+	Name                string             `json:"name"`
+	Type                models.AccountType `json:"type"`
+	InitialBalance      float64            `json:"initial_balance"`
+	Balance             float64            `json:"balance"`
+	Color               string             `json:"color"`
+	StatementClosingDay *int               `json:"statement_closing_day,omitempty"`
+	PaymentDueDay       *int               `json:"payment_due_day,omitempty"`
+	PaymentAccountID    *uint              `json:"payment_account_id,omitempty"`
+	CreatedAt           time.Time          `json:"created_at"`
+}
+
+type Category struct {
+	ID          uint                   `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Deductible  bool                   `json:"deductible"`
+	Type        models.TransactionType `json:"type"`
+	ParentID    *uint                  `json:"parent_id,omitempty"`
+}
+
+type Tag struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type Merchant struct {
+	ID      uint     `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+type Transaction struct {
+	ID          uint                   `json:"id"`
+	Date        time.Time              `json:"date"`
+	Amount      float64                `json:"amount"`
+	Type        models.TransactionType `json:"type"`
+	Description string                 `json:"description"`
+	AccountID   uint                   `json:"account_id"`
+	MerchantID  *uint                  `json:"merchant_id,omitempty"`
+	CategoryIDs []uint                 `json:"category_ids,omitempty"`
+	TagIDs      []uint                 `json:"tag_ids,omitempty"`
+	Splits      []Split                `json:"splits,omitempty"`
+	// AttachedTransactionID is the other side of a transfer, which is in the archive too
+	AttachedTransactionID *uint                  `json:"attached_transaction_id,omitempty"`
+	AttachmentType        *models.AttachmentType `json:"attachment_type,omitempty"`
+	CreatedAt             time.Time              `json:"created_at"`
+}
+
+type Split struct {
+	CategoryID uint    `json:"category_id"`
+	Amount     float64 `json:"amount"`
+	Memo       string  `json:"memo,omitempty"`
+}
+
+type Rule struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Value           string `json:"value"`
+	TransactionType string `json:"transaction_type"`
+	// CategoryID is 0 for rules that only add tags
+	CategoryID uint   `json:"category_id"`
+	TagIDs     []uint `json:"tag_ids,omitempty"`
+	Active     bool   `json:"active"`
+}
+
+type Subscription struct {
+	Key             string                     `json:"key"`
+	Name            string                     `json:"name"`
+	AccountID       uint                       `json:"account_id"`
+	Cadence         models.SubscriptionCadence `json:"cadence"`
+	Amount          float64                    `json:"amount"`
+	Charges         int                        `json:"charges"`
+	FirstChargeDate time.Time                  `json:"first_charge_date"`
+	LastChargeDate  time.Time                  `json:"last_charge_date"`
+	Status          models.SubscriptionStatus  `json:"status"`
+	CancelledAt     *time.Time                 `json:"cancelled_at,omitempty"`
+}
+
+// Share is an account the user shared with someone else, who is identified by email since
+// user IDs differ between instances
+type Share struct {
+	AccountID       uint                   `json:"account_id"`
+	Email           string                 `json:"email"`
+	PermissionLevel models.PermissionLevel `json:"permission_level"`
+	SharedAt        time.Time              `json:"shared_at"`
+}
+
+// Read decodes an archive, rejecting unknown fields so a mistyped or newer archive isn't
+// restored partially
+func Read(r io.Reader) (*Archive, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var archive Archive
+	if err := decoder.Decode(&archive); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	return &archive, nil
+}
+
+// Write encodes an archive as indented JSON
+func Write(w io.Writer, archive *Archive) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// Validate returns the problems that would stop the archive from being restored: an
+// unsupported version, duplicated IDs, unknown types and references to records that aren't
+// in the archive. A valid archive has none.
+func (a *Archive) Validate() []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !supportedVersions[a.Version] {
+		addf("unsupported archive version %d", a.Version)
+		return problems
+	}
+
+	accounts := make(map[uint]bool, len(a.Accounts))
+	for _, account := range a.Accounts {
+		if accounts[account.ID] {
+			addf("account %d is duplicated", account.ID)
+		}
+		accounts[account.ID] = true
+		if !accountTypes[account.Type] {
+			addf("account %d has unknown type %q", account.ID, account.Type)
+		}
+	}
+	for _, account := range a.Accounts {
+		if account.PaymentAccountID != nil && !accounts[*account.PaymentAccountID] {
+			addf("account %d is paid from unknown account %d", account.ID, *account.PaymentAccountID)
+		}
+	}
+
+	categories := make(map[uint]bool, len(a.Categories))
+	for _, category := range a.Categories {
+		if categories[category.ID] {
+			addf("category %d is duplicated", category.ID)
+		}
+		categories[category.ID] = true
+		if !transactionTypes[category.Type] {
+			addf("category %d has unknown type %q", category.ID, category.Type)
+		}
+	}
+	for _, category := range a.Categories {
+		if category.ParentID != nil && !categories[*category.ParentID] {
+			addf("category %d has unknown parent %d", category.ID, *category.ParentID)
+		}
+	}
+
+	tags := make(map[uint]bool, len(a.Tags))
+	for _, tag := range a.Tags {
+		if tags[tag.ID] {
+			addf("tag %d is duplicated", tag.ID)
+		}
+		tags[tag.ID] = true
+	}
+
+	merchants := make(map[uint]bool, len(a.Merchants))
+	for _, merchant := range a.Merchants {
+		if merchants[merchant.ID] {
+			addf("merchant %d is duplicated", merchant.ID)
+		}
+		merchants[merchant.ID] = true
+	}
+
+	transactions := make(map[uint]bool, len(a.Transactions))
+	for _, transaction := range a.Transactions {
+		if transactions[transaction.ID] {
+			addf("transaction %d is duplicated", transaction.ID)
+		}
+		transactions[transaction.ID] = true
+	}
+	for _, transaction := range a.Transactions {
+		if !transactionTypes[transaction.Type] {
+			addf("transaction %d has unknown type %q", transaction.ID, transaction.Type)
+		}
+		if !accounts[transaction.AccountID] {
+			addf("transaction %d belongs to unknown account %d", transaction.ID, transaction.AccountID)
+		}
+		if transaction.MerchantID != nil && !merchants[*transaction.MerchantID] {
+			addf("transaction %d has unknown merchant %d", transaction.ID, *transaction.MerchantID)
+		}
+		for _, id := range transaction.CategoryIDs {
+			if !categories[id] {
+				addf("transaction %d has unknown category %d", transaction.ID, id)
+			}
+		}
+		for _, id := range transaction.TagIDs {
+			if !tags[id] {
+				addf("transaction %d has unknown tag %d", transaction.ID, id)
+			}
+		}
+		for _, split := range transaction.Splits {
+			if !categories[split.CategoryID] {
+				addf("transaction %d has a split in unknown category %d", transaction.ID, split.CategoryID)
+			}
+		}
+		if (transaction.AttachedTransactionID == nil) != (transaction.AttachmentType == nil) {
+			addf("transaction %d has an attachment without both the transaction and its type", transaction.ID)
+		}
+		if transaction.AttachedTransactionID != nil && !transactions[*transaction.AttachedTransactionID] {
+			addf("transaction %d is attached to unknown transaction %d", transaction.ID, *transaction.AttachedTransactionID)
+		}
+		if transaction.AttachmentType != nil && !attachmentTypes[*transaction.AttachmentType] {
+			addf("transaction %d has unknown attachment type %q", transaction.ID, *transaction.AttachmentType)
+		}
+	}
+
+	for i, rule := range a.Rules {
+		if rule.CategoryID != 0 && !categories[rule.CategoryID] {
+			addf("rule %d (%s) assigns unknown category %d", i+1, rule.Name, rule.CategoryID)
+		}
+		for _, id := range rule.TagIDs {
+			if !tags[id] {
+				addf("rule %d (%s) assigns unknown tag %d", i+1, rule.Name, id)
+			}
+		}
+	}
+
+	for i, subscription := range a.Subscriptions {
+		if !accounts[subscription.AccountID] {
+			addf("subscription %d (%s) is charged to unknown account %d", i+1, subscription.Name, subscription.AccountID)
+		}
+	}
+
+	for i, share := range a.Shares {
+		if !accounts[share.AccountID] {
+			addf("share %d shares unknown account %d", i+1, share.AccountID)
+		}
+	}
+
+	return problems
+}
+
+var accountTypes = map[models.AccountType]bool{
+	models.AccountTypeChecking: true,
+	models.AccountTypeSavings:  true,
+	models.AccountTypeCredit:   true,
+	models.AccountTypeCash:     true,
+}
+
+var transactionTypes = map[models.TransactionType]bool{
+	models.TransactionTypeIncome:  true,
+	models.TransactionTypeExpense: true,
+	models.TransactionTypeInitial: true,
+}
+
+var attachmentTypes = map[models.AttachmentType]bool{
+	models.AttachmentTypeOutboundTransfer: true,
+	models.AttachmentTypeInboundTransfer:  true,
+}
@@ -0,0 +1,107 @@
+package backup_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/backup"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+func validArchive() *backup.Archive {
+	outbound, inbound := models.AttachmentTypeOutboundTransfer, models.AttachmentTypeInboundTransfer
+	date := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	return &backup.Archive{
+		Version:    backup.Version,
+		ExportedAt: date,
+		Accounts: []backup.Account{
+			{ID: 10, Name: "Conta Corrente", Type: models.AccountTypeChecking, Color: "#cccccc"},
+			{ID: 11, Name: "Cartão", Type: models.AccountTypeCredit, Color: "#cccccc", PaymentAccountID: uintPtr(10)},
+		},
+		Categories: []backup.Category{
+			{ID: 20, Name: "Saúde", Type: models.TransactionTypeExpense, Deductible: true},
+			{ID: 21, Name: "Farmácia", Type: models.TransactionTypeExpense, ParentID: uintPtr(20)},
+		},
+		Tags:      []backup.Tag{{ID: 30, Name: "reembolsável"}},
+		Merchants: []backup.Merchant{{ID: 40, Name: "Drogasil", Aliases: []string{"RAIA DROGASIL"}}},
+		Transactions: []backup.Transaction{
+			{ID: 50, Date: date, Amount: 80, Type: models.TransactionTypeExpense, AccountID: 11, MerchantID: uintPtr(40), CategoryIDs: []uint{21}, TagIDs: []uint{30}},
+			{ID: 51, Date: date, Amount: 500, Type: models.TransactionTypeExpense, AccountID: 10, AttachedTransactionID: uintPtr(52), AttachmentType: &outbound},
+			{ID: 52, Date: date, Amount: 500, Type: models.TransactionTypeIncome, AccountID: 11, AttachedTransactionID: uintPtr(51), AttachmentType: &inbound},
+		},
+		Rules:         []backup.Rule{{Name: "Farmácia", Type: "merchant", Value: "Drogasil", TransactionType: "expense", CategoryID: 21, TagIDs: []uint{30}, Active: true}},
+		Subscriptions: []backup.Subscription{{Key: "netflix", Name: "Netflix", AccountID: 11, Cadence: models.SubscriptionCadenceMonthly, Status: models.SubscriptionStatusConfirmed}},
+		Shares:        []backup.Share{{AccountID: 10, Email: "ana@example.com", PermissionLevel: models.PermissionRead, SharedAt: date}},
+	}
+}
+
+func TestArchive_Validate(t *testing.T) {
+	if problems := validArchive().Validate(); len(problems) != 0 {
+		t.Fatalf("Expected a valid archive, got %v", problems)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(a *backup.Archive)
+		problem string
+	}{
+		{"unsupported version", func(a *backup.Archive) { a.Version = 99 }, "unsupported archive version 99"},
+		{"duplicated account", func(a *backup.Archive) { a.Accounts[1].ID = 10 }, "account 10 is duplicated"},
+		{"unknown account type", func(a *backup.Archive) { a.Accounts[0].Type = "wallet" }, `account 10 has unknown type "wallet"`},
+		{"unknown payment account", func(a *backup.Archive) { a.Accounts[1].PaymentAccountID = uintPtr(99) }, "account 11 is paid from unknown account 99"},
+		{"unknown parent", func(a *backup.Archive) { a.Categories[1].ParentID = uintPtr(99) }, "category 21 has unknown parent 99"},
+		{"unknown transaction account", func(a *backup.Archive) { a.Transactions[0].AccountID = 99 }, "transaction 50 belongs to unknown account 99"},
+		{"unknown category", func(a *backup.Archive) { a.Transactions[0].CategoryIDs = []uint{99} }, "transaction 50 has unknown category 99"},
+		{"unknown split category", func(a *backup.Archive) {
+			a.Transactions[0].Splits = []backup.Split{{CategoryID: 99, Amount: 80}}
+		}, "transaction 50 has a split in unknown category 99"},
+		{"unknown attached transaction", func(a *backup.Archive) { a.Transactions[1].AttachedTransactionID = uintPtr(99) }, "transaction 51 is attached to unknown transaction 99"},
+		{"attachment without type", func(a *backup.Archive) { a.Transactions[1].AttachmentType = nil }, "transaction 51 has an attachment without both the transaction and its type"},
+		{"unknown rule tag", func(a *backup.Archive) { a.Rules[0].TagIDs = []uint{99} }, "rule 1 (Farmácia) assigns unknown tag 99"},
+		{"unknown share account", func(a *backup.Archive) { a.Shares[0].AccountID = 99 }, "share 1 shares unknown account 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := validArchive()
+			tt.modify(archive)
+			problems := archive.Validate()
+			found := false
+			for _, problem := range problems {
+				if problem == tt.problem {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected problem %q, got %v", tt.problem, problems)
+			}
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	var out bytes.Buffer
+	if err := backup.Write(&out, validArchive()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	archive, err := backup.Read(&out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(archive.Transactions) != 3 || *archive.Transactions[1].AttachedTransactionID != 52 {
+		t.Errorf("Expected the transactions to round trip, got %+v", archive.Transactions)
+	}
+	if archive.Merchants[0].Aliases[0] != "RAIA DROGASIL" {
+		t.Errorf("Expected the merchant aliases to round trip, got %+v", archive.Merchants)
+	}
+}
+
+func TestRead_RejectsUnknownFields(t *testing.T) {
+	_, err := backup.Read(strings.NewReader(`{"version": 1, "budgets": []}`))
+	if err == nil || !strings.Contains(err.Error(), "budgets") {
+		t.Errorf("Expected an error about the unknown field, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// PersonalData is the copy of everything stored about a user, which users can ask for under
+// the LGPD. Besides the archive of what they own, it has their profile and the records of
+// other users that mention them.
+type PersonalData struct {
+	ExportedAt   time.Time       `json:"exported_at"`
+	Profile      Profile         `json:"profile"`
+	Data         *Archive        `json:"data"`
+	SharedWithMe []ReceivedShare `json:"shared_with_me"`
+	Invitations  []Invitation    `json:"invitations"`
+	Alerts       []Alert         `json:"alerts"`
+	Sessions     []Session       `json:"sessions"`
+	Tokens       []Token         `json:"personal_access_tokens"`
+	AuditLog     []AuditEntry    `json:"audit_log"`
+	Identities   []Identity      `json:"linked_identities"`
+}
+
+type Profile struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Timezone  string    `json:"timezone"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// TwoFactorEnabled is whether the user has two-factor authentication. The secret and
+	// recovery codes are left out, since they only protect the account.
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+}
+
+// ReceivedShare is an account another user shared with the user
+type ReceivedShare struct {
+	AccountName     string                 `json:"account_name"`
+	OwnerName       string                 `json:"owner_name"`
+	OwnerEmail      string                 `json:"owner_email"`
+	PermissionLevel models.PermissionLevel `json:"permission_level"`
+	SharedAt        time.Time              `json:"shared_at"`
+}
+
+// Invitation is an invitation to share an account the user sent or received
+type Invitation struct {
+	AccountName     string                  `json:"account_name"`
+	OwnerEmail      string                  `json:"owner_email"`
+	InvitedEmail    string                  `json:"invited_email"`
+	PermissionLevel models.PermissionLevel  `json:"permission_level"`
+	Status          models.InvitationStatus `json:"status"`
+	CreatedAt       time.Time               `json:"created_at"`
+	ExpiresAt       time.Time               `json:"expires_at"`
+	AcceptedAt      *time.Time              `json:"accepted_at,omitempty"`
+}
+
+// Alert is an anomaly found in the user's transactions. Alerts are left out of backups, since
+// they are found again by analyzing the restored transactions.
+type Alert struct {
+	Type          models.AlertType `json:"type"`
+	Message       string           `json:"message"`
+	Subject       string           `json:"subject,omitempty"`
+	TransactionID *uint            `json:"transaction_id,omitempty"`
+	Amount        float64          `json:"amount"`
+	Baseline      float64          `json:"baseline"`
+	ReadAt        *time.Time       `json:"read_at,omitempty"`
+	DismissedAt   *time.Time       `json:"dismissed_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// Session is a login on one of the user's devices, including revoked ones that weren't
+// cleaned up yet
+type Session struct {
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Token is a personal access token of the user, without the token itself
+type Token struct {
+	Name       string              `json:"name"`
+	Prefix     string              `json:"prefix"`
+	Scopes     []models.TokenScope `json:"scopes"`
+	AccountID  *uint               `json:"account_id,omitempty"`
+	ExpiresAt  *time.Time          `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time          `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// Identity is an identity provider's account linked to the user, without the provider's ID
+// of the account
+type Identity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// AuditEntry is a security event of the user's account, such as its logins being locked
+type AuditEntry struct {
+	Action    models.AuditAction `json:"action"`
+	Details   string             `json:"details,omitempty"`
+	UserAgent string             `json:"user_agent"`
+	IPAddress string             `json:"ip_address"`
+	CreatedAt time.Time          `json:"created_at"`
+}
@@ -51,15 +51,32 @@ func InitDB(cfg *config.Config) error {
 	// 	&models.User{},
 	// 	&models.Account{},
 	// 	&models.Transaction{},
+	// 	&models.TransactionSplit{},
 	// 	&models.Category{},
 	// 	&models.CategorizationRule{},
+	// 	&models.Tag{},
 	// 	&models.AccountShare{},
 	// 	&models.ShareInvitation{},
+	// 	&models.Alert{},
+	// 	&models.Subscription{},
+	// 	&models.Merchant{},
+	// 	&models.MerchantAlias{},
+	// 	&models.Session{},
+	// 	&models.RecoveryCode{},
+	// 	&models.PersonalAccessToken{},
+	// 	&models.AuditLog{},
+	// 	&models.PasswordResetToken{},
+	// 	&models.UserIdentity{},
+	// 	&models.DataMigration{},
 	// )
 	// if err != nil {
 	// 	return fmt.Errorf("failed to migrate database: %v", err)
 	// }
 
+	if err := RunDataMigrations(DB); err != nil {
+		return fmt.Errorf("failed to run data migrations: %v", err)
+	}
+
 	return nil
 }
 
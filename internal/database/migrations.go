@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// dataMigration fixes the rows stored before a change to what they mean
+type dataMigration struct {
+	name string
+	run  func(tx *gorm.DB) error
+}
+
+// dataMigrations run in order, each once
+var dataMigrations = []dataMigration{
+	{name: "2026-10-18-transactions-deleted-with-account", run: markTransactionsDeletedWithAccount},
+}
+
+// RunDataMigrations runs the data migrations that haven't run yet, each in a transaction with
+// its record in data_migrations, so concurrent instances don't run one twice
+func RunDataMigrations(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.DataMigration{}); err != nil {
+		return err
+	}
+	for _, migration := range dataMigrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.DataMigration{Name: migration.name})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+			return migration.run(tx)
+		})
+		if err != nil {
+			return fmt.Errorf("data migration %s: %v", migration.name, err)
+		}
+	}
+	return nil
+}
+
+// markTransactionsDeletedWithAccount marks the transactions deleted by deleting their account
+// before deleted_with_account existed. Accounts were deleted in a transaction that soft-deleted
+// their remaining transactions in one statement, so those are the account's last deleted ones,
+// within a minute of the account.
+func markTransactionsDeletedWithAccount(tx *gorm.DB) error {
+	var accounts []models.Account
+	if err := tx.Unscoped().Where("deleted_at IS NOT NULL").Find(&accounts).Error; err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		var last models.Transaction
+		err := tx.Unscoped().
+			Where("account_id = ? AND deleted_at IS NOT NULL", account.ID).
+			Order("deleted_at DESC").
+			First(&last).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		gap := last.DeletedAt.Time.Sub(account.DeletedAt.Time)
+		if gap < -time.Minute || gap > time.Minute {
+			continue
+		}
+		err = tx.Unscoped().Model(&models.Transaction{}).
+			Where("account_id = ? AND deleted_at = ?", account.ID, last.DeletedAt.Time).
+			Update("deleted_with_account", true).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
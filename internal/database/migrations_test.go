@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupMigrationsTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestRunDataMigrations_TransactionsDeletedWithAccount(t *testing.T) {
+	db, user := setupMigrationsTestDB(t)
+	deletedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// deleteTheOldWay deletes an account like DeleteAccount did before deleted_with_account:
+	// its remaining transactions in one statement, then the account
+	deleteTheOldWay := func(account *models.Account, at time.Time) {
+		if err := db.Model(&models.Transaction{}).Where("account_id = ?", account.ID).Update("deleted_at", at.Add(-time.Millisecond)).Error; err != nil {
+			t.Fatalf("Failed to delete transactions: %v", err)
+		}
+		if err := db.Model(account).Update("deleted_at", at).Error; err != nil {
+			t.Fatalf("Failed to delete account: %v", err)
+		}
+	}
+	create := func(account *models.Account, date string, amount float64) *models.Transaction {
+		parsed, _ := time.Parse("2006-01-02", date)
+		transaction := &models.Transaction{AccountID: account.ID, Date: parsed, Amount: amount, Type: models.TransactionTypeExpense}
+		if err := db.Create(transaction).Error; err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+		return transaction
+	}
+
+	card := &models.Account{Name: "Cartão", Type: models.AccountTypeCredit, UserID: user.ID}
+	if err := db.Create(card).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	create(card, "2026-01-10", 400)
+	create(card, "2026-02-10", 100)
+	// Deleted by the user a week before the account
+	mistake := create(card, "2026-02-11", 777)
+	if err := db.Model(mistake).Update("deleted_at", deletedAt.AddDate(0, 0, -7)).Error; err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+	deleteTheOldWay(card, deletedAt)
+
+	// Its only transaction was deleted by the user long before the account
+	empty := &models.Account{Name: "Vazia", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(empty).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	lone := create(empty, "2026-01-05", 50)
+	if err := db.Model(lone).Update("deleted_at", deletedAt.AddDate(0, -1, 0)).Error; err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+	if err := db.Model(empty).Update("deleted_at", deletedAt).Error; err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+
+	if err := RunDataMigrations(db); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The account's history is back, without the transaction deleted before it
+	rows, err := repository.NewStatisticsRepository(db).BalanceChanges(context.Background(), []uint{card.ID, empty.ID}, nil, repository.StatisticsPeriodMonth, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	changes := make(map[string]float64)
+	for _, row := range rows {
+		if row.AccountID != card.ID {
+			t.Errorf("Expected only the card's transactions, got %+v", row)
+		}
+		changes[row.Label] = row.Amount
+	}
+	if len(changes) != 2 || changes["2026-01"] != -400 || changes["2026-02"] != -100 {
+		t.Errorf("Expected -400 in January and -100 in February, got %v", changes)
+	}
+
+	// Later deletions aren't marked by running the migrations again
+	other := &models.Account{Name: "Outra", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	create(other, "2026-04-01", 10)
+	deleteTheOldWay(other, deletedAt.AddDate(0, 2, 0))
+	if err := RunDataMigrations(db); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var marked int64
+	db.Unscoped().Model(&models.Transaction{}).Where("account_id = ? AND deleted_with_account = ?", other.ID, true).Count(&marked)
+	if marked != 0 {
+		t.Errorf("Expected the migration to run once, got %d marked transactions", marked)
+	}
+}
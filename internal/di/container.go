@@ -1,7 +1,11 @@
 package di
 
 import (
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -9,37 +13,99 @@ import (
 
 	"github.com/LeonardsonCC/dinheiros/internal/auth"
 	"github.com/LeonardsonCC/dinheiros/internal/handlers"
+	"github.com/LeonardsonCC/dinheiros/internal/mailer"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/oidc"
+	"github.com/LeonardsonCC/dinheiros/internal/ratelimit"
 	"github.com/LeonardsonCC/dinheiros/internal/repository"
 	"github.com/LeonardsonCC/dinheiros/internal/service"
 )
 
 type Container struct {
 	// Repositories
-	AccountRepository            repository.AccountRepository
-	TransactionRepository        repository.TransactionRepository
-	UserRepository               repository.UserRepository
-	CategoryRepository           repository.CategoryRepository
-	CategorizationRuleRepository repository.CategorizationRuleRepository
-	AccountShareRepository       *repository.AccountShareRepository
+	AccountRepository             repository.AccountRepository
+	TransactionRepository         repository.TransactionRepository
+	UserRepository                repository.UserRepository
+	CategoryRepository            repository.CategoryRepository
+	CategorizationRuleRepository  repository.CategorizationRuleRepository
+	TagRepository                 repository.TagRepository
+	AccountShareRepository        *repository.AccountShareRepository
+	AlertRepository               repository.AlertRepository
+	SubscriptionRepository        repository.SubscriptionRepository
+	MerchantRepository            repository.MerchantRepository
+	BackupRepository              repository.BackupRepository
+	SessionRepository             repository.SessionRepository
+	RecoveryCodeRepository        repository.RecoveryCodeRepository
+	PersonalAccessTokenRepository repository.PersonalAccessTokenRepository
+	AuditLogRepository            repository.AuditLogRepository
+	PasswordResetTokenRepository  repository.PasswordResetTokenRepository
+	UserIdentityRepository        repository.UserIdentityRepository
 
 	// Services
-	AccountService            service.AccountService
-	TransactionService        service.TransactionService
-	UserService               service.UserService
-	CategoryService           service.CategoryService
-	CategorizationRuleService service.CategorizationRuleService
-	AccountShareService       *service.AccountShareService
+	AccountService             service.AccountService
+	TransactionService         service.TransactionService
+	UserService                service.UserService
+	CategoryService            service.CategoryService
+	CategorizationRuleService  service.CategorizationRuleService
+	TagService                 service.TagService
+	AccountShareService        *service.AccountShareService
+	ForecastService            service.ForecastService
+	NetWorthService            service.NetWorthService
+	AlertService               service.AlertService
+	SubscriptionService        service.SubscriptionService
+	MerchantService            service.MerchantService
+	TaxReportService           service.TaxReportService
+	ExportService              service.ExportService
+	BackupService              service.BackupService
+	PrivacyService             service.PrivacyService
+	SessionService             service.SessionService
+	TwoFactorService           service.TwoFactorService
+	PersonalAccessTokenService service.PersonalAccessTokenService
+	PasswordResetService       service.PasswordResetService
+	OIDCLoginService           service.OIDCLoginService
 
 	// Auth
 	JWTManager *auth.JWTManager
+	// AuthIPLimiter limits the logins, registrations, 2FA codes and password resets of each IP
+	// address, LoginEmailLimiter the logins of each email, ResetEmailLimiter the reset links
+	// emailed to each email and TwoFactorUserLimiter the 2FA codes each user verifies in
+	// their sessions
+	AuthIPLimiter        *ratelimit.Limiter
+	LoginEmailLimiter    *ratelimit.Limiter
+	ResetEmailLimiter    *ratelimit.Limiter
+	TwoFactorUserLimiter *ratelimit.Limiter
+	// TrustedProxies are the addresses and CIDR ranges of the reverse proxies whose
+	// X-Forwarded-For headers give the client's IP address. Without them, it's the address
+	// the request came from, since any client can send the headers.
+	TrustedProxies []string
+
+	// Mailer sends the emails of the API
+	Mailer mailer.Mailer
+	// GoogleVerifier verifies the credentials of Google logins
+	GoogleVerifier service.IdentityVerifier
 
 	// Handlers
-	AccountHandler            *handlers.AccountHandler
-	TransactionHandler        *handlers.TransactionHandler
-	UserHandler               *handlers.UserHandler
-	CategoryHandler           *handlers.CategoryHandler
-	CategorizationRuleHandler *handlers.CategorizationRuleHandler
-	AccountShareHandler       *handlers.AccountShareHandler
+	AccountHandler             *handlers.AccountHandler
+	TransactionHandler         *handlers.TransactionHandler
+	UserHandler                *handlers.UserHandler
+	CategoryHandler            *handlers.CategoryHandler
+	CategorizationRuleHandler  *handlers.CategorizationRuleHandler
+	TagHandler                 *handlers.TagHandler
+	AccountShareHandler        *handlers.AccountShareHandler
+	ForecastHandler            *handlers.ForecastHandler
+	NetWorthHandler            *handlers.NetWorthHandler
+	AlertHandler               *handlers.AlertHandler
+	SubscriptionHandler        *handlers.SubscriptionHandler
+	MerchantHandler            *handlers.MerchantHandler
+	ReportHandler              *handlers.ReportHandler
+	ExportHandler              *handlers.ExportHandler
+	BackupHandler              *handlers.BackupHandler
+	PrivacyHandler             *handlers.PrivacyHandler
+	SessionHandler             *handlers.SessionHandler
+	TwoFactorHandler           *handlers.TwoFactorHandler
+	PersonalAccessTokenHandler *handlers.PersonalAccessTokenHandler
+	PasswordResetHandler       *handlers.PasswordResetHandler
+	OIDCHandler                *handlers.OIDCHandler
 }
 
 // getSecret returns the value from Docker secret file, environment variable, or fallback
@@ -58,6 +124,80 @@ func getSecret(key, fallback string) string {
 	return fallback
 }
 
+// getDuration returns the duration set in the environment variable, or fallback when it's
+// unset or invalid
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return fallback
+}
+
+// getTrustedProxies returns the addresses and CIDR ranges listed in TRUSTED_PROXIES,
+// separated by commas
+func getTrustedProxies() ([]string, error) {
+	var proxies []string
+	for _, proxy := range strings.Split(getSecret("TRUSTED_PROXIES", ""), ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// oidcProviderID is the format of OIDC provider IDs, which name them in URLs, environment
+// variables and linked identities
+var oidcProviderID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// getOIDCProviders returns the OpenID Connect providers listed in OIDC_PROVIDERS, separated by
+// commas, each configured by the variables prefixed with its ID, such as OIDC_KEYCLOAK_ISSUER
+// for "keycloak". Providers send users back to the frontend's callback page under appURL.
+func getOIDCProviders(appURL string, client *http.Client) ([]service.OIDCProvider, error) {
+	var providers []service.OIDCProvider
+	for _, id := range strings.Split(getSecret("OIDC_PROVIDERS", ""), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if !oidcProviderID.MatchString(id) || id == models.IdentityProviderGoogle {
+			return nil, fmt.Errorf("invalid OIDC provider ID %q", id)
+		}
+		for _, provider := range providers {
+			if provider.ID == id {
+				return nil, fmt.Errorf("duplicate OIDC provider ID %q", id)
+			}
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(id, "-", "_")) + "_"
+		config := oidc.Config{
+			Issuer:       getSecret(prefix+"ISSUER", ""),
+			ClientID:     getSecret(prefix+"CLIENT_ID", ""),
+			ClientSecret: getSecret(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getSecret(prefix+"REDIRECT_URL", appURL+"/oidc/"+id+"/callback"),
+			Scopes:       strings.Fields(strings.ReplaceAll(getSecret(prefix+"SCOPES", "openid email profile"), ",", " ")),
+		}
+		if config.Issuer == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %q needs %sISSUER and %sCLIENT_ID", id, prefix, prefix)
+		}
+
+		providers = append(providers, service.OIDCProvider{
+			ID:     id,
+			Name:   getSecret(prefix+"NAME", id),
+			Client: oidc.NewClient(config, client),
+		})
+	}
+	return providers, nil
+}
+
 func NewContainer(db *gorm.DB) (*Container, error) {
 	// Initialize JWT manager
 	jwtSecret := getSecret("JWT_SECRET_KEY", "")
@@ -71,16 +211,59 @@ func NewContainer(db *gorm.DB) (*Container, error) {
 		}
 	}
 
-	// Set token duration (24 hours by default)
-	tokenDuration := 24 * time.Hour
-	if durationStr := os.Getenv("JWT_TOKEN_DURATION"); durationStr != "" {
-		duration, err := time.ParseDuration(durationStr)
-		if err == nil {
-			tokenDuration = duration
-		}
+	// Access tokens are short-lived (15 minutes by default) and renewed with refresh tokens,
+	// which keep the session alive while it's used at least every 30 days by default
+	accessTokenDuration := getDuration("JWT_ACCESS_TOKEN_DURATION", 15*time.Minute)
+	refreshTokenDuration := getDuration("JWT_REFRESH_TOKEN_DURATION", 30*24*time.Hour)
+
+	jwtManager := auth.NewJWTManager(jwtSecret, accessTokenDuration)
+
+	// Each IP address can make 20 authentication requests at once and then one every 3
+	// seconds, and each email can be tried 5 times at once and then once a minute. The limits
+	// are kept by each replica.
+	rateLimitStore := ratelimit.NewMemoryStore()
+	authIPLimiter := ratelimit.NewLimiter("auth-ip", rateLimitStore, ratelimit.Limit{Burst: 20, Interval: 3 * time.Second})
+	loginEmailLimiter := ratelimit.NewLimiter("login-email", rateLimitStore, ratelimit.Limit{Burst: 5, Interval: time.Minute})
+	// Each email can be sent 3 reset links at once and then one every 15 minutes
+	resetEmailLimiter := ratelimit.NewLimiter("reset-email", rateLimitStore, ratelimit.Limit{Burst: 3, Interval: 15 * time.Minute})
+	// Each user can verify 5 codes at once and then one a minute
+	twoFactorUserLimiter := ratelimit.NewLimiter("2fa-user", rateLimitStore, ratelimit.Limit{Burst: 5, Interval: time.Minute})
+	// The limits per IP address only work with the client's real address, which proxies in
+	// front of the API send in X-Forwarded-For
+	trustedProxies, err := getTrustedProxies()
+	if err != nil {
+		return nil, err
+	}
+
+	// Emails are written to the log unless MAIL_DRIVER selects files or SMTP
+	emailSender, err := mailer.New(mailer.Config{
+		Driver:   getSecret("MAIL_DRIVER", mailer.DriverLog),
+		From:     getSecret("MAIL_FROM", "Dinheiros <no-reply@localhost>"),
+		Dir:      getSecret("MAIL_DIR", "./mail"),
+		Host:     getSecret("SMTP_HOST", ""),
+		Port:     getSecret("SMTP_PORT", "587"),
+		Username: getSecret("SMTP_USERNAME", ""),
+		Password: getSecret("SMTP_PASSWORD", ""),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	jwtManager := auth.NewJWTManager(jwtSecret, tokenDuration)
+	// APP_URL is where the frontend is served, which the emailed links open
+	appURL := strings.TrimSuffix(getSecret("APP_URL", "http://localhost:8080"), "/")
+
+	// Google credentials are only accepted when issued for our client ID, which defaults to
+	// the one the frontend uses. Google's keys are fetched and cached as Google allows.
+	googleClientID := getSecret("GOOGLE_CLIENT_ID", "1042630940956-jbtm700eqmcggj86h6fdq3dbrseka4vg.apps.googleusercontent.com")
+	identityClient := &http.Client{Timeout: 10 * time.Second}
+	googleKeySet := oidc.NewRemoteKeySet(service.GoogleJWKSURL, identityClient)
+	googleVerifier := service.NewGoogleVerifier(googleClientID, googleKeySet)
+
+	// Self-hosted providers, such as Keycloak or Authentik, are configured with OIDC_PROVIDERS
+	oidcProviders, err := getOIDCProviders(appURL, identityClient)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize repositories
 	accountRepo := repository.NewAccountRepository(db)
@@ -88,43 +271,133 @@ func NewContainer(db *gorm.DB) (*Container, error) {
 	userRepo := repository.NewUserRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
 	categorizationRuleRepo := repository.NewCategorizationRuleRepository(db)
+	tagRepo := repository.NewTagRepository(db)
 	accountShareRepo := repository.NewAccountShareRepository(db)
+	statisticsRepo := repository.NewStatisticsRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	merchantRepo := repository.NewMerchantRepository(db)
+	backupRepo := repository.NewBackupRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	personalAccessTokenRepo := repository.NewPersonalAccessTokenRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	passwordResetTokenRepo := repository.NewPasswordResetTokenRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
 
 	// Initialize services
 	accountService := service.NewAccountService(accountRepo, transactionRepo)
 	categoryService := service.NewCategoryService(db)
-	transactionService := service.NewTransactionService(transactionRepo, accountRepo, categoryService)
-	userService := service.NewUserService(userRepo, jwtManager)
-	categorizationRuleService := service.NewCategorizationRuleService(categorizationRuleRepo)
+	transactionService := service.NewTransactionService(transactionRepo, accountRepo, categoryService, statisticsRepo, merchantRepo)
+	sessionService := service.NewSessionService(sessionRepo, userRepo, jwtManager, refreshTokenDuration)
+	twoFactorService := service.NewTwoFactorService(userRepo, auditLogRepo, recoveryCodeRepo, sessionRepo, sessionService, jwtManager)
+	userService := service.NewUserService(userRepo, auditLogRepo, userIdentityRepo, sessionService, twoFactorService, categoryService, jwtManager)
+	tagService := service.NewTagService(tagRepo, transactionRepo)
+	categorizationRuleService := service.NewCategorizationRuleService(categorizationRuleRepo, tagService)
 	accountShareService := service.NewAccountShareService(accountShareRepo, userRepo, accountRepo)
+	forecastService := service.NewForecastService(accountRepo, transactionRepo)
+	netWorthService := service.NewNetWorthService(accountRepo, statisticsRepo)
+	alertService := service.NewAlertService(alertRepo, transactionRepo, statisticsRepo, subscriptionRepo)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, transactionRepo)
+	merchantService := service.NewMerchantService(merchantRepo, transactionRepo)
+	taxReportService := service.NewTaxReportService(accountRepo, categoryRepo, statisticsRepo)
+	exportService := service.NewExportService(transactionRepo, accountRepo)
+	backupService := service.NewBackupService(backupRepo)
+	privacyService := service.NewPrivacyService(userRepo, accountShareRepo, alertRepo, sessionRepo, personalAccessTokenRepo, auditLogRepo, userIdentityRepo, backupService)
+	personalAccessTokenService := service.NewPersonalAccessTokenService(personalAccessTokenRepo, accountRepo)
+	passwordResetService := service.NewPasswordResetService(userRepo, passwordResetTokenRepo, auditLogRepo, sessionService, emailSender, appURL+"/reset-password")
+	oidcLoginService := service.NewOIDCLoginService(oidcProviders, userService, jwtManager)
 
 	// Initialize handlers
 	accountHandler := handlers.NewAccountHandler(accountService)
-	transactionHandler := handlers.NewTransactionHandler(transactionService, categoryService, categorizationRuleService)
-	userHandler := handlers.NewUserHandler(userService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, categoryService, categorizationRuleService, tagService, alertService)
+	userHandler := handlers.NewUserHandler(userService, googleVerifier)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
 	categorizationRuleHandler := handlers.NewCategorizationRuleHandler(categorizationRuleService)
+	tagHandler := handlers.NewTagHandler(tagService)
 	accountShareHandler := handlers.NewAccountShareHandler(accountShareService)
+	forecastHandler := handlers.NewForecastHandler(forecastService)
+	netWorthHandler := handlers.NewNetWorthHandler(netWorthService)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	merchantHandler := handlers.NewMerchantHandler(merchantService)
+	reportHandler := handlers.NewReportHandler(taxReportService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	backupHandler := handlers.NewBackupHandler(backupService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	personalAccessTokenHandler := handlers.NewPersonalAccessTokenHandler(personalAccessTokenService)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService)
+	oidcHandler := handlers.NewOIDCHandler(oidcLoginService)
 
 	return &Container{
-		AccountRepository:            accountRepo,
-		TransactionRepository:        transactionRepo,
-		UserRepository:               userRepo,
-		CategoryRepository:           categoryRepo,
-		CategorizationRuleRepository: categorizationRuleRepo,
-		AccountShareRepository:       accountShareRepo,
-		AccountService:               accountService,
-		TransactionService:           transactionService,
-		UserService:                  userService,
-		CategoryService:              categoryService,
-		CategorizationRuleService:    categorizationRuleService,
-		AccountShareService:          accountShareService,
-		JWTManager:                   jwtManager,
-		AccountHandler:               accountHandler,
-		TransactionHandler:           transactionHandler,
-		UserHandler:                  userHandler,
-		CategoryHandler:              categoryHandler,
-		CategorizationRuleHandler:    categorizationRuleHandler,
-		AccountShareHandler:          accountShareHandler,
+		AccountRepository:             accountRepo,
+		TransactionRepository:         transactionRepo,
+		UserRepository:                userRepo,
+		CategoryRepository:            categoryRepo,
+		CategorizationRuleRepository:  categorizationRuleRepo,
+		TagRepository:                 tagRepo,
+		AccountShareRepository:        accountShareRepo,
+		AlertRepository:               alertRepo,
+		SubscriptionRepository:        subscriptionRepo,
+		MerchantRepository:            merchantRepo,
+		BackupRepository:              backupRepo,
+		SessionRepository:             sessionRepo,
+		RecoveryCodeRepository:        recoveryCodeRepo,
+		PersonalAccessTokenRepository: personalAccessTokenRepo,
+		AuditLogRepository:            auditLogRepo,
+		PasswordResetTokenRepository:  passwordResetTokenRepo,
+		UserIdentityRepository:        userIdentityRepo,
+		AccountService:                accountService,
+		TransactionService:            transactionService,
+		UserService:                   userService,
+		CategoryService:               categoryService,
+		CategorizationRuleService:     categorizationRuleService,
+		TagService:                    tagService,
+		AccountShareService:           accountShareService,
+		ForecastService:               forecastService,
+		NetWorthService:               netWorthService,
+		AlertService:                  alertService,
+		SubscriptionService:           subscriptionService,
+		MerchantService:               merchantService,
+		TaxReportService:              taxReportService,
+		ExportService:                 exportService,
+		BackupService:                 backupService,
+		PrivacyService:                privacyService,
+		SessionService:                sessionService,
+		TwoFactorService:              twoFactorService,
+		PersonalAccessTokenService:    personalAccessTokenService,
+		PasswordResetService:          passwordResetService,
+		OIDCLoginService:              oidcLoginService,
+		JWTManager:                    jwtManager,
+		AuthIPLimiter:                 authIPLimiter,
+		LoginEmailLimiter:             loginEmailLimiter,
+		ResetEmailLimiter:             resetEmailLimiter,
+		TwoFactorUserLimiter:          twoFactorUserLimiter,
+		TrustedProxies:                trustedProxies,
+		Mailer:                        emailSender,
+		GoogleVerifier:                googleVerifier,
+		AccountHandler:                accountHandler,
+		TransactionHandler:            transactionHandler,
+		UserHandler:                   userHandler,
+		CategoryHandler:               categoryHandler,
+		CategorizationRuleHandler:     categorizationRuleHandler,
+		TagHandler:                    tagHandler,
+		AccountShareHandler:           accountShareHandler,
+		ForecastHandler:               forecastHandler,
+		NetWorthHandler:               netWorthHandler,
+		AlertHandler:                  alertHandler,
+		SubscriptionHandler:           subscriptionHandler,
+		MerchantHandler:               merchantHandler,
+		ReportHandler:                 reportHandler,
+		ExportHandler:                 exportHandler,
+		BackupHandler:                 backupHandler,
+		PrivacyHandler:                privacyHandler,
+		SessionHandler:                sessionHandler,
+		TwoFactorHandler:              twoFactorHandler,
+		PersonalAccessTokenHandler:    personalAccessTokenHandler,
+		PasswordResetHandler:          passwordResetHandler,
+		OIDCHandler:                   oidcHandler,
 	}, nil
 }
@@ -0,0 +1,19 @@
+package di
+
+import "testing"
+
+func TestGetTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8, 192.168.1.1,,")
+	proxies, err := getTrustedProxies()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(proxies) != 2 || proxies[0] != "10.0.0.0/8" || proxies[1] != "192.168.1.1" {
+		t.Errorf("Expected the listed proxies, got %v", proxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy.internal")
+	if _, err := getTrustedProxies(); err == nil {
+		t.Error("Expected a host name to be an invalid proxy")
+	}
+}
@@ -7,12 +7,24 @@ type CreateAccountRequest struct {
 	Type           models.AccountType `json:"type" binding:"required,oneof=checking savings credit_card cash"`
 	InitialBalance float64            `json:"initial_balance" binding:""`
 	Color          string             `json:"color" binding:"omitempty,hexcolor"`
+	BillingCycle
 }
 
+// UpdateAccountRequest updates an account. Billing cycle fields left out keep their value, and
+// ClearBillingCycle removes the card's billing cycle before the fields sent are set.
 type UpdateAccountRequest struct {
 	Name  string             `json:"name" binding:"required"`
 	Type  models.AccountType `json:"type" binding:"required,oneof=checking savings credit_card cash"`
 	Color string             `json:"color" binding:"omitempty,hexcolor"`
+	BillingCycle
+	ClearBillingCycle bool `json:"clear_billing_cycle"`
+}
+
+// BillingCycle configures how a credit card bill is closed and paid
+type BillingCycle struct {
+	StatementClosingDay *int  `json:"statement_closing_day" binding:"omitempty,min=1,max=31"`
+	PaymentDueDay       *int  `json:"payment_due_day" binding:"omitempty,min=1,max=31"`
+	PaymentAccountID    *uint `json:"payment_account_id"`
 }
 
 type AccountResponse struct {
@@ -25,6 +37,10 @@ type AccountResponse struct {
 	IsActive       bool               `json:"is_active"`
 	IsOwner        bool               `json:"is_owner"`
 	OwnerName      string             `json:"owner_name,omitempty"`
+
+	StatementClosingDay *int  `json:"statement_closing_day,omitempty"`
+	PaymentDueDay       *int  `json:"payment_due_day,omitempty"`
+	PaymentAccountID    *uint `json:"payment_account_id,omitempty"`
 }
 
 func ToAccountResponse(account *models.Account) AccountResponse {
@@ -37,6 +53,10 @@ func ToAccountResponse(account *models.Account) AccountResponse {
 		Color:          account.Color,
 		IsActive:       account.DeletedAt.Time.IsZero(),
 		IsOwner:        true, // Default to true, will be overridden by service if needed
+
+		StatementClosingDay: account.StatementClosingDay,
+		PaymentDueDay:       account.PaymentDueDay,
+		PaymentAccountID:    account.PaymentAccountID,
 	}
 }
 
@@ -51,6 +71,10 @@ func ToAccountResponseWithOwnership(account *models.Account, isOwner bool, owner
 		IsActive:       account.DeletedAt.Time.IsZero(),
 		IsOwner:        isOwner,
 		OwnerName:      ownerName,
+
+		StatementClosingDay: account.StatementClosingDay,
+		PaymentDueDay:       account.PaymentDueDay,
+		PaymentAccountID:    account.PaymentAccountID,
 	}
 }
 
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// AlertDTO represents the alert data sent in responses
+type AlertDTO struct {
+	ID            uint             `json:"id"`
+	Type          models.AlertType `json:"type"`
+	Message       string           `json:"message"`
+	Subject       string           `json:"subject"`
+	TransactionID *uint            `json:"transaction_id"`
+	Amount        float64          `json:"amount"`
+	Baseline      float64          `json:"baseline"`
+	Read          bool             `json:"read"`
+	Dismissed     bool             `json:"dismissed"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// ToAlertDTO converts a models.Alert to AlertDTO
+func ToAlertDTO(alert models.Alert) AlertDTO {
+	return AlertDTO{
+		ID:            alert.ID,
+		Type:          alert.Type,
+		Message:       alert.Message,
+		Subject:       alert.Subject,
+		TransactionID: alert.TransactionID,
+		Amount:        alert.Amount,
+		Baseline:      alert.Baseline,
+		Read:          alert.ReadAt != nil,
+		Dismissed:     alert.DismissedAt != nil,
+		CreatedAt:     alert.CreatedAt,
+	}
+}
+
+// ToAlertDTOs converts a slice of models.Alert to a slice of AlertDTO
+func ToAlertDTOs(alerts []models.Alert) []AlertDTO {
+	dtos := make([]AlertDTO, len(alerts))
+	for i, alert := range alerts {
+		dtos[i] = ToAlertDTO(alert)
+	}
+	return dtos
+}
@@ -0,0 +1,20 @@
+package dto
+
+// RestoreBackupResponse counts the records restored from a backup, or that would be restored
+// on a dry run
+type RestoreBackupResponse struct {
+	DryRun               bool `json:"dry_run"`
+	Accounts             int  `json:"accounts"`
+	Categories           int  `json:"categories"`
+	ReusedCategories     int  `json:"reused_categories"`
+	Tags                 int  `json:"tags"`
+	ReusedTags           int  `json:"reused_tags"`
+	Merchants            int  `json:"merchants"`
+	ReusedMerchants      int  `json:"reused_merchants"`
+	MerchantAliases      int  `json:"merchant_aliases"`
+	Transactions         int  `json:"transactions"`
+	Rules                int  `json:"rules"`
+	Subscriptions        int  `json:"subscriptions"`
+	SkippedSubscriptions int  `json:"skipped_subscriptions"`
+	ShareInvitations     int  `json:"share_invitations"`
+}
@@ -8,6 +8,7 @@ type CategorizationRuleDTO struct {
 	Value           string `json:"value"`
 	TransactionType string `json:"transaction_type"`
 	CategoryDst     uint   `json:"category_dst"`
+	TagIDs          []uint `json:"tag_ids"`
 	Active          bool   `json:"active"`
 	CreatedAt       string `json:"created_at"`
 	UpdatedAt       string `json:"updated_at"`
@@ -18,7 +19,8 @@ type CreateCategorizationRuleDTO struct {
 	Type            string `json:"type"`
 	Value           string `json:"value"`
 	TransactionType string `json:"transaction_type"`
-	CategoryDst     uint   `json:"category_dst"`
+	CategoryDst     uint   `json:"category_dst"` // 0 for rules that only add tags
+	TagIDs          []uint `json:"tag_ids"`
 	Active          *bool  `json:"active"`
 }
 
@@ -28,5 +30,6 @@ type UpdateCategorizationRuleDTO struct {
 	Value           *string `json:"value"`
 	TransactionType *string `json:"transaction_type"`
 	CategoryDst     *uint   `json:"category_dst"`
+	TagIDs          *[]uint `json:"tag_ids"`
 	Active          *bool   `json:"active"`
 }
@@ -8,6 +8,9 @@ type CategoryDTO struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	Type        models.TransactionType `json:"type"`
+	Deductible  bool                   `json:"deductible"`
+	ParentID    *uint                  `json:"parent_id,omitempty"`
+	Children    []CategoryDTO          `json:"children,omitempty"`
 }
 
 // ToCategoryDTO converts a models.Category to CategoryDTO
@@ -17,6 +20,8 @@ func ToCategoryDTO(category models.Category) CategoryDTO {
 		Name:        category.Name,
 		Description: category.Description,
 		Type:        category.Type,
+		Deductible:  category.Deductible,
+		ParentID:    category.ParentID,
 	}
 }
 
@@ -29,11 +34,52 @@ func ToCategoryDTOs(categories []models.Category) []CategoryDTO {
 	return dtos
 }
 
+// ToCategoryTree converts a flat slice of models.Category into a tree of CategoryDTO.
+// Categories whose parent is not in the slice are returned as roots.
+func ToCategoryTree(categories []models.Category) []CategoryDTO {
+	known := make(map[uint]bool, len(categories))
+	childrenOf := make(map[uint][]models.Category)
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID != nil && known[*category.ParentID] {
+			childrenOf[*category.ParentID] = append(childrenOf[*category.ParentID], category)
+			continue
+		}
+		roots = append(roots, category)
+	}
+
+	var build func(category models.Category, visited map[uint]bool) CategoryDTO
+	build = func(category models.Category, visited map[uint]bool) CategoryDTO {
+		node := ToCategoryDTO(category)
+		visited[category.ID] = true
+		for _, child := range childrenOf[category.ID] {
+			if visited[child.ID] {
+				continue
+			}
+			node.Children = append(node.Children, build(child, visited))
+		}
+		return node
+	}
+
+	visited := make(map[uint]bool, len(categories))
+	tree := make([]CategoryDTO, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root, visited))
+	}
+	return tree
+}
+
 // CreateCategoryRequest represents the request body for creating a category
 type CreateCategoryRequest struct {
 	Name        string                 `json:"name" binding:"required"`
 	Description string                 `json:"description"`
 	Type        models.TransactionType `json:"type" binding:"required,oneof=income expense transfer"`
+	Deductible  bool                   `json:"deductible"`
+	ParentID    *uint                  `json:"parent_id"`
 }
 
 // ToModel converts CreateCategoryRequest to models.Category
@@ -42,7 +88,9 @@ func (r *CreateCategoryRequest) ToModel(userID uint) models.Category {
 		Name:        r.Name,
 		Description: r.Description,
 		Type:        r.Type,
+		Deductible:  r.Deductible,
 		UserID:      userID,
+		ParentID:    r.ParentID,
 	}
 }
 
@@ -50,12 +98,24 @@ func (r *CreateCategoryRequest) ToModel(userID uint) models.Category {
 // Similar to CreateCategoryRequest but all fields required for now
 // You can make fields optional if needed
 type UpdateCategoryRequest struct {
-	Name string                 `json:"name" binding:"required"`
-	Type models.TransactionType `json:"type" binding:"required,oneof=income expense transfer"`
+	Name     string                 `json:"name" binding:"required"`
+	Type     models.TransactionType `json:"type" binding:"required,oneof=income expense transfer"`
+	ParentID *uint                  `json:"parent_id"`
+	// Deductible is kept when omitted
+	Deductible *bool `json:"deductible"`
 }
 
 // ToModel updates an existing category with the request data
 func (r *UpdateCategoryRequest) ApplyToModel(category *models.Category) {
 	category.Name = r.Name
 	category.Type = r.Type
+	category.ParentID = r.ParentID
+	if r.Deductible != nil {
+		category.Deductible = *r.Deductible
+	}
+}
+
+// MergeCategoriesRequest represents the request body for merging a category into another
+type MergeCategoriesRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
 }
@@ -0,0 +1,56 @@
+package dto
+
+import "github.com/LeonardsonCC/dinheiros/internal/models"
+
+// MerchantAliasDTO represents the merchant alias data sent in responses
+type MerchantAliasDTO struct {
+	ID         uint   `json:"id"`
+	MerchantID uint   `json:"merchant_id"`
+	Pattern    string `json:"pattern"`
+}
+
+// MerchantDTO represents the merchant data sent in responses
+type MerchantDTO struct {
+	ID      uint               `json:"id"`
+	Name    string             `json:"name"`
+	Aliases []MerchantAliasDTO `json:"aliases"`
+}
+
+// ToMerchantAliasDTO converts a models.MerchantAlias to MerchantAliasDTO
+func ToMerchantAliasDTO(alias models.MerchantAlias) MerchantAliasDTO {
+	return MerchantAliasDTO{
+		ID:         alias.ID,
+		MerchantID: alias.MerchantID,
+		Pattern:    alias.Pattern,
+	}
+}
+
+// ToMerchantDTO converts a models.Merchant to MerchantDTO
+func ToMerchantDTO(merchant models.Merchant) MerchantDTO {
+	aliases := make([]MerchantAliasDTO, len(merchant.Aliases))
+	for i, alias := range merchant.Aliases {
+		aliases[i] = ToMerchantAliasDTO(alias)
+	}
+	return MerchantDTO{
+		ID:      merchant.ID,
+		Name:    merchant.Name,
+		Aliases: aliases,
+	}
+}
+
+// ToMerchantDTOs converts a slice of models.Merchant to a slice of MerchantDTO
+func ToMerchantDTOs(merchants []models.Merchant) []MerchantDTO {
+	dtos := make([]MerchantDTO, len(merchants))
+	for i, merchant := range merchants {
+		dtos[i] = ToMerchantDTO(merchant)
+	}
+	return dtos
+}
+
+// CreateMerchantAliasRequest represents the request body for creating a merchant alias
+type CreateMerchantAliasRequest struct {
+	// Pattern is the text descriptions must contain, ignoring case and accents
+	Pattern string `json:"pattern" binding:"required,max=255"`
+	// Name is the merchant the matching transactions are assigned to, created when needed
+	Name string `json:"name" binding:"required,max=255"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+// OIDCProviderResponse is an OpenID Connect provider users can log in with
+type OIDCProviderResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// LoginURL is where the browser goes to log in with the provider
+	LoginURL string `json:"login_url"`
+}
+
+// OIDCCallbackRequest represents the request body completing a login with an OpenID Connect
+// provider, with the query parameters the provider sent the user back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
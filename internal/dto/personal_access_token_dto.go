@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// CreatePersonalAccessTokenRequest represents the request body for creating a personal access token
+type CreatePersonalAccessTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Scopes are read:transactions, write:transactions, import and read:statistics
+	Scopes []models.TokenScope `json:"scopes" binding:"required"`
+	// AccountID restricts the token to one account
+	AccountID *uint `json:"account_id"`
+	// ExpiresAt is omitted for tokens that don't expire
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PersonalAccessTokenResponse represents a personal access token, without the token itself
+type PersonalAccessTokenResponse struct {
+	ID         uint                `json:"id"`
+	Name       string              `json:"name"`
+	Prefix     string              `json:"prefix"`
+	Scopes     []models.TokenScope `json:"scopes"`
+	AccountID  *uint               `json:"account_id,omitempty"`
+	ExpiresAt  *time.Time          `json:"expires_at"`
+	LastUsedAt *time.Time          `json:"last_used_at"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// CreatePersonalAccessTokenResponse represents a new personal access token. Token is only
+// returned here and is sent as a Bearer token.
+type CreatePersonalAccessTokenResponse struct {
+	PersonalAccessTokenResponse
+	Token string `json:"token"`
+}
+
+// ToPersonalAccessTokenResponse converts a personal access token to a response
+func ToPersonalAccessTokenResponse(token *models.PersonalAccessToken) PersonalAccessTokenResponse {
+	return PersonalAccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		AccountID:  token.AccountID,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+// ToPersonalAccessTokenResponses converts personal access tokens to responses
+func ToPersonalAccessTokenResponses(tokens []models.PersonalAccessToken) []PersonalAccessTokenResponse {
+	responses := make([]PersonalAccessTokenResponse, len(tokens))
+	for i := range tokens {
+		responses[i] = ToPersonalAccessTokenResponse(&tokens[i])
+	}
+	return responses
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// RefreshTokenRequest represents the request body for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SessionResponse represents a device the user is logged in on
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is the session of the token making the request
+	Current bool `json:"current"`
+}
+
+// RevokeSessionsResponse represents the result of logging out of all sessions
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// ToSessionResponses converts sessions to responses, marking the current one
+func ToSessionResponses(sessions []models.Session, currentID uint) []SessionResponse {
+	responses := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,9 @@
+package dto
+
+import "github.com/LeonardsonCC/dinheiros/internal/models"
+
+// UpdateSubscriptionStatusRequest represents the request body for reviewing a subscription
+type UpdateSubscriptionStatusRequest struct {
+	// Status is one of confirmed, ignored or cancelled
+	Status models.SubscriptionStatus `json:"status" binding:"required"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "github.com/LeonardsonCC/dinheiros/internal/models"
+
+// TagDTO represents the tag data sent in responses
+type TagDTO struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// ToTagDTO converts a models.Tag to TagDTO
+func ToTagDTO(tag models.Tag) TagDTO {
+	return TagDTO{
+		ID:    tag.ID,
+		Name:  tag.Name,
+		Color: tag.Color,
+	}
+}
+
+// ToTagDTOs converts a slice of models.Tag to a slice of TagDTO
+func ToTagDTOs(tags []models.Tag) []TagDTO {
+	dtos := make([]TagDTO, len(tags))
+	for i, tag := range tags {
+		dtos[i] = ToTagDTO(tag)
+	}
+	return dtos
+}
+
+// CreateTagRequest represents the request body for creating or updating a tag
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Color string `json:"color" binding:"max=20"`
+}
+
+// ToModel converts the request to a models.Tag
+func (r *CreateTagRequest) ToModel(userID uint) *models.Tag {
+	return &models.Tag{
+		Name:   r.Name,
+		Color:  r.Color,
+		UserID: userID,
+	}
+}
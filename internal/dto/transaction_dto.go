@@ -10,6 +10,8 @@ type ListTransactionsRequest struct {
 	Types       []models.TransactionType `form:"types"`
 	AccountIDs  []uint                   `form:"account_ids"`
 	CategoryIDs []uint                   `form:"category_ids"`
+	TagIDs      []uint                   `form:"tag_ids"`
+	MerchantIDs []uint                   `form:"merchant_ids"`
 	Description string                   `form:"description"`
 	MinAmount   *float64                 `form:"min_amount"`
 	MaxAmount   *float64                 `form:"max_amount"`
@@ -19,6 +21,22 @@ type ListTransactionsRequest struct {
 	PageSize    int                      `form:"page_size,default=20" binding:"min=1,max=100"`
 }
 
+// ExportTransactionsRequest has the filters of ListTransactionsRequest without the pagination,
+// since exports include every matching transaction
+type ExportTransactionsRequest struct {
+	Format      string                   `form:"format,default=csv" binding:"oneof=csv ofx ndjson"`
+	Types       []models.TransactionType `form:"types"`
+	AccountIDs  []uint                   `form:"account_ids"`
+	CategoryIDs []uint                   `form:"category_ids"`
+	TagIDs      []uint                   `form:"tag_ids"`
+	MerchantIDs []uint                   `form:"merchant_ids"`
+	Description string                   `form:"description"`
+	MinAmount   *float64                 `form:"min_amount"`
+	MaxAmount   *float64                 `form:"max_amount"`
+	StartDate   *time.Time               `form:"start_date" time_format:"2006-01-02"`
+	EndDate     *time.Time               `form:"end_date" time_format:"2006-01-02"`
+}
+
 type PaginationMeta struct {
 	CurrentPage int   `json:"current_page"`
 	PageSize    int   `json:"page_size"`
@@ -32,13 +50,38 @@ type ListTransactionsResponse struct {
 }
 
 type CreateTransactionRequest struct {
-	Date                  string                 `json:"date" binding:"required"`
-	Amount                float64                `json:"amount" binding:"required,gt=0"`
-	Type                  models.TransactionType `json:"type" binding:"required,oneof=income expense"`
-	Description           string                 `json:"description"`
-	CategoryIDs           []uint                 `json:"category_ids"`
-	ToAccountID           *uint                  `json:"to_account_id,omitempty"`
-	AttachedTransactionID *uint                  `json:"attached_transaction_id,omitempty"`
+	Date                  string                    `json:"date" binding:"required"`
+	Amount                float64                   `json:"amount" binding:"required,gt=0"`
+	Type                  models.TransactionType    `json:"type" binding:"required,oneof=income expense"`
+	Description           string                    `json:"description"`
+	CategoryIDs           []uint                    `json:"category_ids"`
+	TagIDs                []uint                    `json:"tag_ids"`
+	ToAccountID           *uint                     `json:"to_account_id,omitempty"`
+	AttachedTransactionID *uint                     `json:"attached_transaction_id,omitempty"`
+	Splits                []TransactionSplitRequest `json:"splits,omitempty"`
+}
+
+type TransactionSplitRequest struct {
+	CategoryID uint    `json:"category_id" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	Memo       string  `json:"memo"`
+}
+
+type SetTransactionSplitsRequest struct {
+	Splits []TransactionSplitRequest `json:"splits" binding:"dive"`
+}
+
+// ToTransactionSplits converts the split requests into models
+func ToTransactionSplits(requests []TransactionSplitRequest) []models.TransactionSplit {
+	splits := make([]models.TransactionSplit, len(requests))
+	for i, req := range requests {
+		splits[i] = models.TransactionSplit{
+			CategoryID: req.CategoryID,
+			Amount:     req.Amount,
+			Memo:       req.Memo,
+		}
+	}
+	return splits
 }
 
 type CategoryResponse struct {
@@ -46,6 +89,26 @@ type CategoryResponse struct {
 	Name string `json:"name"`
 }
 
+// MerchantResponse is the clean name of the merchant found in the raw description
+type MerchantResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type TagResponse struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type TransactionSplitResponse struct {
+	ID           uint    `json:"id"`
+	CategoryID   uint    `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Amount       float64 `json:"amount"`
+	Memo         string  `json:"memo"`
+}
+
 type AttachedTransactionResponse struct {
 	ID          uint            `json:"id"`
 	Amount      float64         `json:"amount"`
@@ -60,9 +123,13 @@ type TransactionResponse struct {
 	Amount      float64            `json:"amount"`
 	Type        string             `json:"type"`
 	Description string             `json:"description"`
+	Merchant    *MerchantResponse  `json:"merchant,omitempty"`
 	Categories  []CategoryResponse `json:"categories"`
 	Account     AccountResponse    `json:"account"`
 
+	Splits []TransactionSplitResponse `json:"splits,omitempty"`
+	Tags   []TagResponse              `json:"tags,omitempty"`
+
 	AttachedTransaction *AttachedTransactionResponse `json:"attached_transaction,omitempty"`
 	AttachmentType      *string                      `json:"attachment_type,omitempty"`
 }
@@ -76,6 +143,25 @@ func ToTransactionResponse(transaction *models.Transaction) TransactionResponse
 		}
 	}
 
+	var splits []TransactionSplitResponse
+	for _, split := range transaction.Splits {
+		response := TransactionSplitResponse{
+			ID:         split.ID,
+			CategoryID: split.CategoryID,
+			Amount:     split.Amount,
+			Memo:       split.Memo,
+		}
+		if split.Category != nil {
+			response.CategoryName = split.Category.Name
+		}
+		splits = append(splits, response)
+	}
+
+	var tags []TagResponse
+	for _, tag := range transaction.Tags {
+		tags = append(tags, TagResponse{ID: tag.ID, Name: tag.Name, Color: tag.Color})
+	}
+
 	var attachedTransaction *AttachedTransactionResponse
 	if transaction.AttachedTransaction != nil {
 		attachedTransaction = &AttachedTransactionResponse{
@@ -87,6 +173,11 @@ func ToTransactionResponse(transaction *models.Transaction) TransactionResponse
 		}
 	}
 
+	var merchant *MerchantResponse
+	if transaction.Merchant != nil {
+		merchant = &MerchantResponse{ID: transaction.Merchant.ID, Name: transaction.Merchant.Name}
+	}
+
 	var attachmentType *string
 	if transaction.AttachmentType != nil {
 		typeStr := string(*transaction.AttachmentType)
@@ -99,9 +190,13 @@ func ToTransactionResponse(transaction *models.Transaction) TransactionResponse
 		Amount:      transaction.Amount,
 		Type:        string(transaction.Type),
 		Description: transaction.Description,
+		Merchant:    merchant,
 		Categories:  categories,
 		Account:     ToAccountResponse(&transaction.Account),
 
+		Splits: splits,
+		Tags:   tags,
+
 		AttachedTransaction: attachedTransaction,
 		AttachmentType:      attachmentType,
 	}
@@ -120,6 +215,8 @@ type SearchTransactionsRequest struct {
 	Types       []models.TransactionType `form:"types"`
 	AccountIDs  []uint                   `form:"account_ids"`
 	CategoryIDs []uint                   `form:"category_ids"`
+	TagIDs      []uint                   `form:"tag_ids"`
+	MerchantIDs []uint                   `form:"merchant_ids"`
 	Description string                   `form:"description"`
 	MinAmount   float64                  `form:"min_amount"`
 	MaxAmount   float64                  `form:"max_amount"`
@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// TwoFactorChallengeResponse is returned by logins of users with two-factor authentication
+// instead of tokens
+type TwoFactorChallengeResponse struct {
+	Message           string `json:"message"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	// ChallengeToken is sent to /auth/2fa with the code before ExpiresAt
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest represents the request body for the second login step
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	// Code is the six-digit code of the authenticator app or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest represents the request body for enabling 2FA or verifying a code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request body for disabling 2FA
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// TwoFactorStatusResponse represents whether the user has 2FA
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetupResponse represents the secret to add to an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth:// URI to show as a QR code
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse represents new recovery codes, which are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,12 +1,19 @@
 package dto
 
-import "github.com/LeonardsonCC/dinheiros/internal/models"
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
 
 // RegisterRequest represents the request body for user registration
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	// CategoryTemplate selects the starter categories ("pt-BR", "en" or "none"). Defaults to "pt-BR".
+	CategoryTemplate string `json:"category_template"`
 }
 
 // LoginRequest represents the request body for user login
@@ -17,33 +24,51 @@ type LoginRequest struct {
 
 // UserResponse represents the user data in API responses
 type UserResponse struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Timezone string `json:"timezone"`
+	// TwoFactorEnabled is set when logging in asks for a code from an authenticator app
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
 }
 
 // AuthResponse represents the authentication response with token and user data
 type AuthResponse struct {
-	Message string       `json:"message"`
-	Token   string       `json:"token"`
-	User    UserResponse `json:"user"`
+	Message string `json:"message"`
+	// Token is the access token, sent as a Bearer token until ExpiresAt
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// RefreshToken is exchanged for new tokens at /auth/refresh. Each exchange returns a new
+	// refresh token and the old one stops working.
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  UserResponse `json:"user"`
 }
 
 // ToUserResponse converts a user model to a UserResponse DTO
 func ToUserResponse(user *models.User) *UserResponse {
+	timezone := user.Timezone
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
 	return &UserResponse{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
+		ID:               user.ID,
+		Name:             user.Name,
+		Email:            user.Email,
+		Timezone:         timezone,
+		TwoFactorEnabled: user.TwoFactorEnabled,
 	}
 }
 
-// ToAuthResponse creates an authentication response with token and user data
-func ToAuthResponse(message, token string, user *models.User) *AuthResponse {
+// ToAuthResponse creates an authentication response with tokens and user data
+func ToAuthResponse(message string, tokens *auth.TokenPair, user *models.User) *AuthResponse {
 	return &AuthResponse{
-		Message: message,
-		Token:   token,
-		User:    *ToUserResponse(user),
+		Message:               message,
+		Token:                 tokens.AccessToken,
+		ExpiresAt:             tokens.AccessTokenExpiresAt,
+		RefreshToken:          tokens.RefreshToken,
+		RefreshTokenExpiresAt: tokens.RefreshTokenExpiresAt,
+		User:                  *ToUserResponse(user),
 	}
 }
 
@@ -52,6 +77,12 @@ type UpdateNameRequest struct {
 	Name string `json:"name" binding:"required,min=2"`
 }
 
+// UpdateTimezoneRequest represents the request body for updating a user's timezone
+type UpdateTimezoneRequest struct {
+	// Timezone is an IANA timezone name, such as "America/Sao_Paulo"
+	Timezone string `json:"timezone" binding:"required"`
+}
+
 // UpdatePasswordRequest represents the request body for updating a user's password
 type UpdatePasswordRequest struct {
 	CurrentPassword string `json:"currentPassword" binding:"required,min=6"`
@@ -61,4 +92,12 @@ type UpdatePasswordRequest struct {
 // GoogleLoginRequest represents the request body for Google OAuth login
 type GoogleLoginRequest struct {
 	Credential string `json:"credential" binding:"required"`
+	// CategoryTemplate is only used when the Google login creates a new user
+	CategoryTemplate string `json:"category_template"`
+}
+
+// DeleteAccountRequest represents the request body for deleting the user's account
+type DeleteAccountRequest struct {
+	// Password confirms the deletion
+	Password string `json:"password" binding:"required"`
 }
@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// IdentityLinkResponse is returned by logins with an identity provider whose email belongs to
+// a user the identity isn't linked to yet
+type IdentityLinkResponse struct {
+	Message      string `json:"message"`
+	LinkRequired bool   `json:"link_required"`
+	// LinkToken is sent to /auth/link with the user's password before ExpiresAt
+	LinkToken string    `json:"link_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+}
+
+// LinkIdentityRequest represents the request body for linking an identity to the user with
+// its email
+type LinkIdentityRequest struct {
+	LinkToken string `json:"link_token" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+}
+
+// UserIdentityResponse is an identity provider's account linked to the user
+type UserIdentityResponse struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ToUserIdentityResponses converts linked identities to responses
+func ToUserIdentityResponses(identities []models.UserIdentity) []UserIdentityResponse {
+	responses := make([]UserIdentityResponse, len(identities))
+	for i, identity := range identities {
+		responses[i] = UserIdentityResponse{
+			ID:          identity.ID,
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			LastLoginAt: identity.LastLoginAt,
+			CreatedAt:   identity.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -1,6 +1,9 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// Common errors
@@ -11,6 +14,7 @@ var (
 	ErrSameAccountTransfer = errors.New("transfer to the same account is not allowed")
 	ErrFromAccountNotFound = errors.New("source account not found")
 	ErrToAccountNotFound   = errors.New("destination account not found")
+	ErrCategoryInUse       = errors.New("category is in use, choose a replacement category")
 )
 
 // ValidationError represents a validation error
@@ -34,6 +38,15 @@ func (e *NotFoundError) Error() string {
 	return e.Message
 }
 
+// LockedError is returned by logins to an account locked after too many wrong passwords
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "account is locked until " + e.Until.UTC().Format(time.RFC3339)
+}
+
 // ErrorResponse represents an error response to the client
 type ErrorResponse struct {
 	Error string `json:"error"`
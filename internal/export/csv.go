@@ -0,0 +1,102 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+var csvHeader = []string{
+	"id", "date", "type", "amount", "description", "merchant", "account", "categories", "tags",
+	"splits", "attached_transaction_id", "attachment_type", "attached_account",
+}
+
+// CSVWriter writes a header and one row per transaction. Categories and tags are joined with
+// "; " and splits are written as "Category: amount".
+type CSVWriter struct {
+	writer        *csv.Writer
+	loc           *time.Location
+	headerWritten bool
+}
+
+func NewCSVWriter(w io.Writer, loc *time.Location) *CSVWriter {
+	return &CSVWriter{writer: csv.NewWriter(w), loc: loc}
+}
+
+func (c *CSVWriter) Write(transaction *models.Transaction) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	categories := make([]string, len(transaction.Categories))
+	for i, category := range transaction.Categories {
+		categories[i] = category.Name
+	}
+	tags := make([]string, len(transaction.Tags))
+	for i, tag := range transaction.Tags {
+		tags[i] = tag.Name
+	}
+	splits := make([]string, len(transaction.Splits))
+	for i, split := range transaction.Splits {
+		name := strconv.FormatUint(uint64(split.CategoryID), 10)
+		if split.Category != nil {
+			name = split.Category.Name
+		}
+		splits[i] = name + ": " + formatAmount(split.Amount)
+	}
+	merchant := ""
+	if transaction.Merchant != nil {
+		merchant = transaction.Merchant.Name
+	}
+	attachedID, attachmentType, attachedAccount := "", "", ""
+	if transaction.AttachedTransactionID != nil {
+		attachedID = strconv.FormatUint(uint64(*transaction.AttachedTransactionID), 10)
+	}
+	if transaction.AttachmentType != nil {
+		attachmentType = string(*transaction.AttachmentType)
+	}
+	if transaction.AttachedTransaction != nil {
+		attachedAccount = transaction.AttachedTransaction.Account.Name
+	}
+
+	return c.writer.Write([]string{
+		strconv.FormatUint(uint64(transaction.ID), 10),
+		transaction.Date.In(c.loc).Format("2006-01-02"),
+		string(transaction.Type),
+		formatAmount(transaction.Amount),
+		transaction.Description,
+		merchant,
+		transaction.Account.Name,
+		strings.Join(categories, "; "),
+		strings.Join(tags, "; "),
+		strings.Join(splits, "; "),
+		attachedID,
+		attachmentType,
+		attachedAccount,
+	})
+}
+
+// Close writes the header of an empty export and flushes the buffered rows
+func (c *CSVWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *CSVWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.writer.Write(csvHeader)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,46 @@
+package export_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/export"
+)
+
+func TestCSVWriter(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("Timezone database not available: %v", err)
+	}
+
+	var out bytes.Buffer
+	writer := export.NewCSVWriter(&out, saoPaulo)
+	for _, transaction := range testTransactions() {
+		if err := writer.Write(transaction); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The first expense was made late in the evening of the day before in Brazil
+	want := `id,date,type,amount,description,merchant,account,categories,tags,splits,attached_transaction_id,attachment_type,attached_account
+7,2025-02-28,expense,80.00,"COMPRA CARTAO ""DROGARIA"" & CIA",Drogaria,Conta Corrente,Saúde; Casa,reembolsável,Saúde: 60.00; Casa: 20.00,,,
+10,2025-03-10,expense,320.50,Pagamento da fatura,,Conta Corrente,,,,11,outbound_transfer,Cartão
+`
+	if out.String() != want {
+		t.Errorf("Unexpected CSV:\n%s", out.String())
+	}
+}
+
+func TestCSVWriter_Empty(t *testing.T) {
+	var out bytes.Buffer
+	if err := export.NewCSVWriter(&out, time.UTC).Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("id,date,type,")) || bytes.Count(out.Bytes(), []byte("\n")) != 1 {
+		t.Errorf("Expected only the header, got %q", out.String())
+	}
+}
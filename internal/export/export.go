@@ -0,0 +1,64 @@
+// Package export writes transactions to the file formats other finance tools import. Writers
+// stream, so an export of any size only keeps the transaction being written in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatOFX    Format = "ofx"
+	FormatNDJSON Format = "ndjson"
+)
+
+// ContentType is the MIME type of files in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
+
+// Writer writes transactions one at a time. Transactions need their Account, Merchant,
+// Categories, Splits, Tags and AttachedTransaction loaded. Close finishes the file, it
+// doesn't close the underlying writer.
+type Writer interface {
+	Write(transaction *models.Transaction) error
+	Close() error
+}
+
+// AccountWriter is a Writer for formats that group transactions by account. StartAccount is
+// called before the transactions of each account, with the dates of its first and last ones.
+type AccountWriter interface {
+	Writer
+	StartAccount(account models.Account, start, end time.Time) error
+}
+
+// NewWriter returns a writer of the format. Dates are written in loc, or UTC when it's nil.
+func NewWriter(format Format, w io.Writer, loc *time.Location) (Writer, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w, loc), nil
+	case FormatOFX:
+		return NewOFXWriter(w, loc, time.Now()), nil
+	case FormatNDJSON:
+		return NewNDJSONWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
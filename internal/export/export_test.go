@@ -0,0 +1,58 @@
+package export_test
+
+import (
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+var (
+	checking = models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, Balance: 1500}
+	card     = models.Account{Name: "Cartão", Type: models.AccountTypeCredit, Balance: -320.5}
+)
+
+func init() {
+	checking.ID = 1
+	card.ID = 2
+}
+
+// testTransactions returns an expense with categories, tags and splits, and the outbound side
+// of a transfer paying the card
+func testTransactions() []*models.Transaction {
+	health := &models.Category{Name: "Saúde"}
+	home := &models.Category{Name: "Casa"}
+	outbound := models.AttachmentTypeOutboundTransfer
+	attachedID := uint(11)
+
+	expense := &models.Transaction{
+		Date:        time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC),
+		Amount:      80,
+		Type:        models.TransactionTypeExpense,
+		Description: `COMPRA CARTAO "DROGARIA" & CIA`,
+		AccountID:   checking.ID,
+		Account:     checking,
+		Merchant:    &models.Merchant{Name: "Drogaria"},
+		Categories:  []*models.Category{health, home},
+		Tags:        []*models.Tag{{Name: "reembolsável"}},
+		Splits: []models.TransactionSplit{
+			{CategoryID: 3, Category: health, Amount: 60},
+			{CategoryID: 4, Category: home, Amount: 20},
+		},
+	}
+	expense.ID = 7
+
+	transfer := &models.Transaction{
+		Date:                  time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		Amount:                320.5,
+		Type:                  models.TransactionTypeExpense,
+		Description:           "Pagamento da fatura",
+		AccountID:             checking.ID,
+		Account:               checking,
+		AttachedTransactionID: &attachedID,
+		AttachedTransaction:   &models.Transaction{Account: card},
+		AttachmentType:        &outbound,
+	}
+	transfer.ID = 10
+
+	return []*models.Transaction{expense, transfer}
+}
@@ -0,0 +1,28 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// NDJSONWriter writes one JSON object per line, shaped like the transactions of the
+// transaction list endpoints
+type NDJSONWriter struct {
+	encoder *json.Encoder
+}
+
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{encoder: json.NewEncoder(w)}
+}
+
+func (n *NDJSONWriter) Write(transaction *models.Transaction) error {
+	// Encode ends every value with a newline
+	return n.encoder.Encode(dto.ToTransactionResponse(transaction))
+}
+
+func (n *NDJSONWriter) Close() error {
+	return nil
+}
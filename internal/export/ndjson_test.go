@@ -0,0 +1,43 @@
+package export_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/export"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := export.NewNDJSONWriter(&out)
+	for _, transaction := range testTransactions() {
+		if err := writer.Write(transaction); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var lines []dto.TransactionResponse
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var line dto.TransactionResponse
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].ID != 7 || lines[0].Account.Name != "Conta Corrente" || len(lines[0].Categories) != 2 || len(lines[0].Splits) != 2 {
+		t.Errorf("Unexpected first transaction: %+v", lines[0])
+	}
+	if lines[1].AttachedTransaction == nil || lines[1].AttachedTransaction.Account.Name != "Cartão" {
+		t.Errorf("Expected the attached transaction, got %+v", lines[1].AttachedTransaction)
+	}
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+const ofxStatus = "<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>"
+
+// ofxNameLength is the longest payee name OFX allows, the full description goes in the memo
+const ofxNameLength = 32
+
+// OFXWriter writes an OFX 2.2 file with one statement per account. Bank accounts are written
+// in the bank message set and credit cards in the credit card one, so all bank accounts must
+// be started before the credit cards.
+type OFXWriter struct {
+	writer  *bufio.Writer
+	loc     *time.Location
+	now     time.Time
+	started bool
+	// messageSet is the open message set, such as "BANKMSGSRSV1"
+	messageSet string
+	account    *models.Account
+	statements int
+}
+
+func NewOFXWriter(w io.Writer, loc *time.Location, now time.Time) *OFXWriter {
+	return &OFXWriter{writer: bufio.NewWriter(w), loc: loc, now: now}
+}
+
+func (o *OFXWriter) StartAccount(account models.Account, start, end time.Time) error {
+	o.writeHeader()
+	o.endStatement()
+
+	messageSet := "BANKMSGSRSV1"
+	if account.Type == models.AccountTypeCredit {
+		messageSet = "CREDITCARDMSGSRSV1"
+	}
+	if messageSet != o.messageSet {
+		if o.messageSet == "CREDITCARDMSGSRSV1" {
+			return fmt.Errorf("bank account %d started after the credit cards", account.ID)
+		}
+		o.endMessageSet()
+		fmt.Fprintf(o.writer, "<%s>\n", messageSet)
+		o.messageSet = messageSet
+	}
+
+	o.statements++
+	o.account = &account
+	accountID := strconv.FormatUint(uint64(account.ID), 10)
+	if account.Type == models.AccountTypeCredit {
+		fmt.Fprintf(o.writer, "<CCSTMTTRNRS><TRNUID>%d</TRNUID>%s<CCSTMTRS><CURDEF>BRL</CURDEF>\n", o.statements, ofxStatus)
+		fmt.Fprintf(o.writer, "<CCACCTFROM><ACCTID>%s</ACCTID></CCACCTFROM>\n", accountID)
+	} else {
+		fmt.Fprintf(o.writer, "<STMTTRNRS><TRNUID>%d</TRNUID>%s<STMTRS><CURDEF>BRL</CURDEF>\n", o.statements, ofxStatus)
+		fmt.Fprintf(o.writer, "<BANKACCTFROM><BANKID>0000</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>\n", accountID, ofxAccountType(account.Type))
+	}
+	fmt.Fprintf(o.writer, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", o.date(start), o.date(end))
+	return nil
+}
+
+func (o *OFXWriter) Write(transaction *models.Transaction) error {
+	if o.account == nil || o.account.ID != transaction.AccountID {
+		return fmt.Errorf("transaction %d written outside the statement of its account", transaction.ID)
+	}
+
+	transactionType, amount := "CREDIT", transaction.Amount
+	if transaction.Type == models.TransactionTypeExpense {
+		transactionType, amount = "DEBIT", -transaction.Amount
+	}
+	if transaction.AttachmentType != nil {
+		transactionType = "XFER"
+	}
+	name := transaction.Description
+	if transaction.Merchant != nil {
+		name = transaction.Merchant.Name
+	}
+	if runes := []rune(name); len(runes) > ofxNameLength {
+		name = string(runes[:ofxNameLength])
+	}
+
+	fmt.Fprintf(o.writer, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%d</FITID>",
+		transactionType, o.date(transaction.Date), formatAmount(amount), transaction.ID)
+	if name != "" {
+		fmt.Fprintf(o.writer, "<NAME>%s</NAME>", ofxEscape(name))
+	}
+	if transaction.Description != "" {
+		fmt.Fprintf(o.writer, "<MEMO>%s</MEMO>", ofxEscape(transaction.Description))
+	}
+	_, err := o.writer.WriteString("</STMTTRN>\n")
+	return err
+}
+
+func (o *OFXWriter) Close() error {
+	o.writeHeader()
+	o.endStatement()
+	o.endMessageSet()
+	o.writer.WriteString("</OFX>\n")
+	return o.writer.Flush()
+}
+
+func (o *OFXWriter) writeHeader() {
+	if o.started {
+		return
+	}
+	o.started = true
+	o.writer.WriteString(ofxHeader)
+	fmt.Fprintf(o.writer, "<OFX>\n<SIGNONMSGSRSV1><SONRS>%s<DTSERVER>%s</DTSERVER><LANGUAGE>POR</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n",
+		ofxStatus, o.dateTime(o.now))
+}
+
+// endStatement closes the statement of the current account with its balance
+func (o *OFXWriter) endStatement() {
+	if o.account == nil {
+		return
+	}
+	fmt.Fprintf(o.writer, "</BANKTRANLIST>\n<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n",
+		formatAmount(o.account.Balance), o.dateTime(o.now))
+	if o.account.Type == models.AccountTypeCredit {
+		o.writer.WriteString("</CCSTMTRS></CCSTMTTRNRS>\n")
+	} else {
+		o.writer.WriteString("</STMTRS></STMTTRNRS>\n")
+	}
+	o.account = nil
+}
+
+func (o *OFXWriter) endMessageSet() {
+	if o.messageSet != "" {
+		fmt.Fprintf(o.writer, "</%s>\n", o.messageSet)
+	}
+}
+
+func (o *OFXWriter) date(date time.Time) string {
+	return date.In(o.loc).Format("20060102")
+}
+
+// dateTime formats a time with its offset from UTC in hours, as in "20251018093000[-3]"
+func (o *OFXWriter) dateTime(date time.Time) string {
+	local := date.In(o.loc)
+	_, offset := local.Zone()
+	return fmt.Sprintf("%s[%s]", local.Format("20060102150405"), strconv.FormatFloat(float64(offset)/3600, 'f', -1, 64))
+}
+
+func ofxAccountType(accountType models.AccountType) string {
+	if accountType == models.AccountTypeSavings {
+		return "SAVINGS"
+	}
+	return "CHECKING"
+}
+
+func ofxEscape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
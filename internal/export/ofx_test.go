@@ -0,0 +1,82 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/export"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+func TestOFXWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := export.NewOFXWriter(&out, time.UTC, time.Date(2025, 10, 18, 9, 30, 0, 0, time.UTC))
+
+	transactions := testTransactions()
+	if err := writer.StartAccount(checking, transactions[0].Date, transactions[1].Date); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, transaction := range transactions {
+		if err := writer.Write(transaction); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	payment := &models.Transaction{Date: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC), Amount: 320.5, Type: models.TransactionTypeIncome, AccountID: card.ID}
+	payment.ID = 11
+	if err := writer.StartAccount(card, payment.Date, payment.Date); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := writer.Write(payment); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The file must be well-formed XML
+	decoder := xml.NewDecoder(bytes.NewReader(out.Bytes()))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Invalid XML: %v\n%s", err, out.String())
+		}
+	}
+
+	text := out.String()
+	for _, expected := range []string{
+		"<DTSERVER>20251018093000[0]</DTSERVER>",
+		"<BANKMSGSRSV1>\n<STMTTRNRS>",
+		"<ACCTID>1</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE>",
+		"<DTSTART>20250301</DTSTART><DTEND>20250310</DTEND>",
+		"<TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20250301</DTPOSTED><TRNAMT>-80.00</TRNAMT><FITID>7</FITID><NAME>Drogaria</NAME><MEMO>COMPRA CARTAO &#34;DROGARIA&#34; &amp; CIA</MEMO>",
+		"<TRNTYPE>XFER</TRNTYPE>",
+		"<LEDGERBAL><BALAMT>1500.00</BALAMT>",
+		"</BANKMSGSRSV1>\n<CREDITCARDMSGSRSV1>",
+		"<CCACCTFROM><ACCTID>2</ACCTID></CCACCTFROM>",
+		"<TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20250310</DTPOSTED><TRNAMT>320.50</TRNAMT><FITID>11</FITID></STMTTRN>",
+		"</CREDITCARDMSGSRSV1>\n</OFX>",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected OFX to contain %q, got:\n%s", expected, text)
+		}
+	}
+}
+
+func TestOFXWriter_Order(t *testing.T) {
+	writer := export.NewOFXWriter(io.Discard, time.UTC, time.Now())
+	transaction := testTransactions()[0]
+	if err := writer.Write(transaction); err == nil {
+		t.Error("Expected an error writing a transaction outside a statement")
+	}
+	if err := writer.StartAccount(card, time.Now(), time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := writer.StartAccount(checking, time.Now(), time.Now()); err == nil {
+		t.Error("Expected an error starting a bank account after a credit card")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/service"
 )
@@ -61,6 +62,10 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		InitialBalance: req.InitialBalance,
 		UserID:         user,
 		Color:          req.Color,
+
+		StatementClosingDay: req.StatementClosingDay,
+		PaymentDueDay:       req.PaymentDueDay,
+		PaymentAccountID:    req.PaymentAccountID,
 	}
 
 	if account.Color == "" {
@@ -72,6 +77,10 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 
 	if err := h.accountService.CreateAccount(&account); err != nil {
 		log.Printf("[AccountHandler] CreateAccount: Service error: %v", err)
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating account", "message": err.Error()})
 		return
 	}
@@ -191,6 +200,10 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 
 	updatedAccount, err := h.accountService.UpdateAccount(uint(accountID), user, &req)
 	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating account"})
 		return
 	}
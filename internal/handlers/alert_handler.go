@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type AlertHandler struct {
+	service service.AlertService
+}
+
+func NewAlertHandler(service service.AlertService) *AlertHandler {
+	return &AlertHandler{service: service}
+}
+
+// ListAlerts handles fetching the user's alerts
+// @Summary List alerts
+// @Description Get the spending anomaly alerts of the authenticated user, newest first
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Param include_dismissed query bool false "Include dismissed alerts"
+// @Success 200 {array} dto.AlertDTO
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /alerts [get]
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	alerts, err := h.service.ListAlerts(c.Request.Context(), user, c.Query("include_dismissed") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch alerts"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ToAlertDTOs(alerts))
+}
+
+// AnalyzeAlerts handles looking for new anomalies
+// @Summary Analyze transactions
+// @Description Look for spending anomalies in the recent transactions and return the new alerts
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.AlertDTO
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /alerts/analyze [post]
+func (h *AlertHandler) AnalyzeAlerts(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	alerts, err := h.service.Analyze(c.Request.Context(), user, userLocation(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to analyze transactions"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ToAlertDTOs(alerts))
+}
+
+// MarkAlertRead handles marking an alert as read
+// @Summary Mark alert as read
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Alert ID"
+// @Success 200 {object} dto.AlertDTO
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /alerts/{id}/read [post]
+func (h *AlertHandler) MarkAlertRead(c *gin.Context) {
+	h.updateAlert(c, h.service.MarkRead)
+}
+
+// DismissAlert handles dismissing an alert
+// @Summary Dismiss alert
+// @Description Dismiss an alert so it's no longer listed. The same anomaly isn't reported again.
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Alert ID"
+// @Success 200 {object} dto.AlertDTO
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /alerts/{id}/dismiss [post]
+func (h *AlertHandler) DismissAlert(c *gin.Context) {
+	h.updateAlert(c, h.service.Dismiss)
+}
+
+// MarkAllAlertsRead handles marking every alert as read
+// @Summary Mark all alerts as read
+// @Tags alerts
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /alerts/read-all [post]
+func (h *AlertHandler) MarkAllAlertsRead(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if err := h.service.MarkAllRead(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update alerts"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AlertHandler) updateAlert(c *gin.Context, update func(ctx context.Context, id uint, userID uint) (*models.Alert, error)) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	alert, err := update(c.Request.Context(), uint(id), user)
+	if err != nil {
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update alert"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ToAlertDTO(*alert))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/backup"
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+// maxBackupSize is the largest archive accepted for a restore
+const maxBackupSize = 100 << 20 // 100MB
+
+type BackupHandler struct {
+	backupService service.BackupService
+}
+
+func NewBackupHandler(backupService service.BackupService) *BackupHandler {
+	return &BackupHandler{backupService: backupService}
+}
+
+// DownloadBackup handles downloading the archive of the user's data
+// @Summary Download a backup
+// @Description Downloads a versioned JSON archive of everything the user owns: accounts, categories, tags, merchants, transactions with their categories, tags, splits and transfers, categorization rules, subscriptions and account shares
+// @Tags backup
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} backup.Archive
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /backup [get]
+func (h *BackupHandler) DownloadBackup(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	archive, err := h.backupService.Backup(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build backup"})
+		return
+	}
+	var out bytes.Buffer
+	if err := backup.Write(&out, archive); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write backup"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"dinheiros-backup-%s.json\"", archive.ExportedAt.Format("2006-01-02")))
+	c.Data(http.StatusOK, "application/json", out.Bytes())
+}
+
+// RestoreBackup handles restoring an archive into the user's data
+// @Summary Restore a backup
+// @Description Adds the records of a backup archive to the user's data with new IDs, keeping the links between them. Categories, tags and merchants the user already has are reused. Accounts are shared again with the users of this instance with the same email. With dry_run the archive is validated and restored without saving anything.
+// @Tags backup
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param dry_run query bool false "Validate the archive without saving anything"
+// @Param archive body backup.Archive true "Backup archive"
+// @Success 200 {object} dto.RestoreBackupResponse "Dry run"
+// @Success 201 {object} dto.RestoreBackupResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /backup/restore [post]
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+		return
+	}
+	archive, err := backup.Read(http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.backupService.Restore(c.Request.Context(), user, archive, dryRun)
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore backup"})
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, dto.RestoreBackupResponse{
+		DryRun:               dryRun,
+		Accounts:             result.Accounts,
+		Categories:           result.Categories,
+		ReusedCategories:     result.ReusedCategories,
+		Tags:                 result.Tags,
+		ReusedTags:           result.ReusedTags,
+		Merchants:            result.Merchants,
+		ReusedMerchants:      result.ReusedMerchants,
+		MerchantAliases:      result.MerchantAliases,
+		Transactions:         result.Transactions,
+		Rules:                result.Rules,
+		Subscriptions:        result.Subscriptions,
+		SkippedSubscriptions: result.SkippedSubscriptions,
+		ShareInvitations:     result.ShareInvitations,
+	})
+}
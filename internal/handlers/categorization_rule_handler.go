@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/service"
 )
@@ -110,9 +111,14 @@ func (h *CategorizationRuleHandler) CreateRule(c *gin.Context) {
 		Value:           req.Value,
 		TransactionType: req.TransactionType,
 		CategoryDst:     req.CategoryDst,
+		Tags:            toTagRefs(req.TagIDs),
 		Active:          req.Active == nil || *req.Active,
 	}
 	if err := h.Service.CreateRule(c.Request.Context(), &rule); err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -170,10 +176,17 @@ func (h *CategorizationRuleHandler) UpdateRule(c *gin.Context) {
 	if req.CategoryDst != nil {
 		rule.CategoryDst = *req.CategoryDst
 	}
+	if req.TagIDs != nil {
+		rule.Tags = toTagRefs(*req.TagIDs)
+	}
 	if req.Active != nil {
 		rule.Active = *req.Active
 	}
 	if err := h.Service.UpdateRule(c.Request.Context(), rule); err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -212,6 +225,10 @@ func (h *CategorizationRuleHandler) DeleteRule(c *gin.Context) {
 }
 
 func toCategorizationRuleDTO(rule models.CategorizationRule) dto.CategorizationRuleDTO {
+	tagIDs := make([]uint, len(rule.Tags))
+	for i, tag := range rule.Tags {
+		tagIDs[i] = tag.ID
+	}
 	return dto.CategorizationRuleDTO{
 		ID:              rule.ID,
 		UserID:          rule.UserID,
@@ -220,8 +237,18 @@ func toCategorizationRuleDTO(rule models.CategorizationRule) dto.CategorizationR
 		Value:           rule.Value,
 		TransactionType: rule.TransactionType,
 		CategoryDst:     rule.CategoryDst,
+		TagIDs:          tagIDs,
 		Active:          rule.Active,
 		CreatedAt:       rule.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:       rule.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
+
+// toTagRefs builds tag references from IDs; the service loads and validates the actual tags
+func toTagRefs(tagIDs []uint) []models.Tag {
+	tags := make([]models.Tag, len(tagIDs))
+	for i, id := range tagIDs {
+		tags[i] = models.Tag{ID: id}
+	}
+	return tags
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	stdErrors "errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/service"
 )
@@ -25,11 +28,12 @@ func NewCategoryHandler(service service.CategoryService) *CategoryHandler {
 
 // ListCategories returns all categories for the authenticated user
 // @Summary List all categories
-// @Description Get all categories for the authenticated user
+// @Description Get all categories for the authenticated user, optionally nested as a tree
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param tree query boolean false "Return categories nested under their parents"
 // @Success 200 {array} dto.CategoryDTO
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -47,6 +51,11 @@ func (h *CategoryHandler) ListCategories(c *gin.Context) {
 		return
 	}
 
+	if c.Query("tree") == "true" {
+		c.JSON(http.StatusOK, dto.ToCategoryTree(categories))
+		return
+	}
+
 	c.JSON(http.StatusOK, dto.ToCategoryDTOs(categories))
 }
 
@@ -78,6 +87,10 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 
 	category := req.ToModel(userID.(uint))
 	if err := h.service.CreateCategory(c.Request.Context(), &category); err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create category"})
 		return
 	}
@@ -119,6 +132,10 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 	}
 	req.ApplyToModel(category)
 	if err := h.service.UpdateCategory(c.Request.Context(), category); err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update category"})
 		return
 	}
@@ -127,15 +144,18 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 
 // DeleteCategory handles deleting a category
 // @Summary Delete a category
-// @Description Delete a category for the authenticated user
+// @Description Delete a category for the authenticated user. Categories in use by transactions or rules require a replacement category.
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path int true "Category ID"
+// @Param replacement_id query int false "Category that receives the transactions and rules of the deleted category"
 // @Success 204
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /categories/{id} [delete]
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
@@ -145,13 +165,120 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 		return
 	}
 	id := c.Param("id")
-	if err := h.service.DeleteCategory(c.Request.Context(), id, userID.(uint)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "category not found or failed to delete"})
+
+	var replacementID *uint
+	if value := c.Query("replacement_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid replacement_id"})
+			return
+		}
+		replacement := uint(parsed)
+		replacementID = &replacement
+	}
+
+	if err := h.service.DeleteCategory(c.Request.Context(), id, userID.(uint), replacementID); err != nil {
+		h.handleDeleteError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
+// MergeCategories handles merging a category into another one
+// @Summary Merge categories
+// @Description Move all transactions, splits, rules and subcategories of a category into the target category and delete it
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Category ID to merge"
+// @Param request body dto.MergeCategoriesRequest true "Target category"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /categories/{id}/merge [post]
+func (h *CategoryHandler) MergeCategories(c *gin.Context) {
+	userID, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	sourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+	var req dto.MergeCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.MergeCategories(c.Request.Context(), userID.(uint), uint(sourceID), req.TargetID); err != nil {
+		h.handleDeleteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CategoryHandler) handleDeleteError(c *gin.Context, err error) {
+	if e, ok := err.(*errors.ValidationError); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		return
+	}
+	if stdErrors.Is(err, errors.ErrCategoryInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "category not found or failed to delete"})
+}
+
+// ListTemplates returns the available category templates
+// @Summary List category templates
+// @Description Get the starter sets of categories and categorization rules that can be applied
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} service.CategoryTemplate
+// @Failure 401 {object} ErrorResponse
+// @Router /categories/templates [get]
+func (h *CategoryHandler) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, service.CategoryTemplates())
+}
+
+// ApplyTemplate applies a category template to the authenticated user
+// @Summary Apply a category template
+// @Description Create the categories and categorization rules of a template. Existing categories and rules are kept, so a template can be applied again safely.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "Template name"
+// @Success 200 {object} service.ApplyTemplateResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /categories/templates/{name}/apply [post]
+func (h *CategoryHandler) ApplyTemplate(c *gin.Context) {
+	userID, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	result, err := h.service.ApplyTemplate(c.Request.Context(), userID.(uint), c.Param("name"))
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply category template"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // CreateCategoryRequest represents the request body for creating a category
 type CreateCategoryRequest struct {
 	Name        string                 `json:"name" binding:"required"`
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/export"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type ExportHandler struct {
+	exportService service.ExportService
+}
+
+func NewExportHandler(exportService service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// ExportTransactions handles downloading the user's transactions
+// @Summary Export transactions
+// @Description Streams every transaction matching the filters as CSV, OFX or NDJSON, ordered by ID. OFX files have one statement per account.
+// @Tags transactions
+// @Produce text/csv
+// @Produce application/x-ofx
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param format query string false "Output format: csv, ofx or ndjson (default csv)"
+// @Param types query []string false "Transaction types"
+// @Param account_ids query []int false "Account IDs"
+// @Param category_ids query []int false "Category IDs"
+// @Param tag_ids query []int false "Tag IDs"
+// @Param merchant_ids query []int false "Merchant IDs"
+// @Param description query string false "Description contains"
+// @Param min_amount query number false "Minimum amount"
+// @Param max_amount query number false "Maximum amount"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /transactions/export [get]
+func (h *ExportHandler) ExportTransactions(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req dto.ExportTransactionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	loc := userLocation(c)
+	setDatesInLocation(loc, req.StartDate, req.EndDate)
+
+	format := export.Format(req.Format)
+	writer, err := export.NewWriter(format, c.Writer, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"transactions.%s\"", format))
+	c.Status(http.StatusOK)
+
+	filter := repository.TransactionFilter{
+		Types:       req.Types,
+		AccountIDs:  req.AccountIDs,
+		CategoryIDs: req.CategoryIDs,
+		TagIDs:      req.TagIDs,
+		MerchantIDs: req.MerchantIDs,
+		Description: req.Description,
+		MinAmount:   req.MinAmount,
+		MaxAmount:   req.MaxAmount,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+	}
+	// The response has started, so an error can only cut the file short
+	if err := h.exportService.ExportTransactions(c.Request.Context(), user, filter, writer); err != nil {
+		log.Printf("[ExportHandler] ExportTransactions: export failed: %v", err)
+		c.Error(err)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type ForecastHandler struct {
+	forecastService service.ForecastService
+}
+
+func NewForecastHandler(forecastService service.ForecastService) *ForecastHandler {
+	return &ForecastHandler{forecastService: forecastService}
+}
+
+// GetForecast handles the cash-flow forecast
+// @Summary Cash-flow forecast
+// @Description Project the balance of every account day by day from scheduled transactions, recurring transactions and credit card bills
+// @Tags statistics
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Number of days to project (1-365, default 30)"
+// @Success 200 {object} service.Forecast
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /statistics/forecast [get]
+func (h *ForecastHandler) GetForecast(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	days := service.DefaultForecastDays
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+			return
+		}
+		days = parsed
+	}
+
+	forecast, err := h.forecastService.Forecast(user, days, userLocation(c))
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error calculating forecast"})
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type MerchantHandler struct {
+	service service.MerchantService
+}
+
+func NewMerchantHandler(service service.MerchantService) *MerchantHandler {
+	return &MerchantHandler{service: service}
+}
+
+// ListMerchants handles fetching the user's merchants
+// @Summary List merchants
+// @Description Get the merchants found in the user's transactions with their aliases
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.MerchantDTO
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /merchants [get]
+func (h *MerchantHandler) ListMerchants(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	merchants, err := h.service.ListMerchants(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch merchants"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ToMerchantDTOs(merchants))
+}
+
+// CreateMerchantAlias handles creating a merchant alias
+// @Summary Create merchant alias
+// @Description Assign every transaction whose description contains the pattern to the named merchant. The merchants of existing transactions are assigned again.
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateMerchantAliasRequest true "Alias data"
+// @Success 201 {object} dto.MerchantAliasDTO
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /merchants/aliases [post]
+func (h *MerchantHandler) CreateMerchantAlias(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req dto.CreateMerchantAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	alias, err := h.service.CreateAlias(c.Request.Context(), user, req.Pattern, req.Name)
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alias"})
+		return
+	}
+	c.JSON(http.StatusCreated, dto.ToMerchantAliasDTO(*alias))
+}
+
+// DeleteMerchantAlias handles deleting a merchant alias
+// @Summary Delete merchant alias
+// @Description Delete an alias. The merchants of existing transactions are assigned again.
+// @Tags merchants
+// @Security BearerAuth
+// @Param id path int true "Alias ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /merchants/aliases/{id} [delete]
+func (h *MerchantHandler) DeleteMerchantAlias(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.DeleteAlias(c.Request.Context(), uint(id), user); err != nil {
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete alias"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// NormalizeMerchants handles assigning the merchants of all transactions again
+// @Summary Normalize merchants
+// @Description Find the merchants of all the user's transactions again, e.g. for transactions created before merchants existed
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]int
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /merchants/normalize [post]
+func (h *MerchantHandler) NormalizeMerchants(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	updated, err := h.service.Normalize(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to normalize merchants"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type NetWorthHandler struct {
+	netWorthService service.NetWorthService
+}
+
+func NewNetWorthHandler(netWorthService service.NetWorthService) *NetWorthHandler {
+	return &NetWorthHandler{netWorthService: netWorthService}
+}
+
+// GetNetWorth handles the net worth history
+// @Summary Net worth history
+// @Description Balance of every account at the end of each day or month, split into assets and credit card liabilities
+// @Tags statistics
+// @Produce json
+// @Security BearerAuth
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param period query string false "Bucket size: day or month (default month)"
+// @Success 200 {object} service.NetWorth
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /statistics/net-worth [get]
+func (h *NetWorthHandler) GetNetWorth(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	loc := userLocation(c)
+	query := service.NetWorthQuery{Period: repository.StatisticsPeriod(c.Query("period")), Location: loc}
+	if startDateStr := c.Query("startDate"); startDateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", startDateStr, loc)
+		if err == nil {
+			query.StartDate = &t
+		}
+	}
+	if endDateStr := c.Query("endDate"); endDateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", endDateStr, loc)
+		if err == nil {
+			query.EndDate = &t
+		}
+	}
+
+	netWorth, err := h.netWorthService.NetWorthHistory(c.Request.Context(), user, query)
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error calculating net worth"})
+		return
+	}
+	c.JSON(http.StatusOK, netWorth)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+const (
+	// oidcStateCookie keeps the state token of a login with an OpenID Connect provider
+	oidcStateCookie = "oidc_state"
+	// oidcStateCookiePath limits the cookie to the requests of the logins
+	oidcStateCookiePath = "/api/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcLoginService service.OIDCLoginService
+}
+
+func NewOIDCHandler(oidcLoginService service.OIDCLoginService) *OIDCHandler {
+	return &OIDCHandler{oidcLoginService: oidcLoginService}
+}
+
+// setStateCookie sets the state cookie, or removes it when maxAge is negative. The cookie is
+// only sent over HTTPS when the request came over it.
+func setStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcStateCookiePath, "", secure, true)
+}
+
+// ListProviders handles listing the OpenID Connect providers users can log in with
+// @Summary List login providers
+// @Description List the configured OpenID Connect providers, such as a self-hosted Keycloak or Authentik, with the URL the browser goes to to log in with each
+// @Tags users
+// @Produce json
+// @Success 200 {array} dto.OIDCProviderResponse
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	providers := h.oidcLoginService.Providers()
+	response := make([]dto.OIDCProviderResponse, len(providers))
+	for i, provider := range providers {
+		response[i] = dto.OIDCProviderResponse{
+			ID:       provider.ID,
+			Name:     provider.Name,
+			LoginURL: oidcStateCookiePath + "/" + provider.ID + "/login",
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Login handles starting a login with an OpenID Connect provider
+// @Summary Log in with a provider
+// @Description Redirects the browser to the provider's login, with PKCE, keeping the login's state in a signed cookie for 10 minutes. The provider sends the user back to the frontend's callback page, which completes the login at /auth/oidc/{provider}/callback.
+// @Tags users
+// @Param provider path string true "Provider ID"
+// @Param category_template query string false "Category template of a user the login registers"
+// @Success 302
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authorization, err := h.oidcLoginService.Authorize(c.Request.Context(), c.Param("provider"), c.Query("category_template"))
+	if err != nil {
+		var notFound *errors.NotFoundError
+		if stdErrors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": notFound.Error()})
+			return
+		}
+		log.Printf("[OIDCHandler] Login: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+		return
+	}
+
+	setStateCookie(c, authorization.StateToken, int(time.Until(authorization.ExpiresAt).Seconds()))
+	c.Redirect(http.StatusFound, authorization.URL)
+}
+
+// Callback handles completing a login with an OpenID Connect provider
+// @Summary Complete a login with a provider
+// @Description Completes a login started at /auth/oidc/{provider}/login with the code and state the provider sent the user back with, which must match the login's state cookie. The provider's account logs into the user it's linked to, or registers a new one when no user has its verified email. When a user has the email but isn't linked to the account, 409 is returned with a link token to send to /auth/link with the user's password. Users with two-factor authentication get a challenge token to send to /auth/2fa with a code instead.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider ID"
+// @Param input body dto.OIDCCallbackRequest true "Code and state of the provider's redirect"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} dto.IdentityLinkResponse
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/oidc/{provider}/callback [post]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req dto.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The state works once, whatever the outcome
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	setStateCookie(c, "", -1)
+
+	result, err := h.oidcLoginService.Login(c.Request.Context(), c.Param("provider"), req.Code, req.State, stateToken, sessionClient(c))
+	if err != nil {
+		var notFound *errors.NotFoundError
+		switch {
+		case stdErrors.As(err, &notFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": notFound.Error()})
+		case stdErrors.Is(err, errors.ErrUnauthorized):
+			log.Printf("[OIDCHandler] Callback: Login rejected: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login"})
+		default:
+			log.Printf("[OIDCHandler] Callback: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing login"})
+		}
+		return
+	}
+
+	respondLogin(c, "Login successful", result)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService service.PasswordResetService
+}
+
+func NewPasswordResetHandler(passwordResetService service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{passwordResetService: passwordResetService}
+}
+
+// ForgotPassword handles asking for a password reset link
+// @Summary Request a password reset link
+// @Description Emails a link to reset the password, which works once for an hour, to the user with the email. The response is the same whether or not the email is registered. Too many requests from the same IP address or for the same email are rejected.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body dto.ForgotPasswordRequest true "Email of the account"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/forgot [post]
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordResetService.Request(c.Request.Context(), req.Email); err != nil {
+		log.Printf("[PasswordResetHandler] ForgotPassword: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error requesting password reset"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a link to reset the password was sent to it"})
+}
+
+// ResetPassword handles setting a new password with an emailed reset link
+// @Summary Reset the password
+// @Description Sets a new password with the token of an emailed reset link. The token can only be used once, and all the user's sessions are revoked, so they log in again with the new password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body dto.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/reset [post]
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordResetService.Reset(c.Request.Context(), req.Token, req.NewPassword, sessionClient(c)); err != nil {
+		var validationErr *errors.ValidationError
+		if stdErrors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+			return
+		}
+		log.Printf("[PasswordResetHandler] ResetPassword: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error resetting password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
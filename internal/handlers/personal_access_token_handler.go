@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+// outsideTokenAccount reports whether the request uses a personal access token restricted to
+// an account other than accountID. AuthMiddleware only checks the account in the path, which
+// the transaction of a route isn't required to belong to.
+func outsideTokenAccount(c *gin.Context, accountID uint) bool {
+	restricted := c.GetUint("token_account")
+	return restricted != 0 && restricted != accountID
+}
+
+type PersonalAccessTokenHandler struct {
+	tokenService service.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(tokenService service.PersonalAccessTokenService) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{tokenService: tokenService}
+}
+
+// CreateToken handles creating a personal access token
+// @Summary Create personal access token
+// @Description Creates a token for scripts, sent as a Bearer token like an access token. It only gives access to the endpoints of its scopes and, with account_id, to one account. The token is only returned once.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body dto.CreatePersonalAccessTokenRequest true "Token name, scopes, account and expiration"
+// @Success 201 {object} dto.CreatePersonalAccessTokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/tokens [post]
+func (h *PersonalAccessTokenHandler) CreateToken(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req dto.CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, plain, err := h.tokenService.Create(c.Request.Context(), user, service.CreatePersonalAccessToken{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		AccountID: req.AccountID,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		switch e := err.(type) {
+		case *errors.ValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		case *errors.NotFoundError:
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+		default:
+			log.Printf("[PersonalAccessTokenHandler] CreateToken: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CreatePersonalAccessTokenResponse{
+		PersonalAccessTokenResponse: dto.ToPersonalAccessTokenResponse(token),
+		Token:                       plain,
+	})
+}
+
+// ListTokens handles listing the user's personal access tokens
+// @Summary List personal access tokens
+// @Description Lists the user's personal access tokens, including expired ones, newest first. The tokens themselves aren't returned, only their first characters.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.PersonalAccessTokenResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/tokens [get]
+func (h *PersonalAccessTokenHandler) ListTokens(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tokens, err := h.tokenService.List(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ToPersonalAccessTokenResponses(tokens))
+}
+
+// RevokeToken handles deleting a personal access token
+// @Summary Revoke personal access token
+// @Description Deletes a personal access token, which stops working immediately
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "Token ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/tokens/{id} [delete]
+func (h *PersonalAccessTokenHandler) RevokeToken(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token ID"})
+		return
+	}
+
+	if err := h.tokenService.Revoke(c.Request.Context(), user, uint(id)); err != nil {
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type PrivacyHandler struct {
+	privacyService service.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: privacyService}
+}
+
+// ExportPersonalData handles downloading a copy of the user's personal data
+// @Summary Export personal data
+// @Description Downloads everything stored about the user, as provided by the LGPD: their profile, a backup of everything they own, the accounts shared with them, the share invitations they sent or received, their alerts and the devices they logged in on
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} backup.PersonalData
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/export [get]
+func (h *PrivacyHandler) ExportPersonalData(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	data, err := h.privacyService.ExportPersonalData(c.Request.Context(), user)
+	if err != nil {
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export personal data"})
+		return
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export personal data"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"dinheiros-personal-data-%s.json\"", data.ExportedAt.Format("2006-01-02")))
+	c.Data(http.StatusOK, "application/json", out.Bytes())
+}
+
+// DeleteAccount handles deleting the user's account
+// @Summary Delete account
+// @Description Permanently deletes the user with their accounts, transactions, categories, tags, merchants, rules, subscriptions and alerts after confirming their password. Shares with other users are revoked, pending invitations to the user are canceled and the user's tokens stop working.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body dto.DeleteAccountRequest true "Password confirmation"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me [delete]
+func (h *PrivacyHandler) DeleteAccount(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req dto.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.privacyService.DeleteAccount(c.Request.Context(), user, req.Password); err != nil {
+		if stdErrors.Is(err, errors.ErrUnauthorized) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "password is incorrect"})
+			return
+		}
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
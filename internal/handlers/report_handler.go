@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/reports"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type ReportHandler struct {
+	taxReportService service.TaxReportService
+}
+
+func NewReportHandler(taxReportService service.TaxReportService) *ReportHandler {
+	return &ReportHandler{taxReportService: taxReportService}
+}
+
+// GetTaxReport handles the year-end tax report
+// @Summary Year-end tax report
+// @Description Balances of the user's accounts on 31/12 of the year and the year before, expenses in deductible categories and income by source, for the income tax declaration
+// @Tags reports
+// @Produce json
+// @Produce text/csv
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param year path int true "Calendar year"
+// @Param format query string false "Output format: json, csv or pdf (default json)"
+// @Success 200 {object} reports.TaxReport
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/tax/{year} [get]
+func (h *ReportHandler) GetTaxReport(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected json, csv or pdf"})
+		return
+	}
+
+	report, err := h.taxReportService.TaxReport(c.Request.Context(), user, year, userLocation(c))
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build tax report"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var out bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == "pdf" {
+		contentType = "application/pdf"
+		err = reports.WriteTaxReportPDF(&out, report)
+	} else {
+		err = reports.WriteTaxReportCSV(&out, report)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write tax report"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"tax-report-%d.%s\"", year, format))
+	c.Data(http.StatusOK, contentType, out.Bytes())
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+// sessionClient returns the device making the request, which sessions are listed by
+func sessionClient(c *gin.Context) service.SessionClient {
+	return service.SessionClient{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// Refresh handles exchanging a refresh token for new tokens
+// @Summary Refresh tokens
+// @Description Exchanges a refresh token for a new access token and a new refresh token. The refresh token can only be used once; using it again logs out the session it belongs to.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body dto.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/refresh [post]
+func (h *SessionHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, user, err := h.sessionService.Refresh(c.Request.Context(), req.RefreshToken, sessionClient(c))
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrUnauthorized) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		log.Printf("[SessionHandler] Refresh: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating authentication token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToAuthResponse("Token refreshed", tokens, user))
+}
+
+// Logout handles logging out of the current session
+// @Summary Logout
+// @Description Revokes the session of the access token, so its refresh token stops working. The access token itself stops working too.
+// @Tags users
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout [post]
+func (h *SessionHandler) Logout(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	err := h.sessionService.Revoke(c.Request.Context(), user, c.GetUint("session"))
+	if err != nil {
+		if _, ok := err.(*errors.NotFoundError); !ok {
+			log.Printf("[SessionHandler] Logout: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions handles listing the devices the user is logged in on
+// @Summary List sessions
+// @Description Lists the user's active sessions with the device's user agent and IP address, most recently used first. The session making the request is marked as current.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.SessionResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	sessions, err := h.sessionService.List(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ToSessionResponses(sessions, c.GetUint("session")))
+}
+
+// RevokeSession handles logging out of one of the user's sessions
+// @Summary Revoke session
+// @Description Logs out the device of one of the user's sessions
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	if err := h.sessionService.Revoke(c.Request.Context(), user, uint(id)); err != nil {
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllSessions handles logging out of all the user's sessions
+// @Summary Log out all sessions
+// @Description Logs out every device the user is logged in on, including the one making the request unless keep_current is set
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param keep_current query bool false "Keep the session making the request"
+// @Success 200 {object} dto.RevokeSessionsResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/sessions [delete]
+func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var except uint
+	if c.Query("keep_current") == "true" {
+		except = c.GetUint("session")
+	}
+	revoked, err := h.sessionService.RevokeAll(c.Request.Context(), user, except)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.RevokeSessionsResponse{Revoked: revoked})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+type SubscriptionHandler struct {
+	service service.SubscriptionService
+}
+
+func NewSubscriptionHandler(service service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service}
+}
+
+// ListSubscriptions handles fetching the user's subscriptions
+// @Summary List subscriptions
+// @Description List the stored subscriptions with their estimated cost, last charge and price changes. New subscriptions only show up after a refresh.
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param include_ignored query bool false "Include ignored subscriptions"
+// @Success 200 {array} service.SubscriptionSummary
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /subscriptions [get]
+func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	subscriptions, err := h.service.ListSubscriptions(c.Request.Context(), user, userLocation(c), c.Query("include_ignored") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// RefreshSubscriptions handles detecting the user's subscriptions again
+// @Summary Refresh subscriptions
+// @Description Detect recurring charges in the expenses, store the new subscriptions, update the known ones and list them
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param include_ignored query bool false "Include ignored subscriptions"
+// @Success 200 {array} service.SubscriptionSummary
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /subscriptions/refresh [post]
+func (h *SubscriptionHandler) RefreshSubscriptions(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	subscriptions, err := h.service.RefreshSubscriptions(c.Request.Context(), user, userLocation(c), c.Query("include_ignored") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh subscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// UpdateSubscriptionStatus handles confirming, ignoring or cancelling a subscription
+// @Summary Update subscription status
+// @Description Confirm, ignore or cancel a subscription. A cancelled subscription that charges again raises an alert.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Param request body dto.UpdateSubscriptionStatusRequest true "New status"
+// @Success 200 {object} service.SubscriptionSummary
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /subscriptions/{id} [patch]
+func (h *SubscriptionHandler) UpdateSubscriptionStatus(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req dto.UpdateSubscriptionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	subscription, err := h.service.UpdateStatus(c.Request.Context(), uint(id), user, req.Status, userLocation(c))
+	if err != nil {
+		switch e := err.(type) {
+		case *errors.ValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		case *errors.NotFoundError:
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update subscription"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -27,6 +28,7 @@ type UpdateTransactionRequest struct {
 	Type                  string  `json:"type"`
 	Description           string  `json:"description"`
 	CategoryIDs           []uint  `json:"category_ids"`
+	TagIDs                *[]uint `json:"tag_ids,omitempty"`
 	AttachedTransactionID *uint   `json:"attached_transaction_id,omitempty"`
 }
 
@@ -34,6 +36,8 @@ type TransactionHandler struct {
 	transactionService        service.TransactionService
 	categoryService           service.CategoryService
 	categorizationRuleService service.CategorizationRuleService
+	tagService                service.TagService
+	alertService              service.AlertService
 }
 
 type ImportTransactionsRequest struct {
@@ -41,11 +45,13 @@ type ImportTransactionsRequest struct {
 	File      *multipart.FileHeader `form:"file" binding:"required"`
 }
 
-func NewTransactionHandler(transactionService service.TransactionService, categoryService service.CategoryService, categorizationRuleService service.CategorizationRuleService) *TransactionHandler {
+func NewTransactionHandler(transactionService service.TransactionService, categoryService service.CategoryService, categorizationRuleService service.CategorizationRuleService, tagService service.TagService, alertService service.AlertService) *TransactionHandler {
 	return &TransactionHandler{
 		transactionService:        transactionService,
 		categoryService:           categoryService,
 		categorizationRuleService: categorizationRuleService,
+		tagService:                tagService,
+		alertService:              alertService,
 	}
 }
 
@@ -145,6 +151,12 @@ func (h *TransactionHandler) ImportTransactions(c *gin.Context) {
 		return
 	}
 
+	// Statements only have days, which are in the user's timezone
+	loc := userLocation(c)
+	for i := range transactions {
+		transactions[i].Date = dateInLocation(transactions[i].Date, loc)
+	}
+
 	// Return the parsed transactions for review/editing on the frontend
 	c.JSON(http.StatusOK, gin.H{
 		"transactions": transactions,
@@ -185,6 +197,11 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Transfers reach a second account
+	if c.GetUint("token_account") != 0 && (req.ToAccountID != nil || req.AttachedTransactionID != nil) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Tokens restricted to one account can't create transfers"})
+		return
+	}
 
 	// Parse the date
 	parsedDate, err := time.Parse(time.RFC3339, req.Date)
@@ -193,6 +210,17 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
+	// Splits are validated up front so an invalid split doesn't leave a half-created transaction behind
+	splits := dto.ToTransactionSplits(req.Splits)
+	if err := h.transactionService.ValidateSplits(user, req.Amount, splits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.tagService.ResolveTags(c.Request.Context(), user, req.TagIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Handle attachment to another transaction if specified
 	if req.AttachedTransactionID != nil {
 		// Create transaction with attachment
@@ -211,6 +239,12 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 			return
 		}
 
+		transaction, err = h.applySplitsAndTags(c, user, transaction, splits, req.TagIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving transaction splits and tags"})
+			return
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message":     "Transaction created successfully",
 			"transaction": dto.ToTransactionResponse(transaction),
@@ -242,12 +276,34 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
+	transaction, err = h.applySplitsAndTags(c, user, transaction, splits, req.TagIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving transaction splits and tags"})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":     "Transaction created successfully",
 		"transaction": dto.ToTransactionResponse(transaction),
 	})
 }
 
+// applySplitsAndTags saves the splits and tags of a newly created transaction and reloads it
+func (h *TransactionHandler) applySplitsAndTags(c *gin.Context, user uint, transaction *models.Transaction, splits []models.TransactionSplit, tagIDs []uint) (*models.Transaction, error) {
+	if len(splits) == 0 && len(tagIDs) == 0 {
+		return transaction, nil
+	}
+	if len(tagIDs) > 0 {
+		if err := h.tagService.SetTransactionTags(c.Request.Context(), user, transaction.ID, tagIDs); err != nil {
+			return nil, err
+		}
+	}
+	if len(splits) > 0 {
+		return h.transactionService.SetTransactionSplits(user, transaction.ID, splits)
+	}
+	return h.transactionService.GetTransactionByID(user, transaction.ID)
+}
+
 // ListTransactions handles listing all transactions with filters
 // @Summary List all transactions
 // @Description Get all transactions across accounts with filtering and pagination
@@ -257,6 +313,7 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 // @Param types query []string false "Transaction types"
 // @Param account_ids query []int false "Account IDs to filter by"
 // @Param category_ids query []int false "Category IDs to filter by"
+// @Param tag_ids query []int false "Tag IDs to filter by"
 // @Param description query string false "Description filter"
 // @Param min_amount query number false "Minimum amount"
 // @Param max_amount query number false "Maximum amount"
@@ -276,6 +333,7 @@ func (h *TransactionHandler) ListTransactions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
 		return
 	}
+	setDatesInLocation(userLocation(c), req.StartDate, req.EndDate)
 
 	// Get user ID from context
 	userID, exists := c.Get("user")
@@ -290,6 +348,8 @@ func (h *TransactionHandler) ListTransactions(c *gin.Context) {
 		req.Types,
 		req.AccountIDs,
 		req.CategoryIDs,
+		req.TagIDs,
+		req.MerchantIDs,
 		req.Description,
 		req.MinAmount,
 		req.MaxAmount,
@@ -406,6 +466,10 @@ func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 		}
 		return
 	}
+	if outsideTokenAccount(c, transaction.AccountID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	}
 
 	c.JSON(http.StatusOK, dto.ToTransactionResponse(transaction))
 }
@@ -425,11 +489,19 @@ type DashboardSummaryResponse struct {
 
 // GetDashboardSummary handles fetching dashboard summary data
 // @Summary Get dashboard summary
-// @Description Get summary data for the user's dashboard including recent transactions and statistics
+// @Description Get summary data for the user's dashboard for a month, a custom range or a credit card cycle, which only covers the card, compared to the previous period and the same period last year
 // @Tags dashboard
 // @Produce json
 // @Security BearerAuth
+// @Param period query string false "Period type: month, custom or credit_card_cycle" default(month)
+// @Param month query string false "Month of a month period (YYYY-MM), defaults to the current month"
+// @Param date query string false "Date inside the credit card cycle (YYYY-MM-DD), defaults to today"
+// @Param startDate query string false "Start date of a custom period (YYYY-MM-DD)"
+// @Param endDate query string false "End date of a custom period (YYYY-MM-DD)"
+// @Param account_id query int false "Credit card account of a credit_card_cycle period"
+// @Param recent query int false "Number of recent transactions" default(5)
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /summary [get]
@@ -440,28 +512,94 @@ func (h *TransactionHandler) GetDashboardSummary(c *gin.Context) {
 		return
 	}
 
-	totalBalance, totalIncome, totalExpenses, recentTransactions, err := h.transactionService.GetDashboardSummary(user)
+	loc := userLocation(c)
+	period := service.DashboardPeriod{Type: service.DashboardPeriodType(c.Query("period")), Location: loc}
+	if monthStr := c.Query("month"); monthStr != "" {
+		t, err := time.ParseInLocation("2006-01", monthStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month, expected YYYY-MM"})
+			return
+		}
+		period.Date = &t
+	}
+	if dateStr := c.Query("date"); dateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", dateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		period.Date = &t
+	}
+	if startDateStr := c.Query("startDate"); startDateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", startDateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid startDate, expected YYYY-MM-DD"})
+			return
+		}
+		period.StartDate = &t
+	}
+	if endDateStr := c.Query("endDate"); endDateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", endDateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endDate, expected YYYY-MM-DD"})
+			return
+		}
+		period.EndDate = &t
+	}
+	if accountIDStr := c.Query("account_id"); accountIDStr != "" {
+		accountID, err := strconv.ParseUint(accountIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+			return
+		}
+		period.AccountID = uint(accountID)
+	}
+	if recentStr := c.Query("recent"); recentStr != "" {
+		recent, err := strconv.Atoi(recentStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recent"})
+			return
+		}
+		period.RecentLimit = recent
+	}
+
+	summary, err := h.transactionService.GetDashboardSummary(user, period)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching dashboard summary"})
+		switch e := err.(type) {
+		case *errors.ValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching dashboard summary"})
+		}
 		return
 	}
 
 	// Convert transactions to response DTOs
-	recentTransactionsResponse := make([]dto.TransactionResponse, len(recentTransactions))
-	for i, t := range recentTransactions {
+	recentTransactionsResponse := make([]dto.TransactionResponse, len(summary.RecentTransactions))
+	for i, t := range summary.RecentTransactions {
 		transaction := t // Create a new variable to avoid implicit memory aliasing
 		recentTransactionsResponse[i] = dto.ToTransactionResponse(&transaction)
 	}
 
-	// For now, we'll keep the categories and monthly trends empty in the response
-	// as they're not part of the service layer yet
 	transactionsByCategory := make(map[string]float64)
+	for _, category := range summary.TopCategories {
+		transactionsByCategory[category.Name] = category.Amount
+	}
+	// Monthly trends are served by the statistics endpoints
 	monthlyTrends := make(map[string]map[string]float64)
 
 	c.JSON(http.StatusOK, gin.H{
-		"totalBalance":           totalBalance,
-		"totalIncome":            totalIncome,
-		"totalExpenses":          totalExpenses,
+		"period":                 summary.Period,
+		"startDate":              summary.StartDate.Format("2006-01-02"),
+		"endDate":                summary.EndDate.Format("2006-01-02"),
+		"totalBalance":           summary.TotalBalance,
+		"totalIncome":            summary.Income,
+		"totalExpenses":          summary.Expenses,
+		"net":                    summary.Net,
+		"previousPeriod":         summary.PreviousPeriod,
+		"lastYear":               summary.LastYear,
+		"topCategories":          summary.TopCategories,
+		"topMerchants":           summary.TopMerchants,
 		"recentTransactions":     recentTransactionsResponse,
 		"transactionsByCategory": transactionsByCategory,
 		"monthlyTrends":          monthlyTrends,
@@ -510,11 +648,22 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 		return
 	}
 
+	if outsideTokenAccount(c, existingTx.AccountID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	}
+
 	var req UpdateTransactionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Attaching a transfer reaches a second account
+	if c.GetUint("token_account") != 0 && req.AttachedTransactionID != nil &&
+		(existingTx.AttachedTransactionID == nil || *existingTx.AttachedTransactionID != *req.AttachedTransactionID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Tokens restricted to one account can't create transfers"})
+		return
+	}
 
 	// Parse the date
 	parsedDate, err := time.Parse(time.RFC3339, req.Date)
@@ -556,6 +705,18 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 		return
 	}
 
+	// Replace the tags if provided
+	if req.TagIDs != nil {
+		if err := h.tagService.SetTransactionTags(c.Request.Context(), user, existingTx.ID, *req.TagIDs); err != nil {
+			if e, ok := err.(*errors.ValidationError); ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating transaction tags"})
+			return
+		}
+	}
+
 	// Fetch the updated transaction with all its relations
 	updatedTx, err := h.transactionService.GetTransactionByID(user, existingTx.ID)
 	if err != nil {
@@ -569,6 +730,74 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	})
 }
 
+// SetTransactionSplits handles replacing the splits of a transaction
+// @Summary Set transaction splits
+// @Description Split a transaction across multiple categories. The split amounts must add up to the transaction amount. An empty list removes the splits.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param transactionId path int true "Transaction ID"
+// @Param request body dto.SetTransactionSplitsRequest true "Transaction splits"
+// @Success 200 {object} dto.TransactionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /accounts/{id}/transactions/{transactionId}/splits [put]
+func (h *TransactionHandler) SetTransactionSplits(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	transactionID, err := strconv.Atoi(c.Param("transactionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var req dto.SetTransactionSplitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.GetUint("token_account") != 0 {
+		existingTx, err := h.transactionService.GetTransactionByID(user, uint(transactionID))
+		if err == nil && outsideTokenAccount(c, existingTx.AccountID) {
+			err = errors.NewNotFoundError("transaction not found")
+		}
+		if err != nil {
+			if e, ok := err.(*errors.NotFoundError); ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching transaction"})
+			return
+		}
+	}
+
+	transaction, err := h.transactionService.SetTransactionSplits(user, uint(transactionID), dto.ToTransactionSplits(req.Splits))
+	if err != nil {
+		switch e := err.(type) {
+		case *errors.ValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		case *errors.NotFoundError:
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving transaction splits"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Transaction splits saved successfully",
+		"transaction": dto.ToTransactionResponse(transaction),
+	})
+}
+
 // DeleteTransaction handles deleting a transaction
 // @Summary Delete transaction
 // @Description Delete a specific transaction
@@ -609,6 +838,11 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
+	if outsideTokenAccount(c, existingTx.AccountID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	}
+
 	// Check if transaction has attachments and warn user
 	if existingTx.AttachedTransactionID != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete a transaction that is attached to another transaction. Remove the attachment first."})
@@ -641,6 +875,7 @@ type BulkCreateTransactionsRequest struct {
 		Type        string  `json:"type"`
 		Description string  `json:"description"`
 		CategoryIDs []uint  `json:"categoryIds"`
+		TagIDs      []uint  `json:"tagIds"`
 	} `json:"transactions"`
 }
 
@@ -666,6 +901,9 @@ func (h *TransactionHandler) BulkCreateTransactions(c *gin.Context) {
 		return
 	}
 
+	// Dates without a time are days in the user's timezone
+	loc := userLocation(c)
+
 	var createdMu = &sync.Mutex{}
 	var created []models.Transaction
 
@@ -677,15 +915,15 @@ func (h *TransactionHandler) BulkCreateTransactions(c *gin.Context) {
 			var parsedDate time.Time
 			var err error
 			if t.Date != "" {
-				parsedDate, err = time.Parse("2006-01-02", t.Date)
+				parsedDate, err = time.ParseInLocation("2006-01-02", t.Date, loc)
 				if err != nil {
 					parsedDate, err = time.Parse(time.RFC3339, t.Date)
 					if err != nil {
-						parsedDate = time.Now()
+						parsedDate = time.Now().In(loc)
 					}
 				}
 			} else {
-				parsedDate = time.Now()
+				parsedDate = time.Now().In(loc)
 			}
 			txType := models.TransactionType(t.Type)
 
@@ -702,6 +940,11 @@ func (h *TransactionHandler) BulkCreateTransactions(c *gin.Context) {
 			if err != nil {
 				return err
 			}
+			if len(t.TagIDs) > 0 {
+				if err := h.tagService.SetTransactionTags(c.Request.Context(), user, transaction.ID, t.TagIDs); err != nil {
+					return err
+				}
+			}
 			createdMu.Lock()
 			created = append(created, *transaction)
 			createdMu.Unlock()
@@ -714,32 +957,89 @@ func (h *TransactionHandler) BulkCreateTransactions(c *gin.Context) {
 		return
 	}
 
+	// Imports are where anomalies usually show up. The transactions are already created, so
+	// a failed analysis only leaves the alerts for the next one.
+	alerts, err := h.alertService.Analyze(c.Request.Context(), user, loc)
+	if err != nil {
+		log.Printf("[TransactionHandler] BulkCreateTransactions: analyzing alerts failed: %v", err)
+		alerts = []models.Alert{}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":      "Transactions created successfully",
 		"count":        len(created),
 		"transactions": created,
+		"alerts":       dto.ToAlertDTOs(alerts),
 	})
 }
 
-func ensureChartJsFormat(labels []string, data []float64) map[string]interface{} {
+var statisticsSeriesColors = map[string]string{
+	"Income":  "#10b981",
+	"Expense": "#ef4444",
+}
+
+// ensureChartJsFormat renders statistics as chart.js data. A single series keeps the color
+// palette so it can be drawn as a pie chart; multiple series get one color each.
+func ensureChartJsFormat(data *service.StatisticsData) map[string]interface{} {
+	datasets := make([]map[string]interface{}, 0, len(data.Series))
+	for _, series := range data.Series {
+		var color interface{} = []string{"#3b82f6", "#6366f1", "#f59e42", "#ef4444", "#10b981", "#fbbf24", "#a78bfa", "#f472b6", "#34d399", "#f87171"}
+		if len(data.Series) > 1 {
+			color = statisticsSeriesColors[series.Label]
+		}
+		datasets = append(datasets, map[string]interface{}{
+			"label":           series.Label,
+			"data":            series.Data,
+			"backgroundColor": color,
+		})
+	}
 	return map[string]interface{}{
-		"labels": labels,
-		"datasets": []map[string]interface{}{
-			{
-				"label":           "Amount",
-				"data":            data,
-				"backgroundColor": []string{"#3b82f6", "#6366f1", "#f59e42", "#ef4444", "#10b981", "#fbbf24", "#a78bfa", "#f472b6", "#34d399", "#f87171"},
-			},
-		},
+		"labels":   data.Labels,
+		"datasets": datasets,
 	}
 }
 
-func ensureChartJsFormatInt(labels []string, data []int) map[string]interface{} {
-	floatData := make([]float64, len(data))
-	for i, v := range data {
-		floatData[i] = float64(v)
+// dateInLocation returns the calendar day of a date parsed without a timezone as midnight in loc
+func dateInLocation(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
+
+// setDatesInLocation moves the bound query dates to the user's timezone
+func setDatesInLocation(loc *time.Location, dates ...*time.Time) {
+	for _, date := range dates {
+		if date != nil {
+			*date = dateInLocation(*date, loc)
+		}
+	}
+}
+
+// parseStatisticsQuery reads the date range, mode and inclusion flags shared by the statistics
+// endpoints. It responds with 400 and returns false when the mode is invalid.
+func parseStatisticsQuery(c *gin.Context, defaultMode service.StatisticsMode) (service.StatisticsQuery, bool) {
+	loc := userLocation(c)
+	query := service.StatisticsQuery{Location: loc}
+	if startDateStr := c.Query("startDate"); startDateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", startDateStr, loc)
+		if err == nil {
+			query.StartDate = &t
+		}
+	}
+	if endDateStr := c.Query("endDate"); endDateStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", endDateStr, loc)
+		if err == nil {
+			query.EndDate = &t
+		}
+	}
+
+	mode, err := service.ParseStatisticsMode(c.Query("mode"), defaultMode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return query, false
 	}
-	return ensureChartJsFormat(labels, floatData)
+	query.Mode = mode
+	query.IncludeInitial = c.Query("include_initial") == "true"
+	query.IncludeTransfers = c.Query("include_transfers") == "true"
+	return query, true
 }
 
 func (h *TransactionHandler) GetStatisticsTransactionsPerDay(c *gin.Context) {
@@ -748,27 +1048,16 @@ func (h *TransactionHandler) GetStatisticsTransactionsPerDay(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	startDateStr := c.Query("startDate")
-	endDateStr := c.Query("endDate")
-	var startDate, endDate *time.Time
-	if startDateStr != "" {
-		t, err := time.Parse("2006-01-02", startDateStr)
-		if err == nil {
-			startDate = &t
-		}
-	}
-	if endDateStr != "" {
-		t, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			endDate = &t
-		}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeNet)
+	if !ok {
+		return
 	}
-	data, err := h.transactionService.GetTransactionsPerDayWithRange(user, startDate, endDate)
+	data, err := h.transactionService.GetTransactionsPerDay(user, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
 		return
 	}
-	c.JSON(http.StatusOK, ensureChartJsFormatInt(data.Labels, data.Data))
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
 }
 
 func (h *TransactionHandler) GetStatisticsAmountByMonth(c *gin.Context) {
@@ -777,27 +1066,16 @@ func (h *TransactionHandler) GetStatisticsAmountByMonth(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	startDateStr := c.Query("startDate")
-	endDateStr := c.Query("endDate")
-	var startDate, endDate *time.Time
-	if startDateStr != "" {
-		t, err := time.Parse("2006-01-02", startDateStr)
-		if err == nil {
-			startDate = &t
-		}
-	}
-	if endDateStr != "" {
-		t, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			endDate = &t
-		}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeNet)
+	if !ok {
+		return
 	}
-	data, err := h.transactionService.GetAmountByMonth(user, startDate, endDate)
+	data, err := h.transactionService.GetAmountByMonth(user, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
 		return
 	}
-	c.JSON(http.StatusOK, ensureChartJsFormat(data.Labels, data.Data))
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
 }
 
 func (h *TransactionHandler) GetStatisticsAmountByAccount(c *gin.Context) {
@@ -806,27 +1084,16 @@ func (h *TransactionHandler) GetStatisticsAmountByAccount(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	startDateStr := c.Query("startDate")
-	endDateStr := c.Query("endDate")
-	var startDate, endDate *time.Time
-	if startDateStr != "" {
-		t, err := time.Parse("2006-01-02", startDateStr)
-		if err == nil {
-			startDate = &t
-		}
-	}
-	if endDateStr != "" {
-		t, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			endDate = &t
-		}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeNet)
+	if !ok {
+		return
 	}
-	data, err := h.transactionService.GetAmountByAccount(user, startDate, endDate)
+	data, err := h.transactionService.GetAmountByAccount(user, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
 		return
 	}
-	c.JSON(http.StatusOK, ensureChartJsFormat(data.Labels, data.Data))
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
 }
 
 func (h *TransactionHandler) GetStatisticsAmountByCategory(c *gin.Context) {
@@ -835,27 +1102,52 @@ func (h *TransactionHandler) GetStatisticsAmountByCategory(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	startDateStr := c.Query("startDate")
-	endDateStr := c.Query("endDate")
-	var startDate, endDate *time.Time
-	if startDateStr != "" {
-		t, err := time.Parse("2006-01-02", startDateStr)
-		if err == nil {
-			startDate = &t
-		}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeExpense)
+	if !ok {
+		return
 	}
-	if endDateStr != "" {
-		t, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			endDate = &t
-		}
+	data, err := h.transactionService.GetAmountByCategory(user, query, c.Query("rollup") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
+}
+
+func (h *TransactionHandler) GetStatisticsAmountByTag(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeExpense)
+	if !ok {
+		return
+	}
+	data, err := h.transactionService.GetAmountByTag(user, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
+}
+
+func (h *TransactionHandler) GetStatisticsAmountByMerchant(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeExpense)
+	if !ok {
+		return
 	}
-	data, err := h.transactionService.GetAmountByCategory(user, startDate, endDate)
+	data, err := h.transactionService.GetAmountByMerchant(user, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
 		return
 	}
-	c.JSON(http.StatusOK, ensureChartJsFormat(data.Labels, data.Data))
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
 }
 
 func (h *TransactionHandler) GetStatisticsAmountSpentByDay(c *gin.Context) {
@@ -864,12 +1156,16 @@ func (h *TransactionHandler) GetStatisticsAmountSpentByDay(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	data, err := h.transactionService.GetAmountSpentByDay(user)
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeExpense)
+	if !ok {
+		return
+	}
+	data, err := h.transactionService.GetAmountByDay(user, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
 		return
 	}
-	c.JSON(http.StatusOK, ensureChartJsFormat(data.Labels, data.Data))
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
 }
 
 func (h *TransactionHandler) GetStatisticsAmountSpentAndGainedByDay(c *gin.Context) {
@@ -878,37 +1174,16 @@ func (h *TransactionHandler) GetStatisticsAmountSpentAndGainedByDay(c *gin.Conte
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	startDateStr := c.Query("startDate")
-	endDateStr := c.Query("endDate")
-	var startDate, endDate *time.Time
-	if startDateStr != "" {
-		t, err := time.Parse("2006-01-02", startDateStr)
-		if err == nil {
-			startDate = &t
-		}
+	query, ok := parseStatisticsQuery(c, service.StatisticsModeBoth)
+	if !ok {
+		return
 	}
-	if endDateStr != "" {
-		t, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			endDate = &t
-		}
+	data, err := h.transactionService.GetAmountByDay(user, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching statistics"})
+		return
 	}
-	data, labels := h.transactionService.GetAmountSpentAndGainedByDayWithRange(user, startDate, endDate)
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"labels": labels,
-		"datasets": []map[string]interface{}{
-			{
-				"label":           "Spent",
-				"data":            data["spent"],
-				"backgroundColor": "#ef4444",
-			},
-			{
-				"label":           "Gained",
-				"data":            data["gained"],
-				"backgroundColor": "#10b981",
-			},
-		},
-	})
+	c.JSON(http.StatusOK, ensureChartJsFormat(data))
 }
 
 // ListExtractors returns a list of available extractors for transactions import
@@ -926,6 +1201,7 @@ func (h *TransactionHandler) ListExtractors(c *gin.Context) {
 // @Param types query []string false "Transaction types"
 // @Param account_ids query []int false "Account IDs to filter by"
 // @Param category_ids query []int false "Category IDs to filter by"
+// @Param tag_ids query []int false "Tag IDs to filter by"
 // @Param description query string false "Description filter"
 // @Param min_amount query number false "Minimum amount"
 // @Param max_amount query number false "Maximum amount"
@@ -945,6 +1221,7 @@ func (h *TransactionHandler) SearchTransactions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
 		return
 	}
+	setDatesInLocation(userLocation(c), req.StartDate, req.EndDate)
 
 	// Get user ID from context
 	userID := c.GetUint("user")
@@ -960,6 +1237,8 @@ func (h *TransactionHandler) SearchTransactions(c *gin.Context) {
 			Types:       req.Types,
 			AccountIDs:  req.AccountIDs,
 			CategoryIDs: req.CategoryIDs,
+			TagIDs:      req.TagIDs,
+			MerchantIDs: req.MerchantIDs,
 			Description: req.Description,
 			MinAmount:   req.MinAmount,
 			MaxAmount:   req.MaxAmount,
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/service"
+)
+
+func toTwoFactorChallengeResponse(challenge *service.TwoFactorChallenge) dto.TwoFactorChallengeResponse {
+	return dto.TwoFactorChallengeResponse{
+		Message:           "Two-factor authentication required",
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge.Token,
+		ExpiresAt:         challenge.ExpiresAt,
+	}
+}
+
+type TwoFactorHandler struct {
+	twoFactorService service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorService: twoFactorService}
+}
+
+// CompleteLogin handles the second login step of users with two-factor authentication
+// @Summary Complete a two-factor login
+// @Description Exchanges the challenge token returned by the login and a code from the authenticator app, or a recovery code, for the session's tokens. Each code can only be used once. Wrong codes count towards the login lock.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body dto.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/2fa [post]
+func (h *TwoFactorHandler) CompleteLogin(c *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, user, err := h.twoFactorService.CompleteLogin(c.Request.Context(), req.ChallengeToken, req.Code, sessionClient(c))
+	if err != nil {
+		var locked *errors.LockedError
+		if stdErrors.As(err, &locked) {
+			respondLocked(c, locked)
+			return
+		}
+		if stdErrors.Is(err, errors.ErrUnauthorized) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code"})
+			return
+		}
+		log.Printf("[TwoFactorHandler] CompleteLogin: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating authentication token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToAuthResponse("Login successful", tokens, user))
+}
+
+// GetStatus handles getting whether the user has two-factor authentication
+// @Summary Get two-factor authentication status
+// @Description Returns whether two-factor authentication is enabled and how many unused recovery codes the user has left
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.TwoFactorStatusResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/2fa [get]
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	status, err := h.twoFactorService.Status(c.Request.Context(), user)
+	if err != nil {
+		h.handleError(c, err, "failed to get two-factor authentication status")
+		return
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorStatusResponse{Enabled: status.Enabled, RecoveryCodesRemaining: status.RecoveryCodesRemaining})
+}
+
+// Setup handles starting the two-factor authentication setup
+// @Summary Set up two-factor authentication
+// @Description Generates a new secret for an authenticator app. Two-factor authentication is enabled once a code from the app is sent to /users/me/2fa/enable.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.TwoFactorSetupResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	setup, err := h.twoFactorService.Setup(c.Request.Context(), user)
+	if err != nil {
+		h.handleError(c, err, "failed to set up two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorSetupResponse{Secret: setup.Secret, ProvisioningURI: setup.ProvisioningURI})
+}
+
+// Enable handles enabling two-factor authentication
+// @Summary Enable two-factor authentication
+// @Description Enables two-factor authentication after checking a code from the authenticator app that was set up, and returns the recovery codes. They aren't shown again.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body dto.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(c.Request.Context(), user, c.GetUint("session"), req.Code)
+	if err != nil {
+		h.handleError(c, err, "failed to enable two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Verify handles verifying a code in the current session
+// @Summary Verify two-factor authentication
+// @Description Checks a code from the authenticator app, or a recovery code, so sensitive operations can be performed in the current session for the next minutes. Wrong codes count towards the login lock, and each user can verify 5 codes at once and then one a minute.
+// @Tags users
+// @Accept json
+// @Security BearerAuth
+// @Param input body dto.TwoFactorCodeRequest true "Code from the authenticator app or a recovery code"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/2fa/verify [post]
+func (h *TwoFactorHandler) Verify(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.twoFactorService.Verify(c.Request.Context(), user, c.GetUint("session"), req.Code, sessionClient(c)); err != nil {
+		h.handleError(c, err, "failed to verify code")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles replacing the user's recovery codes
+// @Summary Regenerate recovery codes
+// @Description Replaces the user's recovery codes, used or not. Requires a recent two-factor verification.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), user)
+	if err != nil {
+		h.handleError(c, err, "failed to regenerate recovery codes")
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable handles disabling two-factor authentication
+// @Summary Disable two-factor authentication
+// @Description Disables two-factor authentication after confirming the password and removes the recovery codes. Requires a recent two-factor verification.
+// @Tags users
+// @Accept json
+// @Security BearerAuth
+// @Param input body dto.DisableTwoFactorRequest true "Password confirmation"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/2fa [delete]
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req dto.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(c.Request.Context(), user, req.Password); err != nil {
+		h.handleError(c, err, "failed to disable two-factor authentication")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TwoFactorHandler) handleError(c *gin.Context, err error, message string) {
+	if e, ok := err.(*errors.ValidationError); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+		return
+	}
+	if e, ok := err.(*errors.NotFoundError); ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+		return
+	}
+	var locked *errors.LockedError
+	if stdErrors.As(err, &locked) {
+		respondLocked(c, locked)
+		return
+	}
+	if stdErrors.Is(err, errors.ErrUnauthorized) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code or password"})
+		return
+	}
+	log.Printf("[TwoFactorHandler] %s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
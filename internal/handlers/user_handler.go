@@ -1,24 +1,69 @@
 package handlers
 
 import (
+	stdErrors "errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/service"
 )
 
+// userLocation returns the timezone of the authenticated user, set by the auth middleware
+func userLocation(c *gin.Context) *time.Location {
+	if value, exists := c.Get("timezone"); exists {
+		if loc, ok := value.(*time.Location); ok {
+			return loc
+		}
+	}
+	return (&models.User{}).Location()
+}
+
 // UserHandler handles HTTP requests related to user operations
 type UserHandler struct {
-	userService service.UserService
+	userService    service.UserService
+	googleVerifier service.IdentityVerifier
 }
 
 // NewUserHandler creates a new instance of UserHandler
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(userService service.UserService, googleVerifier service.IdentityVerifier) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:    userService,
+		googleVerifier: googleVerifier,
+	}
+}
+
+// respondLocked responds to a login of a user locked after too many wrong passwords
+func respondLocked(c *gin.Context, locked *errors.LockedError) {
+	retryAfter := math.Ceil(time.Until(locked.Until).Seconds())
+	c.Header("Retry-After", strconv.Itoa(int(math.Max(retryAfter, 1))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+}
+
+// respondLogin responds to a successful login with the session's tokens, the two-factor
+// challenge or the identity link to confirm
+func respondLogin(c *gin.Context, message string, result *service.LoginResult) {
+	switch {
+	case result.Challenge != nil:
+		c.JSON(http.StatusOK, toTwoFactorChallengeResponse(result.Challenge))
+	case result.Link != nil:
+		c.JSON(http.StatusConflict, dto.IdentityLinkResponse{
+			Message:      "An account with this email already exists, confirm its password to link the login",
+			LinkRequired: true,
+			LinkToken:    result.Link.Token,
+			ExpiresAt:    result.Link.ExpiresAt,
+			Provider:     result.Link.Provider,
+			Email:        result.Link.Email,
+		})
+	default:
+		c.JSON(http.StatusOK, dto.ToAuthResponse(message, result.Tokens, result.User))
 	}
 }
 
@@ -42,8 +87,13 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// Create user using the service
-	token, user, err := h.userService.Register(req.Name, req.Email, req.Password)
+	tokens, user, err := h.userService.Register(req.Name, req.Email, req.Password, req.CategoryTemplate, sessionClient(c))
 	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+
 		status := http.StatusInternalServerError
 		var errMsg string
 
@@ -58,14 +108,14 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Return success response with JWT token
-	response := dto.ToAuthResponse("User registered successfully", token, user)
+	// Return success response with the session's tokens
+	response := dto.ToAuthResponse("User registered successfully", tokens, user)
 	c.JSON(http.StatusCreated, response)
 }
 
 // Login handles user login
 // @Summary Login a user
-// @Description Authenticate a user and return a token
+// @Description Authenticate a user and return an access token and a refresh token for a new session. Users with two-factor authentication get a challenge token to send to /auth/2fa with a code instead. Repeated wrong passwords lock the account's logins for a while, and too many requests from the same IP address or for the same email are rejected.
 // @Tags users
 // @Accept json
 // @Produce json
@@ -73,6 +123,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 // @Success 200 {object} dto.AuthResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
@@ -83,8 +134,14 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// Authenticate user using the service
-	token, user, err := h.userService.Login(req.Email, req.Password)
+	result, err := h.userService.Login(req.Email, req.Password, sessionClient(c))
 	if err != nil {
+		var locked *errors.LockedError
+		if stdErrors.As(err, &locked) {
+			respondLocked(c, locked)
+			return
+		}
+
 		status := http.StatusInternalServerError
 		errMsg := "An error occurred"
 
@@ -100,9 +157,8 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Return success response with JWT token
-	response := dto.ToAuthResponse("Login successful", token, user)
-	c.JSON(http.StatusOK, response)
+	// Return success response with the session's tokens
+	respondLogin(c, "Login successful", result)
 }
 
 // GetCurrentUser returns the current authenticated user's information
@@ -175,9 +231,48 @@ func (h *UserHandler) UpdateName(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// UpdateTimezone handles updating the current user's timezone
+// @Summary Update user's timezone
+// @Description Update the timezone used to parse dates and group statistics by day and month
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param input body dto.UpdateTimezoneRequest true "Timezone update data"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/timezone [patch]
+func (h *UserHandler) UpdateTimezone(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req dto.UpdateTimezoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	userObj, err := h.userService.UpdateTimezone(user, req.Timezone)
+	if err != nil {
+		if e, ok := err.(*errors.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update timezone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToUserResponse(userObj))
+}
+
 // UpdatePassword handles updating the current user's password
 // @Summary Update user's password
-// @Description Update the current user's password
+// @Description Update the current user's password. The user's other sessions are logged out.
 // @Tags users
 // @Accept json
 // @Produce json
@@ -186,6 +281,7 @@ func (h *UserHandler) UpdateName(c *gin.Context) {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users/me/password [patch]
 func (h *UserHandler) UpdatePassword(c *gin.Context) {
@@ -204,7 +300,7 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 	}
 
 	// Update password
-	err := h.userService.UpdatePassword(userID.(uint), req.CurrentPassword, req.NewPassword)
+	err := h.userService.UpdatePassword(userID.(uint), c.GetUint("session"), req.CurrentPassword, req.NewPassword)
 	if err != nil {
 		errMsg := "Failed to update password"
 		if err.Error() == "current password is incorrect" {
@@ -220,7 +316,7 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 
 // GoogleLogin handles Google OAuth login
 // @Summary Login a user with Google
-// @Description Authenticate a user with Google OAuth and return a token
+// @Description Authenticate a user with a Google Sign-In credential, an ID token whose signature, audience, issuer and verified email are checked, and return a token. The Google account logs into the user it's linked to, or registers a new one when no user has its email. When a user has the email but isn't linked to the Google account, 409 is returned with a link token to send to /auth/link with the user's password. Users with two-factor authentication get a challenge token to send to /auth/2fa with a code instead.
 // @Tags users
 // @Accept json
 // @Produce json
@@ -228,11 +324,10 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 // @Success 200 {object} dto.AuthResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 409 {object} dto.IdentityLinkResponse
 // @Failure 500 {object} map[string]string
 // @Router /auth/google [post]
 func (h *UserHandler) GoogleLogin(c *gin.Context) {
-	log.Printf("[UserHandler] GoogleLogin: Starting Google login")
-
 	var req dto.GoogleLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("[UserHandler] GoogleLogin: JSON binding error: %v", err)
@@ -240,28 +335,130 @@ func (h *UserHandler) GoogleLogin(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[UserHandler] GoogleLogin: Verifying Google token")
-
 	// Verify Google token
-	googleUser, err := service.VerifyGoogleToken(req.Credential)
+	identity, err := h.googleVerifier.Verify(c.Request.Context(), req.Credential)
 	if err != nil {
 		log.Printf("[UserHandler] GoogleLogin: Google token verification failed: %v", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Google token"})
 		return
 	}
 
-	log.Printf("[UserHandler] GoogleLogin: Google token verified for user: %s (%s)", googleUser.Name, googleUser.Email)
+	result, err := h.userService.LoginWithIdentity(identity, req.CategoryTemplate, sessionClient(c))
+	if err != nil {
+		log.Printf("[UserHandler] GoogleLogin: LoginWithIdentity failed: %v", err)
+		if stdErrors.Is(err, errors.ErrUnauthorized) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Google token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing Google login"})
+		return
+	}
+
+	respondLogin(c, "Google login successful", result)
+}
+
+// LinkIdentity handles linking an identity provider's account to the user with its email
+// @Summary Link a login to an existing user
+// @Description Confirms the password of the user a login with an identity provider, such as Google, matched by email, and links the provider's account to the user so it logs into them from then on. Logs in like /auth/login, including its lock after repeated wrong passwords.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body dto.LinkIdentityRequest true "Link token and password"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/link [post]
+func (h *UserHandler) LinkIdentity(c *gin.Context) {
+	var req dto.LinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.userService.LinkIdentity(req.LinkToken, req.Password, sessionClient(c))
+	if err != nil {
+		var locked *errors.LockedError
+		if stdErrors.As(err, &locked) {
+			respondLocked(c, locked)
+			return
+		}
+
+		switch err.Error() {
+		case "invalid link token":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired link token"})
+		case "invalid credentials":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		default:
+			log.Printf("[UserHandler] LinkIdentity: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error linking login"})
+		}
+		return
+	}
+
+	respondLogin(c, "Login linked successfully", result)
+}
 
-	// Find or create user by email
-	token, user, err := h.userService.LoginOrRegisterGoogle(googleUser)
+// ListIdentities handles listing the identity providers' accounts linked to the user
+// @Summary List linked logins
+// @Description Lists the accounts at identity providers, such as Google, that log into the authenticated user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.UserIdentityResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/identities [get]
+func (h *UserHandler) ListIdentities(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	identities, err := h.userService.ListIdentities(user)
 	if err != nil {
-		log.Printf("[UserHandler] GoogleLogin: LoginOrRegisterGoogle failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("[UserHandler] ListIdentities: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list linked logins"})
 		return
 	}
+	c.JSON(http.StatusOK, dto.ToUserIdentityResponses(identities))
+}
 
-	log.Printf("[UserHandler] GoogleLogin: Google login successful for user ID: %d", user.ID)
+// UnlinkIdentity handles unlinking an identity provider's account from the user
+// @Summary Unlink a login
+// @Description Unlinks an identity provider's account from the authenticated user, so it no longer logs into them. Users registered by a provider log in with a password afterwards, which they can set with a password reset. Requires a recent two-factor verification for users with 2FA.
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "Identity ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/identities/{id} [delete]
+func (h *UserHandler) UnlinkIdentity(c *gin.Context) {
+	user := c.GetUint("user")
+	if user == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid identity ID"})
+		return
+	}
 
-	response := dto.ToAuthResponse("Google login successful", token, user)
-	c.JSON(http.StatusOK, response)
+	if err := h.userService.UnlinkIdentity(user, uint(id), sessionClient(c)); err != nil {
+		if e, ok := err.(*errors.NotFoundError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Error()})
+			return
+		}
+		log.Printf("[UserHandler] UnlinkIdentity: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlink login"})
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
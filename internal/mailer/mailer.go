@@ -0,0 +1,249 @@
+// Package mailer sends the emails of the API, such as password reset links. Emails go out
+// through SMTP or, when developing locally, to the log or to files.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidHeader is returned for messages whose recipient or subject span several lines,
+// which would let them add headers of their own
+var ErrInvalidHeader = errors.New("invalid email header")
+
+// Message is a plain text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Drivers of Config
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// Config selects and configures the Mailer New creates. Dir is used by the file driver, and
+// Host, Port, Username and Password by the SMTP driver, which only authenticates when
+// Username is set.
+type Config struct {
+	Driver   string
+	From     string
+	Dir      string
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// New creates the Mailer of the config's driver, which defaults to the log
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return NewLogMailer(cfg.From, os.Stderr), nil
+	case DriverFile:
+		if cfg.Dir == "" {
+			return nil, errors.New("mailer: the file driver needs a directory")
+		}
+		return NewFileMailer(cfg.From, cfg.Dir), nil
+	case DriverSMTP:
+		if cfg.Host == "" {
+			return nil, errors.New("mailer: the smtp driver needs a host")
+		}
+		port := cfg.Port
+		if port == "" {
+			port = "587"
+		}
+		return NewSMTPMailer(cfg.From, cfg.Host, port, cfg.Username, cfg.Password), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown driver %q", cfg.Driver)
+	}
+}
+
+// LogMailer writes emails to a log instead of sending them
+type LogMailer struct {
+	from   string
+	logger *log.Logger
+}
+
+// NewLogMailer creates a LogMailer that writes to out
+func NewLogMailer(from string, out io.Writer) *LogMailer {
+	return &LogMailer{from: from, logger: log.New(out, "", log.LstdFlags)}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	m.logger.Printf("[Mailer] Email from %s to %s: %s\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes each email to a new .eml file in a directory instead of sending it
+type FileMailer struct {
+	from string
+	dir  string
+	now  func() time.Time
+}
+
+// NewFileMailer creates a FileMailer that writes to dir, creating it when needed
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir, now: time.Now}
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	now := m.now()
+	data, err := msg.format(m.from, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+
+	// Files sort by when they were sent
+	file, err := os.CreateTemp(m.dir, now.UTC().Format("20060102T150405.000000000")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// SMTPMailer sends emails through an SMTP server, upgrading the connection with STARTTLS when
+// the server supports it
+type SMTPMailer struct {
+	from     string
+	host     string
+	port     string
+	username string
+	password string
+	now      func() time.Time
+}
+
+// NewSMTPMailer creates an SMTPMailer that authenticates with username and password when
+// username is set
+func NewSMTPMailer(from, host, port, username, password string) *SMTPMailer {
+	return &SMTPMailer{from: from, host: host, port: port, username: username, password: password, now: time.Now}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := msg.format(m.from, m.now())
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.host, m.port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("mailer: the smtp server doesn't support authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(address(m.from)); err != nil {
+		return err
+	}
+	if err := client.Rcpt(address(msg.To)); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// address returns the email address of a "Name <address>" string
+func address(s string) string {
+	if start := strings.LastIndex(s, "<"); start >= 0 {
+		if end := strings.LastIndex(s, ">"); end > start {
+			return s[start+1 : end]
+		}
+	}
+	return s
+}
+
+func (m Message) validate() error {
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+	return nil
+}
+
+// format returns the message with its headers, and the body encoded as quoted-printable
+func (m Message) format(from string, date time.Time) ([]byte, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(m.Body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,228 @@
+package mailer_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LeonardsonCC/dinheiros/internal/mailer"
+)
+
+var message = mailer.Message{
+	To:      "user@example.com",
+	Subject: "Redefinição de senha",
+	Body:    "Open the link below to reset your password:\nhttps://example.com/reset-password?token=abc",
+}
+
+// readMessage parses an email and decodes its quoted-printable body
+func readMessage(t *testing.T, data []byte) (*mail.Message, string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse email: %v", err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("Failed to decode email body: %v", err)
+	}
+	return msg, strings.ReplaceAll(string(body), "\r\n", "\n")
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     mailer.Config
+		want    string
+		wantErr bool
+	}{
+		{name: "default", cfg: mailer.Config{}, want: "*mailer.LogMailer"},
+		{name: "log", cfg: mailer.Config{Driver: mailer.DriverLog}, want: "*mailer.LogMailer"},
+		{name: "file", cfg: mailer.Config{Driver: mailer.DriverFile, Dir: "mail"}, want: "*mailer.FileMailer"},
+		{name: "file without dir", cfg: mailer.Config{Driver: mailer.DriverFile}, wantErr: true},
+		{name: "smtp", cfg: mailer.Config{Driver: mailer.DriverSMTP, Host: "smtp.example.com"}, want: "*mailer.SMTPMailer"},
+		{name: "smtp without host", cfg: mailer.Config{Driver: mailer.DriverSMTP}, wantErr: true},
+		{name: "unknown", cfg: mailer.Config{Driver: "carrier-pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := mailer.New(tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %T", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := fmt.Sprintf("%T", m); got != tt.want {
+				t.Errorf("Expected a %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	var out bytes.Buffer
+	m := mailer.NewLogMailer("Dinheiros <no-reply@example.com>", &out)
+
+	if err := m.Send(context.Background(), message); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{message.To, message.Subject, message.Body} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the log to contain %q, got %q", want, out.String())
+		}
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := mailer.NewFileMailer("Dinheiros <no-reply@example.com>", dir)
+
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), message); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("Expected 2 email files, got %v, %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read email file: %v", err)
+	}
+
+	msg, body := readMessage(t, data)
+	if got := msg.Header.Get("To"); got != message.To {
+		t.Errorf("Expected To %q, got %q", message.To, got)
+	}
+	if got, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); got != message.Subject {
+		t.Errorf("Expected Subject %q, got %q", message.Subject, got)
+	}
+	if body != message.Body {
+		t.Errorf("Expected body %q, got %q", message.Body, body)
+	}
+}
+
+func TestFileMailer_SendInvalidHeader(t *testing.T) {
+	dir := t.TempDir()
+	m := mailer.NewFileMailer("no-reply@example.com", dir)
+
+	msg := message
+	msg.Subject = "Hello\r\nBcc: someone@example.com"
+	if err := m.Send(context.Background(), msg); !errors.Is(err, mailer.ErrInvalidHeader) {
+		t.Errorf("Expected ErrInvalidHeader, got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Errorf("Expected no email file, got %v", files)
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	received := make(chan smtpTransaction, 1)
+	go serveSMTP(t, listener, received)
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	m := mailer.NewSMTPMailer("Dinheiros <no-reply@example.com>", host, port, "", "")
+	if err := m.Send(context.Background(), message); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tx := <-received
+	if tx.from != "no-reply@example.com" || tx.to != message.To {
+		t.Errorf("Expected the envelope from no-reply@example.com to %s, got %s to %s", message.To, tx.from, tx.to)
+	}
+	// The DATA command ends the email with a line break
+	_, body := readMessage(t, tx.data)
+	if body = strings.TrimSuffix(body, "\n"); body != message.Body {
+		t.Errorf("Expected body %q, got %q", message.Body, body)
+	}
+}
+
+type smtpTransaction struct {
+	from string
+	to   string
+	data []byte
+}
+
+// serveSMTP answers one SMTP session without extensions and sends the email it got
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- smtpTransaction) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ESMTP")
+
+	var tx smtpTransaction
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			tx.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			tx.to = strings.Trim(line[len("RCPT TO:"):], "<>")
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			tx.data = data.Bytes()
+			received <- tx
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			t.Errorf("Unexpected SMTP command %q", line)
+			reply("500 Unknown command")
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package merchants turns the raw descriptions bank statements give transactions into clean
+// merchant names, so charges from the same merchant can be grouped together.
+package merchants
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// paymentPrefixes are the words Brazilian banks put before the merchant or person a payment
+// went to. They are matched against the upper-cased description without accents.
+var paymentPrefixes = []*regexp.Regexp{
+	regexp.MustCompile(`^(TRANSFERENCIA|TRANSF|REEMBOLSO|DEVOLUCAO|PAGAMENTO) (ENVIADA?|ENVIADO|RECEBIDA?|RECEBIDO) (PELO|VIA|POR) PIX( |$)`),
+	regexp.MustCompile(`^(PIX|TED|DOC|TEF|TRANSF|TRANSFERENCIA) (ENVIADA?|ENVIADO|RECEBIDA?|RECEBIDO|EMITIDA?|EMITIDO)( |$)`),
+	regexp.MustCompile(`^(ENVIO|RECEBIMENTO) (DE )?(PIX|TED|DOC)( |$)`),
+	regexp.MustCompile(`^PIX (QR ?CODE|QRS?|SAQUE|TROCO)( |$)`),
+	regexp.MustCompile(`^COMPRA (NO |COM |DE )?(CARTAO|DEBITO|CREDITO|ELO|VISA|MASTERCARD|MASTER|MAESTRO)( (DE )?(DEBITO|CREDITO))?( |$)`),
+	regexp.MustCompile(`^(PAG|PAGTO|PAGAMENTO) (DE )?(BOLETO|TITULO|CONTA)( |$)`),
+	regexp.MustCompile(`^(DEB|DEBITO) (AUT|AUTOM|AUTOMATICO)\.?( |$)`),
+	regexp.MustCompile(`^(PIX|COMPRA)( |$)`),
+}
+
+// installmentSuffixes match the installment counter card statements add to a purchase
+var installmentSuffixes = []*regexp.Regexp{
+	regexp.MustCompile(`( |^)(- )?(PARCELA|PARC)\.? ?\d+ ?(/|DE) ?\d+$`),
+	regexp.MustCompile(`( |^)\d{1,2} ?(/|DE) ?\d{1,2}$`),
+}
+
+// paymentFacilitators are the acquirers that put their own name before a "*" and the
+// merchant's after it, as in "MP*LOJA". Other merchants use the "*" the other way around,
+// as in "UBER *TRIP".
+var paymentFacilitators = map[string]bool{
+	"DL": true, "EBN": true, "EBANX": true, "EC": true, "GETNET": true, "HNA": true,
+	"IZ": true, "MAGALUPAY": true, "MERCADOPAGO": true, "MERCPAGO": true, "MP": true,
+	"PAG": true, "PAGS": true, "PAGSEGURO": true, "PAYPAL": true, "PG": true, "PICPAY": true,
+	"SQ": true, "STONE": true, "SUMUP": true, "ZP": true,
+}
+
+// lowercaseWords are kept in lower case inside a name, as in "Fulano de Tal"
+var lowercaseWords = map[string]bool{
+	"da": true, "das": true, "de": true, "do": true, "dos": true, "e": true,
+}
+
+var accents = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// Alias names the merchant of every description containing Pattern
+type Alias struct {
+	Pattern string
+	Name    string
+}
+
+// Normalizer finds the merchant of a description, first among the user's aliases and then
+// by cleaning the description with the built-in patterns
+type Normalizer struct {
+	aliases []Alias
+}
+
+// NewNormalizer returns a normalizer that checks the longest alias patterns first, so a
+// more specific alias wins over a broader one
+func NewNormalizer(aliases []Alias) *Normalizer {
+	sorted := make([]Alias, 0, len(aliases))
+	for _, alias := range aliases {
+		alias.Pattern = Key(alias.Pattern)
+		if alias.Pattern != "" && strings.TrimSpace(alias.Name) != "" {
+			sorted = append(sorted, alias)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Pattern) > len(sorted[j].Pattern) })
+	return &Normalizer{aliases: sorted}
+}
+
+// Name returns the merchant of the description, or an empty string when the description
+// has no name in it
+func (n *Normalizer) Name(description string) string {
+	key := Key(description)
+	for _, alias := range n.aliases {
+		if strings.Contains(key, alias.Pattern) {
+			return strings.TrimSpace(alias.Name)
+		}
+	}
+	return Clean(description)
+}
+
+// Clean removes payment prefixes, card numbers, installment counters and acquirer names from
+// a description and title-cases what's left. A description that is only a payment prefix,
+// such as "ENVIO PIX", is kept as is.
+func Clean(description string) string {
+	words := strings.Fields(description)
+
+	for _, suffix := range installmentSuffixes {
+		if match := suffix.FindString(fold(words)); match != "" && len(strings.Fields(match)) < len(words) {
+			words = words[:len(words)-len(strings.Fields(match))]
+		}
+	}
+
+	words = letterWords(words)
+	for stripped := true; stripped; {
+		stripped = false
+		for _, prefix := range paymentPrefixes {
+			if match := prefix.FindString(fold(words)); match != "" && len(strings.Fields(match)) < len(words) {
+				words = words[len(strings.Fields(match)):]
+				stripped = true
+				break
+			}
+		}
+	}
+
+	return titleCase(letterWords(merchantWords(words)))
+}
+
+// Key is the form names are compared in: lower case, without accents and with single spaces
+func Key(name string) string {
+	return strings.ToLower(fold(strings.Fields(name)))
+}
+
+// merchantWords keeps the merchant's side of an "ACQUIRER*MERCHANT" or "MERCHANT *DETAIL" name
+func merchantWords(words []string) []string {
+	joined := strings.Join(words, " ")
+	star := strings.Index(joined, "*")
+	if star < 0 {
+		return words
+	}
+	before := strings.Fields(joined[:star])
+	after := strings.Fields(strings.ReplaceAll(joined[star+1:], "*", " "))
+	if len(before) == 0 || (len(after) > 0 && paymentFacilitators[fold(before)]) {
+		return after
+	}
+	return before
+}
+
+// letterWords drops the words without letters, such as card numbers, dates and amounts. The
+// "*" separating an acquirer from a merchant is kept.
+func letterWords(words []string) []string {
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		if strings.IndexFunc(word, unicode.IsLetter) >= 0 || strings.Contains(word, "*") {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+func titleCase(words []string) string {
+	titled := make([]string, len(words))
+	for i, word := range words {
+		lower := []rune(strings.ToLower(word))
+		if i > 0 && lowercaseWords[string(lower)] {
+			titled[i] = string(lower)
+			continue
+		}
+		lower[0] = unicode.ToUpper(lower[0])
+		titled[i] = string(lower)
+	}
+	return strings.Join(titled, " ")
+}
+
+func fold(words []string) string {
+	return accents.Replace(strings.ToUpper(strings.Join(words, " ")))
+}
@@ -0,0 +1,72 @@
+package merchants_test
+
+import (
+	"testing"
+
+	"github.com/LeonardsonCC/dinheiros/internal/merchants"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"PIX ENVIADO FULANO DE TAL", "Fulano de Tal"},
+		{"Transferência enviada pelo Pix Jane Smith Santos", "Jane Smith Santos"},
+		{"Reembolso recebido pelo Pix Carlos Oliveira Rosa", "Carlos Oliveira Rosa"},
+		{"COMPRA CARTAO 1234 PADARIA", "Padaria"},
+		{"COMPRA NO DEBITO 12/05 SUPERMERCADO SÃO JOÃO", "Supermercado São João"},
+		{"IFOOD *IFOOD", "Ifood"},
+		{"UBER* TRIP", "Uber"},
+		{"UBER * PENDING", "Uber"},
+		{"MP*BERTPARILLA", "Bertparilla"},
+		{"DL     *GOOGLE YouTube", "Google Youtube"},
+		{"EBANX*CRUNCHYROLL", "Crunchyroll"},
+		{"Hostel Alemanha - Parcela 7/10", "Hostel Alemanha"},
+		{"Magalupay*Netshoes - Parcela 1/4", "Netshoes"},
+		{"NULL                      01 DE 04", "Null"},
+		{"LOJAS RENNER FL 91", "Lojas Renner Fl"},
+		{"DEB AUT CLARO", "Claro"},
+		{"PAG BOLETO", "Pag Boleto"},
+		{"ENVIO PIX", "Envio Pix"},
+		{"123456", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := merchants.Clean(tt.description); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizer_Name(t *testing.T) {
+	normalizer := merchants.NewNormalizer([]merchants.Alias{
+		{Pattern: "ifood", Name: "iFood"},
+		{Pattern: "ifood mercado", Name: "iFood Mercado"},
+		{Pattern: "padaria pão", Name: "Padaria Pão Quente"},
+		{Pattern: " ", Name: "Ignored"},
+	})
+
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"IFOOD *IFOOD", "iFood"},
+		{"IFOOD MERCADO *PEDIDO", "iFood Mercado"},
+		{"COMPRA CARTAO 1234 PADARIA PAO QUENTE", "Padaria Pão Quente"},
+		{"PIX ENVIADO FULANO DE TAL", "Fulano de Tal"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizer.Name(tt.description); got != tt.want {
+			t.Errorf("Name(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := merchants.Key("  Padaria   SÃO joão "); got != "padaria sao joao" {
+		t.Errorf("Key() = %q, want %q", got, "padaria sao joao")
+	}
+}
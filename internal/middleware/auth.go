@@ -5,15 +5,19 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	appErrors "github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/service"
 )
 
-// AuthMiddleware creates a middleware that validates JWT tokens and sets the user in the context
-func AuthMiddleware(userService service.UserService, jwtManager *auth.JWTManager) gin.HandlerFunc {
+// AuthMiddleware creates a middleware that validates JWT tokens and sets the user and their
+// session in the context. Personal access tokens are accepted too, for the routes of their
+// scopes.
+func AuthMiddleware(userService service.UserService, sessionService service.SessionService, tokenService service.PersonalAccessTokenService, jwtManager *auth.JWTManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip authentication for public routes
 		if isPublicRoute(c.Request.URL.Path) {
@@ -42,6 +46,11 @@ func AuthMiddleware(userService service.UserService, jwtManager *auth.JWTManager
 			return
 		}
 
+		if strings.HasPrefix(tokenString, service.PersonalAccessTokenPrefix) {
+			authenticatePersonalAccessToken(c, userService, tokenService, tokenString)
+			return
+		}
+
 		// Validate the JWT token
 		claims, err := jwtManager.VerifyToken(tokenString)
 		if err != nil {
@@ -54,26 +63,86 @@ func AuthMiddleware(userService service.UserService, jwtManager *auth.JWTManager
 			return
 		}
 
+		// Tokens of sessions that were logged out stop working before they expire. Tokens
+		// issued before sessions existed have no session and must log in again.
+		if claims.SessionID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		session, err := sessionService.Verify(c.Request.Context(), claims.UserID, claims.SessionID)
+		if err != nil {
+			if !errors.Is(err, appErrors.ErrUnauthorized) {
+				log.Printf("[AuthMiddleware] Error verifying session %d: %v", claims.SessionID, err)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		}
+
 		// Get the user from the database
 		user, err := userService.FindByID(claims.UserID)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 			return
 		}
+		// A deleted user's ID can be given to a new user, whose tokens are all issued after
+		// they were created. IssuedAt only has whole seconds.
+		if claims.IssuedAt != nil && claims.IssuedAt.Time.Before(user.CreatedAt.Truncate(time.Second)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
 
 		// Store the user ID in the context for later use in handlers
 		log.Printf("[AuthMiddleware] Setting user ID in context: %d", user.ID)
 		c.Set("user", user.ID)
+		c.Set("session", claims.SessionID)
+		c.Set("two_factor_enabled", user.TwoFactorEnabled)
+		if session.TwoFactorVerifiedAt != nil {
+			c.Set("two_factor_verified_at", *session.TwoFactorVerifiedAt)
+		}
+		c.Set("timezone", user.Location())
 		c.Next()
 	}
 }
 
+// authenticatePersonalAccessToken sets the user of a personal access token in the context.
+// Requests with tokens have no session, and those restricted to an account have the account as
+// token_account.
+func authenticatePersonalAccessToken(c *gin.Context, userService service.UserService, tokenService service.PersonalAccessTokenService, tokenString string) {
+	token, err := tokenService.Authenticate(c.Request.Context(), tokenString)
+	if err != nil {
+		if !errors.Is(err, appErrors.ErrUnauthorized) {
+			log.Printf("[AuthMiddleware] Error authenticating personal access token: %v", err)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if !authorizeToken(c, token) {
+		return
+	}
+
+	user, err := userService.FindByID(token.UserID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.Set("user", user.ID)
+	c.Set("personal_access_token", token.ID)
+	if token.AccountID != nil {
+		c.Set("token_account", *token.AccountID)
+	}
+	c.Set("timezone", user.Location())
+	c.Next()
+}
+
 // isPublicRoute checks if the given path is a public route that doesn't require authentication
 func isPublicRoute(path string) bool {
 	// Define public routes that don't require authentication
 	publicRoutes := []string{
 		"/api/auth/register",
 		"/api/auth/login",
+		"/api/auth/refresh",
+		"/api/auth/2fa",
 		"/api/health",
 	}
 
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// tokenAccountAccess is how a route reaches accounts, which decides whether personal access
+// tokens restricted to one account can use it
+type tokenAccountAccess int
+
+const (
+	// accountIndependent routes don't read or change account data
+	accountIndependent tokenAccountAccess = iota
+	// accountInPath routes work on the account of their :id parameter
+	accountInPath
+	// accountInQuery routes filter by the account_ids query parameter, which is set to the
+	// token's account when it's missing
+	accountInQuery
+	// allAccounts routes combine all the user's accounts
+	allAccounts
+)
+
+type tokenRoute struct {
+	scope  models.TokenScope
+	access tokenAccountAccess
+}
+
+// tokenRoutes are the only routes personal access tokens can use, by method and path. Managing
+// the user's profile, sessions and tokens always needs a login.
+var tokenRoutes = map[string]tokenRoute{
+	"GET /api/accounts":                                 {models.ScopeReadTransactions, allAccounts},
+	"GET /api/accounts/:id":                             {models.ScopeReadTransactions, accountInPath},
+	"GET /api/accounts/:id/transactions":                {models.ScopeReadTransactions, accountInPath},
+	"GET /api/accounts/:id/transactions/:transactionId": {models.ScopeReadTransactions, accountInPath},
+	"GET /api/transactions":                             {models.ScopeReadTransactions, accountInQuery},
+	"GET /api/transactions/search":                      {models.ScopeReadTransactions, accountInQuery},
+	"GET /api/transactions/export":                      {models.ScopeReadTransactions, accountInQuery},
+	"GET /api/categories":                               {models.ScopeReadTransactions, accountIndependent},
+	"GET /api/tags":                                     {models.ScopeReadTransactions, accountIndependent},
+	"GET /api/merchants":                                {models.ScopeReadTransactions, accountIndependent},
+
+	"POST /api/accounts/:id/transactions":                      {models.ScopeWriteTransactions, accountInPath},
+	"POST /api/accounts/:id/transactions/bulk":                 {models.ScopeWriteTransactions, accountInPath},
+	"PUT /api/accounts/:id/transactions/:transactionId":        {models.ScopeWriteTransactions, accountInPath},
+	"PUT /api/accounts/:id/transactions/:transactionId/splits": {models.ScopeWriteTransactions, accountInPath},
+	"DELETE /api/accounts/:id/transactions/:transactionId":     {models.ScopeWriteTransactions, accountInPath},
+	"POST /api/accounts/:id/transactions/import":               {models.ScopeImport, accountInPath},
+	"GET /api/accounts/transactions/extractors":                {models.ScopeImport, accountIndependent},
+
+	"GET /api/summary":                                   {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/transactions-per-day":           {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-by-month":                {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-by-account":              {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-by-category":             {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-by-tag":                  {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-by-merchant":             {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-spent-by-day":            {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/amount-spent-and-gained-by-day": {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/forecast":                       {models.ScopeReadStatistics, allAccounts},
+	"GET /api/statistics/net-worth":                      {models.ScopeReadStatistics, allAccounts},
+	"GET /api/reports/tax/:year":                         {models.ScopeReadStatistics, allAccounts},
+}
+
+// authorizeToken checks that the personal access token can use the route, responding with 403
+// and returning false when it can't
+func authorizeToken(c *gin.Context, token *models.PersonalAccessToken) bool {
+	route, ok := tokenRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Personal access tokens can't be used for this endpoint"})
+		return false
+	}
+	if !token.HasScope(route.scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + string(route.scope) + " scope"})
+		return false
+	}
+	if token.AccountID == nil {
+		return true
+	}
+
+	account := strconv.FormatUint(uint64(*token.AccountID), 10)
+	allowed := true
+	switch route.access {
+	case accountInPath:
+		allowed = c.Param("id") == account
+	case accountInQuery:
+		// Handlers read the query parameters after this, so setting them restricts the results
+		query := c.Request.URL.Query()
+		for _, id := range query["account_ids"] {
+			if id != account {
+				allowed = false
+			}
+		}
+		if allowed && len(query["account_ids"]) == 0 {
+			query.Set("account_ids", account)
+			c.Request.URL.RawQuery = query.Encode()
+		}
+	case allAccounts:
+		allowed = false
+	}
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is restricted to account " + account})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// setupTokenRoutes registers the routes with a handler that authorizes the token and echoes
+// the query it ends up with
+func setupTokenRoutes(token *models.PersonalAccessToken) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := func(c *gin.Context) {
+		if authorizeToken(c, token) {
+			c.String(http.StatusOK, c.Request.URL.RawQuery)
+		}
+	}
+	r.GET("/api/accounts", handler)
+	r.GET("/api/accounts/:id/transactions", handler)
+	r.POST("/api/accounts/:id/transactions", handler)
+	r.GET("/api/transactions", handler)
+	r.GET("/api/statistics/amount-by-category", handler)
+	r.GET("/api/users/me", handler)
+	return r
+}
+
+func TestAuthorizeToken(t *testing.T) {
+	accountID := uint(7)
+	readOnly := &models.PersonalAccessToken{Scopes: "read:transactions read:statistics"}
+	restricted := &models.PersonalAccessToken{Scopes: "read:transactions write:transactions read:statistics", AccountID: &accountID}
+
+	tests := []struct {
+		name   string
+		token  *models.PersonalAccessToken
+		method string
+		path   string
+		status int
+		query  string
+	}{
+		{"unlisted route", readOnly, http.MethodGet, "/api/users/me", http.StatusForbidden, ""},
+		{"listed route", readOnly, http.MethodGet, "/api/accounts/3/transactions", http.StatusOK, ""},
+		{"missing scope", readOnly, http.MethodPost, "/api/accounts/3/transactions", http.StatusForbidden, ""},
+		{"account in path", restricted, http.MethodPost, "/api/accounts/7/transactions", http.StatusOK, ""},
+		{"other account in path", restricted, http.MethodGet, "/api/accounts/3/transactions", http.StatusForbidden, ""},
+		{"missing account in query", restricted, http.MethodGet, "/api/transactions?page=2", http.StatusOK, "account_ids=7&page=2"},
+		{"account in query", restricted, http.MethodGet, "/api/transactions?account_ids=7", http.StatusOK, "account_ids=7"},
+		{"other account in query", restricted, http.MethodGet, "/api/transactions?account_ids=7&account_ids=3", http.StatusForbidden, ""},
+		{"all accounts", readOnly, http.MethodGet, "/api/statistics/amount-by-category", http.StatusOK, ""},
+		{"all accounts with a restricted token", restricted, http.MethodGet, "/api/statistics/amount-by-category", http.StatusForbidden, ""},
+		{"account list with a restricted token", restricted, http.MethodGet, "/api/accounts", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupTokenRoutes(tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusOK && w.Body.String() != tt.query {
+				t.Errorf("Expected the query %q, got %q", tt.query, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeonardsonCC/dinheiros/internal/ratelimit"
+)
+
+// RateLimit creates a middleware that lets through the requests of each key as the limiter
+// allows, and responds to the others with 429 and a Retry-After header. Requests without a
+// key aren't limited, and neither are requests when the limiter fails.
+func RateLimit(limiter *ratelimit.Limiter, key func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), k)
+		if err != nil {
+			log.Printf("[RateLimit] Error checking the limit of %s: %v", k, err)
+			c.Next()
+			return
+		}
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ClientIP returns the IP address of the client making the request, which is only taken from
+// X-Forwarded-For when the request came through one of the engine's trusted proxies
+func ClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// AuthenticatedUser returns the ID of the authenticated user, for routes behind the auth
+// middleware
+func AuthenticatedUser(c *gin.Context) string {
+	user := c.GetUint("user")
+	if user == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(user), 10)
+}
+
+// RequestEmail returns the email of a JSON request body, like the login's, in lowercase. The
+// body is left for the handler to read again.
+func RequestEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(request.Email))
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRecent2FA creates a middleware for sensitive operations, which users with two-factor
+// authentication can only perform up to maxAge after entering a code in their session. Older
+// sessions get a 403 with two_factor_required set, and can verify a code again at
+// /users/me/2fa/verify. It must run after AuthMiddleware.
+func RequireRecent2FA(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("two_factor_enabled") {
+			c.Next()
+			return
+		}
+
+		verifiedAt := c.GetTime("two_factor_verified_at")
+		if verifiedAt.IsZero() || time.Since(verifiedAt) > maxAge {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":               "Recent two-factor verification required",
+				"two_factor_required": true,
+			})
+			return
+		}
+		c.Next()
+	}
+}
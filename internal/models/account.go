@@ -21,4 +21,10 @@ type Account struct {
 	User           User          `json:"-" gorm:"foreignKey:UserID"`
 	Transactions   []Transaction `json:"transactions,omitempty" gorm:"foreignKey:AccountID"`
 	Color          string        `json:"color" gorm:"type:varchar(7);default:'#cccccc';not null"`
+
+	// Credit card billing cycle. On the due date the bill closed on the statement
+	// closing day is paid from the payment account.
+	StatementClosingDay *int  `json:"statement_closing_day,omitempty"`
+	PaymentDueDay       *int  `json:"payment_due_day,omitempty"`
+	PaymentAccountID    *uint `json:"payment_account_id,omitempty"`
 }
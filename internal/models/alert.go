@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type AlertType string
+
+const (
+	// AlertTypeCategorySpike is a category spending much more than its trailing average
+	AlertTypeCategorySpike AlertType = "category_spike"
+	// AlertTypeSubscriptionPriceIncrease is a recurring charge billed at a higher amount
+	AlertTypeSubscriptionPriceIncrease AlertType = "subscription_price_increase"
+	// AlertTypeLargeExpense is a single expense far above the user's usual expenses
+	AlertTypeLargeExpense AlertType = "large_expense"
+	// AlertTypeCancelledSubscriptionCharge is a charge from a subscription the user cancelled
+	AlertTypeCancelledSubscriptionCharge AlertType = "subscription_charged_after_cancel"
+)
+
+// Alert is an anomaly found in the user's transactions. Key identifies the anomaly, so
+// analyzing the same transactions again doesn't report it twice.
+type Alert struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	UserID        uint         `gorm:"not null;uniqueIndex:idx_user_alert_key" json:"user_id"`
+	User          User         `gorm:"foreignKey:UserID" json:"-"`
+	Key           string       `gorm:"size:255;not null;uniqueIndex:idx_user_alert_key" json:"-"`
+	Type          AlertType    `gorm:"size:50;not null" json:"type"`
+	Message       string       `gorm:"not null" json:"message"`
+	Subject       string       `gorm:"size:255" json:"subject"`
+	TransactionID *uint        `json:"transaction_id"`
+	Transaction   *Transaction `gorm:"foreignKey:TransactionID" json:"-"`
+	// Amount is the anomalous value and Baseline the usual value it was compared to
+	Amount      float64    `gorm:"type:decimal(10,2)" json:"amount"`
+	Baseline    float64    `gorm:"type:decimal(10,2)" json:"baseline"`
+	ReadAt      *time.Time `json:"read_at"`
+	DismissedAt *time.Time `json:"dismissed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	// AuditActionAccountLocked is a user's logins being locked after too many wrong passwords
+	AuditActionAccountLocked AuditAction = "account_locked"
+	// AuditActionPasswordReset is a user setting a new password with an emailed reset link
+	AuditActionPasswordReset AuditAction = "password_reset"
+	// AuditActionIdentityLinked is a user linking an identity provider's account to theirs
+	AuditActionIdentityLinked AuditAction = "identity_linked"
+	// AuditActionIdentityUnlinked is a user unlinking an identity provider's account
+	AuditActionIdentityUnlinked AuditAction = "identity_unlinked"
+)
+
+// AuditLog records a security event of a user's account, along with the client that caused it
+type AuditLog struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	UserID    uint        `gorm:"not null;index" json:"user_id"`
+	User      User        `gorm:"foreignKey:UserID" json:"-"`
+	Action    AuditAction `gorm:"size:50;not null" json:"action"`
+	Details   string      `json:"details"`
+	UserAgent string      `gorm:"size:512" json:"user_agent"`
+	IPAddress string      `gorm:"size:45" json:"ip_address"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -12,6 +12,7 @@ type CategorizationRule struct {
 	Value           string    `gorm:"size:1024;not null" json:"value"`
 	TransactionType string    `gorm:"size:20;not null" json:"transaction_type"`
 	CategoryDst     uint      `gorm:"not null" json:"category_dst"`
+	Tags            []Tag     `gorm:"many2many:categorization_rule_tags;" json:"tags,omitempty"`
 	Active          bool      `gorm:"default:true" json:"active"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
@@ -4,11 +4,17 @@ import "gorm.io/gorm"
 
 type Category struct {
 	gorm.Model
-	Name         string          `json:"name" gorm:"uniqueIndex:idx_user_name_type;not null"`
-	Description  string          `json:"description"`
+	Name        string `json:"name" gorm:"uniqueIndex:idx_user_name_type;not null"`
+	Description string `json:"description"`
+	// Deductible marks expenses that can be deducted in the income tax declaration, such as
+	// health and education. Subcategories of a deductible category are deductible too.
+	Deductible   bool            `json:"deductible" gorm:"not null;default:false"`
 	Type         TransactionType `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_user_name_type"`
 	UserID       uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_user_name_type"`
 	User         User            `json:"-" gorm:"foreignKey:UserID"`
+	ParentID     *uint           `json:"parent_id,omitempty" gorm:"index"`
+	Parent       *Category       `json:"-" gorm:"foreignKey:ParentID"`
+	Children     []Category      `json:"children,omitempty" gorm:"foreignKey:ParentID"`
 	Transactions []*Transaction  `json:"transactions,omitempty" gorm:"many2many:transaction_categories;"`
 }
 
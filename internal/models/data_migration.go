@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// DataMigration records a one-time fix of existing rows that already ran, so it runs once
+type DataMigration struct {
+	Name      string    `gorm:"primaryKey;size:255" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
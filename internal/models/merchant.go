@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Merchant is the clean name a user's transactions are grouped under, e.g. "iFood" for both
+// "IFOOD *IFOOD" and "COMPRA CARTAO 1234 IFOOD". Key is the name as merchants.Key compares it.
+type Merchant struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	UserID    uint            `gorm:"not null;uniqueIndex:idx_user_merchant_key" json:"user_id"`
+	User      User            `gorm:"foreignKey:UserID" json:"-"`
+	Key       string          `gorm:"size:255;not null;uniqueIndex:idx_user_merchant_key" json:"-"`
+	Name      string          `gorm:"size:255;not null" json:"name"`
+	Aliases   []MerchantAlias `gorm:"foreignKey:MerchantID" json:"aliases,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// MerchantAlias assigns the merchant to every transaction whose description contains Pattern,
+// ignoring case and accents
+type MerchantAlias struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_user_merchant_alias_pattern" json:"user_id"`
+	User       User      `gorm:"foreignKey:UserID" json:"-"`
+	MerchantID uint      `gorm:"not null;index" json:"merchant_id"`
+	Merchant   *Merchant `gorm:"foreignKey:MerchantID" json:"-"`
+	Pattern    string    `gorm:"size:255;not null;uniqueIndex:idx_user_merchant_alias_pattern" json:"pattern"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type TokenScope string
+
+const (
+	// ScopeReadTransactions allows reading accounts, their transactions and the categories,
+	// tags and merchants they reference
+	ScopeReadTransactions TokenScope = "read:transactions"
+	// ScopeWriteTransactions allows creating, updating and deleting transactions
+	ScopeWriteTransactions TokenScope = "write:transactions"
+	// ScopeImport allows importing statements into accounts
+	ScopeImport TokenScope = "import"
+	// ScopeReadStatistics allows reading the dashboard, statistics and reports
+	ScopeReadStatistics TokenScope = "read:statistics"
+)
+
+// TokenScopes are the scopes personal access tokens can be given
+var TokenScopes = []TokenScope{ScopeReadTransactions, ScopeWriteTransactions, ScopeImport, ScopeReadStatistics}
+
+// PersonalAccessToken is a long-lived credential users create for scripts. It only gives
+// access to the endpoints of its scopes and, when AccountID is set, to one account. Only the
+// token's hash is stored; Prefix is kept so users can tell their tokens apart.
+type PersonalAccessToken struct {
+	ID        uint     `gorm:"primaryKey" json:"id"`
+	UserID    uint     `gorm:"not null;index" json:"user_id"`
+	User      User     `gorm:"foreignKey:UserID" json:"-"`
+	Name      string   `gorm:"size:100;not null" json:"name"`
+	TokenHash string   `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Prefix    string   `gorm:"size:16;not null" json:"prefix"`
+	AccountID *uint    `gorm:"index" json:"account_id"`
+	Account   *Account `gorm:"foreignKey:AccountID" json:"-"`
+	// Scopes are separated by spaces
+	Scopes     string     `gorm:"size:255;not null" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ScopeList returns the token's scopes
+func (t *PersonalAccessToken) ScopeList() []TokenScope {
+	fields := strings.Fields(t.Scopes)
+	scopes := make([]TokenScope, len(fields))
+	for i, field := range fields {
+		scopes[i] = TokenScope(field)
+	}
+	return scopes
+}
+
+// HasScope reports whether the token was given the scope
+func (t *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the token can no longer be used at the given time
+func (t *PersonalAccessToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session is a login on one device. The device keeps a refresh token, of which only the hash
+// is stored, and exchanges it for new access tokens until the session expires or is revoked.
+// Each exchange replaces the refresh token.
+type Session struct {
+	ID               uint   `gorm:"primaryKey" json:"id"`
+	UserID           uint   `gorm:"not null;index" json:"user_id"`
+	User             User   `gorm:"foreignKey:UserID" json:"-"`
+	RefreshTokenHash string `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	// PreviousTokenHash is the refresh token replaced by the last exchange. It being used
+	// again means someone else has a copy of it.
+	PreviousTokenHash string     `gorm:"size:64;index" json:"-"`
+	UserAgent         string     `gorm:"size:512" json:"user_agent"`
+	IPAddress         string     `gorm:"size:45" json:"ip_address"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	// TwoFactorVerifiedAt is when the user last entered a 2FA code in the session. Sensitive
+	// operations ask for a new code when it's too long ago.
+	TwoFactorVerifiedAt *time.Time `json:"two_factor_verified_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the session can still be used at the given time
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type SubscriptionStatus string
+
+const (
+	// SubscriptionStatusDetected is a subscription found in the transactions the user hasn't reviewed yet
+	SubscriptionStatusDetected SubscriptionStatus = "detected"
+	// SubscriptionStatusConfirmed is a subscription the user confirmed
+	SubscriptionStatusConfirmed SubscriptionStatus = "confirmed"
+	// SubscriptionStatusIgnored is a recurring charge the user doesn't consider a subscription
+	SubscriptionStatusIgnored SubscriptionStatus = "ignored"
+	// SubscriptionStatusCancelled is a subscription the user cancelled and shouldn't charge again
+	SubscriptionStatusCancelled SubscriptionStatus = "cancelled"
+)
+
+type SubscriptionCadence string
+
+const (
+	SubscriptionCadenceWeekly  SubscriptionCadence = "weekly"
+	SubscriptionCadenceMonthly SubscriptionCadence = "monthly"
+	SubscriptionCadenceYearly  SubscriptionCadence = "yearly"
+)
+
+// Subscription is a recurring charge detected in the user's expenses. Key is the normalized
+// description the charges share; the other charge details are refreshed on every detection
+// while Status is only changed by the user.
+type Subscription struct {
+	ID              uint                `gorm:"primaryKey" json:"id"`
+	UserID          uint                `gorm:"not null;uniqueIndex:idx_user_subscription_key" json:"user_id"`
+	User            User                `gorm:"foreignKey:UserID" json:"-"`
+	Key             string              `gorm:"size:255;not null;uniqueIndex:idx_user_subscription_key" json:"-"`
+	Name            string              `gorm:"size:255;not null" json:"name"`
+	AccountID       uint                `json:"account_id"`
+	Cadence         SubscriptionCadence `gorm:"size:20;not null" json:"cadence"`
+	Amount          float64             `gorm:"type:decimal(10,2)" json:"amount"`
+	Charges         int                 `json:"charges"`
+	FirstChargeDate time.Time           `json:"first_charge_date"`
+	LastChargeDate  time.Time           `json:"last_charge_date"`
+	Status          SubscriptionStatus  `gorm:"size:20;not null;default:detected" json:"status"`
+	CancelledAt     *time.Time          `json:"cancelled_at"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Tag is a free-form label that cuts across categories, e.g. "viagem-2026" or "reembolsável"
+type Tag struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Name         string         `gorm:"size:100;not null;uniqueIndex:idx_user_tag_name" json:"name"`
+	Color        string         `gorm:"size:20" json:"color"`
+	UserID       uint           `gorm:"not null;uniqueIndex:idx_user_tag_name" json:"user_id"`
+	User         User           `gorm:"foreignKey:UserID" json:"-"`
+	Transactions []*Transaction `gorm:"many2many:transaction_tags;" json:"-"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
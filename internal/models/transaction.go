@@ -29,17 +29,27 @@ type Transaction struct {
 	Description string          `json:"description"`
 	AccountID   uint            `json:"account_id" gorm:"not null"`
 	Account     Account         `json:"-" gorm:"foreignKey:AccountID"`
-
-	AttachedTransactionID *uint           `json:"attached_transaction_id,omitempty"`
-	AttachedTransaction   *Transaction    `json:"attached_transaction,omitempty" gorm:"foreignKey:AttachedTransactionID"`
-	AttachmentType        *AttachmentType `json:"attachment_type,omitempty" gorm:"type:varchar(20)"`
-	Categories            []*Category     `json:"categories,omitempty" gorm:"many2many:transaction_categories;"`
+	// MerchantID is the merchant found in the raw Description
+	MerchantID *uint     `json:"merchant_id,omitempty" gorm:"index"`
+	Merchant   *Merchant `json:"merchant,omitempty" gorm:"foreignKey:MerchantID"`
+	// DeletedWithAccount marks the transactions soft-deleted by deleting their account, which
+	// stay part of the account's balance history and come back when it's reactivated
+	DeletedWithAccount bool `json:"-" gorm:"not null;default:false"`
+
+	AttachedTransactionID *uint              `json:"attached_transaction_id,omitempty"`
+	AttachedTransaction   *Transaction       `json:"attached_transaction,omitempty" gorm:"foreignKey:AttachedTransactionID"`
+	AttachmentType        *AttachmentType    `json:"attachment_type,omitempty" gorm:"type:varchar(20)"`
+	Categories            []*Category        `json:"categories,omitempty" gorm:"many2many:transaction_categories;"`
+	Splits                []TransactionSplit `json:"splits,omitempty" gorm:"foreignKey:TransactionID"`
+	Tags                  []*Tag             `json:"tags,omitempty" gorm:"many2many:transaction_tags;"`
 }
 
 type SearchTransactionParams struct {
 	Types       []TransactionType
 	AccountIDs  []uint
 	CategoryIDs []uint
+	TagIDs      []uint
+	MerchantIDs []uint
 	Description string
 	MinAmount   float64
 	MaxAmount   float64
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// TransactionSplit is a part of a transaction assigned to a single category.
+// The amounts of all splits of a transaction add up to the transaction amount.
+type TransactionSplit struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	TransactionID uint      `json:"transaction_id" gorm:"not null;index"`
+	CategoryID    uint      `json:"category_id" gorm:"not null;index"`
+	Category      *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Amount        float64   `json:"amount" gorm:"type:decimal(10,2);not null"`
+	Memo          string    `json:"memo"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
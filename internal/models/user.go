@@ -1,16 +1,53 @@
 package models
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// DefaultTimezone is used for users that haven't chosen a timezone
+const DefaultTimezone = "America/Sao_Paulo"
+
 type User struct {
 	gorm.Model
-	Name     string    `json:"name" gorm:"not null"`
-	Email    string    `json:"email" gorm:"unique;not null"`
-	Password string    `json:"-" gorm:"not null"`
-	Accounts []Account `json:"accounts,omitempty" gorm:"foreignKey:UserID"`
+	Name     string `json:"name" gorm:"not null"`
+	Email    string `json:"email" gorm:"unique;not null"`
+	Password string `json:"-" gorm:"not null"`
+	// Timezone is the IANA name of the zone dates are parsed and grouped in
+	Timezone string `json:"timezone" gorm:"not null;default:America/Sao_Paulo"`
+	// TOTPSecret is the base32 secret of the user's authenticator app. It's set when 2FA
+	// setup starts, but only asked for once TwoFactorEnabled is set.
+	TOTPSecret       string `json:"-" gorm:"column:totp_secret;size:64"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled" gorm:"not null;default:false"`
+	// TOTPLastStep is the time step of the last accepted code, so a code can't be used twice
+	TOTPLastStep int64 `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+	// FailedLoginAttempts counts the wrong passwords entered since the last successful login.
+	// Too many of them lock logins until LockedUntil.
+	FailedLoginAttempts int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil         *time.Time `json:"-"`
+	Accounts            []Account  `json:"accounts,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// Locked reports whether the user's logins are locked at the given time
+func (u *User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// Location returns the user's timezone, falling back to DefaultTimezone when it isn't set or
+// can't be loaded
+func (u *User) Location() *time.Location {
+	if u.Timezone != "" {
+		if loc, err := time.LoadLocation(u.Timezone); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 func (u *User) HashPassword() error {
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdentityProviderGoogle is the provider of the identities of Google logins
+const IdentityProviderGoogle = "google"
+
+// UserIdentity links a user to their account at an identity provider, such as Google, so
+// logging in with the provider logs into the user. Subject is the provider's ID of the
+// account, which doesn't change like its email can.
+type UserIdentity struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	User     User   `gorm:"foreignKey:UserID" json:"-"`
+	Provider string `gorm:"size:50;not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	// Email is the email the provider had for the account when it was linked
+	Email       string     `gorm:"size:255" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrExchangeFailed is returned when the provider doesn't exchange an authorization code for
+// tokens, wrapping the provider's error
+var ErrExchangeFailed = errors.New("oidc: code exchange failed")
+
+// Config is the registration of a client at a provider
+type Config struct {
+	// Issuer is the provider's issuer URL, where its discovery document is published
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends users back with the authorization code. It
+	// must be registered at the provider.
+	RedirectURL string
+	// Scopes are requested along with "openid"
+	Scopes []string
+}
+
+// Metadata is the part of a provider's discovery document the client uses
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Discover fetches the discovery document of the issuer (OpenID Connect Discovery 1.0),
+// which must be for the same issuer
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Metadata, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: fetching discovery document: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: fetching discovery document: unexpected status %d", resp.StatusCode)
+	}
+
+	var metadata Metadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("oidc: decoding discovery document: %w", err)
+	}
+	// A document for another issuer could make us accept that issuer's tokens
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("oidc: discovery document is for issuer %q, not %q", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing endpoints")
+	}
+	return &metadata, nil
+}
+
+// Client logs users in with a provider's authorization code flow with PKCE (RFC 7636). The
+// provider's discovery document is fetched on first use, so the provider being down doesn't
+// keep the API from starting, and fetched again on the next use when it fails.
+type Client struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	verifier *Verifier
+}
+
+// NewClient creates a Client of the provider, which makes requests with the HTTP client, or
+// with http.DefaultClient when it's nil
+func NewClient(config Config, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{config: config, client: client}
+}
+
+// discover returns the provider's metadata and the verifier of its ID tokens
+func (c *Client) discover(ctx context.Context) (*Metadata, *Verifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metadata == nil {
+		metadata, err := Discover(ctx, c.client, c.config.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.metadata = metadata
+		c.verifier = NewVerifier(NewRemoteKeySet(metadata.JWKSURI, c.client), c.config.ClientID, metadata.Issuer)
+	}
+	return c.metadata, c.verifier, nil
+}
+
+// AuthCodeURL returns the provider's URL that asks the user to log in and sends them back to
+// the redirect URL with an authorization code and the state. The nonce is put in the ID
+// token, and the code verifier must be sent along with the code to exchange it.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, _, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := []string{"openid"}
+	for _, scope := range c.config.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {c.config.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// tokenResponse is the part of the token endpoint's response the client reads, successful
+// (RFC 6749 section 5.1) or not (section 5.2)
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange exchanges an authorization code for the user's ID token, which is verified and
+// must have the nonce of the authorization. It returns an error wrapping ErrExchangeFailed
+// when the provider refuses the code, or ErrInvalidToken when the ID token isn't valid.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IDToken, error) {
+	metadata, verifier, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	// Confidential clients authenticate with HTTP Basic, the method providers must support,
+	// unless the provider only accepts the secret in the form
+	basicAuth := c.config.ClientSecret != "" &&
+		(len(metadata.TokenEndpointAuthMethodsSupported) == 0 ||
+			slices.Contains(metadata.TokenEndpointAuthMethodsSupported, "client_secret_basic"))
+	if !basicAuth {
+		form.Set("client_id", c.config.ClientID)
+		if c.config.ClientSecret != "" {
+			form.Set("client_secret", c.config.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: exchanging code: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: reading token response: %w", err)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("oidc: decoding token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if token.Error != "" {
+			return nil, fmt.Errorf("%w: %s %s", ErrExchangeFailed, token.Error, token.ErrorDescription)
+		}
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrExchangeFailed, resp.StatusCode)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token in the response", ErrExchangeFailed)
+	}
+
+	idToken, err := verifier.Verify(ctx, token.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	// The nonce ties the token to the authorization this browser started
+	if nonce == "" || idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	return idToken, nil
+}
+
+// RandomValue returns a random, URL-safe value for states, nonces and PKCE code verifiers
+func RandomValue() (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
@@ -0,0 +1,288 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/LeonardsonCC/dinheiros/internal/oidc"
+)
+
+const (
+	clientSecret = "client-secret"
+	redirectURL  = "https://app.example.com/oidc/mock/callback"
+)
+
+// authorization is a login the mock provider issued a code for
+type authorization struct {
+	redirectURL string
+	challenge   string
+	nonce       string
+}
+
+// mockProvider is an OpenID Connect provider that logs in whoever asks, for testing clients
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// authMethods are the token endpoint auth methods the discovery document lists
+	authMethods []string
+	// discoveryDown makes the discovery document fail
+	discoveryDown bool
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	p := &mockProvider{t: t, key: generateRSAKey(t), codes: map[string]authorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jwks(t, map[string]crypto.PublicKey{"mock": &p.key.PublicKey}))
+	})
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discoveryDown {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"token_endpoint_auth_methods_supported": p.authMethods,
+	})
+}
+
+// authorize logs the user in at the authorization URL, returning the code and state it
+// redirects back with
+func (p *mockProvider) authorize(authURL string) (string, string) {
+	p.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("Failed to parse authorization URL: %v", err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		p.t.Fatalf("Expected an S256 code challenge, got %q", query.Get("code_challenge_method"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code := "code-" + query.Get("state")
+	p.codes[code] = authorization{
+		redirectURL: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+	}
+	return code, query.Get("state")
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": "mock rejected the request"})
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError("invalid_request")
+		return
+	}
+
+	id, secret, basicAuth := r.BasicAuth()
+	if !basicAuth {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if basicAuth && len(p.authMethods) > 0 && !slices.Contains(p.authMethods, "client_secret_basic") {
+		tokenError("invalid_client")
+		return
+	}
+	if id != clientID || secret != clientSecret {
+		tokenError("invalid_client")
+		return
+	}
+
+	p.mu.Lock()
+	auth, found := p.codes[r.PostForm.Get("code")]
+	// Codes work once
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if r.PostForm.Get("grant_type") != "authorization_code" || !found ||
+		r.PostForm.Get("redirect_uri") != auth.redirectURL ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken := signToken(p.t, jwt.SigningMethodRS256, p.key, "mock", jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            clientID,
+		"sub":            "mock-user",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+		"nonce":          auth.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	})
+	_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
+
+func (p *mockProvider) client() *oidc.Client {
+	return oidc.NewClient(oidc.Config{
+		Issuer:       p.server.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}, p.server.Client())
+}
+
+// startLogin returns the authorization code of a new login with the state, nonce and code
+// verifier, as the provider's redirect would
+func startLogin(t *testing.T, provider *mockProvider, client *oidc.Client, nonce, codeVerifier string) string {
+	t.Helper()
+	state, err := oidc.RandomValue()
+	if err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+	authURL, err := client.AuthCodeURL(context.Background(), state, nonce, codeVerifier)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	code, returnedState := provider.authorize(authURL)
+	if returnedState != state {
+		t.Fatalf("Expected the state to be returned, got %q", returnedState)
+	}
+	return code
+}
+
+func TestDiscover(t *testing.T) {
+	provider := newMockProvider(t)
+	ctx := context.Background()
+
+	metadata, err := oidc.Discover(ctx, provider.server.Client(), provider.server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if metadata.TokenEndpoint != provider.server.URL+"/token" || metadata.JWKSURI != provider.server.URL+"/jwks" {
+		t.Errorf("Expected the provider's endpoints, got %+v", metadata)
+	}
+
+	// The document must be for the configured issuer
+	if _, err := oidc.Discover(ctx, provider.server.Client(), provider.server.URL+"/realms/other"); err == nil {
+		t.Error("Expected an error for another issuer's document")
+	}
+}
+
+func TestClient_Exchange(t *testing.T) {
+	provider := newMockProvider(t)
+	client := provider.client()
+	ctx := context.Background()
+
+	authURL, err := client.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	query, _ := url.ParseQuery(authURL[strings.Index(authURL, "?")+1:])
+	if !strings.HasPrefix(authURL, provider.server.URL+"/authorize?") ||
+		query.Get("response_type") != "code" || query.Get("client_id") != clientID ||
+		query.Get("redirect_uri") != redirectURL || query.Get("scope") != "openid email profile" {
+		t.Errorf("Unexpected authorization URL %s", authURL)
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge") == "verifier" {
+		t.Errorf("Expected the code verifier's challenge, got %q", query.Get("code_challenge"))
+	}
+
+	nonce, _ := oidc.RandomValue()
+	verifier, _ := oidc.RandomValue()
+	code := startLogin(t, provider, client, nonce, verifier)
+	token, err := client.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token.Subject != "mock-user" || token.Email != "user@example.com" || !token.EmailVerified {
+		t.Errorf("Unexpected ID token %+v", token)
+	}
+
+	// Codes work once
+	if _, err := client.Exchange(ctx, code, verifier, nonce); !errors.Is(err, oidc.ErrExchangeFailed) {
+		t.Errorf("Expected ErrExchangeFailed for a used code, got %v", err)
+	}
+
+	// Codes only work with the verifier of their challenge
+	code = startLogin(t, provider, client, nonce, verifier)
+	if _, err := client.Exchange(ctx, code, "another-verifier", nonce); !errors.Is(err, oidc.ErrExchangeFailed) {
+		t.Errorf("Expected ErrExchangeFailed for another verifier, got %v", err)
+	}
+
+	// The ID token must be for the login's nonce
+	code = startLogin(t, provider, client, nonce, verifier)
+	if _, err := client.Exchange(ctx, code, verifier, "another-nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for another nonce, got %v", err)
+	}
+
+	// The client must be the registered one
+	wrongSecret := oidc.NewClient(oidc.Config{Issuer: provider.server.URL, ClientID: clientID, ClientSecret: "wrong", RedirectURL: redirectURL}, provider.server.Client())
+	code = startLogin(t, provider, wrongSecret, nonce, verifier)
+	if _, err := wrongSecret.Exchange(ctx, code, verifier, nonce); !errors.Is(err, oidc.ErrExchangeFailed) {
+		t.Errorf("Expected ErrExchangeFailed for a wrong secret, got %v", err)
+	}
+}
+
+func TestClient_ExchangeSecretPost(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.authMethods = []string{"client_secret_post"}
+	client := provider.client()
+
+	nonce, _ := oidc.RandomValue()
+	verifier, _ := oidc.RandomValue()
+	code := startLogin(t, provider, client, nonce, verifier)
+	if _, err := client.Exchange(context.Background(), code, verifier, nonce); err != nil {
+		t.Errorf("Expected the secret to be sent in the form, got %v", err)
+	}
+}
+
+func TestClient_DiscoveryRetried(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.discoveryDown = true
+	client := provider.client()
+	ctx := context.Background()
+
+	if _, err := client.AuthCodeURL(ctx, "state", "nonce", "verifier"); err == nil {
+		t.Fatal("Expected an error while the provider is down")
+	}
+
+	provider.mu.Lock()
+	provider.discoveryDown = false
+	provider.mu.Unlock()
+	if _, err := client.AuthCodeURL(ctx, "state", "nonce", "verifier"); err != nil {
+		t.Errorf("Expected the discovery document to be fetched again, got %v", err)
+	}
+}
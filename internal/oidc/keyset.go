@@ -0,0 +1,232 @@
+// Package oidc verifies the ID tokens of OpenID Connect providers, such as Google: JWTs
+// signed with the provider's keys, published as a JSON Web Key Set (RFC 7517). It also logs
+// users in with the authorization code flow of providers found by discovery, such as
+// self-hosted Keycloak or Authentik.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned for tokens signed with a key the key set doesn't have
+var ErrKeyNotFound = errors.New("oidc: signing key not found")
+
+const (
+	// defaultKeyCacheDuration is how long keys are cached when the provider doesn't say
+	defaultKeyCacheDuration = time.Hour
+	// minKeyRefreshInterval is how often unknown key IDs can make the keys be fetched again,
+	// so tokens with made-up key IDs can't make each request fetch them
+	minKeyRefreshInterval = time.Minute
+)
+
+// KeySet finds the public key that signed a token by its key ID
+type KeySet interface {
+	Key(ctx context.Context, keyID string) (crypto.PublicKey, error)
+}
+
+// StaticKeySet is a KeySet of fixed keys, for providers whose keys are configured and for tests
+type StaticKeySet map[string]crypto.PublicKey
+
+func (s StaticKeySet) Key(_ context.Context, keyID string) (crypto.PublicKey, error) {
+	key, ok := s[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// RemoteKeySet fetches a provider's key set from its JWKS URL and caches it for as long as
+// the response's Cache-Control allows. Keys are fetched again early when a token is signed
+// with a key ID the cache doesn't have, which is how providers rotate keys.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	expiresAt time.Time
+}
+
+// NewRemoteKeySet creates a RemoteKeySet that fetches the keys from url with the client, or
+// with http.DefaultClient when it's nil
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RemoteKeySet{url: url, client: client, now: time.Now}
+}
+
+func (s *RemoteKeySet) Key(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Before(s.expiresAt) {
+		if key, ok := s.keys[keyID]; ok {
+			return key, nil
+		}
+		if now.Sub(s.fetchedAt) < minKeyRefreshInterval {
+			return nil, ErrKeyNotFound
+		}
+	}
+
+	if err := s.fetch(ctx, now); err != nil {
+		return nil, err
+	}
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// fetch replaces the cached keys with the provider's current ones
+func (s *RemoteKeySet) fetch(ctx context.Context, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: fetching keys: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: fetching keys: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("oidc: decoding keys: %w", err)
+	}
+	keys, err := set.publicKeys()
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+	s.fetchedAt = now
+	s.expiresAt = now.Add(cacheDuration(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// cacheDuration returns the max-age of a Cache-Control header, or defaultKeyCacheDuration
+func cacheDuration(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultKeyCacheDuration
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	KeyID   string `json:"kid"`
+	KeyType string `json:"kty"`
+	Use     string `json:"use"`
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+	// Elliptic curve keys
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// publicKeys returns the signing keys of the set by key ID. Keys of other types or uses are
+// skipped, so providers can add them without breaking verification.
+func (s jsonWebKeySet) publicKeys() (map[string]crypto.PublicKey, error) {
+	keys := make(map[string]crypto.PublicKey, len(s.Keys))
+	for _, jwk := range s.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var (
+			key crypto.PublicKey
+			err error
+		)
+		switch jwk.KeyType {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("oidc: key %q: %w", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (k jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Curve {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	// Converting the key checks that the point is on the curve
+	if _, err := key.ECDH(); err != nil {
+		return nil, errors.New("invalid EC key")
+	}
+	return key, nil
+}
@@ -0,0 +1,236 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/LeonardsonCC/dinheiros/internal/oidc"
+)
+
+const (
+	issuer   = "https://accounts.example.com"
+	clientID = "client-id"
+)
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return key
+}
+
+// signToken signs the claims with the key, setting the key ID header
+func signToken(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, keyID string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// validClaims returns the claims of a valid token, which tests change
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            issuer,
+		"aud":            clientID,
+		"sub":            "1234567890",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key := generateRSAKey(t)
+	verifier := oidc.NewVerifier(oidc.StaticKeySet{"key-1": &key.PublicKey}, clientID, issuer, "accounts.example.com")
+	ctx := context.Background()
+
+	token, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, key, "key-1", validClaims()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token.Subject != "1234567890" || token.Email != "user@example.com" || !token.EmailVerified || token.Name != "Test User" {
+		t.Errorf("Unexpected identity %+v", token)
+	}
+
+	// Some providers send email_verified as a string, and the issuer without the scheme
+	claims := validClaims()
+	claims["email_verified"] = "true"
+	claims["iss"] = "accounts.example.com"
+	token, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, key, "key-1", claims))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !token.EmailVerified {
+		t.Error("Expected the email to be verified")
+	}
+}
+
+func TestVerifier_VerifyInvalid(t *testing.T) {
+	key := generateRSAKey(t)
+	otherKey := generateRSAKey(t)
+	verifier := oidc.NewVerifier(oidc.StaticKeySet{"key-1": &key.PublicKey}, clientID, issuer)
+	ctx := context.Background()
+
+	with := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "other audience", token: signToken(t, jwt.SigningMethodRS256, key, "key-1", with("aud", "another-client"))},
+		{name: "other issuer", token: signToken(t, jwt.SigningMethodRS256, key, "key-1", with("iss", "https://evil.example.com"))},
+		{name: "expired", token: signToken(t, jwt.SigningMethodRS256, key, "key-1", with("exp", time.Now().Add(-time.Hour).Unix()))},
+		{name: "no expiration", token: signToken(t, jwt.SigningMethodRS256, key, "key-1", with("exp", nil))},
+		{name: "no subject", token: signToken(t, jwt.SigningMethodRS256, key, "key-1", with("sub", nil))},
+		{name: "unknown key", token: signToken(t, jwt.SigningMethodRS256, key, "key-2", validClaims())},
+		{name: "wrong key", token: signToken(t, jwt.SigningMethodRS256, otherKey, "key-1", validClaims())},
+		{name: "symmetric algorithm", token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), "key-1", validClaims())},
+		{name: "malformed", token: "not-a-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(ctx, tt.token); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("Expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	// Without a client ID no token is valid
+	unconfigured := oidc.NewVerifier(oidc.StaticKeySet{"key-1": &key.PublicKey}, "", issuer)
+	if _, err := unconfigured.Verify(ctx, signToken(t, jwt.SigningMethodRS256, key, "key-1", validClaims())); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken without a client ID, got %v", err)
+	}
+}
+
+func TestVerifier_VerifyECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	verifier := oidc.NewVerifier(oidc.StaticKeySet{"ec": &key.PublicKey}, clientID, issuer)
+
+	if _, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodES256, key, "ec", validClaims())); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+// jwks encodes the RSA and EC public keys as a JSON Web Key Set
+func jwks(t *testing.T, keys map[string]crypto.PublicKey) []byte {
+	t.Helper()
+	encode := func(n *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(n.Bytes())
+	}
+	set := map[string][]map[string]string{"keys": {
+		// Encryption keys are skipped
+		{"kid": "enc", "kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	for keyID, key := range keys {
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			set["keys"] = append(set["keys"], map[string]string{"kid": keyID, "kty": "RSA", "use": "sig", "alg": "RS256", "n": encode(key.N), "e": encode(big.NewInt(int64(key.E)))})
+		case *ecdsa.PublicKey:
+			set["keys"] = append(set["keys"], map[string]string{"kid": keyID, "kty": "EC", "crv": "P-256", "x": encode(key.X), "y": encode(key.Y)})
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Failed to encode key set: %v", err)
+	}
+	return data
+}
+
+func TestRemoteKeySet_Key(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	rotatedKey := generateRSAKey(t)
+
+	keys := map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_, _ = w.Write(jwks(t, keys))
+	}))
+	defer server.Close()
+
+	keySet := oidc.NewRemoteKeySet(server.URL, server.Client())
+	ctx := context.Background()
+
+	key, err := keySet.Key(ctx, "rsa")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !rsaKey.PublicKey.Equal(key) {
+		t.Error("Expected the RSA key of the set")
+	}
+	key, err = keySet.Key(ctx, "ec")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !ecKey.PublicKey.Equal(key) {
+		t.Error("Expected the EC key of the set")
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the keys to be fetched once, got %d", fetches.Load())
+	}
+
+	// Unknown keys don't fetch the keys again right away
+	keys["rotated"] = &rotatedKey.PublicKey
+	if _, err := keySet.Key(ctx, "rotated"); !errors.Is(err, oidc.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if _, err := keySet.Key(ctx, "enc"); !errors.Is(err, oidc.ErrKeyNotFound) {
+		t.Errorf("Expected the encryption key to be skipped, got %v", err)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the cached keys to be used, got %d fetches", fetches.Load())
+	}
+}
+
+func TestRemoteKeySet_KeyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	keySet := oidc.NewRemoteKeySet(server.URL, server.Client())
+	if _, err := keySet.Key(context.Background(), "rsa"); err == nil || errors.Is(err, oidc.ErrKeyNotFound) {
+		t.Errorf("Expected the fetch error, got %v", err)
+	}
+}
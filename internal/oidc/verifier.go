@@ -0,0 +1,118 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for ID tokens that aren't valid for the verifier, wrapping the
+// reason
+var ErrInvalidToken = errors.New("oidc: invalid ID token")
+
+// clockSkew is how far the provider's clock can be from ours
+const clockSkew = time.Minute
+
+// IDToken is the verified identity of an ID token
+type IDToken struct {
+	Issuer  string
+	Subject string
+	// Email is only known to belong to the user when EmailVerified is set
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Nonce         string
+	ExpiresAt     time.Time
+}
+
+// Verifier verifies the ID tokens a provider issued for one client: their signature by one
+// of the provider's keys, their issuer, audience and expiration
+type Verifier struct {
+	keySet   KeySet
+	clientID string
+	issuers  []string
+	now      func() time.Time
+}
+
+// NewVerifier creates a Verifier of the tokens issued for clientID by any of the issuers,
+// which for most providers is one
+func NewVerifier(keySet KeySet, clientID string, issuers ...string) *Verifier {
+	return &Verifier{keySet: keySet, clientID: clientID, issuers: issuers, now: time.Now}
+}
+
+// idTokenClaims are the claims of ID tokens the verifier reads
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Email         string    `json:"email"`
+	EmailVerified claimBool `json:"email_verified"`
+	Name          string    `json:"name"`
+	Picture       string    `json:"picture"`
+	Nonce         string    `json:"nonce"`
+}
+
+// Verify verifies the ID token and returns its identity, or an error wrapping
+// ErrInvalidToken when it isn't valid
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (*IDToken, error) {
+	if v.clientID == "" {
+		return nil, fmt.Errorf("%w: no client ID configured", ErrInvalidToken)
+	}
+
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(rawToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return v.keySet.Key(ctx, keyID)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithAudience(v.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(v.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if !slices.Contains(v.issuers, claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return &IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		Nonce:         claims.Nonce,
+		ExpiresAt:     claims.ExpiresAt.Time,
+	}, nil
+}
+
+// claimBool is a boolean claim that some providers send as a string
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = claimBool(v == "true")
+	default:
+		*b = false
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package ratelimit limits how often a key, such as a client IP or an email, can do something
+// with token buckets. Each key has a bucket of Burst tokens, one of which each request takes,
+// and that is refilled with a token every Interval.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is how many requests a key can make at once and how often it can make another one
+// afterwards
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// Result is whether a request was allowed, how many more requests the key can make right away
+// and, when it wasn't allowed, how long until it can make another one
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. MemoryStore keeps them in the process, so each replica of the API
+// limits requests on its own; replicas that should share the limits need a Store backed by
+// storage they share.
+type Store interface {
+	// Take takes a token from the key's bucket at the given time if it has one
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Limiter limits the requests of each key. Limiters with different names can share a Store.
+type Limiter struct {
+	name  string
+	store Store
+	limit Limit
+	now   func() time.Time
+}
+
+func NewLimiter(name string, store Store, limit Limit) *Limiter {
+	return &Limiter{name: name, store: store, limit: limit, now: time.Now}
+}
+
+// Allow takes a token from the key's bucket if it has one
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.store.Take(ctx, l.name+":"+key, l.limit, l.now())
+}
+
+// sweepInterval is how often MemoryStore removes the buckets that refilled
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill adds the tokens the bucket earned since it was last updated
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+float64(elapsed)/float64(b.limit.Interval))
+		b.updated = now
+	}
+}
+
+// MemoryStore keeps buckets in memory. Buckets that refilled are removed, since a new bucket
+// is full too.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+
+	if b.tokens < 1 {
+		retryAfter := time.Duration((1 - b.tokens) * float64(limit.Interval))
+		return Result{RetryAfter: retryAfter}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// Len returns how many buckets the store keeps
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
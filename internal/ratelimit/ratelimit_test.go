@@ -0,0 +1,98 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/ratelimit"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Burst: 3, Interval: 10 * time.Second}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "a", limit, now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("Expected the request to be allowed with %d remaining, got %+v", i, result)
+		}
+	}
+
+	result, _ := store.Take(ctx, "a", limit, now.Add(4*time.Second))
+	if result.Allowed {
+		t.Fatal("Expected the request over the burst to be denied")
+	}
+	if result.RetryAfter != 6*time.Second {
+		t.Errorf("Expected to retry after 6s, got %v", result.RetryAfter)
+	}
+
+	// Other keys have their own bucket
+	if result, _ := store.Take(ctx, "b", limit, now); !result.Allowed {
+		t.Error("Expected another key to be allowed")
+	}
+
+	// A token is refilled every interval
+	if result, _ := store.Take(ctx, "a", limit, now.Add(10*time.Second)); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected a refilled token to be allowed, got %+v", result)
+	}
+	if result, _ := store.Take(ctx, "a", limit, now.Add(11*time.Second)); result.Allowed {
+		t.Error("Expected the request to be denied until the next token")
+	}
+
+	// The bucket never holds more than the burst
+	for i := 0; i < 3; i++ {
+		if result, _ := store.Take(ctx, "a", limit, now.Add(time.Hour)); !result.Allowed {
+			t.Fatalf("Expected request %d after refilling to be allowed", i+1)
+		}
+	}
+	if result, _ := store.Take(ctx, "a", limit, now.Add(time.Hour)); result.Allowed {
+		t.Error("Expected the request over the burst to be denied after refilling")
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Burst: 2, Interval: time.Second}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	store.Take(ctx, "a", limit, now)
+	store.Take(ctx, "b", ratelimit.Limit{Burst: 2, Interval: time.Hour}, now)
+	store.Take(ctx, "b", ratelimit.Limit{Burst: 2, Interval: time.Hour}, now)
+	if store.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", store.Len())
+	}
+
+	// "a" refilled by the next sweep, while "b" still needs tokens
+	store.Take(ctx, "c", limit, now.Add(2*time.Minute))
+	if store.Len() != 2 {
+		t.Errorf("Expected the refilled bucket to be removed, got %d buckets", store.Len())
+	}
+	if result, _ := store.Take(ctx, "b", ratelimit.Limit{Burst: 2, Interval: time.Hour}, now.Add(2*time.Minute)); result.Allowed {
+		t.Error("Expected the bucket that didn't refill to be kept")
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	login := ratelimit.NewLimiter("login", store, ratelimit.Limit{Burst: 1, Interval: time.Hour})
+	register := ratelimit.NewLimiter("register", store, ratelimit.Limit{Burst: 1, Interval: time.Hour})
+
+	if result, err := login.Allow(ctx, "192.0.2.1"); err != nil || !result.Allowed {
+		t.Fatalf("Expected the first request to be allowed, got %+v, %v", result, err)
+	}
+	if result, _ := login.Allow(ctx, "192.0.2.1"); result.Allowed {
+		t.Error("Expected the second request to be denied")
+	}
+	// Limiters sharing a store don't share buckets
+	if result, _ := register.Allow(ctx, "192.0.2.1"); !result.Allowed {
+		t.Error("Expected another limiter's request to be allowed")
+	}
+}
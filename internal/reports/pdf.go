@@ -0,0 +1,205 @@
+// Package reports renders the reports users download, such as the year-end tax report, as
+// CSV and PDF files.
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size and margins, in points
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 50.0
+	lineSpacing  = 1.4
+	textSize     = 10.0
+	headingSize  = 13.0
+	titleSize    = 16.0
+	courierWidth = 0.6
+)
+
+// Font is one of the standard PDF fonts, which readers have built in, so none is embedded
+type Font string
+
+const (
+	FontHelvetica     Font = "F1"
+	FontHelveticaBold Font = "F2"
+	FontCourier       Font = "F3"
+)
+
+var fontNames = []struct {
+	font Font
+	name string
+}{
+	{FontHelvetica, "Helvetica"},
+	{FontHelveticaBold, "Helvetica-Bold"},
+	{FontCourier, "Courier"},
+}
+
+// Column is a fixed-width column of a table row. Right aligned columns end at the column's
+// right edge, so amounts line up.
+type Column struct {
+	Width int
+	Right bool
+}
+
+// Document is a minimal PDF writer for text reports. Text flows from the top of an A4 page
+// down and continues on a new page when the page is full. Only characters of the Windows-1252
+// encoding are supported, which covers Portuguese; others are written as "?".
+type Document struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+	y       float64
+}
+
+func NewDocument() *Document {
+	d := &Document{}
+	d.newPage()
+	return d
+}
+
+// Title writes a large bold line
+func (d *Document) Title(text string) {
+	d.line(FontHelveticaBold, titleSize, pageMargin, text)
+}
+
+// Heading writes a bold line with some space above it
+func (d *Document) Heading(text string) {
+	d.Space()
+	d.line(FontHelveticaBold, headingSize, pageMargin, text)
+}
+
+// Text writes a line of regular text
+func (d *Document) Text(text string) {
+	d.line(FontHelvetica, textSize, pageMargin, text)
+}
+
+// Row writes the cells in a monospaced font, one per column. Cells longer than their column
+// are cut.
+func (d *Document) Row(columns []Column, cells ...string) {
+	d.advance(textSize)
+	x := pageMargin
+	charWidth := courierWidth * textSize
+	for i, column := range columns {
+		if i >= len(cells) {
+			break
+		}
+		cell := []rune(cells[i])
+		if len(cell) > column.Width {
+			cell = cell[:column.Width]
+		}
+		cellX := x
+		if column.Right {
+			cellX += float64(column.Width-len(cell)) * charWidth
+		}
+		d.write(FontCourier, textSize, cellX, string(cell))
+		x += float64(column.Width+1) * charWidth
+	}
+}
+
+// Space leaves an empty line
+func (d *Document) Space() {
+	d.advance(textSize)
+}
+
+// WriteTo writes the PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	offsets := make([]int, 0)
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 and 2 are the catalog and the page tree, then come the fonts and every page
+	// with its content stream
+	firstPage := 3 + len(fontNames)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	fonts := make([]string, len(fontNames))
+	for i, font := range fontNames {
+		fonts[i] = fmt.Sprintf("/%s %d 0 R", font.font, 3+i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, font := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.name))
+	}
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, strings.Join(fonts, " "), firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+func (d *Document) line(font Font, size float64, x float64, text string) {
+	d.advance(size)
+	d.write(font, size, x, text)
+}
+
+// advance moves down one line of the given font size, starting a new page when it doesn't fit
+func (d *Document) advance(size float64) {
+	d.y -= size * lineSpacing
+	if d.y < pageMargin {
+		d.newPage()
+		d.y -= size * lineSpacing
+	}
+}
+
+func (d *Document) write(font Font, size float64, x float64, text string) {
+	fmt.Fprintf(d.current, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, escape(text))
+}
+
+func (d *Document) newPage() {
+	d.current = &bytes.Buffer{}
+	d.pages = append(d.pages, d.current)
+	d.y = pageHeight - pageMargin
+}
+
+// windows1252 maps the characters Windows-1252 puts in the range Latin-1 leaves for control codes
+var windows1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// escape encodes text as a PDF string in Windows-1252
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7F:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case windows1252[r] != 0:
+			fmt.Fprintf(&b, "\\%03o", windows1252[r])
+		case r < 0x20:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
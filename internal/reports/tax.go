@@ -0,0 +1,132 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TaxReport gathers what the Brazilian income tax declaration (IRPF) asks for a calendar year
+type TaxReport struct {
+	Year            int                 `json:"year"`
+	Accounts        []TaxAccountBalance `json:"accounts"`
+	Deductible      []TaxCategoryTotal  `json:"deductible"`
+	DeductibleTotal float64             `json:"deductible_total"`
+	Income          []TaxIncomeSource   `json:"income"`
+	IncomeTotal     float64             `json:"income_total"`
+}
+
+// TaxAccountBalance is the balance of an account on the last day of the year and of the year
+// before, as declared in "Bens e Direitos"
+type TaxAccountBalance struct {
+	AccountID       uint    `json:"account_id"`
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	PreviousBalance float64 `json:"previous_balance"`
+	Balance         float64 `json:"balance"`
+}
+
+// TaxCategoryTotal is the amount spent in a deductible category and its subcategories
+type TaxCategoryTotal struct {
+	CategoryID uint    `json:"category_id"`
+	Name       string  `json:"name"`
+	Amount     float64 `json:"amount"`
+	Count      int     `json:"count"`
+}
+
+// TaxIncomeSource is the income received from a payer
+type TaxIncomeSource struct {
+	Source string  `json:"source"`
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// WriteTaxReportCSV writes the report as three tables separated by an empty line: account
+// balances, deductible expenses and income. Amounts use a dot as the decimal separator.
+func WriteTaxReportCSV(w io.Writer, report *TaxReport) error {
+	previousYear, year := strconv.Itoa(report.Year-1), strconv.Itoa(report.Year)
+
+	accounts := [][]string{{"account", "type", "balance_" + previousYear + "_12_31", "balance_" + year + "_12_31"}}
+	for _, account := range report.Accounts {
+		accounts = append(accounts, []string{account.Name, account.Type, csvAmount(account.PreviousBalance), csvAmount(account.Balance)})
+	}
+	deductible := [][]string{{"deductible_category", "transactions", "amount"}}
+	for _, category := range report.Deductible {
+		deductible = append(deductible, []string{category.Name, strconv.Itoa(category.Count), csvAmount(category.Amount)})
+	}
+	deductible = append(deductible, []string{"total", "", csvAmount(report.DeductibleTotal)})
+	income := [][]string{{"income_source", "transactions", "amount"}}
+	for _, source := range report.Income {
+		income = append(income, []string{source.Source, strconv.Itoa(source.Count), csvAmount(source.Amount)})
+	}
+	income = append(income, []string{"total", "", csvAmount(report.IncomeTotal)})
+
+	for i, table := range [][][]string{accounts, deductible, income} {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := csv.NewWriter(w).WriteAll(table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTaxReportPDF writes the report as a printable PDF with amounts in reais
+func WriteTaxReportPDF(w io.Writer, report *TaxReport) error {
+	doc := NewDocument()
+	doc.Title(fmt.Sprintf("Tax report %d", report.Year))
+	doc.Text(fmt.Sprintf("Calendar year from 01/01/%d to 31/12/%d", report.Year, report.Year))
+
+	accountColumns := []Column{{Width: 38}, {Width: 18, Right: true}, {Width: 18, Right: true}}
+	doc.Heading("Account balances")
+	doc.Row(accountColumns, "Account", fmt.Sprintf("31/12/%d", report.Year-1), fmt.Sprintf("31/12/%d", report.Year))
+	for _, account := range report.Accounts {
+		doc.Row(accountColumns, account.Name, pdfAmount(account.PreviousBalance), pdfAmount(account.Balance))
+	}
+
+	totalColumns := []Column{{Width: 44}, {Width: 12, Right: true}, {Width: 18, Right: true}}
+	doc.Heading("Deductible expenses")
+	doc.Row(totalColumns, "Category", "Transactions", "Amount")
+	for _, category := range report.Deductible {
+		doc.Row(totalColumns, category.Name, strconv.Itoa(category.Count), pdfAmount(category.Amount))
+	}
+	doc.Row(totalColumns, "Total", "", pdfAmount(report.DeductibleTotal))
+
+	doc.Heading("Income by source")
+	doc.Row(totalColumns, "Source", "Transactions", "Amount")
+	for _, source := range report.Income {
+		doc.Row(totalColumns, source.Source, strconv.Itoa(source.Count), pdfAmount(source.Amount))
+	}
+	doc.Row(totalColumns, "Total", "", pdfAmount(report.IncomeTotal))
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+func csvAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// pdfAmount formats an amount the Brazilian way, as in "R$ 1.234,56"
+func pdfAmount(amount float64) string {
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	sign := ""
+	if amount < 0 && cents > 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%sR$ %s,%02d", sign, grouped.String(), cents%100)
+}
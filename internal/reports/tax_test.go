@@ -0,0 +1,89 @@
+package reports_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+
+	"github.com/LeonardsonCC/dinheiros/internal/reports"
+)
+
+func testTaxReport() *reports.TaxReport {
+	return &reports.TaxReport{
+		Year: 2025,
+		Accounts: []reports.TaxAccountBalance{
+			{AccountID: 1, Name: "Conta Corrente", Type: "checking", PreviousBalance: 1000, Balance: 12345.6},
+		},
+		Deductible:      []reports.TaxCategoryTotal{{CategoryID: 2, Name: "Saúde", Amount: 350.5, Count: 2}},
+		DeductibleTotal: 350.5,
+		Income:          []reports.TaxIncomeSource{{Source: "Empresa (Matriz), Ltda", Amount: 60000, Count: 12}},
+		IncomeTotal:     60000,
+	}
+}
+
+func TestWriteTaxReportCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := reports.WriteTaxReportCSV(&out, testTaxReport()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := `account,type,balance_2024_12_31,balance_2025_12_31
+Conta Corrente,checking,1000.00,12345.60
+
+deductible_category,transactions,amount
+Saúde,2,350.50
+total,,350.50
+
+income_source,transactions,amount
+"Empresa (Matriz), Ltda",12,60000.00
+total,,60000.00
+`
+	if out.String() != want {
+		t.Errorf("Unexpected CSV:\n%s", out.String())
+	}
+}
+
+func TestWriteTaxReportPDF(t *testing.T) {
+	var out bytes.Buffer
+	if err := reports.WriteTaxReportPDF(&out, testTaxReport()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	if reader.NumPage() != 1 {
+		t.Fatalf("Expected 1 page, got %d", reader.NumPage())
+	}
+	text, err := reader.Page(1).GetPlainText(nil)
+	if err != nil {
+		t.Fatalf("Failed to extract text: %v", err)
+	}
+	for _, expected := range []string{"Tax report 2025", "Conta Corrente", "R$ 12.345,60", "Saúde", "R$ 350,50", "Empresa (Matriz), Ltda", "R$ 60.000,00"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected PDF text to contain %q, got %q", expected, text)
+		}
+	}
+}
+
+func TestDocument_PageBreak(t *testing.T) {
+	doc := reports.NewDocument()
+	for i := 0; i < 100; i++ {
+		doc.Text("Linha")
+	}
+	var out bytes.Buffer
+	if _, err := doc.WriteTo(&out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	if reader.NumPage() < 2 {
+		t.Errorf("Expected the text to continue on a new page, got %d pages", reader.NumPage())
+	}
+}
@@ -62,6 +62,15 @@ func (r *AccountShareRepository) GetInvitationsByAccountID(accountID uint) ([]mo
 	return invitations, err
 }
 
+// GetInvitationsByUser returns the invitations the user sent and the ones sent to their email,
+// whatever their status
+func (r *AccountShareRepository) GetInvitationsByUser(userID uint, email string) ([]models.ShareInvitation, error) {
+	var invitations []models.ShareInvitation
+	err := r.db.Preload("Account").Preload("OwnerUser").
+		Where("owner_user_id = ? OR invited_email = ?", userID, email).Order("id").Find(&invitations).Error
+	return invitations, err
+}
+
 func (r *AccountShareRepository) UpdateInvitationStatus(id uint, status models.InvitationStatus) error {
 	updates := map[string]interface{}{
 		"status": status,
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+type AlertRepository interface {
+	// FindByUserID returns the user's alerts, newest first. Dismissed alerts are only
+	// returned when includeDismissed is set.
+	FindByUserID(ctx context.Context, userID uint, includeDismissed bool) ([]models.Alert, error)
+	FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Alert, error)
+	// CreateIfNew stores the alerts whose key the user has no alert for yet, including
+	// dismissed ones, and returns the stored alerts
+	CreateIfNew(ctx context.Context, alerts []models.Alert) ([]models.Alert, error)
+	Update(ctx context.Context, alert *models.Alert) error
+	MarkAllRead(ctx context.Context, userID uint) error
+}
+
+type alertRepository struct {
+	db *gorm.DB
+}
+
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+func (r *alertRepository) FindByUserID(ctx context.Context, userID uint, includeDismissed bool) ([]models.Alert, error) {
+	var alerts []models.Alert
+	tx := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if !includeDismissed {
+		tx = tx.Where("dismissed_at IS NULL")
+	}
+	err := tx.Order("created_at DESC, id DESC").Find(&alerts).Error
+	return alerts, err
+}
+
+func (r *alertRepository) FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Alert, error) {
+	var alert models.Alert
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&alert).Error; err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *alertRepository) CreateIfNew(ctx context.Context, alerts []models.Alert) ([]models.Alert, error) {
+	created := make([]models.Alert, 0)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, alert := range alerts {
+			result := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+				DoNothing: true,
+			}).Create(&alert)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, alert)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *alertRepository) Update(ctx context.Context, alert *models.Alert) error {
+	return r.db.WithContext(ctx).Save(alert).Error
+}
+
+func (r *alertRepository) MarkAllRead(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Alert{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAlertTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Alert{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestAlertRepository_CreateIfNew(t *testing.T) {
+	db, user := setupAlertTestDB(t)
+	repo := NewAlertRepository(db)
+	ctx := context.Background()
+
+	alerts := []models.Alert{
+		{UserID: user.ID, Key: "large_expense:1", Type: models.AlertTypeLargeExpense, Message: "Large expense"},
+		{UserID: user.ID, Key: "category_spike:2026-10:Mercado", Type: models.AlertTypeCategorySpike, Message: "Spike"},
+	}
+	created, err := repo.CreateIfNew(ctx, alerts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(created) != 2 || created[0].ID == 0 || created[1].ID == 0 {
+		t.Fatalf("Expected 2 stored alerts, got %+v", created)
+	}
+
+	// Dismissed alerts still block the same anomaly from being reported again
+	now := time.Now()
+	created[0].DismissedAt = &now
+	if err := repo.Update(ctx, &created[0]); err != nil {
+		t.Fatalf("Failed to dismiss alert: %v", err)
+	}
+
+	alerts = append(alerts, models.Alert{UserID: user.ID, Key: "large_expense:3", Type: models.AlertTypeLargeExpense, Message: "Another large expense"})
+	created, err = repo.CreateIfNew(ctx, alerts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(created) != 1 || created[0].Key != "large_expense:3" {
+		t.Errorf("Expected only the new alert to be stored, got %+v", created)
+	}
+
+	// The same key is allowed for other users
+	other := &models.User{Name: "Other", Email: "other@example.com", Password: "hashedpassword"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	created, err = repo.CreateIfNew(ctx, []models.Alert{{UserID: other.ID, Key: "large_expense:1", Type: models.AlertTypeLargeExpense, Message: "Large expense"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(created) != 1 {
+		t.Errorf("Expected the other user's alert to be stored, got %+v", created)
+	}
+}
+
+func TestAlertRepository_FindByUserIDAndMarkAllRead(t *testing.T) {
+	db, user := setupAlertTestDB(t)
+	repo := NewAlertRepository(db)
+	ctx := context.Background()
+
+	created, err := repo.CreateIfNew(ctx, []models.Alert{
+		{UserID: user.ID, Key: "a", Type: models.AlertTypeLargeExpense, Message: "A"},
+		{UserID: user.ID, Key: "b", Type: models.AlertTypeLargeExpense, Message: "B"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create alerts: %v", err)
+	}
+	now := time.Now()
+	created[0].DismissedAt = &now
+	if err := repo.Update(ctx, &created[0]); err != nil {
+		t.Fatalf("Failed to dismiss alert: %v", err)
+	}
+
+	alerts, err := repo.FindByUserID(ctx, user.ID, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Key != "b" {
+		t.Errorf("Expected only the active alert, got %+v", alerts)
+	}
+	alerts, err = repo.FindByUserID(ctx, user.ID, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Errorf("Expected 2 alerts including dismissed, got %d", len(alerts))
+	}
+
+	if err := repo.MarkAllRead(ctx, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	alerts, err = repo.FindByUserID(ctx, user.ID, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, alert := range alerts {
+		if alert.ReadAt == nil {
+			t.Errorf("Expected alert %s to be read", alert.Key)
+		}
+	}
+}
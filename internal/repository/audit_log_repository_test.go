@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAuditLogTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.AuditLog{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestAuditLogRepository_FindByUserID(t *testing.T) {
+	db, user := setupAuditLogTestDB(t)
+	repo := NewAuditLogRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, details := range []string{"first", "second"} {
+		entry := &models.AuditLog{
+			UserID:    user.ID,
+			Action:    models.AuditActionAccountLocked,
+			Details:   details,
+			IPAddress: "192.0.2.1",
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.Create(ctx, entry); err != nil {
+			t.Fatalf("Failed to create audit entry: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &models.AuditLog{UserID: user.ID + 1, Action: models.AuditActionAccountLocked}); err != nil {
+		t.Fatalf("Failed to create audit entry: %v", err)
+	}
+
+	entries, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 2 || entries[0].Details != "second" || entries[1].Details != "first" {
+		t.Errorf("Expected the user's 2 entries, newest first, got %+v", entries)
+	}
+}
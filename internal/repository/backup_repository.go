@@ -0,0 +1,511 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// UserData is everything a user owns. Records reference each other by their IDs, which are
+// only meaningful within the same UserData when it is restored.
+type UserData struct {
+	Accounts   []models.Account
+	Categories []models.Category
+	Tags       []models.Tag
+	// Merchants have their aliases loaded
+	Merchants []models.Merchant
+	// Transactions have their categories, tags and splits loaded. Transfers to transactions
+	// of accounts the user doesn't own are left unattached.
+	Transactions  []models.Transaction
+	Rules         []models.CategorizationRule
+	Subscriptions []models.Subscription
+	// Shares have the user the account is shared with loaded
+	Shares []models.AccountShare
+}
+
+// RestoreResult counts the records a restore created. Categories, tags and merchants the user
+// already has with the same name are reused, and subscriptions the user already has are
+// skipped. Shares are restored as invitations.
+type RestoreResult struct {
+	Accounts             int
+	Categories           int
+	ReusedCategories     int
+	Tags                 int
+	ReusedTags           int
+	Merchants            int
+	ReusedMerchants      int
+	MerchantAliases      int
+	Transactions         int
+	Rules                int
+	Subscriptions        int
+	SkippedSubscriptions int
+	ShareInvitations     int
+}
+
+type BackupRepository interface {
+	Load(ctx context.Context, userID uint) (*UserData, error)
+	// Restore creates the records of data for the user with new IDs, in a single database
+	// transaction. With dryRun the transaction is rolled back, so the result tells what
+	// would be restored without saving anything.
+	Restore(ctx context.Context, userID uint, data *UserData, dryRun bool) (*RestoreResult, error)
+}
+
+// restoredInvitationDuration is how long the invitations of restored shares can be accepted,
+// as long as the ones users create
+const restoredInvitationDuration = 7 * 24 * time.Hour
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+const restoreBatchSize = 500
+
+type backupRepository struct {
+	db *gorm.DB
+}
+
+func NewBackupRepository(db *gorm.DB) BackupRepository {
+	return &backupRepository{db: db}
+}
+
+func (r *backupRepository) Load(ctx context.Context, userID uint) (*UserData, error) {
+	db := r.db.WithContext(ctx)
+	data := &UserData{}
+
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&data.Accounts).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&data.Categories).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&data.Tags).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Preload("Aliases", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Where("user_id = ?", userID).Order("id").Find(&data.Merchants).Error; err != nil {
+		return nil, err
+	}
+
+	accountIDs := make([]uint, len(data.Accounts))
+	for i, account := range data.Accounts {
+		accountIDs[i] = account.ID
+	}
+	if len(accountIDs) > 0 {
+		if err := db.Preload("Categories").Preload("Tags").Preload("Splits").
+			Where("account_id IN ?", accountIDs).Order("id").Find(&data.Transactions).Error; err != nil {
+			return nil, err
+		}
+	}
+	owned := make(map[uint]bool, len(data.Transactions))
+	for _, transaction := range data.Transactions {
+		owned[transaction.ID] = true
+	}
+	for i := range data.Transactions {
+		if attached := data.Transactions[i].AttachedTransactionID; attached != nil && !owned[*attached] {
+			data.Transactions[i].AttachedTransactionID = nil
+			data.Transactions[i].AttachmentType = nil
+		}
+	}
+
+	if err := db.Preload("Tags").Where("user_id = ?", userID).Order("id").Find(&data.Rules).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&data.Subscriptions).Error; err != nil {
+		return nil, err
+	}
+	var shares []models.AccountShare
+	if err := db.Preload("SharedUser").Where("owner_user_id = ?", userID).Order("id").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	// Shares with deleted users can't be shared again
+	for _, share := range shares {
+		if share.SharedUser.ID != 0 {
+			data.Shares = append(data.Shares, share)
+		}
+	}
+	return data, nil
+}
+
+func (r *backupRepository) Restore(ctx context.Context, userID uint, data *UserData, dryRun bool) (*RestoreResult, error) {
+	var result *RestoreResult
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		restorer := &restorer{
+			tx:           tx,
+			userID:       userID,
+			result:       &RestoreResult{},
+			accounts:     make(map[uint]uint),
+			categories:   make(map[uint]uint),
+			tags:         make(map[uint]uint),
+			merchants:    make(map[uint]uint),
+			transactions: make(map[uint]uint),
+		}
+		if err := restorer.restore(data); err != nil {
+			return err
+		}
+		result = restorer.result
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// restorer creates the records of a restore, mapping the IDs they had in the backup to the
+// IDs they got
+type restorer struct {
+	tx     *gorm.DB
+	userID uint
+	result *RestoreResult
+
+	accounts     map[uint]uint
+	categories   map[uint]uint
+	tags         map[uint]uint
+	merchants    map[uint]uint
+	transactions map[uint]uint
+}
+
+func (r *restorer) restore(data *UserData) error {
+	steps := []func(*UserData) error{
+		r.restoreAccounts,
+		r.restoreCategories,
+		r.restoreTags,
+		r.restoreMerchants,
+		r.restoreTransactions,
+		r.restoreRules,
+		r.restoreSubscriptions,
+		r.restoreShares,
+	}
+	for _, step := range steps {
+		if err := step(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreAccounts(data *UserData) error {
+	for _, backup := range data.Accounts {
+		account := backup
+		account.ID = 0
+		account.DeletedAt = gorm.DeletedAt{}
+		account.UserID = r.userID
+		account.PaymentAccountID = nil
+		account.Transactions = nil
+		if err := r.tx.Omit(clause.Associations).Create(&account).Error; err != nil {
+			return err
+		}
+		r.accounts[backup.ID] = account.ID
+		r.result.Accounts++
+	}
+	// Credit cards can be paid from accounts created after them
+	for _, backup := range data.Accounts {
+		if backup.PaymentAccountID == nil {
+			continue
+		}
+		if err := r.tx.Model(&models.Account{}).Where("id = ?", r.accounts[backup.ID]).
+			Update("payment_account_id", r.accounts[*backup.PaymentAccountID]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreCategories(data *UserData) error {
+	var existing []models.Category
+	if err := r.tx.Where("user_id = ?", r.userID).Find(&existing).Error; err != nil {
+		return err
+	}
+	type categoryKey struct {
+		name            string
+		transactionType models.TransactionType
+	}
+	byKey := make(map[categoryKey]uint, len(existing))
+	for _, category := range existing {
+		byKey[categoryKey{category.Name, category.Type}] = category.ID
+	}
+
+	created := make(map[uint]bool)
+	for _, backup := range data.Categories {
+		if id, ok := byKey[categoryKey{backup.Name, backup.Type}]; ok {
+			r.categories[backup.ID] = id
+			r.result.ReusedCategories++
+			continue
+		}
+		category := models.Category{Name: backup.Name, Description: backup.Description, Deductible: backup.Deductible, Type: backup.Type, UserID: r.userID}
+		if err := r.tx.Omit(clause.Associations).Create(&category).Error; err != nil {
+			return err
+		}
+		r.categories[backup.ID] = category.ID
+		created[backup.ID] = true
+		r.result.Categories++
+	}
+	// Parents are set once every category exists. Reused categories keep their parents.
+	for _, backup := range data.Categories {
+		if backup.ParentID == nil || !created[backup.ID] {
+			continue
+		}
+		if err := r.tx.Model(&models.Category{}).Where("id = ?", r.categories[backup.ID]).
+			Update("parent_id", r.categories[*backup.ParentID]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreTags(data *UserData) error {
+	var existing []models.Tag
+	if err := r.tx.Where("user_id = ?", r.userID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]uint, len(existing))
+	for _, tag := range existing {
+		byName[tag.Name] = tag.ID
+	}
+
+	for _, backup := range data.Tags {
+		if id, ok := byName[backup.Name]; ok {
+			r.tags[backup.ID] = id
+			r.result.ReusedTags++
+			continue
+		}
+		tag := models.Tag{Name: backup.Name, Color: backup.Color, UserID: r.userID}
+		if err := r.tx.Omit(clause.Associations).Create(&tag).Error; err != nil {
+			return err
+		}
+		r.tags[backup.ID] = tag.ID
+		r.result.Tags++
+	}
+	return nil
+}
+
+func (r *restorer) restoreMerchants(data *UserData) error {
+	var existing []models.Merchant
+	if err := r.tx.Where("user_id = ?", r.userID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byKey := make(map[string]uint, len(existing))
+	for _, merchant := range existing {
+		byKey[merchant.Key] = merchant.ID
+	}
+	var patterns []string
+	if err := r.tx.Model(&models.MerchantAlias{}).Where("user_id = ?", r.userID).Pluck("pattern", &patterns).Error; err != nil {
+		return err
+	}
+	existingPatterns := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		existingPatterns[pattern] = true
+	}
+
+	for _, backup := range data.Merchants {
+		if id, ok := byKey[backup.Key]; ok {
+			r.merchants[backup.ID] = id
+			r.result.ReusedMerchants++
+		} else {
+			merchant := models.Merchant{Key: backup.Key, Name: backup.Name, UserID: r.userID}
+			if err := r.tx.Omit(clause.Associations).Create(&merchant).Error; err != nil {
+				return err
+			}
+			r.merchants[backup.ID] = merchant.ID
+			byKey[backup.Key] = merchant.ID
+			r.result.Merchants++
+		}
+
+		for _, backupAlias := range backup.Aliases {
+			if existingPatterns[backupAlias.Pattern] {
+				continue
+			}
+			alias := models.MerchantAlias{UserID: r.userID, MerchantID: r.merchants[backup.ID], Pattern: backupAlias.Pattern}
+			if err := r.tx.Omit(clause.Associations).Create(&alias).Error; err != nil {
+				return err
+			}
+			existingPatterns[backupAlias.Pattern] = true
+			r.result.MerchantAliases++
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreTransactions(data *UserData) error {
+	for start := 0; start < len(data.Transactions); start += restoreBatchSize {
+		end := start + restoreBatchSize
+		if end > len(data.Transactions) {
+			end = len(data.Transactions)
+		}
+		backups := data.Transactions[start:end]
+
+		transactions := make([]models.Transaction, len(backups))
+		for i, backup := range backups {
+			transaction := models.Transaction{
+				Date:        backup.Date,
+				Amount:      backup.Amount,
+				Type:        backup.Type,
+				Description: backup.Description,
+				AccountID:   r.accounts[backup.AccountID],
+			}
+			transaction.CreatedAt = backup.CreatedAt
+			if backup.MerchantID != nil {
+				merchantID := r.merchants[*backup.MerchantID]
+				transaction.MerchantID = &merchantID
+			}
+			for _, category := range backup.Categories {
+				transaction.Categories = append(transaction.Categories, &models.Category{Model: gorm.Model{ID: r.categories[category.ID]}})
+			}
+			for _, tag := range backup.Tags {
+				transaction.Tags = append(transaction.Tags, &models.Tag{ID: r.tags[tag.ID]})
+			}
+			transactions[i] = transaction
+		}
+		// Only the links to the categories and tags are created, they were restored before
+		if err := r.tx.Omit("Account", "Merchant", "AttachedTransaction", "Splits", "Categories.*", "Tags.*").
+			Create(&transactions).Error; err != nil {
+			return err
+		}
+
+		var splits []models.TransactionSplit
+		for i, backup := range backups {
+			r.transactions[backup.ID] = transactions[i].ID
+			for _, split := range backup.Splits {
+				splits = append(splits, models.TransactionSplit{
+					TransactionID: transactions[i].ID,
+					CategoryID:    r.categories[split.CategoryID],
+					Amount:        split.Amount,
+					Memo:          split.Memo,
+				})
+			}
+		}
+		if len(splits) > 0 {
+			if err := r.tx.Omit("Category").Create(&splits).Error; err != nil {
+				return err
+			}
+		}
+		r.result.Transactions += len(transactions)
+	}
+
+	// Transfers are attached once both sides exist
+	for _, backup := range data.Transactions {
+		if backup.AttachedTransactionID == nil {
+			continue
+		}
+		attachedID, ok := r.transactions[*backup.AttachedTransactionID]
+		if !ok {
+			continue
+		}
+		if err := r.tx.Model(&models.Transaction{}).Where("id = ?", r.transactions[backup.ID]).Updates(map[string]interface{}{
+			"attached_transaction_id": attachedID,
+			"attachment_type":         backup.AttachmentType,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreRules(data *UserData) error {
+	for _, backup := range data.Rules {
+		rule := models.CategorizationRule{
+			UserID:          r.userID,
+			Name:            backup.Name,
+			Type:            backup.Type,
+			Value:           backup.Value,
+			TransactionType: backup.TransactionType,
+			CategoryDst:     r.categories[backup.CategoryDst],
+			Active:          backup.Active,
+		}
+		for _, tag := range backup.Tags {
+			rule.Tags = append(rule.Tags, models.Tag{ID: r.tags[tag.ID]})
+		}
+		if err := r.tx.Omit("Tags.*").Create(&rule).Error; err != nil {
+			return err
+		}
+		// Active defaults to true, so creating an inactive rule doesn't save it as inactive
+		if !backup.Active {
+			if err := r.tx.Model(&rule).Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		r.result.Rules++
+	}
+	return nil
+}
+
+func (r *restorer) restoreSubscriptions(data *UserData) error {
+	var keys []string
+	if err := r.tx.Model(&models.Subscription{}).Where("user_id = ?", r.userID).Pluck("key", &keys).Error; err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		existing[key] = true
+	}
+
+	for _, backup := range data.Subscriptions {
+		if existing[backup.Key] {
+			r.result.SkippedSubscriptions++
+			continue
+		}
+		subscription := backup
+		subscription.ID = 0
+		subscription.UserID = r.userID
+		subscription.AccountID = r.accounts[backup.AccountID]
+		if err := r.tx.Omit(clause.Associations).Create(&subscription).Error; err != nil {
+			return err
+		}
+		existing[backup.Key] = true
+		r.result.Subscriptions++
+	}
+	return nil
+}
+
+// restoreShares invites the users the accounts were shared with again, as the user with the
+// same email in this instance may not be the same person. Whether the emails belong to users
+// isn't looked up, so a restore can't tell who has an account.
+func (r *restorer) restoreShares(data *UserData) error {
+	var owner models.User
+	if err := r.tx.Select("email").First(&owner, r.userID).Error; err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(restoredInvitationDuration)
+	for _, backup := range data.Shares {
+		if backup.SharedUser.Email == "" || backup.SharedUser.Email == owner.Email {
+			continue
+		}
+		token, err := generateInvitationToken()
+		if err != nil {
+			return err
+		}
+		invitation := models.ShareInvitation{
+			AccountID:       r.accounts[backup.AccountID],
+			OwnerUserID:     r.userID,
+			InvitedEmail:    backup.SharedUser.Email,
+			InvitationToken: token,
+			PermissionLevel: backup.PermissionLevel,
+			Status:          models.InvitationPending,
+			ExpiresAt:       expiresAt,
+		}
+		if err := r.tx.Omit(clause.Associations).Create(&invitation).Error; err != nil {
+			return err
+		}
+		r.result.ShareInvitations++
+	}
+	return nil
+}
+
+// generateInvitationToken returns a token in the format of the invitations users create
+func generateInvitationToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
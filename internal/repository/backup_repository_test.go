@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBackupTestDB(t *testing.T) (*gorm.DB, *models.User, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.AccountShare{}, &models.ShareInvitation{}, &models.Merchant{}, &models.MerchantAlias{}, &models.CategorizationRule{}, &models.Subscription{}, &models.Alert{}, &models.Session{}, &models.RecoveryCode{}, &models.PersonalAccessToken{}, &models.AuditLog{}, &models.PasswordResetToken{}, &models.UserIdentity{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// The user whose data is backed up and the user it is restored to
+	source := &models.User{Name: "Source", Email: "source@example.com", Password: "hashedpassword"}
+	target := &models.User{Name: "Target", Email: "target@example.com", Password: "hashedpassword"}
+	for _, user := range []*models.User{source, target} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create test user: %v", err)
+		}
+	}
+	return db, source, target
+}
+
+// seedBackupData gives the user two accounts with a transfer between them, a categorized,
+// tagged and split expense and the rules, alerts, subscriptions and shares that reference them
+func seedBackupData(t *testing.T, db *gorm.DB, user *models.User) {
+	create := func(value interface{}) {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatalf("Failed to create %T: %v", value, err)
+		}
+	}
+
+	checking := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID, Balance: 500}
+	create(checking)
+	closingDay, dueDay := 3, 10
+	card := &models.Account{Name: "Cartão", Type: models.AccountTypeCredit, UserID: user.ID, StatementClosingDay: &closingDay, PaymentDueDay: &dueDay, PaymentAccountID: &checking.ID}
+	create(card)
+
+	health := &models.Category{Name: "Saúde", Type: models.TransactionTypeExpense, UserID: user.ID, Deductible: true}
+	create(health)
+	pharmacy := &models.Category{Name: "Farmácia", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &health.ID}
+	create(pharmacy)
+	tag := &models.Tag{Name: "reembolsável", UserID: user.ID}
+	create(tag)
+	merchant := &models.Merchant{Key: "drogasil", Name: "Drogasil", UserID: user.ID}
+	create(merchant)
+	create(&models.MerchantAlias{UserID: user.ID, MerchantID: merchant.ID, Pattern: "RAIA DROGASIL"})
+
+	date := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	expense := &models.Transaction{Date: date, Amount: 80, Type: models.TransactionTypeExpense, Description: "RAIA DROGASIL", AccountID: card.ID, MerchantID: &merchant.ID,
+		Categories: []*models.Category{pharmacy}, Tags: []*models.Tag{tag}}
+	create(expense)
+	create(&[]models.TransactionSplit{
+		{TransactionID: expense.ID, CategoryID: pharmacy.ID, Amount: 50},
+		{TransactionID: expense.ID, CategoryID: health.ID, Amount: 30, Memo: "consulta"},
+	})
+
+	outbound, inbound := models.AttachmentTypeOutboundTransfer, models.AttachmentTypeInboundTransfer
+	payment := &models.Transaction{Date: date, Amount: 80, Type: models.TransactionTypeExpense, Description: "Pagamento fatura", AccountID: checking.ID}
+	create(payment)
+	received := &models.Transaction{Date: date, Amount: 80, Type: models.TransactionTypeIncome, Description: "Pagamento fatura", AccountID: card.ID,
+		AttachedTransactionID: &payment.ID, AttachmentType: &inbound}
+	create(received)
+	db.Model(payment).Updates(map[string]interface{}{"attached_transaction_id": received.ID, "attachment_type": outbound})
+
+	rule := &models.CategorizationRule{UserID: user.ID, Name: "Farmácia", Type: "merchant", Value: "Drogasil", TransactionType: "expense", CategoryDst: pharmacy.ID, Tags: []models.Tag{*tag}}
+	if err := db.Omit("Tags.*").Create(rule).Error; err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	db.Model(rule).Update("active", false)
+	create(&models.Alert{UserID: user.ID, Key: "large_expense:1", Type: models.AlertTypeLargeExpense, Message: "Large expense", TransactionID: &expense.ID, Amount: 80})
+	create(&models.Subscription{UserID: user.ID, Key: "netflix", Name: "Netflix", AccountID: card.ID, Cadence: models.SubscriptionCadenceMonthly, Amount: 39.9, Status: models.SubscriptionStatusConfirmed})
+
+	ana := &models.User{Name: "Ana", Email: "ana@example.com", Password: "hashedpassword"}
+	db.Where("email = ?", ana.Email).FirstOrCreate(ana)
+	create(&models.AccountShare{AccountID: checking.ID, OwnerUserID: user.ID, SharedUserID: ana.ID, PermissionLevel: models.PermissionRead, SharedAt: date})
+	bob := &models.User{Name: "Bob", Email: "bob@example.com", Password: "hashedpassword"}
+	db.Where("email = ?", bob.Email).FirstOrCreate(bob)
+	create(&models.AccountShare{AccountID: card.ID, OwnerUserID: user.ID, SharedUserID: bob.ID, PermissionLevel: models.PermissionRead, SharedAt: date})
+}
+
+func TestBackupRepository_Load(t *testing.T) {
+	db, source, _ := setupBackupTestDB(t)
+	seedBackupData(t, db, source)
+	repo := NewBackupRepository(db)
+
+	data, err := repo.Load(context.Background(), source.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(data.Accounts) != 2 || len(data.Categories) != 2 || len(data.Tags) != 1 || len(data.Merchants) != 1 {
+		t.Fatalf("Expected 2 accounts, 2 categories, 1 tag and 1 merchant, got %d, %d, %d and %d",
+			len(data.Accounts), len(data.Categories), len(data.Tags), len(data.Merchants))
+	}
+	if len(data.Merchants[0].Aliases) != 1 {
+		t.Errorf("Expected the merchant aliases to be loaded, got %+v", data.Merchants[0])
+	}
+	if len(data.Transactions) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(data.Transactions))
+	}
+	expense := data.Transactions[0]
+	if len(expense.Categories) != 1 || len(expense.Tags) != 1 || len(expense.Splits) != 2 {
+		t.Errorf("Expected the categories, tags and splits to be loaded, got %+v", expense)
+	}
+	if len(data.Rules) != 1 || len(data.Rules[0].Tags) != 1 || len(data.Subscriptions) != 1 {
+		t.Errorf("Expected the rule with its tag and the subscription, got %+v and %+v", data.Rules, data.Subscriptions)
+	}
+	if len(data.Shares) != 2 || data.Shares[0].SharedUser.Email != "ana@example.com" {
+		t.Errorf("Expected the shares with their users, got %+v", data.Shares)
+	}
+}
+
+func TestBackupRepository_Restore(t *testing.T) {
+	db, source, target := setupBackupTestDB(t)
+	seedBackupData(t, db, source)
+	repo := NewBackupRepository(db)
+	ctx := context.Background()
+
+	// The target already has a category with the same name, which is reused
+	existing := &models.Category{Name: "Saúde", Type: models.TransactionTypeExpense, UserID: target.ID}
+	if err := db.Create(existing).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	data, err := repo.Load(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Bob doesn't exist in the instance the backup is restored to, which the result doesn't tell
+	db.Unscoped().Where("email = ?", "bob@example.com").Delete(&models.User{})
+
+	result, err := repo.Restore(ctx, target.ID, data, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Accounts != 2 || result.Categories != 1 || result.ReusedCategories != 1 || result.Tags != 1 || result.Merchants != 1 ||
+		result.MerchantAliases != 1 || result.Transactions != 3 || result.Rules != 1 || result.Subscriptions != 1 || result.ShareInvitations != 2 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	restored, err := repo.Load(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(restored.Accounts) != 2 || len(restored.Transactions) != 3 {
+		t.Fatalf("Expected 2 accounts and 3 transactions, got %d and %d", len(restored.Accounts), len(restored.Transactions))
+	}
+	checking, card := restored.Accounts[0], restored.Accounts[1]
+	if checking.ID == data.Accounts[0].ID || card.PaymentAccountID == nil || *card.PaymentAccountID != checking.ID {
+		t.Errorf("Expected the card to be paid from the new checking account %d, got %+v", checking.ID, card.PaymentAccountID)
+	}
+	if checking.Balance != 500 || card.StatementClosingDay == nil || *card.StatementClosingDay != 3 {
+		t.Errorf("Expected the account details to be kept, got %+v and %+v", checking, card)
+	}
+
+	var pharmacy models.Category
+	db.Where("user_id = ? AND name = ?", target.ID, "Farmácia").First(&pharmacy)
+	if pharmacy.ParentID == nil || *pharmacy.ParentID != existing.ID {
+		t.Errorf("Expected Farmácia under the existing Saúde %d, got %v", existing.ID, pharmacy.ParentID)
+	}
+
+	expense, payment, received := restored.Transactions[0], restored.Transactions[1], restored.Transactions[2]
+	if expense.AccountID != card.ID || expense.MerchantID == nil || *expense.MerchantID != restored.Merchants[0].ID {
+		t.Errorf("Expected the expense in the new card with the new merchant, got %+v", expense)
+	}
+	if len(expense.Categories) != 1 || expense.Categories[0].ID != pharmacy.ID || len(expense.Tags) != 1 || expense.Tags[0].ID != restored.Tags[0].ID {
+		t.Errorf("Expected the expense linked to the new category and tag, got %+v and %+v", expense.Categories, expense.Tags)
+	}
+	if len(expense.Splits) != 2 || expense.Splits[1].CategoryID != existing.ID || expense.Splits[1].Memo != "consulta" {
+		t.Errorf("Expected the splits in the new categories, got %+v", expense.Splits)
+	}
+	if payment.AttachedTransactionID == nil || *payment.AttachedTransactionID != received.ID ||
+		received.AttachedTransactionID == nil || *received.AttachedTransactionID != payment.ID {
+		t.Errorf("Expected the transfer sides attached to each other, got %v and %v", payment.AttachedTransactionID, received.AttachedTransactionID)
+	}
+	if payment.AttachmentType == nil || *payment.AttachmentType != models.AttachmentTypeOutboundTransfer {
+		t.Errorf("Expected the outbound attachment type, got %v", payment.AttachmentType)
+	}
+
+	rule := restored.Rules[0]
+	if rule.CategoryDst != pharmacy.ID || rule.Active || len(rule.Tags) != 1 || rule.Tags[0].ID != restored.Tags[0].ID {
+		t.Errorf("Expected the inactive rule with the new category and tag, got %+v", rule)
+	}
+	if restored.Subscriptions[0].AccountID != card.ID || restored.Subscriptions[0].Status != models.SubscriptionStatusConfirmed {
+		t.Errorf("Expected the confirmed subscription in the new card, got %+v", restored.Subscriptions[0])
+	}
+	// Shares are only restored once the users accept the invitations again
+	if len(restored.Shares) != 0 {
+		t.Errorf("Expected no shares, got %+v", restored.Shares)
+	}
+	var invitations []models.ShareInvitation
+	db.Where("owner_user_id = ?", target.ID).Order("id").Find(&invitations)
+	if len(invitations) != 2 || invitations[0].AccountID != checking.ID || invitations[0].InvitedEmail != "ana@example.com" ||
+		invitations[1].AccountID != card.ID || invitations[1].InvitedEmail != "bob@example.com" {
+		t.Fatalf("Expected invitations to ana for the checking account and to bob for the card, got %+v", invitations)
+	}
+	for _, invitation := range invitations {
+		if invitation.Status != models.InvitationPending || invitation.PermissionLevel != models.PermissionRead ||
+			len(invitation.InvitationToken) != 64 || !invitation.ExpiresAt.After(time.Now()) {
+			t.Errorf("Expected a pending read invitation, got %+v", invitation)
+		}
+	}
+	if invitations[0].InvitationToken == invitations[1].InvitationToken {
+		t.Error("Expected every invitation to have its own token")
+	}
+
+	// The source data is untouched
+	again, _ := repo.Load(ctx, source.ID)
+	if len(again.Transactions) != 3 || *again.Transactions[1].AttachedTransactionID != data.Transactions[2].ID {
+		t.Errorf("Expected the source transactions to be unchanged, got %+v", again.Transactions)
+	}
+
+	// Restoring again reuses the merchant and skips the subscription
+	result, err = repo.Restore(ctx, target.ID, data, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.ReusedMerchants != 1 || result.MerchantAliases != 0 || result.SkippedSubscriptions != 1 || result.ReusedCategories != 2 {
+		t.Errorf("Expected the existing records to be reused, got %+v", result)
+	}
+}
+
+func TestBackupRepository_Restore_DryRun(t *testing.T) {
+	db, source, target := setupBackupTestDB(t)
+	seedBackupData(t, db, source)
+	repo := NewBackupRepository(db)
+	ctx := context.Background()
+
+	data, err := repo.Load(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result, err := repo.Restore(ctx, target.ID, data, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Accounts != 2 || result.Transactions != 3 {
+		t.Errorf("Expected the dry run to count 2 accounts and 3 transactions, got %+v", result)
+	}
+
+	restored, err := repo.Load(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(restored.Accounts) != 0 || len(restored.Categories) != 0 || len(restored.Transactions) != 0 {
+		t.Errorf("Expected the dry run to save nothing, got %+v", restored)
+	}
+}
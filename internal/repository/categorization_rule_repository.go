@@ -26,27 +26,39 @@ func NewCategorizationRuleRepository(db *gorm.DB) CategorizationRuleRepository {
 
 func (r *categorizationRuleRepository) FindByUserID(ctx context.Context, userID uint) ([]models.CategorizationRule, error) {
 	var rules []models.CategorizationRule
-	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&rules).Error
+	err := r.db.WithContext(ctx).Preload("Tags").Where("user_id = ?", userID).Find(&rules).Error
 	return rules, err
 }
 
 func (r *categorizationRuleRepository) FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.CategorizationRule, error) {
 	var rule models.CategorizationRule
-	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
+	err := r.db.WithContext(ctx).Preload("Tags").Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
 	if err != nil {
 		return nil, err
 	}
 	return &rule, nil
 }
 
+// Create saves the rule and links it to its tags. The tags themselves must already exist.
 func (r *categorizationRuleRepository) Create(ctx context.Context, rule *models.CategorizationRule) error {
-	return r.db.WithContext(ctx).Create(rule).Error
+	return r.db.WithContext(ctx).Omit("Tags.*").Create(rule).Error
 }
 
 func (r *categorizationRuleRepository) Update(ctx context.Context, rule *models.CategorizationRule) error {
-	return r.db.WithContext(ctx).Save(rule).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(rule).Error; err != nil {
+			return err
+		}
+		return tx.Model(rule).Omit("Tags.*").Association("Tags").Replace(rule.Tags)
+	})
 }
 
 func (r *categorizationRuleRepository) Delete(ctx context.Context, id uint, userID uint) error {
-	return r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.CategorizationRule{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.CategorizationRule{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Exec("DELETE FROM categorization_rule_tags WHERE categorization_rule_id = ?", id).Error
+	})
 }
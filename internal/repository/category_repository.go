@@ -14,6 +14,8 @@ type CategoryRepository interface {
 	FindByIDAndUserID(ctx context.Context, id string, userID uint) (*models.Category, error)
 	Update(ctx context.Context, category *models.Category) error
 	Delete(ctx context.Context, id string, userID uint) error
+	CountUsage(ctx context.Context, categoryID uint) (int64, error)
+	Reassign(ctx context.Context, sourceID, targetID uint) error
 }
 
 type categoryRepository struct {
@@ -51,3 +53,64 @@ func (r *categoryRepository) Update(ctx context.Context, category *models.Catego
 func (r *categoryRepository) Delete(ctx context.Context, id string, userID uint) error {
 	return r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Category{}).Error
 }
+
+// CountUsage returns how many transactions, splits and categorization rules reference the category
+func (r *categoryRepository) CountUsage(ctx context.Context, categoryID uint) (int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var transactions int64
+	if err := db.Table("transaction_categories").
+		Joins("JOIN transactions ON transactions.id = transaction_categories.transaction_id").
+		Where("transaction_categories.category_id = ? AND transactions.deleted_at IS NULL", categoryID).
+		Count(&transactions).Error; err != nil {
+		return 0, err
+	}
+
+	var splits int64
+	if err := db.Model(&models.TransactionSplit{}).Where("category_id = ?", categoryID).Count(&splits).Error; err != nil {
+		return 0, err
+	}
+
+	var rules int64
+	if err := db.Model(&models.CategorizationRule{}).Where("category_dst = ?", categoryID).Count(&rules).Error; err != nil {
+		return 0, err
+	}
+
+	return transactions + splits + rules, nil
+}
+
+// Reassign moves every transaction, split, categorization rule and subcategory of the
+// source category to the target category. Callers are expected to run it inside a
+// database transaction together with the deletion of the source category.
+func (r *categoryRepository) Reassign(ctx context.Context, sourceID, targetID uint) error {
+	db := r.db.WithContext(ctx)
+
+	// Link the target to transactions that only had the source, then drop the source links
+	if err := db.Exec(`INSERT INTO transaction_categories (transaction_id, category_id)
+		SELECT tc.transaction_id, ? FROM transaction_categories tc
+		WHERE tc.category_id = ? AND NOT EXISTS (
+			SELECT 1 FROM transaction_categories existing
+			WHERE existing.transaction_id = tc.transaction_id AND existing.category_id = ?
+		)`, targetID, sourceID, targetID).Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM transaction_categories WHERE category_id = ?", sourceID).Error; err != nil {
+		return err
+	}
+
+	if err := db.Model(&models.TransactionSplit{}).
+		Where("category_id = ?", sourceID).
+		Update("category_id", targetID).Error; err != nil {
+		return err
+	}
+
+	if err := db.Model(&models.CategorizationRule{}).
+		Where("category_dst = ?", sourceID).
+		Update("category_dst", targetID).Error; err != nil {
+		return err
+	}
+
+	return db.Model(&models.Category{}).
+		Where("parent_id = ? AND id <> ?", sourceID, targetID).
+		Update("parent_id", targetID).Error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strconv"
 	"testing"
 
 	"github.com/LeonardsonCC/dinheiros/internal/models"
@@ -16,7 +17,7 @@ func setupCategoryTestDB(t *testing.T) (*gorm.DB, *models.User) {
 	}
 
 	// Auto migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.AccountShare{}, &models.ShareInvitation{})
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.CategorizationRule{}, &models.AccountShare{}, &models.ShareInvitation{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -339,3 +340,123 @@ func TestCategoryRepository_FindByUserID_WithContext(t *testing.T) {
 		t.Error("Expected error with cancelled context, got nil")
 	}
 }
+
+func TestCategoryRepository_Create_WithParent(t *testing.T) {
+	db, user := setupCategoryTestDB(t)
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	parent := &models.Category{
+		Name:   "Moradia",
+		Type:   models.TransactionTypeExpense,
+		UserID: user.ID,
+	}
+	if err := repo.Create(ctx, parent); err != nil {
+		t.Fatalf("Failed to create parent category: %v", err)
+	}
+
+	child := &models.Category{
+		Name:     "Aluguel",
+		Type:     models.TransactionTypeExpense,
+		UserID:   user.ID,
+		ParentID: &parent.ID,
+	}
+	if err := repo.Create(ctx, child); err != nil {
+		t.Fatalf("Failed to create child category: %v", err)
+	}
+
+	found, err := repo.FindByIDAndUserID(ctx, strconv.FormatUint(uint64(child.ID), 10), user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if found.ParentID == nil || *found.ParentID != parent.ID {
+		t.Errorf("Expected parent ID %d, got %v", parent.ID, found.ParentID)
+	}
+
+	var withChildren models.Category
+	if err := db.Preload("Children").First(&withChildren, parent.ID).Error; err != nil {
+		t.Fatalf("Failed to load parent with children: %v", err)
+	}
+
+	if len(withChildren.Children) != 1 || withChildren.Children[0].ID != child.ID {
+		t.Errorf("Expected parent to have child %d, got %+v", child.ID, withChildren.Children)
+	}
+}
+
+func TestCategoryRepository_Reassign(t *testing.T) {
+	db, user := setupCategoryTestDB(t)
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	source := &models.Category{Name: "Mercado", Type: models.TransactionTypeExpense, UserID: user.ID}
+	target := &models.Category{Name: "Supermercado", Type: models.TransactionTypeExpense, UserID: user.ID}
+	for _, category := range []*models.Category{source, target} {
+		if err := repo.Create(ctx, category); err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+	child := &models.Category{Name: "Hortifruti", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &source.ID}
+	if err := repo.Create(ctx, child); err != nil {
+		t.Fatalf("Failed to create child category: %v", err)
+	}
+
+	account := &models.Account{Name: "Conta", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	// One transaction only has the source, the other has both source and target
+	onlySource := &models.Transaction{Amount: 10, Type: models.TransactionTypeExpense, AccountID: account.ID, Categories: []*models.Category{source}}
+	both := &models.Transaction{Amount: 20, Type: models.TransactionTypeExpense, AccountID: account.ID, Categories: []*models.Category{source, target}}
+	for _, transaction := range []*models.Transaction{onlySource, both} {
+		if err := db.Create(transaction).Error; err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+	if err := db.Create(&models.TransactionSplit{TransactionID: both.ID, CategoryID: source.ID, Amount: 20}).Error; err != nil {
+		t.Fatalf("Failed to create split: %v", err)
+	}
+	rule := &models.CategorizationRule{UserID: user.ID, Name: "Mercado", Type: "text", Value: "mercado", TransactionType: "expense", CategoryDst: source.ID}
+	if err := db.Create(rule).Error; err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	usage, err := repo.CountUsage(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if usage != 4 {
+		t.Errorf("Expected usage 4 for source category, got %d", usage)
+	}
+
+	if err := repo.Reassign(ctx, source.ID, target.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	usage, err = repo.CountUsage(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if usage != 0 {
+		t.Errorf("Expected source category to be unused after reassign, got %d", usage)
+	}
+
+	var links int64
+	db.Table("transaction_categories").Where("category_id = ?", target.ID).Count(&links)
+	if links != 2 {
+		t.Errorf("Expected 2 transactions linked to target, got %d", links)
+	}
+
+	var updatedRule models.CategorizationRule
+	db.First(&updatedRule, rule.ID)
+	if updatedRule.CategoryDst != target.ID {
+		t.Errorf("Expected rule to point at %d, got %d", target.ID, updatedRule.CategoryDst)
+	}
+
+	var updatedChild models.Category
+	db.First(&updatedChild, child.ID)
+	if updatedChild.ParentID == nil || *updatedChild.ParentID != target.ID {
+		t.Errorf("Expected child to be moved under %d, got %v", target.ID, updatedChild.ParentID)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+type MerchantRepository interface {
+	FindByUserID(ctx context.Context, userID uint) ([]models.Merchant, error)
+	FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Merchant, error)
+	// FindOrCreate loads the user's merchant with the key of the given merchant, creating it
+	// when the user has none
+	FindOrCreate(ctx context.Context, merchant *models.Merchant) error
+	Update(ctx context.Context, merchant *models.Merchant) error
+	FindAliases(ctx context.Context, userID uint) ([]models.MerchantAlias, error)
+	CreateAlias(ctx context.Context, alias *models.MerchantAlias) error
+	DeleteAlias(ctx context.Context, id uint, userID uint) error
+	// SetTransactionMerchants sets the merchant of each transaction, nil removing it
+	SetTransactionMerchants(ctx context.Context, merchantIDs map[uint]*uint) error
+	// DeleteUnused removes the user's merchants no transaction or alias points to
+	DeleteUnused(ctx context.Context, userID uint) error
+}
+
+type merchantRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantRepository(db *gorm.DB) MerchantRepository {
+	return &merchantRepository{db: db}
+}
+
+func (r *merchantRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Merchant, error) {
+	var merchants []models.Merchant
+	err := r.db.WithContext(ctx).
+		Preload("Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("pattern") }).
+		Where("user_id = ?", userID).
+		Order("name, id").
+		Find(&merchants).Error
+	return merchants, err
+}
+
+func (r *merchantRepository) FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Merchant, error) {
+	var merchant models.Merchant
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&merchant).Error; err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+// FindOrCreate ignores the conflict when a concurrent import creates the same merchant first
+func (r *merchantRepository) FindOrCreate(ctx context.Context, merchant *models.Merchant) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+		DoNothing: true,
+	}).Create(merchant).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND key = ?", merchant.UserID, merchant.Key).First(merchant).Error
+}
+
+func (r *merchantRepository) Update(ctx context.Context, merchant *models.Merchant) error {
+	return r.db.WithContext(ctx).Omit("Aliases").Save(merchant).Error
+}
+
+func (r *merchantRepository) FindAliases(ctx context.Context, userID uint) ([]models.MerchantAlias, error) {
+	var aliases []models.MerchantAlias
+	err := r.db.WithContext(ctx).Preload("Merchant").Where("user_id = ?", userID).Order("pattern").Find(&aliases).Error
+	return aliases, err
+}
+
+func (r *merchantRepository) CreateAlias(ctx context.Context, alias *models.MerchantAlias) error {
+	return r.db.WithContext(ctx).Omit("Merchant").Create(alias).Error
+}
+
+func (r *merchantRepository) DeleteAlias(ctx context.Context, id uint, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var alias models.MerchantAlias
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&alias).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&alias).Error
+	})
+}
+
+func (r *merchantRepository) SetTransactionMerchants(ctx context.Context, merchantIDs map[uint]*uint) error {
+	if len(merchantIDs) == 0 {
+		return nil
+	}
+
+	// One update per merchant instead of one per transaction
+	byMerchant := make(map[uint][]uint)
+	var withoutMerchant []uint
+	for transactionID, merchantID := range merchantIDs {
+		if merchantID == nil {
+			withoutMerchant = append(withoutMerchant, transactionID)
+			continue
+		}
+		byMerchant[*merchantID] = append(byMerchant[*merchantID], transactionID)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for merchantID, transactionIDs := range byMerchant {
+			if err := tx.Model(&models.Transaction{}).Where("id IN ?", transactionIDs).Update("merchant_id", merchantID).Error; err != nil {
+				return err
+			}
+		}
+		if len(withoutMerchant) > 0 {
+			return tx.Model(&models.Transaction{}).Where("id IN ?", withoutMerchant).Update("merchant_id", nil).Error
+		}
+		return nil
+	})
+}
+
+func (r *merchantRepository) DeleteUnused(ctx context.Context, userID uint) error {
+	db := r.db.WithContext(ctx)
+	used := db.Unscoped().Model(&models.Transaction{}).Select("merchant_id").Where("merchant_id IS NOT NULL")
+	aliased := db.Model(&models.MerchantAlias{}).Select("merchant_id").Where("user_id = ?", userID)
+	return db.Where("user_id = ? AND id NOT IN (?) AND id NOT IN (?)", userID, used, aliased).Delete(&models.Merchant{}).Error
+}
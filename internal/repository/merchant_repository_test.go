@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupMerchantTestDB(t *testing.T) (*gorm.DB, *models.User, *models.Account) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.AccountShare{}, &models.ShareInvitation{}, &models.Merchant{}, &models.MerchantAlias{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// Create a test account
+	account := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create test account: %v", err)
+	}
+
+	return db, user, account
+}
+
+func TestMerchantRepository_FindOrCreate(t *testing.T) {
+	db, user, _ := setupMerchantTestDB(t)
+	repo := NewMerchantRepository(db)
+	ctx := context.Background()
+
+	first := &models.Merchant{UserID: user.ID, Key: "padaria", Name: "Padaria"}
+	if err := repo.FindOrCreate(ctx, first); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second := &models.Merchant{UserID: user.ID, Key: "padaria", Name: "PADARIA"}
+	if err := repo.FindOrCreate(ctx, second); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first.ID == 0 || second.ID != first.ID || second.Name != "Padaria" {
+		t.Errorf("Expected the existing merchant %d, got %d (%s)", first.ID, second.ID, second.Name)
+	}
+
+	merchants, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(merchants) != 1 {
+		t.Errorf("Expected 1 merchant, got %d", len(merchants))
+	}
+}
+
+func TestMerchantRepository_SetTransactionMerchants(t *testing.T) {
+	db, user, account := setupMerchantTestDB(t)
+	repo := NewMerchantRepository(db)
+	transactionRepo := NewTransactionRepository(db)
+	ctx := context.Background()
+
+	merchant := &models.Merchant{UserID: user.ID, Key: "uber", Name: "Uber"}
+	if err := repo.FindOrCreate(ctx, merchant); err != nil {
+		t.Fatalf("Failed to create merchant: %v", err)
+	}
+	var transactions []*models.Transaction
+	for _, description := range []string{"UBER *TRIP", "UBER *PENDING", "PIX ENVIADO FULANO"} {
+		transaction := &models.Transaction{Date: time.Now(), Amount: 10, Type: models.TransactionTypeExpense, Description: description, AccountID: account.ID, MerchantID: &merchant.ID}
+		if err := transactionRepo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	// The transfer isn't an Uber ride
+	if err := repo.SetTransactionMerchants(ctx, map[uint]*uint{transactions[2].ID: nil}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	found, total, err := transactionRepo.FindByUserID(user.ID, nil, nil, nil, nil, []uint{merchant.ID}, "", nil, nil, nil, nil, 1, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total != 2 || len(found) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", total)
+	}
+	for _, transaction := range found {
+		if transaction.Merchant == nil || transaction.Merchant.Name != "Uber" {
+			t.Errorf("Expected merchant to be preloaded, got %v", transaction.Merchant)
+		}
+	}
+}
+
+func TestMerchantRepository_DeleteUnused(t *testing.T) {
+	db, user, account := setupMerchantTestDB(t)
+	repo := NewMerchantRepository(db)
+	transactionRepo := NewTransactionRepository(db)
+	ctx := context.Background()
+
+	used := &models.Merchant{UserID: user.ID, Key: "padaria", Name: "Padaria"}
+	aliased := &models.Merchant{UserID: user.ID, Key: "ifood", Name: "iFood"}
+	unused := &models.Merchant{UserID: user.ID, Key: "mercado", Name: "Mercado"}
+	for _, merchant := range []*models.Merchant{used, aliased, unused} {
+		if err := repo.FindOrCreate(ctx, merchant); err != nil {
+			t.Fatalf("Failed to create merchant: %v", err)
+		}
+	}
+	transaction := &models.Transaction{Date: time.Now(), Amount: 10, Type: models.TransactionTypeExpense, Description: "PADARIA", AccountID: account.ID, MerchantID: &used.ID}
+	if err := transactionRepo.Create(transaction); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if err := repo.CreateAlias(ctx, &models.MerchantAlias{UserID: user.ID, MerchantID: aliased.ID, Pattern: "ifood"}); err != nil {
+		t.Fatalf("Failed to create alias: %v", err)
+	}
+
+	if err := repo.DeleteUnused(ctx, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	merchants, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	kept := make(map[uint]models.Merchant)
+	for _, merchant := range merchants {
+		kept[merchant.ID] = merchant
+	}
+	if _, ok := kept[used.ID]; len(kept) != 2 || !ok {
+		t.Fatalf("Expected the used and aliased merchants, got %v", merchants)
+	}
+	if aliases := kept[aliased.ID].Aliases; len(aliases) != 1 || aliases[0].Pattern != "ifood" {
+		t.Errorf("Expected the alias to be preloaded, got %v", aliases)
+	}
+}
+
+func TestMerchantRepository_DeleteAlias_NotFound(t *testing.T) {
+	db, user, _ := setupMerchantTestDB(t)
+	repo := NewMerchantRepository(db)
+
+	err := repo.DeleteAlias(context.Background(), 999, user.ID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected record not found, got %v", err)
+	}
+}
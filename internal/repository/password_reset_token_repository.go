@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+type PasswordResetTokenRepository interface {
+	// Replace removes the user's reset tokens and stores the new one, so only the latest
+	// emailed link works
+	Replace(ctx context.Context, token *models.PasswordResetToken) error
+	// Use marks the unused, unexpired token with the hash as used and returns it, or
+	// ErrNotFound when there's no such token
+	Use(ctx context.Context, hash string, now time.Time) (*models.PasswordResetToken, error)
+	// DeleteExpired removes the tokens that expired or were used
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+type passwordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+func (r *passwordResetTokenRepository) Replace(ctx context.Context, token *models.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", token.UserID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *passwordResetTokenRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Where("expires_at <= ? OR used_at IS NOT NULL", now).Delete(&models.PasswordResetToken{}).Error
+	})
+}
+
+func (r *passwordResetTokenRepository) Use(ctx context.Context, hash string, now time.Time) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		// Guard against another request using the token at the same time
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		token.UsedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
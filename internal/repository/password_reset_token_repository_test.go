@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPasswordResetTokenTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.PasswordResetToken{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestPasswordResetTokenRepository_Replace(t *testing.T) {
+	db, user := setupPasswordResetTokenTestDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, hash := range []string{"first", "second"} {
+		token := &models.PasswordResetToken{UserID: user.ID, TokenHash: hash, ExpiresAt: now.Add(time.Hour)}
+		if err := repo.Replace(ctx, token); err != nil {
+			t.Fatalf("Failed to replace reset token: %v", err)
+		}
+	}
+
+	var hashes []string
+	if err := db.Model(&models.PasswordResetToken{}).Where("user_id = ?", user.ID).Pluck("token_hash", &hashes).Error; err != nil {
+		t.Fatalf("Failed to list reset tokens: %v", err)
+	}
+	if len(hashes) != 1 || hashes[0] != "second" {
+		t.Errorf("Expected only the latest token to be kept, got %v", hashes)
+	}
+}
+
+func TestPasswordResetTokenRepository_Use(t *testing.T) {
+	db, user := setupPasswordResetTokenTestDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	token := &models.PasswordResetToken{UserID: user.ID, TokenHash: "hash", ExpiresAt: now.Add(time.Hour)}
+	if err := repo.Replace(ctx, token); err != nil {
+		t.Fatalf("Failed to replace reset token: %v", err)
+	}
+
+	used, err := repo.Use(ctx, "hash", now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if used.UserID != user.ID || used.UsedAt == nil {
+		t.Errorf("Expected the user's token marked as used, got %+v", used)
+	}
+
+	// Tokens can only be used once
+	if _, err := repo.Use(ctx, "hash", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound using the token again, got %v", err)
+	}
+	if _, err := repo.Use(ctx, "unknown", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown token, got %v", err)
+	}
+}
+
+func TestPasswordResetTokenRepository_UseExpired(t *testing.T) {
+	db, user := setupPasswordResetTokenTestDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	token := &models.PasswordResetToken{UserID: user.ID, TokenHash: "hash", ExpiresAt: now.Add(time.Hour)}
+	if err := repo.Replace(ctx, token); err != nil {
+		t.Fatalf("Failed to replace reset token: %v", err)
+	}
+
+	if _, err := repo.Use(ctx, "hash", now.Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an expired token, got %v", err)
+	}
+}
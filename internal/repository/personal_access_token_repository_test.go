@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPersonalAccessTokenTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.PersonalAccessToken{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func createTestPersonalAccessToken(t *testing.T, repo PersonalAccessTokenRepository, userID uint, name, hash string) *models.PersonalAccessToken {
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hash,
+		Prefix:    "dnh_abcd",
+		Scopes:    "read:transactions import",
+	}
+	if err := repo.Create(context.Background(), token); err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+	return token
+}
+
+func TestPersonalAccessTokenRepository_FindByHash(t *testing.T) {
+	db, user := setupPersonalAccessTokenTestDB(t)
+	repo := NewPersonalAccessTokenRepository(db)
+	ctx := context.Background()
+	token := createTestPersonalAccessToken(t, repo, user.ID, "Import script", "hash-1")
+
+	found, err := repo.FindByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.ID != token.ID || !found.HasScope(models.ScopeImport) || found.HasScope(models.ScopeWriteTransactions) {
+		t.Errorf("Expected the token with its scopes, got %+v", found)
+	}
+
+	if _, err := repo.FindByHash(ctx, "unknown"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	usedAt := time.Now().Truncate(time.Second)
+	if err := repo.Touch(ctx, token.ID, usedAt); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	found, err = repo.FindByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.LastUsedAt == nil || !found.LastUsedAt.Equal(usedAt) {
+		t.Errorf("Expected last used at %v, got %v", usedAt, found.LastUsedAt)
+	}
+}
+
+func TestPersonalAccessTokenRepository_Delete(t *testing.T) {
+	db, user := setupPersonalAccessTokenTestDB(t)
+	repo := NewPersonalAccessTokenRepository(db)
+	ctx := context.Background()
+	first := createTestPersonalAccessToken(t, repo, user.ID, "Import script", "hash-1")
+	createTestPersonalAccessToken(t, repo, user.ID, "Reports", "hash-2")
+
+	if err := repo.Delete(ctx, user.ID+1, first.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another user's token, got %v", err)
+	}
+	if err := repo.Delete(ctx, user.ID, first.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Delete(ctx, user.ID, first.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a deleted token, got %v", err)
+	}
+
+	tokens, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].Name != "Reports" {
+		t.Errorf("Expected only the remaining token, got %+v", tokens)
+	}
+}
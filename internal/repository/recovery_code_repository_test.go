@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupRecoveryCodeTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.RecoveryCode{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestRecoveryCodeRepository_Use(t *testing.T) {
+	db, user := setupRecoveryCodeTestDB(t)
+	repo := NewRecoveryCodeRepository(db)
+	ctx := context.Background()
+
+	if err := repo.Replace(ctx, user.ID, []string{"hash-1", "hash-2", "hash-3"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Use(ctx, user.ID, "hash-2", time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Use(ctx, user.ID, "hash-2", time.Now()); err != ErrNotFound {
+		t.Errorf("Expected a used code to be rejected, got %v", err)
+	}
+	if err := repo.Use(ctx, user.ID+1, "hash-1", time.Now()); err != ErrNotFound {
+		t.Errorf("Expected another user's code to be rejected, got %v", err)
+	}
+	count, err := repo.CountUnused(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 unused codes, got %d", count)
+	}
+
+	// Replacing the codes drops the old ones, used or not
+	if err := repo.Replace(ctx, user.ID, []string{"hash-4"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Use(ctx, user.ID, "hash-1", time.Now()); err != ErrNotFound {
+		t.Errorf("Expected a replaced code to be rejected, got %v", err)
+	}
+	var total int64
+	db.Model(&models.RecoveryCode{}).Count(&total)
+	if total != 1 {
+		t.Errorf("Expected only the new code to be stored, got %d", total)
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	// FindByID returns ErrNotFound when the session doesn't exist
+	FindByID(ctx context.Context, id uint) (*models.Session, error)
+	// FindByTokenHash returns the session whose current or previous refresh token has the
+	// hash, or ErrNotFound
+	FindByTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	// FindByUserID returns all the user's sessions, including revoked and expired ones,
+	// most recently used first
+	FindByUserID(ctx context.Context, userID uint) ([]models.Session, error)
+	// Rotate saves the session's new refresh token and client, as long as its refresh token
+	// is still oldHash and it isn't revoked. Otherwise another request rotated or revoked it
+	// first and ErrNotFound is returned.
+	Rotate(ctx context.Context, session *models.Session, oldHash string) error
+	// Revoke revokes one of the user's sessions, returning ErrNotFound when the user has no
+	// such session or it is already revoked
+	Revoke(ctx context.Context, userID, id uint, now time.Time) error
+	// RevokeAll revokes the user's sessions except exceptID, which may be 0, and returns how
+	// many were revoked
+	RevokeAll(ctx context.Context, userID, exceptID uint, now time.Time) (int64, error)
+	// DeleteExpired removes the user's sessions that expired before now
+	DeleteExpired(ctx context.Context, userID uint, now time.Time) error
+	// MarkTwoFactorVerified records that the user entered a 2FA code in the session
+	MarkTwoFactorVerified(ctx context.Context, id uint, at time.Time) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindByTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).
+		Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("last_used_at DESC, id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Rotate(ctx context.Context, session *models.Session, oldHash string) error {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  session.RefreshTokenHash,
+			"previous_token_hash": session.PreviousTokenHash,
+			"user_agent":          session.UserAgent,
+			"ip_address":          session.IPAddress,
+			"last_used_at":        session.LastUsedAt,
+			"expires_at":          session.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, userID, id uint, now time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeAll(ctx context.Context, userID, exceptID uint, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?", userID, exceptID, now).
+		Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
+
+func (r *sessionRepository) DeleteExpired(ctx context.Context, userID uint, now time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at < ?", userID, now).
+		Delete(&models.Session{}).Error
+}
+
+func (r *sessionRepository) MarkTwoFactorVerified(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ?", id).
+		Update("two_factor_verified_at", at).Error
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSessionTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Session{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func createTestSession(t *testing.T, repo SessionRepository, userID uint, hash string, expiresAt time.Time) *models.Session {
+	session := &models.Session{
+		UserID:           userID,
+		RefreshTokenHash: hash,
+		UserAgent:        "Mozilla/5.0",
+		IPAddress:        "192.0.2.1",
+		LastUsedAt:       time.Now(),
+		ExpiresAt:        expiresAt,
+	}
+	if err := repo.Create(context.Background(), session); err != nil {
+		t.Fatalf("Failed to create test session: %v", err)
+	}
+	return session
+}
+
+func TestSessionRepository_Rotate(t *testing.T) {
+	db, user := setupSessionTestDB(t)
+	repo := NewSessionRepository(db)
+	ctx := context.Background()
+	session := createTestSession(t, repo, user.ID, "hash-1", time.Now().Add(time.Hour))
+
+	session.RefreshTokenHash = "hash-2"
+	session.PreviousTokenHash = "hash-1"
+	session.UserAgent = "curl/8.0"
+	if err := repo.Rotate(ctx, session, "hash-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The old token still finds the session, so its reuse can be detected
+	found, err := repo.FindByTokenHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.ID != session.ID || found.RefreshTokenHash != "hash-2" || found.UserAgent != "curl/8.0" {
+		t.Errorf("Expected the rotated session, got %+v", found)
+	}
+
+	// A second rotation from the same token loses the race
+	session.RefreshTokenHash = "hash-3"
+	if err := repo.Rotate(ctx, session, "hash-1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if _, err := repo.FindByTokenHash(ctx, "unknown"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSessionRepository_Revoke(t *testing.T) {
+	db, user := setupSessionTestDB(t)
+	repo := NewSessionRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	current := createTestSession(t, repo, user.ID, "hash-1", now.Add(time.Hour))
+	other := createTestSession(t, repo, user.ID, "hash-2", now.Add(time.Hour))
+	createTestSession(t, repo, user.ID, "hash-3", now.Add(-time.Hour))
+
+	if err := repo.Revoke(ctx, user.ID+1, other.ID, now); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another user's session, got %v", err)
+	}
+	if err := repo.Revoke(ctx, user.ID, other.ID, now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Revoke(ctx, user.ID, other.ID, now); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a revoked session, got %v", err)
+	}
+
+	// A revoked session can't be rotated
+	other.RefreshTokenHash = "hash-4"
+	if err := repo.Rotate(ctx, other, "hash-2"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	createTestSession(t, repo, user.ID, "hash-5", now.Add(time.Hour))
+	revoked, err := repo.RevokeAll(ctx, user.ID, current.ID, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("Expected 1 session revoked, got %d", revoked)
+	}
+	found, err := repo.FindByID(ctx, current.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !found.Active(now) {
+		t.Error("Expected the kept session to stay active")
+	}
+}
+
+func TestSessionRepository_DeleteExpired(t *testing.T) {
+	db, user := setupSessionTestDB(t)
+	repo := NewSessionRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	createTestSession(t, repo, user.ID, "hash-1", now.Add(time.Hour))
+	createTestSession(t, repo, user.ID, "hash-2", now.Add(-time.Hour))
+
+	if err := repo.DeleteExpired(ctx, user.ID, now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sessions, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].RefreshTokenHash != "hash-1" {
+		t.Errorf("Expected only the unexpired session, got %+v", sessions)
+	}
+}
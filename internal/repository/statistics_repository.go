@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+// StatisticsPeriod is the size of the date buckets used when grouping by date
+type StatisticsPeriod string
+
+const (
+	StatisticsPeriodDay   StatisticsPeriod = "day"
+	StatisticsPeriodMonth StatisticsPeriod = "month"
+)
+
+// StatisticsFilter selects the transactions that are aggregated. An empty Types list
+// includes every transaction type and an empty AccountIDs list every account the user can
+// see. Dates are bucketed in Location, or UTC when it's nil.
+type StatisticsFilter struct {
+	UserID           uint
+	AccountIDs       []uint
+	StartDate        *time.Time
+	EndDate          *time.Time
+	Types            []models.TransactionType
+	ExcludeTransfers bool
+	Location         *time.Location
+}
+
+// StatisticsRow is one group of an aggregation, split by transaction type so callers
+// can decide how income and expenses are combined
+type StatisticsRow struct {
+	Label  string
+	Type   models.TransactionType
+	Amount float64
+	Count  int64
+}
+
+// CategoryPartRow is the amount of a transaction, or of one of its splits, in a category.
+// PartID is the split ID, or 0 for transactions without splits.
+type CategoryPartRow struct {
+	TransactionID uint
+	PartID        uint
+	CategoryID    uint
+	Type          models.TransactionType
+	Amount        float64
+}
+
+// BalanceChangeRow is the net change of an account balance within a date bucket
+type BalanceChangeRow struct {
+	AccountID uint
+	Label     string
+	Amount    float64
+}
+
+// StatisticsRepository aggregates transactions in the database instead of loading them
+type StatisticsRepository interface {
+	AmountByPeriod(ctx context.Context, filter StatisticsFilter, period StatisticsPeriod) ([]StatisticsRow, error)
+	AmountByAccount(ctx context.Context, filter StatisticsFilter) ([]StatisticsRow, error)
+	AmountByCategory(ctx context.Context, filter StatisticsFilter, rollup bool) ([]StatisticsRow, error)
+	AmountByTag(ctx context.Context, filter StatisticsFilter) ([]StatisticsRow, error)
+	AmountByMerchant(ctx context.Context, filter StatisticsFilter) ([]StatisticsRow, error)
+	// CategoryParts lists the amounts the filtered transactions have in the given categories
+	CategoryParts(ctx context.Context, filter StatisticsFilter, categoryIDs []uint) ([]CategoryPartRow, error)
+	// BalanceChanges sums the balance changes of the given accounts per bucket of loc up to the
+	// end date. Initial balance entries are left out.
+	BalanceChanges(ctx context.Context, accountIDs []uint, endDate *time.Time, period StatisticsPeriod, loc *time.Location) ([]BalanceChangeRow, error)
+	// FirstTransactionDays returns the day, in loc, of the first transaction of every given
+	// account that has any, initial balance entries included
+	FirstTransactionDays(ctx context.Context, accountIDs []uint, loc *time.Location) (map[uint]string, error)
+}
+
+type statisticsRepository struct {
+	db *gorm.DB
+}
+
+func NewStatisticsRepository(db *gorm.DB) StatisticsRepository {
+	return &statisticsRepository{db: db}
+}
+
+// AmountByPeriod groups transactions by day or month
+func (r *statisticsRepository) AmountByPeriod(ctx context.Context, filter StatisticsFilter, period StatisticsPeriod) ([]StatisticsRow, error) {
+	bucket := r.dateBucket("transactions.date", period, filter.Location, filter.StartDate, filter.EndDate)
+
+	var rows []StatisticsRow
+	err := r.transactions(ctx, filter).
+		Select(bucket + " AS label, transactions.type AS type, SUM(transactions.amount) AS amount, COUNT(*) AS count").
+		Group(bucket + ", transactions.type").
+		Order("label").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// AmountByAccount groups transactions by account name
+func (r *statisticsRepository) AmountByAccount(ctx context.Context, filter StatisticsFilter) ([]StatisticsRow, error) {
+	var rows []StatisticsRow
+	err := r.transactions(ctx, filter).
+		Joins("JOIN accounts ON accounts.id = transactions.account_id").
+		Select("accounts.name AS label, transactions.type AS type, SUM(transactions.amount) AS amount, COUNT(*) AS count").
+		Group("accounts.name, transactions.type").
+		Order("label").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// AmountByCategory groups transactions by category name. Split transactions count each split
+// amount in its own category. With rollup, amounts are reported on the top-level categories
+// only, so intermediate categories get no subtotal of their own, and a transaction linked to
+// a parent and one of its children counts only once.
+func (r *statisticsRepository) AmountByCategory(ctx context.Context, filter StatisticsFilter, rollup bool) ([]StatisticsRow, error) {
+	db := r.db.WithContext(ctx)
+	whole, splits := r.categoryParts(ctx, filter)
+	parts := db.Table("(? UNION ALL ?) AS parts", whole, splits)
+
+	var rows []StatisticsRow
+	if !rollup {
+		err := parts.
+			Joins("JOIN categories ON categories.id = parts.category_id AND categories.deleted_at IS NULL").
+			Select("categories.name AS label, parts.type AS type, SUM(parts.amount) AS amount, COUNT(*) AS count").
+			Group("categories.name, parts.type").
+			Order("label").
+			Scan(&rows).Error
+		return rows, err
+	}
+
+	// Walk every category up to its top-level ancestor. Categories whose parent is gone are
+	// their own root, and the depth limit guards against cycles.
+	err := db.Raw(`WITH RECURSIVE ancestry (category_id, ancestor_id, parent_id, depth) AS (
+			SELECT id, id, parent_id, 0 FROM categories WHERE deleted_at IS NULL
+			UNION ALL
+			SELECT ancestry.category_id, categories.id, categories.parent_id, ancestry.depth + 1
+			FROM ancestry
+			JOIN categories ON categories.id = ancestry.parent_id AND categories.deleted_at IS NULL
+			WHERE ancestry.depth < 32
+		),
+		category_roots (category_id, root_id) AS (
+			SELECT category_id, ancestor_id FROM ancestry
+			WHERE parent_id IS NULL OR NOT EXISTS (
+				SELECT 1 FROM categories parents WHERE parents.id = ancestry.parent_id AND parents.deleted_at IS NULL
+			)
+		)
+		SELECT roots.name AS label, rolled.type AS type, SUM(rolled.amount) AS amount, COUNT(*) AS count
+		FROM (
+			SELECT DISTINCT parts.transaction_id, parts.part_id, category_roots.root_id, parts.type, parts.amount
+			FROM (? UNION ALL ?) AS parts
+			JOIN category_roots ON category_roots.category_id = parts.category_id
+		) AS rolled
+		JOIN categories roots ON roots.id = rolled.root_id
+		GROUP BY roots.name, rolled.type
+		ORDER BY label`, whole, splits).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *statisticsRepository) CategoryParts(ctx context.Context, filter StatisticsFilter, categoryIDs []uint) ([]CategoryPartRow, error) {
+	var rows []CategoryPartRow
+	if len(categoryIDs) == 0 {
+		return rows, nil
+	}
+
+	whole, splits := r.categoryParts(ctx, filter)
+	err := r.db.WithContext(ctx).Table("(? UNION ALL ?) AS parts", whole, splits).
+		Where("parts.category_id IN ?", categoryIDs).
+		Order("parts.transaction_id, parts.part_id, parts.category_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// categoryParts returns the queries for the category amounts of the filtered transactions
+// without splits and of the splits
+func (r *statisticsRepository) categoryParts(ctx context.Context, filter StatisticsFilter) (*gorm.DB, *gorm.DB) {
+	// Transactions without splits count their full amount in each of their categories
+	whole := r.transactions(ctx, filter).
+		Joins("JOIN transaction_categories ON transaction_categories.transaction_id = transactions.id").
+		Where("NOT EXISTS (SELECT 1 FROM transaction_splits WHERE transaction_splits.transaction_id = transactions.id)").
+		Select("transactions.id AS transaction_id, 0 AS part_id, transaction_categories.category_id AS category_id, transactions.type AS type, transactions.amount AS amount")
+
+	splits := r.transactions(ctx, filter).
+		Joins("JOIN transaction_splits ON transaction_splits.transaction_id = transactions.id").
+		Select("transactions.id AS transaction_id, transaction_splits.id AS part_id, transaction_splits.category_id AS category_id, transactions.type AS type, transaction_splits.amount AS amount")
+
+	return whole, splits
+}
+
+// AmountByTag groups transactions by tag name
+func (r *statisticsRepository) AmountByTag(ctx context.Context, filter StatisticsFilter) ([]StatisticsRow, error) {
+	var rows []StatisticsRow
+	err := r.transactions(ctx, filter).
+		Joins("JOIN transaction_tags ON transaction_tags.transaction_id = transactions.id").
+		Joins("JOIN tags ON tags.id = transaction_tags.tag_id").
+		Select("tags.name AS label, transactions.type AS type, SUM(transactions.amount) AS amount, COUNT(*) AS count").
+		Group("tags.name, transactions.type").
+		Order("label").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// AmountByMerchant groups transactions by merchant name. Transactions without a merchant are
+// grouped by their description.
+func (r *statisticsRepository) AmountByMerchant(ctx context.Context, filter StatisticsFilter) ([]StatisticsRow, error) {
+	label := "COALESCE(merchants.name, transactions.description)"
+
+	var rows []StatisticsRow
+	err := r.transactions(ctx, filter).
+		Joins("LEFT JOIN merchants ON merchants.id = transactions.merchant_id").
+		Select(label + " AS label, transactions.type AS type, SUM(transactions.amount) AS amount, COUNT(*) AS count").
+		Group(label + ", transactions.type").
+		Order("label").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// BalanceChanges includes the transactions of soft-deleted accounts that were deleted together
+// with the account, so their history can be reconstructed up to the deletion. Transactions the
+// user deleted before the account stay out.
+func (r *statisticsRepository) BalanceChanges(ctx context.Context, accountIDs []uint, endDate *time.Time, period StatisticsPeriod, loc *time.Location) ([]BalanceChangeRow, error) {
+	var rows []BalanceChangeRow
+	if len(accountIDs) == 0 {
+		return rows, nil
+	}
+
+	bucket := r.dateBucket("transactions.date", period, loc, nil, endDate)
+	tx := r.balanceTransactions(ctx, accountIDs).
+		Where("transactions.type <> ?", models.TransactionTypeInitial)
+	if endDate != nil {
+		endOfDay := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+		tx = tx.Where("transactions.date <= ?", endOfDay)
+	}
+
+	err := tx.
+		Select("transactions.account_id AS account_id, "+bucket+" AS label, "+
+			"SUM(CASE WHEN transactions.type = ? THEN -transactions.amount ELSE transactions.amount END) AS amount", models.TransactionTypeExpense).
+		Group("transactions.account_id, " + bucket).
+		Order("label").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// FirstTransactionDays considers the same transactions as BalanceChanges
+func (r *statisticsRepository) FirstTransactionDays(ctx context.Context, accountIDs []uint, loc *time.Location) (map[uint]string, error) {
+	days := make(map[uint]string, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return days, nil
+	}
+
+	var rows []struct {
+		AccountID uint
+		Day       string
+	}
+	bucket := r.dateBucket("transactions.date", StatisticsPeriodDay, loc, nil, nil)
+	err := r.balanceTransactions(ctx, accountIDs).
+		Select("transactions.account_id AS account_id, MIN(" + bucket + ") AS day").
+		Group("transactions.account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		days[row.AccountID] = row.Day
+	}
+	return days, nil
+}
+
+// balanceTransactions returns the query for the transactions of the accounts, including the
+// ones deleted together with their soft-deleted account
+func (r *statisticsRepository) balanceTransactions(ctx context.Context, accountIDs []uint) *gorm.DB {
+	return r.db.WithContext(ctx).Unscoped().Model(&models.Transaction{}).
+		Where("transactions.account_id IN ?", accountIDs).
+		Where("transactions.deleted_at IS NULL OR transactions.deleted_with_account = ?", true)
+}
+
+// transactions returns the query for the transactions of the accounts the user owns or
+// that are shared with them, restricted by the filter
+func (r *statisticsRepository) transactions(ctx context.Context, filter StatisticsFilter) *gorm.DB {
+	db := r.db.WithContext(ctx)
+
+	owned := db.Table("accounts").Select("id").Where("user_id = ?", filter.UserID)
+	shared := db.Table("account_shares").Select("account_id").Where("shared_user_id = ?", filter.UserID)
+
+	tx := db.Model(&models.Transaction{}).
+		Where("transactions.account_id IN (?) OR transactions.account_id IN (?)", owned, shared)
+
+	if filter.StartDate != nil {
+		tx = tx.Where("transactions.date >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		// Include the entire end date
+		endOfDay := time.Date(filter.EndDate.Year(), filter.EndDate.Month(), filter.EndDate.Day(), 23, 59, 59, 999999999, filter.EndDate.Location())
+		tx = tx.Where("transactions.date <= ?", endOfDay)
+	}
+	if len(filter.AccountIDs) > 0 {
+		tx = tx.Where("transactions.account_id IN ?", filter.AccountIDs)
+	}
+	if len(filter.Types) > 0 {
+		tx = tx.Where("transactions.type IN ?", filter.Types)
+	}
+	if filter.ExcludeTransfers {
+		// Both sides of a transfer between accounts are linked to each other
+		tx = tx.Where("transactions.attachment_type IS NULL")
+	}
+	return tx
+}
+
+// dateBucket returns the SQL expression that formats a date column as YYYY-MM-DD or YYYY-MM in
+// the given location. The dates are only a hint of the range the column values fall in.
+func (r *statisticsRepository) dateBucket(column string, period StatisticsPeriod, loc *time.Location, startDate, endDate *time.Time) string {
+	if r.db.Dialector.Name() == "postgres" {
+		if loc != nil {
+			// Location names are looked up in the timezone database, so they never contain quotes
+			column = "(" + column + " AT TIME ZONE '" + strings.ReplaceAll(loc.String(), "'", "''") + "')"
+		}
+		if period == StatisticsPeriodMonth {
+			return "to_char(" + column + ", 'YYYY-MM')"
+		}
+		return "to_char(" + column + ", 'YYYY-MM-DD')"
+	}
+
+	format := "'%Y-%m-%d'"
+	if period == StatisticsPeriodMonth {
+		format = "'%Y-%m'"
+	}
+	if loc == nil {
+		return "strftime(" + format + ", " + column + ")"
+	}
+
+	from := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if startDate != nil {
+		from = startDate.AddDate(0, 0, -1)
+	}
+	to := time.Now().AddDate(1, 0, 0)
+	if endDate != nil {
+		to = endDate.AddDate(0, 0, 2)
+	}
+	return "strftime(" + format + ", " + column + ", " + sqliteZoneOffset(column, loc, from, to) + ")"
+}
+
+// sqliteZoneOffset returns an SQLite date modifier that shifts the column from UTC to the wall
+// clock of loc. SQLite doesn't know about time zones, so the offset in effect between every
+// zone transition from one date to the other is spelled out. Values outside the range use the
+// offset of the closest end.
+func sqliteZoneOffset(column string, loc *time.Location, from, to time.Time) string {
+	_, offset := from.In(loc).Zone()
+	var cases strings.Builder
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset == offset {
+			continue
+		}
+
+		// Narrow the transition down to the second
+		before, after := day.Unix(), next.Unix()
+		for after-before > 1 {
+			middle := before + (after-before)/2
+			if _, o := time.Unix(middle, 0).In(loc).Zone(); o == offset {
+				before = middle
+			} else {
+				after = middle
+			}
+		}
+		fmt.Fprintf(&cases, " WHEN CAST(strftime('%%s', %s) AS INTEGER) < %d THEN '%+d seconds'", column, after, offset)
+		offset = nextOffset
+	}
+
+	if cases.Len() == 0 {
+		return fmt.Sprintf("'%+d seconds'", offset)
+	}
+	return fmt.Sprintf("CASE%s ELSE '%+d seconds' END", cases.String(), offset)
+}
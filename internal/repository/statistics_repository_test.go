@@ -0,0 +1,678 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupStatisticsTestDB(tb testing.TB) (*gorm.DB, *models.User, *models.Account) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.AccountShare{}, &models.ShareInvitation{}, &models.Merchant{})
+	if err != nil {
+		tb.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		tb.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// Create a test account
+	account := &models.Account{
+		Name:   "Conta Corrente",
+		Type:   models.AccountTypeChecking,
+		UserID: user.ID,
+	}
+	if err := db.Create(account).Error; err != nil {
+		tb.Fatalf("Failed to create test account: %v", err)
+	}
+
+	return db, user, account
+}
+
+func createStatisticsTransaction(tb testing.TB, repo TransactionRepository, accountID uint, date time.Time, amount float64, transactionType models.TransactionType) *models.Transaction {
+	transaction := &models.Transaction{Date: date, Amount: amount, Type: transactionType, Description: "Test", AccountID: accountID}
+	if err := repo.Create(transaction); err != nil {
+		tb.Fatalf("Failed to create transaction: %v", err)
+	}
+	return transaction
+}
+
+// sumByLabel adds the amounts of every transaction type per label
+func sumByLabel(rows []StatisticsRow) map[string]float64 {
+	sums := make(map[string]float64)
+	for _, row := range rows {
+		sums[row.Label] += row.Amount
+	}
+	return sums
+}
+
+func TestStatisticsRepository_AmountByPeriod(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC), 100, models.TransactionTypeExpense)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 10, 18, 0, 0, 0, time.UTC), 50, models.TransactionTypeExpense)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC), 1000, models.TransactionTypeIncome)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 2, 3, 12, 0, 0, 0, time.UTC), 30, models.TransactionTypeExpense)
+	deleted := createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 2, 4, 12, 0, 0, 0, time.UTC), 999, models.TransactionTypeExpense)
+	if err := transactionRepo.Delete(deleted.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+
+	// Transactions of other users must not be counted
+	otherUser := &models.User{Name: "Other", Email: "other@example.com", Password: "hashedpassword"}
+	if err := db.Create(otherUser).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherAccount := &models.Account{Name: "Other", Type: models.AccountTypeChecking, UserID: otherUser.ID}
+	if err := db.Create(otherAccount).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	createStatisticsTransaction(t, transactionRepo, otherAccount.ID, time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC), 777, models.TransactionTypeExpense)
+
+	rows, err := repo.AmountByPeriod(ctx, StatisticsFilter{UserID: user.ID}, StatisticsPeriodMonth)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums := sumByLabel(rows)
+	if len(sums) != 2 || sums["2026-01"] != 1150 || sums["2026-02"] != 30 {
+		t.Errorf("Unexpected monthly sums: %v", sums)
+	}
+
+	// The end date includes the whole day
+	start := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	rows, err = repo.AmountByPeriod(ctx, StatisticsFilter{UserID: user.ID, StartDate: &start, EndDate: &end}, StatisticsPeriodDay)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %+v", len(rows), rows)
+	}
+	if rows[0].Label != "2026-01-10" || rows[0].Type != models.TransactionTypeExpense || rows[0].Amount != 150 || rows[0].Count != 2 {
+		t.Errorf("Unexpected first row: %+v", rows[0])
+	}
+	if rows[1].Label != "2026-01-31" || rows[1].Type != models.TransactionTypeIncome || rows[1].Amount != 1000 || rows[1].Count != 1 {
+		t.Errorf("Unexpected second row: %+v", rows[1])
+	}
+}
+
+func TestStatisticsRepository_AmountByPeriod_Location(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("Timezone database not available: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Timezone database not available: %v", err)
+	}
+
+	// Late evening in Brazil is already the next day, and month, in UTC
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 31, 22, 30, 0, 0, saoPaulo), 100, models.TransactionTypeExpense)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 2, 1, 10, 0, 0, 0, saoPaulo), 50, models.TransactionTypeExpense)
+
+	rows, err := repo.AmountByPeriod(ctx, StatisticsFilter{UserID: user.ID}, StatisticsPeriodMonth)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sums := sumByLabel(rows); len(sums) != 1 || sums["2026-02"] != 150 {
+		t.Errorf("Unexpected UTC monthly sums: %v", sums)
+	}
+
+	rows, err = repo.AmountByPeriod(ctx, StatisticsFilter{UserID: user.ID, Location: saoPaulo}, StatisticsPeriodMonth)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sums := sumByLabel(rows); len(sums) != 2 || sums["2026-01"] != 100 || sums["2026-02"] != 50 {
+		t.Errorf("Unexpected local monthly sums: %v", sums)
+	}
+
+	// Offsets change with daylight saving time
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 15, 23, 30, 0, 0, newYork), 10, models.TransactionTypeIncome)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 7, 15, 23, 30, 0, 0, newYork), 20, models.TransactionTypeIncome)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, newYork)
+	end := time.Date(2026, 12, 31, 0, 0, 0, 0, newYork)
+	rows, err = repo.AmountByPeriod(ctx, StatisticsFilter{
+		UserID:    user.ID,
+		StartDate: &start,
+		EndDate:   &end,
+		Types:     []models.TransactionType{models.TransactionTypeIncome},
+		Location:  newYork,
+	}, StatisticsPeriodDay)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sums := sumByLabel(rows); len(sums) != 2 || sums["2026-01-15"] != 10 || sums["2026-07-15"] != 20 {
+		t.Errorf("Unexpected daily sums across daylight saving time: %v", sums)
+	}
+}
+
+func TestStatisticsRepository_AmountByPeriod_TypesAndTransfers(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	date := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	createStatisticsTransaction(t, transactionRepo, account.ID, date, 1000, models.TransactionTypeInitial)
+	createStatisticsTransaction(t, transactionRepo, account.ID, date, 200, models.TransactionTypeIncome)
+	createStatisticsTransaction(t, transactionRepo, account.ID, date, 80, models.TransactionTypeExpense)
+
+	// Both sides of a transfer are linked to each other
+	outbound := models.AttachmentTypeOutboundTransfer
+	inbound := models.AttachmentTypeInboundTransfer
+	sent := &models.Transaction{Date: date, Amount: 300, Type: models.TransactionTypeExpense, Description: "Transfer", AccountID: account.ID, AttachmentType: &outbound}
+	if err := transactionRepo.Create(sent); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	received := &models.Transaction{Date: date, Amount: 300, Type: models.TransactionTypeIncome, Description: "Transfer", AccountID: account.ID, AttachedTransactionID: &sent.ID, AttachmentType: &inbound}
+	if err := transactionRepo.Create(received); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	filter := StatisticsFilter{
+		UserID:           user.ID,
+		Types:            []models.TransactionType{models.TransactionTypeIncome, models.TransactionTypeExpense},
+		ExcludeTransfers: true,
+	}
+	rows, err := repo.AmountByPeriod(ctx, filter, StatisticsPeriodMonth)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	byType := make(map[models.TransactionType]float64)
+	for _, row := range rows {
+		byType[row.Type] += row.Amount
+	}
+	if len(byType) != 2 || byType[models.TransactionTypeIncome] != 200 || byType[models.TransactionTypeExpense] != 80 {
+		t.Errorf("Unexpected amounts without initial balance and transfers: %v", byType)
+	}
+
+	rows, err = repo.AmountByPeriod(ctx, StatisticsFilter{UserID: user.ID}, StatisticsPeriodMonth)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	byType = make(map[models.TransactionType]float64)
+	for _, row := range rows {
+		byType[row.Type] += row.Amount
+	}
+	if byType[models.TransactionTypeInitial] != 1000 || byType[models.TransactionTypeIncome] != 500 || byType[models.TransactionTypeExpense] != 380 {
+		t.Errorf("Unexpected amounts with every transaction: %v", byType)
+	}
+}
+
+func TestStatisticsRepository_AmountByAccountAndTag(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	tagRepo := NewTagRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	savings := &models.Account{Name: "Poupança", Type: models.AccountTypeSavings, UserID: user.ID}
+	if err := db.Create(savings).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	travel := &models.Tag{Name: "viagem-2026", UserID: user.ID}
+	if err := tagRepo.Create(ctx, travel); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	hotel := createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 300, models.TransactionTypeExpense)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 20, models.TransactionTypeExpense)
+	createStatisticsTransaction(t, transactionRepo, savings.ID, time.Now(), 500, models.TransactionTypeIncome)
+	if err := tagRepo.ReplaceTransactionTags(ctx, hotel.ID, []uint{travel.ID}); err != nil {
+		t.Fatalf("Failed to tag transaction: %v", err)
+	}
+
+	rows, err := repo.AmountByAccount(ctx, StatisticsFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums := sumByLabel(rows)
+	if len(sums) != 2 || sums["Conta Corrente"] != 320 || sums["Poupança"] != 500 {
+		t.Errorf("Unexpected account sums: %v", sums)
+	}
+
+	rows, err = repo.AmountByTag(ctx, StatisticsFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums = sumByLabel(rows)
+	if len(sums) != 1 || sums["viagem-2026"] != 300 {
+		t.Errorf("Unexpected tag sums: %v", sums)
+	}
+}
+
+func TestStatisticsRepository_AmountByMerchant(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	merchant := &models.Merchant{UserID: user.ID, Key: "padaria", Name: "Padaria"}
+	if err := db.Create(merchant).Error; err != nil {
+		t.Fatalf("Failed to create merchant: %v", err)
+	}
+	for _, amount := range []float64{12, 8} {
+		transaction := &models.Transaction{Date: time.Now(), Amount: amount, Type: models.TransactionTypeExpense, Description: "COMPRA CARTAO 1234 PADARIA", AccountID: account.ID, MerchantID: &merchant.ID}
+		if err := transactionRepo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 50, models.TransactionTypeExpense)
+
+	rows, err := repo.AmountByMerchant(ctx, StatisticsFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums := sumByLabel(rows)
+	if len(sums) != 2 || sums["Padaria"] != 20 || sums["Test"] != 50 {
+		t.Errorf("Unexpected merchant sums: %v", sums)
+	}
+}
+
+func TestStatisticsRepository_AmountByCategory(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	food := &models.Category{Name: "Alimentação", Type: models.TransactionTypeExpense, UserID: user.ID}
+	if err := db.Create(food).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	groceries := &models.Category{Name: "Mercado", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &food.ID}
+	home := &models.Category{Name: "Casa", Type: models.TransactionTypeExpense, UserID: user.ID}
+	for _, category := range []*models.Category{groceries, home} {
+		if err := db.Create(category).Error; err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+
+	// Linked to both the parent and the child, so the rollup must count it once
+	market := createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 100, models.TransactionTypeExpense)
+	if err := transactionRepo.AssociateCategories(market.ID, []uint{food.ID, groceries.ID}); err != nil {
+		t.Fatalf("Failed to associate categories: %v", err)
+	}
+
+	// Split between groceries and home
+	split := createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 200, models.TransactionTypeExpense)
+	if err := transactionRepo.ReplaceSplits(split.ID, []models.TransactionSplit{
+		{CategoryID: groceries.ID, Amount: 150},
+		{CategoryID: home.ID, Amount: 50},
+	}); err != nil {
+		t.Fatalf("Failed to replace splits: %v", err)
+	}
+
+	rows, err := repo.AmountByCategory(ctx, StatisticsFilter{UserID: user.ID}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums := sumByLabel(rows)
+	if len(sums) != 3 || sums["Alimentação"] != 100 || sums["Mercado"] != 250 || sums["Casa"] != 50 {
+		t.Errorf("Unexpected category sums: %v", sums)
+	}
+
+	rows, err = repo.AmountByCategory(ctx, StatisticsFilter{UserID: user.ID}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums = sumByLabel(rows)
+	if len(sums) != 2 || sums["Alimentação"] != 250 || sums["Casa"] != 50 {
+		t.Errorf("Unexpected rolled up category sums: %v", sums)
+	}
+}
+
+func TestStatisticsRepository_AmountByCategoryNestedRollup(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	// Alimentação > Mercado > Hortifruti, and Lazer > Cinema
+	food := &models.Category{Name: "Alimentação", Type: models.TransactionTypeExpense, UserID: user.ID}
+	leisure := &models.Category{Name: "Lazer", Type: models.TransactionTypeExpense, UserID: user.ID}
+	for _, category := range []*models.Category{food, leisure} {
+		if err := db.Create(category).Error; err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+	groceries := &models.Category{Name: "Mercado", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &food.ID}
+	movies := &models.Category{Name: "Cinema", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &leisure.ID}
+	for _, category := range []*models.Category{groceries, movies} {
+		if err := db.Create(category).Error; err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+	produce := &models.Category{Name: "Hortifruti", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &groceries.ID}
+	if err := db.Create(produce).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	for categoryID, amount := range map[uint]float64{produce.ID: 30, groceries.ID: 70, movies.ID: 40} {
+		transaction := createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), amount, models.TransactionTypeExpense)
+		if err := transactionRepo.AssociateCategories(transaction.ID, []uint{categoryID}); err != nil {
+			t.Fatalf("Failed to associate categories: %v", err)
+		}
+	}
+
+	rows, err := repo.AmountByCategory(ctx, StatisticsFilter{UserID: user.ID}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums := sumByLabel(rows)
+	if len(sums) != 2 || sums["Alimentação"] != 100 || sums["Lazer"] != 40 {
+		t.Errorf("Expected grandchildren to roll up to the top-level category, got %v", sums)
+	}
+	if _, ok := sums["Mercado"]; ok {
+		t.Errorf("Expected no subtotal for the intermediate category, got %v", sums)
+	}
+
+	// Without rollup, every level has only its own amount
+	rows, err = repo.AmountByCategory(ctx, StatisticsFilter{UserID: user.ID}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums = sumByLabel(rows)
+	if len(sums) != 3 || sums["Hortifruti"] != 30 || sums["Mercado"] != 70 || sums["Cinema"] != 40 {
+		t.Errorf("Expected the amounts of each category, got %v", sums)
+	}
+
+	// Children of a deleted category are their own root
+	if err := db.Delete(leisure).Error; err != nil {
+		t.Fatalf("Failed to delete category: %v", err)
+	}
+	rows, err = repo.AmountByCategory(ctx, StatisticsFilter{UserID: user.ID}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sums = sumByLabel(rows)
+	if len(sums) != 2 || sums["Alimentação"] != 100 || sums["Cinema"] != 40 {
+		t.Errorf("Expected the orphaned category to be its own root, got %v", sums)
+	}
+}
+
+func TestStatisticsRepository_CategoryParts(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	health := &models.Category{Name: "Saúde", Type: models.TransactionTypeExpense, UserID: user.ID, Deductible: true}
+	home := &models.Category{Name: "Casa", Type: models.TransactionTypeExpense, UserID: user.ID}
+	for _, category := range []*models.Category{health, home} {
+		if err := db.Create(category).Error; err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+	other := &models.Account{Name: "Poupança", Type: models.AccountTypeSavings, UserID: user.ID}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	doctor := createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 300, models.TransactionTypeExpense)
+	if err := transactionRepo.AssociateCategories(doctor.ID, []uint{health.ID}); err != nil {
+		t.Fatalf("Failed to associate categories: %v", err)
+	}
+	pharmacy := createStatisticsTransaction(t, transactionRepo, account.ID, time.Now(), 80, models.TransactionTypeExpense)
+	if err := transactionRepo.ReplaceSplits(pharmacy.ID, []models.TransactionSplit{
+		{CategoryID: health.ID, Amount: 60},
+		{CategoryID: home.ID, Amount: 20},
+	}); err != nil {
+		t.Fatalf("Failed to replace splits: %v", err)
+	}
+	elsewhere := createStatisticsTransaction(t, transactionRepo, other.ID, time.Now(), 40, models.TransactionTypeExpense)
+	if err := transactionRepo.AssociateCategories(elsewhere.ID, []uint{health.ID}); err != nil {
+		t.Fatalf("Failed to associate categories: %v", err)
+	}
+
+	parts, err := repo.CategoryParts(ctx, StatisticsFilter{UserID: user.ID, AccountIDs: []uint{account.ID}}, []uint{health.ID})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %v", parts)
+	}
+	if parts[0].TransactionID != doctor.ID || parts[0].PartID != 0 || parts[0].Amount != 300 {
+		t.Errorf("Unexpected whole transaction part: %+v", parts[0])
+	}
+	if parts[1].TransactionID != pharmacy.ID || parts[1].PartID == 0 || parts[1].Amount != 60 {
+		t.Errorf("Unexpected split part: %+v", parts[1])
+	}
+}
+
+func TestStatisticsRepository_BalanceChanges(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	accountRepo := NewAccountRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	card := &models.Account{Name: "Cartão", Type: models.AccountTypeCredit, UserID: user.ID}
+	if err := db.Create(card).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC), 1000, models.TransactionTypeInitial)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC), 3000, models.TransactionTypeIncome)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC), 1200, models.TransactionTypeExpense)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), 50, models.TransactionTypeExpense)
+	removed := createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 1, 21, 12, 0, 0, 0, time.UTC), 999, models.TransactionTypeExpense)
+	if err := transactionRepo.Delete(removed.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+	createStatisticsTransaction(t, transactionRepo, card.ID, time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC), 400, models.TransactionTypeExpense)
+	// Deleted by the user before the account was, so it isn't part of the account's history
+	mistake := createStatisticsTransaction(t, transactionRepo, card.ID, time.Date(2026, 2, 11, 12, 0, 0, 0, time.UTC), 777, models.TransactionTypeExpense)
+	if err := transactionRepo.Delete(mistake.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+
+	// Transactions deleted together with their account are still part of its history
+	if err := accountRepo.SoftDelete(card.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+	if err := transactionRepo.SoftDeleteByAccountID(card.ID); err != nil {
+		t.Fatalf("Failed to delete transactions: %v", err)
+	}
+
+	end := time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)
+	rows, err := repo.BalanceChanges(ctx, []uint{account.ID, card.ID}, &end, StatisticsPeriodMonth, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	type key struct {
+		accountID uint
+		label     string
+	}
+	changes := make(map[key]float64)
+	for _, row := range rows {
+		changes[key{row.AccountID, row.Label}] = row.Amount
+	}
+	expected := map[key]float64{
+		{account.ID, "2026-01"}: 1800,
+		{card.ID, "2026-02"}:    -400,
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d rows, got %v", len(expected), changes)
+	}
+	for k, amount := range expected {
+		if changes[k] != amount {
+			t.Errorf("Expected %.2f for account %d in %s, got %.2f", amount, k.accountID, k.label, changes[k])
+		}
+	}
+}
+
+func TestStatisticsRepository_FirstTransactionDays(t *testing.T) {
+	db, user, account := setupStatisticsTestDB(t)
+	transactionRepo := NewTransactionRepository(db)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("Timezone database not available: %v", err)
+	}
+
+	// Initial balance entries count, and late evening in Brazil is already the next day in UTC
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2025, 12, 31, 22, 0, 0, 0, saoPaulo), 100, models.TransactionTypeInitial)
+	createStatisticsTransaction(t, transactionRepo, account.ID, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), 50, models.TransactionTypeExpense)
+
+	// Deleted before the account, so it isn't the first transaction
+	card := &models.Account{Name: "Cartão", Type: models.AccountTypeCredit, UserID: user.ID}
+	if err := db.Create(card).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	mistake := createStatisticsTransaction(t, transactionRepo, card.ID, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), 10, models.TransactionTypeExpense)
+	if err := transactionRepo.Delete(mistake.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+	createStatisticsTransaction(t, transactionRepo, card.ID, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), 20, models.TransactionTypeExpense)
+	if err := NewAccountRepository(db).SoftDelete(card.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+	if err := transactionRepo.SoftDeleteByAccountID(card.ID); err != nil {
+		t.Fatalf("Failed to delete transactions: %v", err)
+	}
+
+	// Accounts without transactions are left out
+	empty := &models.Account{Name: "Vazia", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(empty).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	days, err := repo.FirstTransactionDays(ctx, []uint{account.ID, card.ID, empty.ID}, saoPaulo)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(days) != 2 || days[account.ID] != "2025-12-31" || days[card.ID] != "2025-06-01" {
+		t.Errorf("Unexpected first days: %v", days)
+	}
+
+	days, err = repo.FirstTransactionDays(ctx, []uint{account.ID}, time.UTC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if days[account.ID] != "2026-01-01" {
+		t.Errorf("Expected the first UTC day to be 2026-01-01, got %v", days)
+	}
+}
+
+// seedStatisticsBenchmark creates a year of transactions spread over a few categories
+func seedStatisticsBenchmark(b *testing.B, count int) (*gorm.DB, *models.User) {
+	db, user, account := setupStatisticsTestDB(b)
+
+	categories := make([]models.Category, 5)
+	for i := range categories {
+		categories[i] = models.Category{Name: fmt.Sprintf("Category %d", i), Type: models.TransactionTypeExpense, UserID: user.ID}
+	}
+	if err := db.Create(&categories).Error; err != nil {
+		b.Fatalf("Failed to create categories: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	transactions := make([]models.Transaction, count)
+	for i := range transactions {
+		transactions[i] = models.Transaction{
+			Date:        start.Add(time.Duration(i%365) * 24 * time.Hour),
+			Amount:      float64(i%200) + 0.5,
+			Type:        models.TransactionTypeExpense,
+			Description: "Benchmark",
+			AccountID:   account.ID,
+			Categories:  []*models.Category{&categories[i%len(categories)]},
+		}
+	}
+	if err := db.Omit("Categories.*").CreateInBatches(&transactions, 500).Error; err != nil {
+		b.Fatalf("Failed to create transactions: %v", err)
+	}
+
+	return db, user
+}
+
+func BenchmarkAmountByMonth_InMemory(b *testing.B) {
+	db, user := seedStatisticsBenchmark(b, 5000)
+	repo := NewTransactionRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transactions, _, err := repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		byMonth := make(map[string]float64)
+		for _, tx := range transactions {
+			byMonth[tx.Date.Format("2006-01")] += tx.Amount
+		}
+	}
+}
+
+func BenchmarkAmountByMonth_SQL(b *testing.B) {
+	db, user := seedStatisticsBenchmark(b, 5000)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.AmountByPeriod(ctx, StatisticsFilter{UserID: user.ID}, StatisticsPeriodMonth); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAmountByCategory_InMemory(b *testing.B) {
+	db, user := seedStatisticsBenchmark(b, 5000)
+	repo := NewTransactionRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transactions, _, err := repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		byCategory := make(map[string]float64)
+		for _, tx := range transactions {
+			for _, cat := range tx.Categories {
+				byCategory[cat.Name] += tx.Amount
+			}
+		}
+	}
+}
+
+func BenchmarkAmountByCategory_SQL(b *testing.B) {
+	db, user := seedStatisticsBenchmark(b, 5000)
+	repo := NewStatisticsRepository(db)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.AmountByCategory(ctx, StatisticsFilter{UserID: user.ID}, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+type SubscriptionRepository interface {
+	FindByUserID(ctx context.Context, userID uint) ([]models.Subscription, error)
+	FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Subscription, error)
+	// SaveDetected creates the detected subscriptions the user doesn't have yet and refreshes
+	// the charge details of the existing ones, keeping their status
+	SaveDetected(ctx context.Context, userID uint, subscriptions []models.Subscription) error
+	Update(ctx context.Context, subscription *models.Subscription) error
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{db: db}
+}
+
+func (r *subscriptionRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name, id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *subscriptionRepository) FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *subscriptionRepository) SaveDetected(ctx context.Context, userID uint, subscriptions []models.Subscription) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.Subscription
+		if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byKey := make(map[string]*models.Subscription, len(existing))
+		for i := range existing {
+			byKey[existing[i].Key] = &existing[i]
+		}
+
+		for _, detected := range subscriptions {
+			stored, ok := byKey[detected.Key]
+			if !ok {
+				detected.UserID = userID
+				if detected.Status == "" {
+					detected.Status = models.SubscriptionStatusDetected
+				}
+				if err := tx.Create(&detected).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(stored).Updates(map[string]interface{}{
+				"name":              detected.Name,
+				"account_id":        detected.AccountID,
+				"cadence":           detected.Cadence,
+				"amount":            detected.Amount,
+				"charges":           detected.Charges,
+				"first_charge_date": detected.FirstChargeDate,
+				"last_charge_date":  detected.LastChargeDate,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *subscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) error {
+	return r.db.WithContext(ctx).Save(subscription).Error
+}
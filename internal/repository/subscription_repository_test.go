@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSubscriptionTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Subscription{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestSubscriptionRepository_SaveDetected(t *testing.T) {
+	db, user := setupSubscriptionTestDB(t)
+	repo := NewSubscriptionRepository(db)
+	ctx := context.Background()
+
+	first := time.Date(2026, 7, 5, 0, 0, 0, 0, time.UTC)
+	detected := []models.Subscription{
+		{Key: "netflix com", Name: "NETFLIX.COM", Cadence: models.SubscriptionCadenceMonthly, Amount: 39.90, Charges: 3, FirstChargeDate: first, LastChargeDate: first.AddDate(0, 2, 0)},
+		{Key: "academia", Name: "Academia", Cadence: models.SubscriptionCadenceMonthly, Amount: 120, Charges: 3, FirstChargeDate: first, LastChargeDate: first.AddDate(0, 2, 0)},
+	}
+	if err := repo.SaveDetected(ctx, user.ID, detected); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	subscriptions, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(subscriptions) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got %d", len(subscriptions))
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Status != models.SubscriptionStatusDetected || subscription.UserID != user.ID {
+			t.Errorf("Unexpected new subscription: %+v", subscription)
+		}
+	}
+
+	// The user's status survives a new detection, which only refreshes the charges
+	netflix := subscriptions[1]
+	cancelledAt := time.Now()
+	netflix.Status = models.SubscriptionStatusCancelled
+	netflix.CancelledAt = &cancelledAt
+	if err := repo.Update(ctx, &netflix); err != nil {
+		t.Fatalf("Failed to update subscription: %v", err)
+	}
+
+	detected[0].Amount = 44.90
+	detected[0].Charges = 4
+	detected[0].LastChargeDate = first.AddDate(0, 3, 0)
+	if err := repo.SaveDetected(ctx, user.ID, detected[:1]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updated, err := repo.FindByIDAndUserID(ctx, netflix.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated.Status != models.SubscriptionStatusCancelled || updated.CancelledAt == nil {
+		t.Errorf("Expected the cancelled status to be kept, got %+v", updated)
+	}
+	if updated.Amount != 44.90 || updated.Charges != 4 || !updated.LastChargeDate.Equal(first.AddDate(0, 3, 0)) {
+		t.Errorf("Expected the charges to be refreshed, got %+v", updated)
+	}
+
+	subscriptions, err = repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(subscriptions) != 2 {
+		t.Errorf("Expected subscriptions that weren't detected again to be kept, got %d", len(subscriptions))
+	}
+
+	// Other users can't see the subscription
+	if _, err := repo.FindByIDAndUserID(ctx, netflix.ID, user.ID+1); err == nil {
+		t.Error("Expected error for another user's subscription, got nil")
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+type TagRepository interface {
+	FindByUserID(ctx context.Context, userID uint) ([]models.Tag, error)
+	FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Tag, error)
+	FindByIDsAndUserID(ctx context.Context, ids []uint, userID uint) ([]models.Tag, error)
+	Create(ctx context.Context, tag *models.Tag) error
+	Update(ctx context.Context, tag *models.Tag) error
+	Delete(ctx context.Context, id uint, userID uint) error
+	ReplaceTransactionTags(ctx context.Context, transactionID uint, tagIDs []uint) error
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) FindByIDAndUserID(ctx context.Context, id uint, userID uint) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *tagRepository) FindByIDsAndUserID(ctx context.Context, ids []uint, userID uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ? AND user_id = ?", ids, userID).Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+func (r *tagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Save(tag).Error
+}
+
+// Delete removes the tag together with its links to transactions and categorization rules
+func (r *tagRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM transaction_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM categorization_rule_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+}
+
+// ReplaceTransactionTags sets the tags of a transaction. An empty list removes all tags.
+func (r *tagRepository) ReplaceTransactionTags(ctx context.Context, transactionID uint, tagIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM transaction_tags WHERE transaction_id = ?", transactionID).Error; err != nil {
+			return err
+		}
+		seen := make(map[uint]bool, len(tagIDs))
+		for _, tagID := range tagIDs {
+			if seen[tagID] {
+				continue
+			}
+			seen[tagID] = true
+			if err := tx.Exec(
+				"INSERT INTO transaction_tags (transaction_id, tag_id) VALUES (?, ?)",
+				transactionID, tagID,
+			).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
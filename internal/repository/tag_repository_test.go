@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTagTestDB(t *testing.T) (*gorm.DB, *models.User, *models.Account) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.CategorizationRule{}, &models.AccountShare{}, &models.ShareInvitation{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// Create a test account
+	account := &models.Account{
+		Name:   "Test Account",
+		Type:   models.AccountTypeChecking,
+		UserID: user.ID,
+	}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create test account: %v", err)
+	}
+
+	return db, user, account
+}
+
+func TestTagRepository_Create_DuplicateName(t *testing.T) {
+	db, user, _ := setupTagTestDB(t)
+	repo := NewTagRepository(db)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &models.Tag{Name: "viagem-2026", UserID: user.ID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := repo.Create(ctx, &models.Tag{Name: "viagem-2026", UserID: user.ID}); err == nil {
+		t.Error("Expected error for duplicate tag name, got nil")
+	}
+}
+
+func TestTagRepository_ReplaceTransactionTags(t *testing.T) {
+	db, user, account := setupTagTestDB(t)
+	repo := NewTagRepository(db)
+	transactionRepo := NewTransactionRepository(db)
+	ctx := context.Background()
+
+	travel := &models.Tag{Name: "viagem-2026", UserID: user.ID}
+	refundable := &models.Tag{Name: "reembolsável", UserID: user.ID}
+	for _, tag := range []*models.Tag{travel, refundable} {
+		if err := repo.Create(ctx, tag); err != nil {
+			t.Fatalf("Failed to create tag: %v", err)
+		}
+	}
+
+	tagged := &models.Transaction{Date: time.Now(), Amount: 300, Type: models.TransactionTypeExpense, Description: "Hotel", AccountID: account.ID}
+	untagged := &models.Transaction{Date: time.Now(), Amount: 50, Type: models.TransactionTypeExpense, Description: "Padaria", AccountID: account.ID}
+	for _, transaction := range []*models.Transaction{tagged, untagged} {
+		if err := transactionRepo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+
+	if err := repo.ReplaceTransactionTags(ctx, tagged.ID, []uint{travel.ID, refundable.ID, travel.ID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	found, err := transactionRepo.FindByID(tagged.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(found.Tags) != 2 {
+		t.Errorf("Expected 2 tags, got %d", len(found.Tags))
+	}
+
+	// Filtering by tag only returns the tagged transaction
+	transactions, total, err := transactionRepo.FindByUserID(user.ID, nil, nil, nil, []uint{travel.ID}, nil, "", nil, nil, nil, nil, 1, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total != 1 || len(transactions) != 1 || transactions[0].ID != tagged.ID {
+		t.Errorf("Expected only transaction %d, got %d results (total %d)", tagged.ID, len(transactions), total)
+	}
+
+	if err := repo.ReplaceTransactionTags(ctx, tagged.ID, []uint{refundable.ID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transactions, _, err = transactionRepo.FindByUserID(user.ID, nil, nil, nil, []uint{travel.ID}, nil, "", nil, nil, nil, nil, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("Expected no transactions with the removed tag, got %d", len(transactions))
+	}
+}
+
+func TestTagRepository_Delete(t *testing.T) {
+	db, user, account := setupTagTestDB(t)
+	repo := NewTagRepository(db)
+	ruleRepo := NewCategorizationRuleRepository(db)
+	ctx := context.Background()
+
+	tag := &models.Tag{Name: "empresa", UserID: user.ID}
+	if err := repo.Create(ctx, tag); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	transaction := &models.Transaction{Date: time.Now(), Amount: 80, Type: models.TransactionTypeExpense, AccountID: account.ID}
+	if err := db.Create(transaction).Error; err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if err := repo.ReplaceTransactionTags(ctx, transaction.ID, []uint{tag.ID}); err != nil {
+		t.Fatalf("Failed to tag transaction: %v", err)
+	}
+
+	rule := &models.CategorizationRule{UserID: user.ID, Name: "Uber", Type: "regex", Value: "(?i)uber", TransactionType: "expense", Tags: []models.Tag{*tag}}
+	if err := ruleRepo.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	if err := repo.Delete(ctx, tag.ID, user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var links int64
+	db.Table("transaction_tags").Where("tag_id = ?", tag.ID).Count(&links)
+	if links != 0 {
+		t.Errorf("Expected transaction links to be removed, got %d", links)
+	}
+
+	found, err := ruleRepo.FindByIDAndUserID(ctx, rule.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Expected rule to still exist, got %v", err)
+	}
+	if len(found.Tags) != 0 {
+		t.Errorf("Expected rule to have no tags, got %d", len(found.Tags))
+	}
+}
+
+func TestCategorizationRuleRepository_Update_ReplacesTags(t *testing.T) {
+	db, user, _ := setupTagTestDB(t)
+	repo := NewTagRepository(db)
+	ruleRepo := NewCategorizationRuleRepository(db)
+	ctx := context.Background()
+
+	first := &models.Tag{Name: "viagem-2026", UserID: user.ID}
+	second := &models.Tag{Name: "empresa", UserID: user.ID}
+	for _, tag := range []*models.Tag{first, second} {
+		if err := repo.Create(ctx, tag); err != nil {
+			t.Fatalf("Failed to create tag: %v", err)
+		}
+	}
+
+	rule := &models.CategorizationRule{UserID: user.ID, Name: "Hotel", Type: "regex", Value: "(?i)hotel", TransactionType: "expense", Tags: []models.Tag{*first}}
+	if err := ruleRepo.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	rule.Tags = []models.Tag{*second}
+	if err := ruleRepo.Update(ctx, rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	found, err := ruleRepo.FindByIDAndUserID(ctx, rule.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(found.Tags) != 1 || found.Tags[0].ID != second.ID {
+		t.Errorf("Expected rule tags to be replaced by %d, got %+v", second.ID, found.Tags)
+	}
+
+	var tags int64
+	db.Model(&models.Tag{}).Count(&tags)
+	if tags != 2 {
+		t.Errorf("Expected updating the rule not to create tags, got %d tags", tags)
+	}
+}
@@ -21,6 +21,8 @@ type TransactionRepository interface {
 		transactionTypes []models.TransactionType,
 		accountIDs []uint,
 		categoryIDs []uint,
+		tagIDs []uint,
+		merchantIDs []uint,
 		description string,
 		minAmount *float64,
 		maxAmount *float64,
@@ -33,8 +35,16 @@ type TransactionRepository interface {
 	Delete(id uint, userID uint) error
 	SoftDeleteByAccountID(accountID uint) error
 	ReactivateByAccountID(accountID uint) error
-	GetDashboardSummary(userID uint) (float64, float64, float64, []models.Transaction, error)
+	GetDashboardSummary(userID uint, accountIDs []uint, startDate, endDate time.Time, recentLimit int) (float64, float64, float64, []models.Transaction, error)
+	GetPeriodTotals(userID uint, accountIDs []uint, startDate, endDate time.Time) (float64, float64, error)
 	AssociateCategories(transactionID uint, categoryIDs []uint) error
+	ReplaceSplits(transactionID uint, splits []models.TransactionSplit) error
+	// FindInBatches calls fn with the user's transactions matching the filter, batchSize at a
+	// time and ordered by ID, so all of them can be read without loading them at once
+	FindInBatches(userID uint, filter TransactionFilter, batchSize int, fn func([]models.Transaction) error) error
+	// DateRange returns the dates of the first and last transactions matching the filter, and
+	// false when there are none
+	DateRange(userID uint, filter TransactionFilter) (time.Time, time.Time, bool, error)
 
 	// Transaction management
 	Begin() *gorm.DB
@@ -43,6 +53,20 @@ type TransactionRepository interface {
 	WithTx(tx *gorm.DB) TransactionRepository
 }
 
+// TransactionFilter selects transactions. Empty lists and nil values don't filter.
+type TransactionFilter struct {
+	Types       []models.TransactionType
+	AccountIDs  []uint
+	CategoryIDs []uint
+	TagIDs      []uint
+	MerchantIDs []uint
+	Description string
+	MinAmount   *float64
+	MaxAmount   *float64
+	StartDate   *time.Time
+	EndDate     *time.Time
+}
+
 type transactionRepository struct {
 	db *gorm.DB
 }
@@ -63,6 +87,10 @@ func (r *transactionRepository) FindByID(id uint, userID uint) (*models.Transact
 	var transaction models.Transaction
 	// First try to find by direct account ownership
 	err := r.db.Preload("Categories").
+		Preload("Splits").
+		Preload("Splits.Category").
+		Preload("Tags").
+		Preload("Merchant").
 		Preload("AttachedTransaction").
 		Preload("AttachedTransaction.Account").
 		Joins("JOIN accounts ON accounts.id = transactions.account_id").
@@ -80,6 +108,10 @@ func (r *transactionRepository) FindByID(id uint, userID uint) (*models.Transact
 	if shareCheckErr == nil {
 		// Try to find transaction where user has shared access to the account
 		err = r.db.Preload("Categories").
+			Preload("Splits").
+			Preload("Splits.Category").
+			Preload("Tags").
+			Preload("Merchant").
 			Preload("AttachedTransaction").
 			Preload("AttachedTransaction.Account").
 			Joins("JOIN accounts ON accounts.id = transactions.account_id").
@@ -101,6 +133,8 @@ func (r *transactionRepository) FindByAccountID(accountID uint, userID uint) ([]
 		nil,               // transactionTypes
 		[]uint{accountID}, // accountIDs
 		nil,               // categoryIDs
+		nil,               // tagIDs
+		nil,               // merchantIDs
 		"",                // description
 		nil,               // minAmount
 		nil,               // maxAmount
@@ -118,6 +152,8 @@ func (r *transactionRepository) Search(userID uint, searchParams models.SearchTr
 		searchParams.Types,
 		searchParams.AccountIDs,
 		searchParams.CategoryIDs,
+		searchParams.TagIDs,
+		searchParams.MerchantIDs,
 		searchParams.Description,
 		getPointerOrZeroIsNil(searchParams.MinAmount),
 		getPointerOrZeroIsNil(searchParams.MaxAmount),
@@ -143,6 +179,8 @@ func (r *transactionRepository) FindByUserID(
 	transactionTypes []models.TransactionType,
 	accountIDs []uint,
 	categoryIDs []uint,
+	tagIDs []uint,
+	merchantIDs []uint,
 	description string,
 	minAmount *float64,
 	maxAmount *float64,
@@ -166,36 +204,17 @@ func (r *transactionRepository) FindByUserID(
 		tx = tx.Or("accounts.id IN ?", sharedAccountIDs)
 	}
 
-	// Apply filters
-	if len(transactionTypes) > 0 {
-		tx = tx.Where("transactions.type IN ?", transactionTypes)
-	}
-
-	if len(accountIDs) > 0 {
-		tx = tx.Where("transactions.account_id IN ?", accountIDs)
-	}
-
-	if description != "" {
-		tx = tx.Where("transactions.description LIKE ?", "%"+description+"%")
-	}
-
-	if minAmount != nil {
-		tx = tx.Where("transactions.amount >= ?", *minAmount)
-	}
-
-	if maxAmount != nil {
-		tx = tx.Where("transactions.amount <= ?", *maxAmount)
-	}
-
-	if startDate != nil {
-		tx = tx.Where("transactions.date >= ?", *startDate)
-	}
-
-	if endDate != nil {
-		// Include the entire end date
-		endOfDay := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
-		tx = tx.Where("transactions.date <= ?", endOfDay)
-	}
+	tx = r.applyFilter(tx, TransactionFilter{
+		Types:       transactionTypes,
+		AccountIDs:  accountIDs,
+		TagIDs:      tagIDs,
+		MerchantIDs: merchantIDs,
+		Description: description,
+		MinAmount:   minAmount,
+		MaxAmount:   maxAmount,
+		StartDate:   startDate,
+		EndDate:     endDate,
+	})
 
 	// Get total count for pagination
 	if page > 0 && pageSize > 0 {
@@ -219,7 +238,11 @@ func (r *transactionRepository) FindByUserID(
 	// Execute the query with preloading categories and attached transactions
 	err := tx.
 		Preload("Account").
+		Preload("Merchant").
 		Preload("Categories").
+		Preload("Splits").
+		Preload("Splits.Category").
+		Preload("Tags").
 		Preload("AttachedTransaction").
 		Preload("AttachedTransaction.Account").
 		Order("transactions.date DESC, transactions.id DESC").
@@ -240,6 +263,95 @@ func (r *transactionRepository) FindByUserID(
 	return transactions, total, nil
 }
 
+func (r *transactionRepository) FindInBatches(userID uint, filter TransactionFilter, batchSize int, fn func([]models.Transaction) error) error {
+	var batch []models.Transaction
+	return r.visibleTransactions(userID, filter).
+		Preload("Account").
+		Preload("Merchant").
+		Preload("Categories").
+		Preload("Splits").
+		Preload("Splits.Category").
+		Preload("Tags").
+		Preload("AttachedTransaction").
+		Preload("AttachedTransaction.Account").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+func (r *transactionRepository) DateRange(userID uint, filter TransactionFilter) (time.Time, time.Time, bool, error) {
+	var first, last []time.Time
+	if err := r.visibleTransactions(userID, filter).Order("transactions.date ASC").Limit(1).Pluck("transactions.date", &first).Error; err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	if len(first) == 0 {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	if err := r.visibleTransactions(userID, filter).Order("transactions.date DESC").Limit(1).Pluck("transactions.date", &last).Error; err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	return first[0], last[0], true, nil
+}
+
+// visibleTransactions returns the query for the transactions of the accounts the user owns or
+// that are shared with them, restricted by the filter. Categories are matched with a subquery,
+// so a transaction in several of the categories is returned once.
+func (r *transactionRepository) visibleTransactions(userID uint, filter TransactionFilter) *gorm.DB {
+	owned := r.db.Table("accounts").Select("id").Where("user_id = ?", userID)
+	shared := r.db.Table("account_shares").Select("account_id").Where("shared_user_id = ?", userID)
+
+	tx := r.db.Model(&models.Transaction{}).
+		Where("transactions.account_id IN (?) OR transactions.account_id IN (?)", owned, shared)
+	tx = r.applyFilter(tx, filter)
+	if len(filter.CategoryIDs) > 0 {
+		tx = tx.Where("transactions.id IN (?)", r.db.Table("transaction_categories").Select("transaction_id").Where("category_id IN ?", filter.CategoryIDs))
+	}
+	return tx
+}
+
+// applyFilter restricts the query to the filter, except for the categories, which
+// callers match either with a join or a subquery
+func (r *transactionRepository) applyFilter(tx *gorm.DB, filter TransactionFilter) *gorm.DB {
+	if len(filter.Types) > 0 {
+		tx = tx.Where("transactions.type IN ?", filter.Types)
+	}
+
+	if len(filter.AccountIDs) > 0 {
+		tx = tx.Where("transactions.account_id IN ?", filter.AccountIDs)
+	}
+
+	if len(filter.TagIDs) > 0 {
+		tx = tx.Where("transactions.id IN (?)", r.db.Table("transaction_tags").Select("transaction_id").Where("tag_id IN ?", filter.TagIDs))
+	}
+
+	if len(filter.MerchantIDs) > 0 {
+		tx = tx.Where("transactions.merchant_id IN ?", filter.MerchantIDs)
+	}
+
+	if filter.Description != "" {
+		tx = tx.Where("transactions.description LIKE ?", "%"+filter.Description+"%")
+	}
+
+	if filter.MinAmount != nil {
+		tx = tx.Where("transactions.amount >= ?", *filter.MinAmount)
+	}
+
+	if filter.MaxAmount != nil {
+		tx = tx.Where("transactions.amount <= ?", *filter.MaxAmount)
+	}
+
+	if filter.StartDate != nil {
+		tx = tx.Where("transactions.date >= ?", *filter.StartDate)
+	}
+
+	if filter.EndDate != nil {
+		// Include the entire end date
+		endOfDay := time.Date(filter.EndDate.Year(), filter.EndDate.Month(), filter.EndDate.Day(), 23, 59, 59, 999999999, filter.EndDate.Location())
+		tx = tx.Where("transactions.date <= ?", endOfDay)
+	}
+	return tx
+}
+
 func (r *transactionRepository) Update(transaction *models.Transaction) error {
 	return r.db.Save(transaction).Error
 }
@@ -251,20 +363,33 @@ func (r *transactionRepository) Delete(id uint, userID uint) error {
 		return err
 	}
 
-	// Delete the transaction
-	return r.db.Delete(&models.Transaction{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Splits have no meaning without their transaction
+		if err := tx.Where("transaction_id = ?", id).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+
+		// Delete the transaction
+		return tx.Delete(&models.Transaction{}, id).Error
+	})
 }
 
 func (r *transactionRepository) SoftDeleteByAccountID(accountID uint) error {
-	// Soft delete all transactions for the given account ID
-	// GORM will automatically set the deleted_at timestamp when using Delete()
-	return r.db.Where("account_id = ?", accountID).Delete(&models.Transaction{}).Error
+	// Soft delete all transactions for the given account ID, marked as deleted with the
+	// account so they're told apart from the ones the user deleted before
+	return r.db.Model(&models.Transaction{}).Where("account_id = ?", accountID).Updates(map[string]interface{}{
+		"deleted_at":           time.Now(),
+		"deleted_with_account": true,
+	}).Error
 }
 
 func (r *transactionRepository) ReactivateByAccountID(accountID uint) error {
-	// Reactivate all soft-deleted transactions for the given account ID
-	// by setting deleted_at to NULL
-	return r.db.Unscoped().Model(&models.Transaction{}).Where("account_id = ? AND deleted_at IS NOT NULL", accountID).Update("deleted_at", nil).Error
+	// Reactivate the transactions deleted together with the account by setting deleted_at to
+	// NULL, leaving the ones the user deleted before deleted
+	return r.db.Unscoped().Model(&models.Transaction{}).Where("account_id = ? AND deleted_with_account = ?", accountID, true).Updates(map[string]interface{}{
+		"deleted_at":           nil,
+		"deleted_with_account": false,
+	}).Error
 }
 
 // Begin starts a new transaction
@@ -314,7 +439,53 @@ func (r *transactionRepository) AssociateCategories(transactionID uint, category
 	return tx.Commit().Error
 }
 
-func (r *transactionRepository) GetDashboardSummary(userID uint) (float64, float64, float64, []models.Transaction, error) {
+// ReplaceSplits replaces the splits of a transaction and keeps its categories in sync with
+// the categories used by the splits. An empty slice removes the splits and keeps the categories.
+func (r *transactionRepository) ReplaceSplits(transactionID uint, splits []models.TransactionSplit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_id = ?", transactionID).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+
+		if len(splits) == 0 {
+			return nil
+		}
+
+		seen := make(map[uint]bool)
+		for i := range splits {
+			splits[i].ID = 0
+			splits[i].TransactionID = transactionID
+			seen[splits[i].CategoryID] = true
+		}
+		if err := tx.Omit("Category").Create(&splits).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM transaction_categories WHERE transaction_id = ?", transactionID).Error; err != nil {
+			return err
+		}
+		for _, split := range splits {
+			if !seen[split.CategoryID] {
+				continue
+			}
+			seen[split.CategoryID] = false
+			if err := tx.Exec(
+				"INSERT INTO transaction_categories (transaction_id, category_id) VALUES (?, ?)",
+				transactionID, split.CategoryID,
+			).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetDashboardSummary returns the total balance of the user's accounts, the income and expenses
+// between the given dates and the most recent transactions of that period
+// GetDashboardSummary returns the balance of the user's accounts, and the totals and most recent
+// transactions of the period like GetPeriodTotals
+func (r *transactionRepository) GetDashboardSummary(userID uint, accountIDs []uint, startDate, endDate time.Time, recentLimit int) (float64, float64, float64, []models.Transaction, error) {
 	// Get total balance from all accounts
 	var totalBalance struct{ Sum float64 }
 	err := r.db.Model(&models.Account{}).
@@ -326,47 +497,45 @@ func (r *transactionRepository) GetDashboardSummary(userID uint) (float64, float
 		return 0, 0, 0, nil, err
 	}
 
-	// Get total income for current month
-	now := time.Now()
-	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-
-	var totalIncome struct{ Sum float64 }
-	err = r.db.Model(&models.Transaction{}).
-		Joins("JOIN accounts ON accounts.id = transactions.account_id").
-		Where("accounts.user_id = ? AND transactions.type = ? AND transactions.date >= ?",
-			userID, models.TransactionTypeIncome, firstOfMonth).
-		Select("COALESCE(SUM(amount), 0) as sum").
-		Scan(&totalIncome).Error
-
+	totalIncome, totalExpenses, err := r.GetPeriodTotals(userID, accountIDs, startDate, endDate)
 	if err != nil {
 		return 0, 0, 0, nil, err
 	}
 
-	// Get total expenses for current month
-	var totalExpenses struct{ Sum float64 }
-	err = r.db.Model(&models.Transaction{}).
-		Joins("JOIN accounts ON accounts.id = transactions.account_id").
-		Where("accounts.user_id = ? AND transactions.type = ? AND transactions.date >= ?",
-			userID, models.TransactionTypeExpense, firstOfMonth).
-		Select("COALESCE(SUM(amount), 0) as sum").
-		Scan(&totalExpenses).Error
+	// Get the most recent transactions of the period
+	var recentTransactions []models.Transaction
+	err = r.visibleTransactions(userID, TransactionFilter{AccountIDs: accountIDs, StartDate: &startDate, EndDate: &endDate}).
+		Preload("Categories").
+		Order("transactions.date DESC").
+		Limit(recentLimit).
+		Find(&recentTransactions).Error
 
 	if err != nil {
 		return 0, 0, 0, nil, err
 	}
 
-	// Get recent transactions (last 5)
-	var recentTransactions []models.Transaction
-	err = r.db.Preload("Categories").
-		Joins("JOIN accounts ON accounts.id = transactions.account_id").
-		Where("accounts.user_id = ?", userID).
-		Order("date DESC").
-		Limit(5).
-		Find(&recentTransactions).Error
+	return totalBalance.Sum, totalIncome, totalExpenses, recentTransactions, nil
+}
+
+// GetPeriodTotals sums the income and expenses between the given dates, including the whole
+// end date, of the accounts the user owns or that are shared with them, like the statistics,
+// or only of accountIDs when given. Transfers between accounts are neither income nor expenses
+// and are left out.
+func (r *transactionRepository) GetPeriodTotals(userID uint, accountIDs []uint, startDate, endDate time.Time) (float64, float64, error) {
+	var totals struct {
+		Income   float64
+		Expenses float64
+	}
+	err := r.visibleTransactions(userID, TransactionFilter{AccountIDs: accountIDs, StartDate: &startDate, EndDate: &endDate}).
+		Where("transactions.attachment_type IS NULL").
+		Select("COALESCE(SUM(CASE WHEN transactions.type = ? THEN transactions.amount ELSE 0 END), 0) as income, "+
+			"COALESCE(SUM(CASE WHEN transactions.type = ? THEN transactions.amount ELSE 0 END), 0) as expenses",
+			models.TransactionTypeIncome, models.TransactionTypeExpense).
+		Scan(&totals).Error
 
 	if err != nil {
-		return 0, 0, 0, nil, err
+		return 0, 0, err
 	}
 
-	return totalBalance.Sum, totalIncome.Sum, totalExpenses.Sum, recentTransactions, nil
+	return totals.Income, totals.Expenses, nil
 }
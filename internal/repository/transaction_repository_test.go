@@ -16,7 +16,7 @@ func setupTransactionTestDB(t *testing.T) (*gorm.DB, *models.User, *models.Accou
 	}
 
 	// Auto migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.AccountShare{}, &models.ShareInvitation{})
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.AccountShare{}, &models.ShareInvitation{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -218,7 +218,7 @@ func TestTransactionRepository_FindByUserID_Basic(t *testing.T) {
 	}
 
 	// Find all transactions for user
-	foundTransactions, total, err := repo.FindByUserID(user.ID, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+	foundTransactions, total, err := repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -270,7 +270,7 @@ func TestTransactionRepository_FindByUserID_WithFilters(t *testing.T) {
 
 	// Test filter by transaction type
 	expenseTypes := []models.TransactionType{models.TransactionTypeExpense}
-	foundTransactions, total, err := repo.FindByUserID(user.ID, expenseTypes, nil, nil, "", nil, nil, nil, nil, 0, 0)
+	foundTransactions, total, err := repo.FindByUserID(user.ID, expenseTypes, nil, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -286,7 +286,7 @@ func TestTransactionRepository_FindByUserID_WithFilters(t *testing.T) {
 	// Test filter by amount range
 	minAmount := 75.0
 	maxAmount := 150.0
-	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, nil, nil, "", &minAmount, &maxAmount, nil, nil, 0, 0)
+	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "", &minAmount, &maxAmount, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -296,7 +296,7 @@ func TestTransactionRepository_FindByUserID_WithFilters(t *testing.T) {
 	}
 
 	// Test filter by description
-	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, nil, nil, "Food", nil, nil, nil, nil, 0, 0)
+	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "Food", nil, nil, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -307,7 +307,7 @@ func TestTransactionRepository_FindByUserID_WithFilters(t *testing.T) {
 
 	// Test filter by account ID
 	accountIDs := []uint{account.ID}
-	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, accountIDs, nil, "", nil, nil, nil, nil, 0, 0)
+	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, accountIDs, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -337,7 +337,7 @@ func TestTransactionRepository_FindByUserID_WithPagination(t *testing.T) {
 	}
 
 	// Test pagination - page 1, size 2
-	foundTransactions, total, err := repo.FindByUserID(user.ID, nil, nil, nil, "", nil, nil, nil, nil, 1, 2)
+	foundTransactions, total, err := repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, 1, 2)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -351,7 +351,7 @@ func TestTransactionRepository_FindByUserID_WithPagination(t *testing.T) {
 	}
 
 	// Test pagination - page 2, size 2
-	foundTransactions, total, err = repo.FindByUserID(user.ID, nil, nil, nil, "", nil, nil, nil, nil, 2, 2)
+	foundTransactions, total, err = repo.FindByUserID(user.ID, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, 2, 2)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -571,8 +571,9 @@ func TestTransactionRepository_GetDashboardSummary(t *testing.T) {
 		}
 	}
 
-	// Get dashboard summary
-	totalBalance, totalIncome, totalExpenses, recentTransactions, err := repo.GetDashboardSummary(user.ID)
+	// Get dashboard summary for the current month
+	endOfMonth := currentMonth.AddDate(0, 1, -1)
+	totalBalance, totalIncome, totalExpenses, recentTransactions, err := repo.GetDashboardSummary(user.ID, nil, currentMonth, endOfMonth, 5)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -606,6 +607,48 @@ func TestTransactionRepository_GetDashboardSummary(t *testing.T) {
 			}
 		}
 	}
+
+	// Recent transactions only come from the requested period
+	if len(recentTransactions) != 3 {
+		t.Errorf("Expected 3 recent transactions in the current month, got %d", len(recentTransactions))
+	}
+
+	// The previous month is summarized on its own
+	previousMonth := currentMonth.AddDate(0, -1, 0)
+	_, totalIncome, totalExpenses, _, err = repo.GetDashboardSummary(user.ID, nil, previousMonth, currentMonth.AddDate(0, 0, -1), 5)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if totalIncome != 500.00 || totalExpenses != 0 {
+		t.Errorf("Expected previous month income 500.00 and no expenses, got %f and %f", totalIncome, totalExpenses)
+	}
+}
+
+func TestTransactionRepository_GetPeriodTotals_ExcludesTransfers(t *testing.T) {
+	db, user, account, _ := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	date := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	outbound := models.AttachmentTypeOutboundTransfer
+	transactions := []*models.Transaction{
+		{Date: date, Amount: 2000.00, Type: models.TransactionTypeIncome, Description: "Salary", AccountID: account.ID},
+		{Date: date, Amount: 150.00, Type: models.TransactionTypeExpense, Description: "Groceries", AccountID: account.ID},
+		{Date: date, Amount: 800.00, Type: models.TransactionTypeExpense, Description: "Transfer to savings", AccountID: account.ID, AttachmentType: &outbound},
+		{Date: date, Amount: 1000.00, Type: models.TransactionTypeInitial, Description: "Initial Balance", AccountID: account.ID},
+	}
+	for _, transaction := range transactions {
+		if err := repo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+
+	income, expenses, err := repo.GetPeriodTotals(user.ID, nil, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if income != 2000.00 || expenses != 150.00 {
+		t.Errorf("Expected income 2000.00 and expenses 150.00, got %f and %f", income, expenses)
+	}
 }
 
 func TestTransactionRepository_SoftDeleteByAccountID(t *testing.T) {
@@ -638,7 +681,7 @@ func TestTransactionRepository_SoftDeleteByAccountID(t *testing.T) {
 	}
 
 	// Verify transactions exist before soft delete
-	foundTransactions, _, err := repo.FindByUserID(user.ID, nil, []uint{account.ID}, nil, "", nil, nil, nil, nil, 0, 0)
+	foundTransactions, _, err := repo.FindByUserID(user.ID, nil, []uint{account.ID}, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -654,7 +697,7 @@ func TestTransactionRepository_SoftDeleteByAccountID(t *testing.T) {
 	}
 
 	// Verify transactions are soft deleted (should not appear in normal queries)
-	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, []uint{account.ID}, nil, "", nil, nil, nil, nil, 0, 0)
+	foundTransactions, _, err = repo.FindByUserID(user.ID, nil, []uint{account.ID}, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
 	if err != nil {
 		t.Errorf("Expected no error after soft delete, got %v", err)
 	}
@@ -685,3 +728,178 @@ func TestTransactionRepository_SoftDeleteByAccountID(t *testing.T) {
 		t.Errorf("Expected 2 transactions with deleted_at set, got %d", len(softDeletedTransactions))
 	}
 }
+
+func TestTransactionRepository_ReplaceSplits(t *testing.T) {
+	db, user, account, category := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	other := &models.Category{
+		Name:   "Household",
+		Type:   models.TransactionTypeExpense,
+		UserID: user.ID,
+	}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	transaction := &models.Transaction{
+		Date:        time.Now(),
+		Amount:      150.00,
+		Type:        models.TransactionTypeExpense,
+		Description: "Supermarket",
+		AccountID:   account.ID,
+	}
+	if err := repo.Create(transaction); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	splits := []models.TransactionSplit{
+		{CategoryID: category.ID, Amount: 100.00, Memo: "Groceries"},
+		{CategoryID: other.ID, Amount: 50.00, Memo: "Cleaning"},
+	}
+	if err := repo.ReplaceSplits(transaction.ID, splits); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	found, err := repo.FindByID(transaction.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(found.Splits) != 2 {
+		t.Fatalf("Expected 2 splits, got %d", len(found.Splits))
+	}
+	if found.Splits[0].Category == nil || found.Splits[0].Category.Name != "Food" {
+		t.Errorf("Expected first split category to be loaded, got %+v", found.Splits[0].Category)
+	}
+	if len(found.Categories) != 2 {
+		t.Errorf("Expected categories to follow the splits, got %d", len(found.Categories))
+	}
+
+	// Replacing with a single split removes the old ones
+	if err := repo.ReplaceSplits(transaction.ID, []models.TransactionSplit{{CategoryID: other.ID, Amount: 150.00}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	found, err = repo.FindByID(transaction.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(found.Splits) != 1 || found.Splits[0].CategoryID != other.ID {
+		t.Errorf("Expected a single split for category %d, got %+v", other.ID, found.Splits)
+	}
+	if len(found.Categories) != 1 || found.Categories[0].ID != other.ID {
+		t.Errorf("Expected categories to be replaced, got %+v", found.Categories)
+	}
+
+	var count int64
+	db.Model(&models.TransactionSplit{}).Where("transaction_id = ?", transaction.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 split row, got %d", count)
+	}
+}
+
+func TestTransactionRepository_FindInBatches(t *testing.T) {
+	db, user, account, category := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		transaction := &models.Transaction{
+			Date:        start.AddDate(0, 0, i),
+			Amount:      float64(100 + i*10),
+			Type:        models.TransactionTypeExpense,
+			Description: "Transaction " + string(rune(i+'1')),
+			AccountID:   account.ID,
+		}
+		if err := repo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction %d: %v", i, err)
+		}
+		if i%2 == 0 {
+			if err := repo.AssociateCategories(transaction.ID, []uint{category.ID}); err != nil {
+				t.Fatalf("Failed to associate category: %v", err)
+			}
+		}
+	}
+
+	var batches, ids []uint
+	err := repo.FindInBatches(user.ID, TransactionFilter{}, 2, func(transactions []models.Transaction) error {
+		batches = append(batches, uint(len(transactions)))
+		for _, transaction := range transactions {
+			ids = append(ids, transaction.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(batches) != 3 || batches[0] != 2 || batches[2] != 1 {
+		t.Errorf("Expected batches of 2, 2 and 1, got %v", batches)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("Expected transactions ordered by ID, got %v", ids)
+		}
+	}
+
+	var categorized []models.Transaction
+	err = repo.FindInBatches(user.ID, TransactionFilter{CategoryIDs: []uint{category.ID}}, 10, func(transactions []models.Transaction) error {
+		categorized = append(categorized, transactions...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(categorized) != 3 {
+		t.Fatalf("Expected 3 categorized transactions, got %d", len(categorized))
+	}
+	if len(categorized[0].Categories) != 1 || categorized[0].Account.ID != account.ID {
+		t.Errorf("Expected categories and account to be preloaded, got %+v", categorized[0])
+	}
+
+	// Other users see nothing
+	err = repo.FindInBatches(user.ID+1, TransactionFilter{}, 10, func(transactions []models.Transaction) error {
+		t.Errorf("Expected no transactions for another user, got %d", len(transactions))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestTransactionRepository_DateRange(t *testing.T) {
+	db, user, account, _ := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	_, _, found, err := repo.DateRange(user.ID, TransactionFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found {
+		t.Error("Expected no range without transactions")
+	}
+
+	dates := []time.Time{
+		time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC),
+	}
+	for i, date := range dates {
+		transaction := &models.Transaction{Date: date, Amount: 10, Type: models.TransactionTypeIncome, Description: "Salary", AccountID: account.ID}
+		if i == 2 {
+			transaction.Type = models.TransactionTypeExpense
+		}
+		if err := repo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+
+	first, last, found, err := repo.DateRange(user.ID, TransactionFilter{Types: []models.TransactionType{models.TransactionTypeIncome}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !found || !first.Equal(dates[1]) || !last.Equal(dates[0]) {
+		t.Errorf("Expected range %v to %v, got %v to %v (found %v)", dates[1], dates[0], first, last, found)
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupUserIdentityTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.UserIdentity{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func createTestUserIdentity(t *testing.T, repo UserIdentityRepository, userID uint, provider, subject string) *models.UserIdentity {
+	identity := &models.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    "test@example.com",
+	}
+	if err := repo.Create(context.Background(), identity); err != nil {
+		t.Fatalf("Failed to create test identity: %v", err)
+	}
+	return identity
+}
+
+func TestUserIdentityRepository_FindByProviderSubject(t *testing.T) {
+	db, user := setupUserIdentityTestDB(t)
+	repo := NewUserIdentityRepository(db)
+	ctx := context.Background()
+	identity := createTestUserIdentity(t, repo, user.ID, models.IdentityProviderGoogle, "1234")
+
+	found, err := repo.FindByProviderSubject(ctx, models.IdentityProviderGoogle, "1234")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.ID != identity.ID || found.UserID != user.ID {
+		t.Errorf("Expected the user's identity, got %+v", found)
+	}
+
+	// Subjects are only unique within their provider
+	if _, err := repo.FindByProviderSubject(ctx, "other", "1234"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another provider, got %v", err)
+	}
+	if err := repo.Create(ctx, &models.UserIdentity{UserID: user.ID, Provider: models.IdentityProviderGoogle, Subject: "1234"}); err == nil {
+		t.Error("Expected an error linking the same identity twice")
+	}
+
+	loginAt := time.Now().Truncate(time.Second)
+	if err := repo.Touch(ctx, identity.ID, loginAt); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	found, err = repo.FindByProviderSubject(ctx, models.IdentityProviderGoogle, "1234")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.LastLoginAt == nil || !found.LastLoginAt.Equal(loginAt) {
+		t.Errorf("Expected last login at %v, got %v", loginAt, found.LastLoginAt)
+	}
+}
+
+func TestUserIdentityRepository_Delete(t *testing.T) {
+	db, user := setupUserIdentityTestDB(t)
+	repo := NewUserIdentityRepository(db)
+	ctx := context.Background()
+	first := createTestUserIdentity(t, repo, user.ID, models.IdentityProviderGoogle, "1234")
+	createTestUserIdentity(t, repo, user.ID, "other", "5678")
+
+	if err := repo.Delete(ctx, user.ID+1, first.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another user's identity, got %v", err)
+	}
+	if err := repo.Delete(ctx, user.ID, first.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Delete(ctx, user.ID, first.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an unlinked identity, got %v", err)
+	}
+
+	identities, err := repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(identities) != 1 || identities[0].Provider != "other" {
+		t.Errorf("Expected only the remaining identity, got %+v", identities)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"errors"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -24,8 +25,22 @@ type UserRepository interface {
 	FindByEmail(email string) (*models.User, error)
 	// Update updates an existing user
 	Update(user *models.User) error
+	// AdvanceTOTPStep records the time step of an accepted 2FA code. It returns ErrNotFound
+	// when a code of that step or a later one was already accepted, so each code works once
+	// even when two requests use it at the same time.
+	AdvanceTOTPStep(id uint, step int64) error
+	// RecordFailedLogin counts a wrong password entered by the user and returns how many were
+	// entered since the last successful login
+	RecordFailedLogin(id uint) (int, error)
+	// LockUntil locks the user's logins until the given time
+	LockUntil(id uint, until time.Time) error
+	// ResetFailedLogins clears the user's wrong passwords and lock after a successful login
+	ResetFailedLogins(id uint) error
 	// Delete removes a user from the database
 	Delete(id uint) error
+	// DeleteWithData permanently removes a user with everything they own in a single
+	// transaction. Shares with the user are revoked and pending invitations to them canceled.
+	DeleteWithData(id uint) error
 }
 
 type userRepository struct {
@@ -86,6 +101,53 @@ func (r *userRepository) Update(user *models.User) error {
 	return nil
 }
 
+// AdvanceTOTPStep implements UserRepository
+func (r *userRepository) AdvanceTOTPStep(id uint, step int64) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordFailedLogin implements UserRepository
+func (r *userRepository) RecordFailedLogin(id uint) (int, error) {
+	var attempts int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Update("failed_login_attempts", gorm.Expr("failed_login_attempts + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Pluck("failed_login_attempts", &attempts).Error
+	})
+	return attempts, err
+}
+
+// LockUntil implements UserRepository
+func (r *userRepository) LockUntil(id uint, until time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("locked_until", until).Error
+}
+
+// ResetFailedLogins implements UserRepository
+func (r *userRepository) ResetFailedLogins(id uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error
+}
+
 // Delete implements UserRepository
 func (r *userRepository) Delete(id uint) error {
 	if err := r.db.Delete(&models.User{}, id).Error; err != nil {
@@ -93,3 +155,62 @@ func (r *userRepository) Delete(id uint) error {
 	}
 	return nil
 }
+
+// DeleteWithData implements UserRepository
+func (r *userRepository) DeleteWithData(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		const (
+			accounts     = "SELECT id FROM accounts WHERE user_id = @user"
+			transactions = "SELECT id FROM transactions WHERE account_id IN (" + accounts + ")"
+		)
+		args := map[string]interface{}{
+			"user":     id,
+			"email":    user.Email,
+			"pending":  models.InvitationPending,
+			"canceled": models.InvitationCanceled,
+		}
+		// Records are deleted before the records they reference
+		statements := []string{
+			"UPDATE share_invitations SET status = @canceled WHERE invited_email = @email AND status = @pending",
+			"DELETE FROM share_invitations WHERE owner_user_id = @user OR account_id IN (" + accounts + ")",
+			"DELETE FROM account_shares WHERE owner_user_id = @user OR shared_user_id = @user OR account_id IN (" + accounts + ")",
+			"DELETE FROM alerts WHERE user_id = @user",
+			"DELETE FROM sessions WHERE user_id = @user",
+			"DELETE FROM recovery_codes WHERE user_id = @user",
+			"DELETE FROM audit_logs WHERE user_id = @user",
+			"DELETE FROM password_reset_tokens WHERE user_id = @user",
+			"DELETE FROM user_identities WHERE user_id = @user",
+			"DELETE FROM personal_access_tokens WHERE user_id = @user OR account_id IN (" + accounts + ")",
+			"UPDATE transactions SET attached_transaction_id = NULL, attachment_type = NULL WHERE attached_transaction_id IN (" + transactions + ")",
+			"DELETE FROM transaction_splits WHERE transaction_id IN (" + transactions + ")",
+			"DELETE FROM transaction_categories WHERE transaction_id IN (" + transactions + ") OR category_id IN (SELECT id FROM categories WHERE user_id = @user)",
+			"DELETE FROM transaction_tags WHERE transaction_id IN (" + transactions + ") OR tag_id IN (SELECT id FROM tags WHERE user_id = @user)",
+			"DELETE FROM transactions WHERE account_id IN (" + accounts + ")",
+			"DELETE FROM subscriptions WHERE user_id = @user",
+			"DELETE FROM categorization_rule_tags WHERE categorization_rule_id IN (SELECT id FROM categorization_rules WHERE user_id = @user)",
+			"DELETE FROM categorization_rules WHERE user_id = @user",
+			// Transactions the user recorded in accounts shared with them keep their data
+			"UPDATE transactions SET merchant_id = NULL WHERE merchant_id IN (SELECT id FROM merchants WHERE user_id = @user)",
+			"DELETE FROM merchant_aliases WHERE user_id = @user",
+			"DELETE FROM merchants WHERE user_id = @user",
+			"DELETE FROM tags WHERE user_id = @user",
+			"DELETE FROM categories WHERE user_id = @user",
+			"DELETE FROM accounts WHERE user_id = @user",
+			"DELETE FROM users WHERE id = @user",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement, args).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
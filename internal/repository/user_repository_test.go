@@ -2,6 +2,7 @@ package repository
 
 import (
 	"testing"
+	"time"
 
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"gorm.io/driver/sqlite"
@@ -189,6 +190,84 @@ func TestUserRepository_Update(t *testing.T) {
 	}
 }
 
+func TestUserRepository_AdvanceTOTPStep(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+
+	user := &models.User{Name: "John Doe", Email: "john@example.com", Password: "hashedpassword"}
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := repo.AdvanceTOTPStep(user.ID, 100); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The same step and earlier ones were used already
+	if err := repo.AdvanceTOTPStep(user.ID, 100); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a used step, got %v", err)
+	}
+	if err := repo.AdvanceTOTPStep(user.ID, 99); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an earlier step, got %v", err)
+	}
+	if err := repo.AdvanceTOTPStep(user.ID, 101); err != nil {
+		t.Errorf("Expected no error for a later step, got %v", err)
+	}
+
+	found, err := repo.FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.TOTPLastStep != 101 {
+		t.Errorf("Expected the last step to be 101, got %d", found.TOTPLastStep)
+	}
+}
+
+func TestUserRepository_FailedLogins(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+
+	user := &models.User{Name: "John Doe", Email: "john@example.com", Password: "hashedpassword"}
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		attempts, err := repo.RecordFailedLogin(user.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if attempts != want {
+			t.Errorf("Expected %d failed logins, got %d", want, attempts)
+		}
+	}
+	if _, err := repo.RecordFailedLogin(999); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an unknown user, got %v", err)
+	}
+
+	until := time.Now().Add(time.Minute)
+	if err := repo.LockUntil(user.ID, until); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	found, err := repo.FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !found.Locked(time.Now()) || found.Locked(until) {
+		t.Errorf("Expected the user to be locked until %v, got %v", until, found.LockedUntil)
+	}
+
+	if err := repo.ResetFailedLogins(user.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	found, err = repo.FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found.FailedLoginAttempts != 0 || found.LockedUntil != nil {
+		t.Errorf("Expected the failed logins and lock to be cleared, got %d and %v", found.FailedLoginAttempts, found.LockedUntil)
+	}
+}
+
 func TestUserRepository_Delete(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewUserRepository(db)
@@ -227,3 +306,118 @@ func TestUserRepository_Delete_NonExistent(t *testing.T) {
 		t.Errorf("Expected no error for deleting non-existent user, got %v", err)
 	}
 }
+
+func TestUserRepository_DeleteWithData(t *testing.T) {
+	db, source, target := setupBackupTestDB(t)
+	seedBackupData(t, db, source)
+	seedBackupData(t, db, target)
+	repo := NewUserRepository(db)
+
+	// The target shares an account with the source and invites them to another one
+	var targetAccount models.Account
+	db.Where("user_id = ?", target.ID).First(&targetAccount)
+	if err := db.Create(&models.AccountShare{AccountID: targetAccount.ID, OwnerUserID: target.ID, SharedUserID: source.ID, PermissionLevel: models.PermissionRead, SharedAt: time.Now()}).Error; err != nil {
+		t.Fatalf("Failed to create share: %v", err)
+	}
+	invitation := &models.ShareInvitation{AccountID: targetAccount.ID, OwnerUserID: target.ID, InvitedEmail: source.Email, InvitationToken: "to-source", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(invitation).Error; err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+	var sourceAccount models.Account
+	db.Where("user_id = ?", source.ID).First(&sourceAccount)
+	if err := db.Create(&models.ShareInvitation{AccountID: sourceAccount.ID, OwnerUserID: source.ID, InvitedEmail: target.Email, InvitationToken: "from-source", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+	if err := db.Create(&models.Session{UserID: source.ID, RefreshTokenHash: "source-session", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := db.Create(&models.RecoveryCode{UserID: source.ID, CodeHash: "source-code"}).Error; err != nil {
+		t.Fatalf("Failed to create recovery code: %v", err)
+	}
+	if err := db.Create(&models.PersonalAccessToken{UserID: source.ID, Name: "Script", TokenHash: "source-token", Prefix: "dnh_source", Scopes: "import"}).Error; err != nil {
+		t.Fatalf("Failed to create personal access token: %v", err)
+	}
+	if err := db.Create(&models.AuditLog{UserID: source.ID, Action: models.AuditActionAccountLocked}).Error; err != nil {
+		t.Fatalf("Failed to create audit entry: %v", err)
+	}
+	if err := db.Create(&models.PasswordResetToken{UserID: source.ID, TokenHash: "source-reset", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("Failed to create reset token: %v", err)
+	}
+	if err := db.Create(&models.UserIdentity{UserID: source.ID, Provider: models.IdentityProviderGoogle, Subject: "source-subject"}).Error; err != nil {
+		t.Fatalf("Failed to create identity: %v", err)
+	}
+	// The source recorded an expense in the shared account with one of their merchants
+	var sourceMerchant models.Merchant
+	db.Where("user_id = ?", source.ID).First(&sourceMerchant)
+	sharedExpense := &models.Transaction{Date: time.Now(), Amount: 30, Type: models.TransactionTypeExpense, Description: "Padaria", AccountID: targetAccount.ID, MerchantID: &sourceMerchant.ID}
+	if err := db.Create(sharedExpense).Error; err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	// The target's token restricted to the account they share with the source is kept
+	if err := db.Create(&models.PersonalAccessToken{UserID: target.ID, Name: "Script", TokenHash: "target-token", Prefix: "dnh_target", Scopes: "import", AccountID: &targetAccount.ID}).Error; err != nil {
+		t.Fatalf("Failed to create personal access token: %v", err)
+	}
+
+	if err := repo.DeleteWithData(source.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	count := func(model interface{}, query string, args ...interface{}) int64 {
+		var n int64
+		if err := db.Unscoped().Model(model).Where(query, args...).Count(&n).Error; err != nil {
+			t.Fatalf("Failed to count %T: %v", model, err)
+		}
+		return n
+	}
+	if n := count(&models.User{}, "id = ?", source.ID); n != 0 {
+		t.Errorf("Expected the user to be removed, found %d", n)
+	}
+	for _, model := range []interface{}{&models.Account{}, &models.Category{}, &models.Tag{}, &models.Merchant{}, &models.MerchantAlias{}, &models.CategorizationRule{}, &models.Subscription{}, &models.Alert{}, &models.Session{}, &models.RecoveryCode{}, &models.PersonalAccessToken{}, &models.AuditLog{}, &models.PasswordResetToken{}, &models.UserIdentity{}} {
+		if n := count(model, "user_id = ?", source.ID); n != 0 {
+			t.Errorf("Expected the user's %T to be removed, found %d", model, n)
+		}
+	}
+	if n := count(&models.PersonalAccessToken{}, "user_id = ?", target.ID); n != 1 {
+		t.Errorf("Expected the target's token to be kept, found %d", n)
+	}
+	if n := count(&models.AccountShare{}, "owner_user_id = ? OR shared_user_id = ?", source.ID, source.ID); n != 0 {
+		t.Errorf("Expected the shares to be revoked, found %d", n)
+	}
+	if n := count(&models.ShareInvitation{}, "owner_user_id = ?", source.ID); n != 0 {
+		t.Errorf("Expected the user's invitations to be removed, found %d", n)
+	}
+	var canceled models.ShareInvitation
+	db.First(&canceled, invitation.ID)
+	if canceled.Status != models.InvitationCanceled {
+		t.Errorf("Expected the invitation to the user to be canceled, got %s", canceled.Status)
+	}
+
+	// Only the target's records are left
+	var transactions, splits, links, ruleTags int64
+	db.Unscoped().Model(&models.Transaction{}).Count(&transactions)
+	db.Model(&models.TransactionSplit{}).Count(&splits)
+	db.Table("transaction_categories").Count(&links)
+	db.Table("categorization_rule_tags").Count(&ruleTags)
+	if transactions != 4 || splits != 2 || links != 1 || ruleTags != 1 {
+		t.Errorf("Expected the target's 4 transactions, 2 splits, 1 category link and 1 rule tag, got %d, %d, %d and %d", transactions, splits, links, ruleTags)
+	}
+	var kept models.Transaction
+	if err := db.First(&kept, sharedExpense.ID).Error; err != nil {
+		t.Fatalf("Expected the expense in the shared account to be kept, got %v", err)
+	}
+	if kept.MerchantID != nil {
+		t.Errorf("Expected the expense to lose the deleted merchant, got %v", *kept.MerchantID)
+	}
+	if n := count(&models.Account{}, "user_id = ?", target.ID); n != 2 {
+		t.Errorf("Expected the target's accounts to be kept, found %d", n)
+	}
+}
+
+func TestUserRepository_DeleteWithData_NonExistent(t *testing.T) {
+	db, _, _ := setupBackupTestDB(t)
+	repo := NewUserRepository(db)
+
+	if err := repo.DeleteWithData(999); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -15,6 +16,12 @@ import (
 func SetupRoutes(container *di.Container) *gin.Engine {
 	r := gin.Default()
 
+	// Only the configured proxies' X-Forwarded-For headers are trusted for the client's IP
+	// address, which the auth routes are rate limited by
+	if err := r.SetTrustedProxies(container.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+
 	// Set multipart memory limit to 10MB
 	r.MaxMultipartMemory = 10 << 20 // 10MB
 
@@ -39,24 +46,44 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 	{
 		api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-		// Auth routes
+		// Auth routes, which are rate limited so passwords and codes can't be brute-forced
+		ipLimit := middleware.RateLimit(container.AuthIPLimiter, middleware.ClientIP)
+		emailLimit := middleware.RateLimit(container.LoginEmailLimiter, middleware.RequestEmail)
+		resetEmailLimit := middleware.RateLimit(container.ResetEmailLimiter, middleware.RequestEmail)
 		authGroup := api.Group("/auth")
 		{
-			authGroup.POST("/register", container.UserHandler.Register)
-			authGroup.POST("/login", container.UserHandler.Login)
+			authGroup.POST("/register", ipLimit, container.UserHandler.Register)
+			authGroup.POST("/login", ipLimit, emailLimit, container.UserHandler.Login)
 			// Google OAuth login
-			authGroup.POST("/google", container.UserHandler.GoogleLogin)
+			authGroup.POST("/google", ipLimit, container.UserHandler.GoogleLogin)
+			// Linking a Google or OIDC login to the user with its email confirms the user's password
+			authGroup.POST("/link", ipLimit, container.UserHandler.LinkIdentity)
+			authGroup.POST("/refresh", container.SessionHandler.Refresh)
+			// Second step of the login of users with two-factor authentication
+			authGroup.POST("/2fa", ipLimit, container.TwoFactorHandler.CompleteLogin)
+			// Forgotten passwords are reset with a link emailed to the user
+			authGroup.POST("/password/forgot", ipLimit, resetEmailLimit, container.PasswordResetHandler.ForgotPassword)
+			authGroup.POST("/password/reset", ipLimit, container.PasswordResetHandler.ResetPassword)
+			// Logins with the configured OpenID Connect providers
+			authGroup.GET("/oidc/providers", container.OIDCHandler.ListProviders)
+			authGroup.GET("/oidc/:provider/login", ipLimit, container.OIDCHandler.Login)
+			authGroup.POST("/oidc/:provider/callback", ipLimit, container.OIDCHandler.Callback)
 		}
 
 		// Protected routes
 		protected := api.Group("")
 		// Create auth middleware with required dependencies
-		authMiddleware := middleware.AuthMiddleware(container.UserService, container.JWTManager)
+		authMiddleware := middleware.AuthMiddleware(container.UserService, container.SessionService, container.PersonalAccessTokenService, container.JWTManager)
 		protected.Use(authMiddleware)
+		// Sensitive operations ask users with two-factor authentication for a recent code
+		recent2FA := middleware.RequireRecent2FA(10 * time.Minute)
 		{
 			// Dashboard summary
 			protected.GET("/summary", container.TransactionHandler.GetDashboardSummary)
 
+			// Logging out needs the session of the access token
+			protected.POST("/auth/logout", container.SessionHandler.Logout)
+
 			// Account routes
 			accounts := protected.Group("/accounts")
 			{
@@ -78,7 +105,7 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 					// Account sharing routes
 					shares := account.Group("/shares")
 					{
-						shares.POST("", container.AccountShareHandler.CreateShareInvitation)
+						shares.POST("", recent2FA, container.AccountShareHandler.CreateShareInvitation)
 						shares.GET("", container.AccountShareHandler.GetAccountShares)
 						shares.DELETE("/:userId", container.AccountShareHandler.RevokeShare)
 					}
@@ -98,6 +125,7 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 						transactions.GET("/:transactionId", container.TransactionHandler.GetTransaction)
 						transactions.PUT("/:transactionId", container.TransactionHandler.UpdateTransaction)
 						transactions.DELETE("/:transactionId", container.TransactionHandler.DeleteTransaction)
+						transactions.PUT("/:transactionId/splits", container.TransactionHandler.SetTransactionSplits)
 						transactions.POST("/import", container.TransactionHandler.ImportTransactions)
 						transactions.POST("/bulk", container.TransactionHandler.BulkCreateTransactions)
 					}
@@ -108,6 +136,7 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 			{
 				transactions.GET("", container.TransactionHandler.ListTransactions)
 				transactions.GET("/search", container.TransactionHandler.SearchTransactions)
+				transactions.GET("/export", container.ExportHandler.ExportTransactions)
 			}
 
 			// Category routes
@@ -115,8 +144,11 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 			{
 				categories.GET("", container.CategoryHandler.ListCategories)
 				categories.POST("", container.CategoryHandler.CreateCategory)
+				categories.GET("/templates", container.CategoryHandler.ListTemplates)
+				categories.POST("/templates/:name/apply", container.CategoryHandler.ApplyTemplate)
 				categories.PUT(":id", container.CategoryHandler.UpdateCategory)
 				categories.DELETE(":id", container.CategoryHandler.DeleteCategory)
+				categories.POST(":id/merge", container.CategoryHandler.MergeCategories)
 			}
 
 			// User profile routes
@@ -124,7 +156,28 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 			{
 				user.GET("", container.UserHandler.GetCurrentUser)
 				user.PATCH("", container.UserHandler.UpdateName)
-				user.PATCH("/password", container.UserHandler.UpdatePassword)
+				user.PATCH("/password", recent2FA, container.UserHandler.UpdatePassword)
+				user.PATCH("/timezone", container.UserHandler.UpdateTimezone)
+				user.GET("/export", container.PrivacyHandler.ExportPersonalData)
+				user.DELETE("", recent2FA, container.PrivacyHandler.DeleteAccount)
+				user.GET("/sessions", container.SessionHandler.ListSessions)
+				user.DELETE("/sessions", container.SessionHandler.RevokeAllSessions)
+				user.DELETE("/sessions/:id", container.SessionHandler.RevokeSession)
+				user.GET("/tokens", container.PersonalAccessTokenHandler.ListTokens)
+				user.POST("/tokens", recent2FA, container.PersonalAccessTokenHandler.CreateToken)
+				user.DELETE("/tokens/:id", container.PersonalAccessTokenHandler.RevokeToken)
+				user.GET("/identities", container.UserHandler.ListIdentities)
+				user.DELETE("/identities/:id", recent2FA, container.UserHandler.UnlinkIdentity)
+
+				twoFactor := user.Group("/2fa")
+				{
+					twoFactor.GET("", container.TwoFactorHandler.GetStatus)
+					twoFactor.POST("/setup", container.TwoFactorHandler.Setup)
+					twoFactor.POST("/enable", container.TwoFactorHandler.Enable)
+					twoFactor.POST("/verify", middleware.RateLimit(container.TwoFactorUserLimiter, middleware.AuthenticatedUser), container.TwoFactorHandler.Verify)
+					twoFactor.POST("/recovery-codes", recent2FA, container.TwoFactorHandler.RegenerateRecoveryCodes)
+					twoFactor.DELETE("", recent2FA, container.TwoFactorHandler.Disable)
+				}
 			}
 
 			// Statistics routes
@@ -134,8 +187,21 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 				statistics.GET("/amount-by-month", container.TransactionHandler.GetStatisticsAmountByMonth)
 				statistics.GET("/amount-by-account", container.TransactionHandler.GetStatisticsAmountByAccount)
 				statistics.GET("/amount-by-category", container.TransactionHandler.GetStatisticsAmountByCategory)
+				statistics.GET("/amount-by-tag", container.TransactionHandler.GetStatisticsAmountByTag)
+				statistics.GET("/amount-by-merchant", container.TransactionHandler.GetStatisticsAmountByMerchant)
 				statistics.GET("/amount-spent-by-day", container.TransactionHandler.GetStatisticsAmountSpentByDay)
 				statistics.GET("/amount-spent-and-gained-by-day", container.TransactionHandler.GetStatisticsAmountSpentAndGainedByDay)
+				statistics.GET("/forecast", container.ForecastHandler.GetForecast)
+				statistics.GET("/net-worth", container.NetWorthHandler.GetNetWorth)
+			}
+
+			// Tag routes
+			tags := protected.Group("/tags")
+			{
+				tags.GET("", container.TagHandler.ListTags)
+				tags.POST("", container.TagHandler.CreateTag)
+				tags.PUT(":id", container.TagHandler.UpdateTag)
+				tags.DELETE(":id", container.TagHandler.DeleteTag)
 			}
 
 			// Categorization rule routes
@@ -148,6 +214,46 @@ func SetupRoutes(container *di.Container) *gin.Engine {
 				categorizationRules.DELETE(":id", container.CategorizationRuleHandler.DeleteRule)
 			}
 
+			// Alert routes
+			alerts := protected.Group("/alerts")
+			{
+				alerts.GET("", container.AlertHandler.ListAlerts)
+				alerts.POST("/analyze", container.AlertHandler.AnalyzeAlerts)
+				alerts.POST("/read-all", container.AlertHandler.MarkAllAlertsRead)
+				alerts.POST(":id/read", container.AlertHandler.MarkAlertRead)
+				alerts.POST(":id/dismiss", container.AlertHandler.DismissAlert)
+			}
+
+			// Merchant routes
+			merchants := protected.Group("/merchants")
+			{
+				merchants.GET("", container.MerchantHandler.ListMerchants)
+				merchants.POST("/normalize", container.MerchantHandler.NormalizeMerchants)
+				merchants.POST("/aliases", container.MerchantHandler.CreateMerchantAlias)
+				merchants.DELETE("/aliases/:id", container.MerchantHandler.DeleteMerchantAlias)
+			}
+
+			// Subscription routes
+			subscriptions := protected.Group("/subscriptions")
+			{
+				subscriptions.GET("", container.SubscriptionHandler.ListSubscriptions)
+				subscriptions.POST("/refresh", container.SubscriptionHandler.RefreshSubscriptions)
+				subscriptions.PATCH(":id", container.SubscriptionHandler.UpdateSubscriptionStatus)
+			}
+
+			// Report routes
+			reports := protected.Group("/reports")
+			{
+				reports.GET("/tax/:year", container.ReportHandler.GetTaxReport)
+			}
+
+			// Backup routes
+			backups := protected.Group("/backup")
+			{
+				backups.GET("", container.BackupHandler.DownloadBackup)
+				backups.POST("/restore", container.BackupHandler.RestoreBackup)
+			}
+
 			// Global sharing routes
 			shares := protected.Group("/shares")
 			{
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/di"
+)
+
+func setupRoutesTest(t *testing.T, trustedProxies string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("TRUSTED_PROXIES", trustedProxies)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	container, err := di.NewContainer(db)
+	if err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+	return SetupRoutes(container)
+}
+
+// linkRequests sends link requests from the address, each with a different X-Forwarded-For,
+// and returns the status of the last one
+func linkRequests(r *gin.Engine, remoteAddr string, count int) int {
+	status := 0
+	for i := 0; i < count; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/link", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		status = w.Code
+	}
+	return status
+}
+
+func TestSetupRoutes_ForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	// The auth routes let each IP address make 20 requests at once
+	r := setupRoutesTest(t, "")
+	if status := linkRequests(r, "198.51.100.7:4000", 20); status != http.StatusBadRequest {
+		t.Fatalf("Expected the first requests to reach the handler, got %d", status)
+	}
+	if status := linkRequests(r, "198.51.100.7:4000", 1); status != http.StatusTooManyRequests {
+		t.Errorf("Expected a spoofed X-Forwarded-For not to reset the limit, got %d", status)
+	}
+
+	// Behind a trusted proxy, each client has its own limit
+	r = setupRoutesTest(t, "10.0.0.0/8, 192.168.1.1")
+	if status := linkRequests(r, "10.0.3.4:4000", 21); status != http.StatusBadRequest {
+		t.Errorf("Expected the clients behind the proxy to be limited apart, got %d", status)
+	}
+	if status := linkRequests(r, "198.51.100.7:4000", 21); status != http.StatusTooManyRequests {
+		t.Errorf("Expected X-Forwarded-For from other addresses to be ignored, got %d", status)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	appErrors "github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/repository"
 )
@@ -37,6 +38,10 @@ func (s *accountService) CreateAccount(account *models.Account) error {
 	log.Printf("[AccountService] CreateAccount: Starting account creation for user %d", account.UserID)
 	log.Printf("[AccountService] CreateAccount: Account data: %+v", account)
 
+	if err := s.validateBillingCycle(account, account.UserID); err != nil {
+		return err
+	}
+
 	tx := s.repo.Begin()
 	if tx.Error != nil {
 		log.Printf("[AccountService] CreateAccount: Failed to begin transaction: %v", tx.Error)
@@ -149,6 +154,24 @@ func (s *accountService) UpdateAccount(id uint, userID uint, req *dto.UpdateAcco
 	if existing.Color == "" {
 		existing.Color = "#cccccc"
 	}
+	// Clients that don't know about billing cycles leave them out, which must not wipe them
+	if req.ClearBillingCycle {
+		existing.StatementClosingDay = nil
+		existing.PaymentDueDay = nil
+		existing.PaymentAccountID = nil
+	}
+	if req.StatementClosingDay != nil {
+		existing.StatementClosingDay = req.StatementClosingDay
+	}
+	if req.PaymentDueDay != nil {
+		existing.PaymentDueDay = req.PaymentDueDay
+	}
+	if req.PaymentAccountID != nil {
+		existing.PaymentAccountID = req.PaymentAccountID
+	}
+	if err := s.validateBillingCycle(existing, userID); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Update(existing); err != nil {
 		return nil, err
@@ -156,6 +179,32 @@ func (s *accountService) UpdateAccount(id uint, userID uint, req *dto.UpdateAcco
 	return existing, nil
 }
 
+// validateBillingCycle clears the billing cycle of accounts that aren't credit cards and makes
+// sure the payment account is another non credit card account the user has access to
+func (s *accountService) validateBillingCycle(account *models.Account, userID uint) error {
+	if account.Type != models.AccountTypeCredit {
+		account.StatementClosingDay = nil
+		account.PaymentDueDay = nil
+		account.PaymentAccountID = nil
+		return nil
+	}
+	if account.PaymentAccountID == nil {
+		return nil
+	}
+
+	if account.ID != 0 && *account.PaymentAccountID == account.ID {
+		return appErrors.NewValidationError("a credit card can't pay its own bill")
+	}
+	paymentAccount, err := s.repo.FindByID(*account.PaymentAccountID, userID)
+	if err != nil {
+		return appErrors.NewValidationError("payment account not found")
+	}
+	if paymentAccount.Type == models.AccountTypeCredit {
+		return appErrors.NewValidationError("payment account can't be a credit card")
+	}
+	return nil
+}
+
 func (s *accountService) DeleteAccount(id uint, userID uint) error {
 	// Only account owners can delete accounts
 	isOwner, err := s.repo.IsOwner(id, userID)
@@ -185,17 +234,18 @@ func (s *accountService) DeleteAccount(id uint, userID uint) error {
 		return err
 	}
 
-	// Soft delete all transactions associated with this account
-	transactionRepoTx := s.transactionRepo.WithTx(tx)
-	err = transactionRepoTx.SoftDeleteByAccountID(account.ID)
+	// Soft delete the account
+	accountRepoTx := s.repo.WithTx(tx)
+	err = accountRepoTx.SoftDelete(id, userID)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Soft delete the account
-	accountRepoTx := s.repo.WithTx(tx)
-	err = accountRepoTx.SoftDelete(id, userID)
+	// Soft delete all transactions associated with this account, marked as deleted with it
+	// for the account's balance history
+	transactionRepoTx := s.transactionRepo.WithTx(tx)
+	err = transactionRepoTx.SoftDeleteByAccountID(account.ID)
 	if err != nil {
 		tx.Rollback()
 		return err
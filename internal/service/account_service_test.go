@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupAccountServiceTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	if err := db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Transaction{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestAccountService_UpdateAccountBillingCycle(t *testing.T) {
+	db, user := setupAccountServiceTestDB(t)
+	service := NewAccountService(repository.NewAccountRepository(db), repository.NewTransactionRepository(db))
+
+	checking := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(checking).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	closingDay, dueDay := 5, 12
+	card := &models.Account{
+		Name:                "Nubank",
+		Type:                models.AccountTypeCredit,
+		UserID:              user.ID,
+		StatementClosingDay: &closingDay,
+		PaymentDueDay:       &dueDay,
+		PaymentAccountID:    &checking.ID,
+	}
+	if err := db.Create(card).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	// Updates without the billing cycle keep it
+	updated, err := service.UpdateAccount(card.ID, user.ID, &dto.UpdateAccountRequest{Name: "Roxinho", Type: models.AccountTypeCredit, Color: "#820ad1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated.Name != "Roxinho" || updated.StatementClosingDay == nil || *updated.StatementClosingDay != 5 ||
+		updated.PaymentDueDay == nil || *updated.PaymentDueDay != 12 || updated.PaymentAccountID == nil {
+		t.Errorf("Expected the billing cycle to be kept, got %+v", updated)
+	}
+
+	// Fields sent replace only themselves
+	newDueDay := 15
+	updated, err = service.UpdateAccount(card.ID, user.ID, &dto.UpdateAccountRequest{Name: "Roxinho", Type: models.AccountTypeCredit, BillingCycle: dto.BillingCycle{PaymentDueDay: &newDueDay}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *updated.StatementClosingDay != 5 || *updated.PaymentDueDay != 15 || *updated.PaymentAccountID != checking.ID {
+		t.Errorf("Expected only the due day to change, got %+v", updated)
+	}
+
+	// The billing cycle is only removed when asked to
+	updated, err = service.UpdateAccount(card.ID, user.ID, &dto.UpdateAccountRequest{Name: "Roxinho", Type: models.AccountTypeCredit, ClearBillingCycle: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var stored models.Account
+	if err := db.First(&stored, card.ID).Error; err != nil {
+		t.Fatalf("Failed to find account: %v", err)
+	}
+	if updated.StatementClosingDay != nil || stored.StatementClosingDay != nil || stored.PaymentDueDay != nil || stored.PaymentAccountID != nil {
+		t.Errorf("Expected the billing cycle to be cleared, got %+v", stored)
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+const (
+	// alertHistoryMonths is how far back the history is searched for the usual spending
+	alertHistoryMonths = 6
+	// alertRecentDays is how old a transaction can be and still raise an alert
+	alertRecentDays = 30
+
+	// categorySpikeMonths is how many full months make up a category's trailing average
+	categorySpikeMonths = 3
+	// categorySpikeFactor is how many times its trailing average a category must spend this month
+	categorySpikeFactor = 2.0
+	// categorySpikeMinIncrease keeps small categories from raising alerts over small amounts
+	categorySpikeMinIncrease = 50.0
+
+	// priceIncreaseMinMonths is how many months a charge must repeat at the old price
+	priceIncreaseMinMonths = 2
+
+	// largeExpenseMinSamples is how many earlier expenses are needed to know what's usual
+	largeExpenseMinSamples = 10
+	// largeExpenseDeviations is how many standard deviations above the mean a large expense is
+	largeExpenseDeviations = 3.0
+)
+
+type AlertService interface {
+	// Analyze looks for anomalies in the user's recent transactions, using the months of loc,
+	// and stores and returns the ones that weren't reported before
+	Analyze(ctx context.Context, userID uint, loc *time.Location) ([]models.Alert, error)
+	ListAlerts(ctx context.Context, userID uint, includeDismissed bool) ([]models.Alert, error)
+	MarkRead(ctx context.Context, id uint, userID uint) (*models.Alert, error)
+	Dismiss(ctx context.Context, id uint, userID uint) (*models.Alert, error)
+	MarkAllRead(ctx context.Context, userID uint) error
+}
+
+type alertService struct {
+	alertRepo        repository.AlertRepository
+	transactionRepo  repository.TransactionRepository
+	statisticsRepo   repository.StatisticsRepository
+	subscriptionRepo repository.SubscriptionRepository
+	now              func() time.Time
+}
+
+func NewAlertService(alertRepo repository.AlertRepository, transactionRepo repository.TransactionRepository, statisticsRepo repository.StatisticsRepository, subscriptionRepo repository.SubscriptionRepository) AlertService {
+	return &alertService{alertRepo: alertRepo, transactionRepo: transactionRepo, statisticsRepo: statisticsRepo, subscriptionRepo: subscriptionRepo, now: time.Now}
+}
+
+func (s *alertService) Analyze(ctx context.Context, userID uint, loc *time.Location) ([]models.Alert, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	now := s.now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	recentStart := today.AddDate(0, 0, -alertRecentDays)
+
+	alerts, err := s.categorySpikes(ctx, userID, today, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	historyStart := today.AddDate(0, -alertHistoryMonths, 0)
+	found, _, err := s.transactionRepo.FindByUserID(userID, []models.TransactionType{models.TransactionTypeExpense}, nil, nil, nil, nil, "", nil, nil, &historyStart, &today, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Transfers between accounts aren't spending
+	expenses := make([]models.Transaction, 0, len(found))
+	for _, tx := range found {
+		if tx.AttachedTransactionID != nil {
+			continue
+		}
+		tx.Date = tx.Date.In(loc)
+		expenses = append(expenses, tx)
+	}
+	alerts = append(alerts, priceIncreases(userID, expenses, recentStart)...)
+	alerts = append(alerts, largeExpenses(userID, expenses, recentStart)...)
+
+	subscriptions, err := s.subscriptionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	alerts = append(alerts, cancelledSubscriptionCharges(userID, expenses, subscriptions)...)
+
+	if len(alerts) == 0 {
+		return []models.Alert{}, nil
+	}
+	return s.alertRepo.CreateIfNew(ctx, alerts)
+}
+
+// categorySpikes compares this month's spending on every category with its average over the
+// previous full months
+func (s *alertService) categorySpikes(ctx context.Context, userID uint, today time.Time, loc *time.Location) ([]models.Alert, error) {
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc)
+	trailingStart := monthStart.AddDate(0, -categorySpikeMonths, 0)
+	trailingEnd := monthStart.AddDate(0, 0, -1)
+
+	filter := repository.StatisticsFilter{
+		UserID:           userID,
+		StartDate:        &monthStart,
+		EndDate:          &today,
+		Types:            []models.TransactionType{models.TransactionTypeExpense},
+		ExcludeTransfers: true,
+		Location:         loc,
+	}
+	current, err := s.statisticsRepo.AmountByCategory(ctx, filter, false)
+	if err != nil {
+		return nil, err
+	}
+	filter.StartDate, filter.EndDate = &trailingStart, &trailingEnd
+	trailing, err := s.statisticsRepo.AmountByCategory(ctx, filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	averages := make(map[string]float64, len(trailing))
+	for _, row := range trailing {
+		averages[row.Label] += row.Amount / categorySpikeMonths
+	}
+
+	month := monthStart.Format("2006-01")
+	alerts := make([]models.Alert, 0)
+	for _, row := range current {
+		average := averages[row.Label]
+		if toCents(average) <= 0 || row.Amount < average*categorySpikeFactor || row.Amount-average < categorySpikeMinIncrease {
+			continue
+		}
+		alerts = append(alerts, models.Alert{
+			UserID:   userID,
+			Key:      "category_spike:" + month + ":" + row.Label,
+			Type:     models.AlertTypeCategorySpike,
+			Subject:  row.Label,
+			Message:  fmt.Sprintf("Spending on %s is %.2f this month, %.1fx its %d-month average of %.2f", row.Label, row.Amount, row.Amount/average, categorySpikeMonths, average),
+			Amount:   roundCents(row.Amount),
+			Baseline: roundCents(average),
+		})
+	}
+	return alerts, nil
+}
+
+// priceIncreases finds recent charges that repeat a monthly charge of the previous months at
+// a higher amount
+func priceIncreases(userID uint, expenses []models.Transaction, since time.Time) []models.Alert {
+	type chargeKey struct {
+		accountID   uint
+		description string
+	}
+	groups := make(map[chargeKey][]models.Transaction)
+	for _, tx := range expenses {
+		key := chargeKey{accountID: tx.AccountID, description: normalizeRecurringDescription(tx.Description)}
+		if key.description == "" {
+			continue
+		}
+		groups[key] = append(groups[key], tx)
+	}
+
+	alerts := make([]models.Alert, 0)
+	for _, group := range groups {
+		if len(group) <= priceIncreaseMinMonths {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+		last, previous := group[len(group)-1], group[len(group)-2]
+		if last.Date.Before(since) || toCents(last.Amount) <= toCents(previous.Amount) || monthIndex(last.Date)-monthIndex(previous.Date) != 1 {
+			continue
+		}
+
+		// The old price must have been charged in consecutive months before the increase
+		months := 1
+		month := monthIndex(previous.Date)
+		for i := len(group) - 3; i >= 0 && toCents(group[i].Amount) == toCents(previous.Amount); i-- {
+			if gap := month - monthIndex(group[i].Date); gap == 1 {
+				months++
+				month--
+			} else if gap != 0 {
+				break
+			}
+		}
+		if months < priceIncreaseMinMonths {
+			continue
+		}
+
+		id := last.ID
+		alerts = append(alerts, models.Alert{
+			UserID:        userID,
+			Key:           "subscription_price_increase:" + strconv.FormatUint(uint64(last.ID), 10),
+			Type:          models.AlertTypeSubscriptionPriceIncrease,
+			Subject:       last.Description,
+			Message:       fmt.Sprintf("%s charged %.2f, up from %.2f", last.Description, last.Amount, previous.Amount),
+			TransactionID: &id,
+			Amount:        roundCents(last.Amount),
+			Baseline:      roundCents(previous.Amount),
+		})
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Key < alerts[j].Key })
+	return alerts
+}
+
+// largeExpenses finds recent expenses more than a few standard deviations above the mean of
+// the older expenses
+func largeExpenses(userID uint, expenses []models.Transaction, since time.Time) []models.Alert {
+	var sum, squares float64
+	samples := 0
+	for _, tx := range expenses {
+		if tx.Date.Before(since) {
+			sum += tx.Amount
+			squares += tx.Amount * tx.Amount
+			samples++
+		}
+	}
+	if samples < largeExpenseMinSamples {
+		return []models.Alert{}
+	}
+	mean := sum / float64(samples)
+	deviation := math.Sqrt(math.Max(squares/float64(samples)-mean*mean, 0))
+	threshold := mean + largeExpenseDeviations*deviation
+
+	alerts := make([]models.Alert, 0)
+	for _, tx := range expenses {
+		if tx.Date.Before(since) || tx.Amount <= threshold {
+			continue
+		}
+		id := tx.ID
+		alerts = append(alerts, models.Alert{
+			UserID:        userID,
+			Key:           "large_expense:" + strconv.FormatUint(uint64(tx.ID), 10),
+			Type:          models.AlertTypeLargeExpense,
+			Subject:       tx.Description,
+			Message:       fmt.Sprintf("%s of %.2f is well above your usual expense of %.2f", tx.Description, tx.Amount, mean),
+			TransactionID: &id,
+			Amount:        roundCents(tx.Amount),
+			Baseline:      roundCents(mean),
+		})
+	}
+	return alerts
+}
+
+// cancelledSubscriptionCharges finds expenses charged by a subscription after the user
+// cancelled it
+func cancelledSubscriptionCharges(userID uint, expenses []models.Transaction, subscriptions []models.Subscription) []models.Alert {
+	cancelled := make(map[string]models.Subscription)
+	for _, subscription := range subscriptions {
+		if subscription.Status == models.SubscriptionStatusCancelled && subscription.CancelledAt != nil {
+			cancelled[subscription.Key] = subscription
+		}
+	}
+	if len(cancelled) == 0 {
+		return []models.Alert{}
+	}
+
+	alerts := make([]models.Alert, 0)
+	for _, tx := range expenses {
+		subscription, ok := cancelled[normalizeSubscriptionDescription(tx.Description)]
+		if !ok || !tx.Date.After(*subscription.CancelledAt) {
+			continue
+		}
+		id := tx.ID
+		alerts = append(alerts, models.Alert{
+			UserID:        userID,
+			Key:           "subscription_charged_after_cancel:" + strconv.FormatUint(uint64(tx.ID), 10),
+			Type:          models.AlertTypeCancelledSubscriptionCharge,
+			Subject:       subscription.Name,
+			Message:       fmt.Sprintf("%s charged %.2f after you cancelled it on %s", subscription.Name, tx.Amount, subscription.CancelledAt.In(tx.Date.Location()).Format("2006-01-02")),
+			TransactionID: &id,
+			Amount:        roundCents(tx.Amount),
+			Baseline:      roundCents(subscription.Amount),
+		})
+	}
+	return alerts
+}
+
+func (s *alertService) ListAlerts(ctx context.Context, userID uint, includeDismissed bool) ([]models.Alert, error) {
+	return s.alertRepo.FindByUserID(ctx, userID, includeDismissed)
+}
+
+func (s *alertService) MarkRead(ctx context.Context, id uint, userID uint) (*models.Alert, error) {
+	alert, err := s.findAlert(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if alert.ReadAt == nil {
+		now := s.now()
+		alert.ReadAt = &now
+		if err := s.alertRepo.Update(ctx, alert); err != nil {
+			return nil, err
+		}
+	}
+	return alert, nil
+}
+
+// Dismiss hides the alert from the list. Dismissed alerts are also read.
+func (s *alertService) Dismiss(ctx context.Context, id uint, userID uint) (*models.Alert, error) {
+	alert, err := s.findAlert(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if alert.ReadAt == nil {
+		alert.ReadAt = &now
+	}
+	if alert.DismissedAt == nil {
+		alert.DismissedAt = &now
+	}
+	if err := s.alertRepo.Update(ctx, alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+func (s *alertService) MarkAllRead(ctx context.Context, userID uint) error {
+	return s.alertRepo.MarkAllRead(ctx, userID)
+}
+
+func (s *alertService) findAlert(ctx context.Context, id uint, userID uint) (*models.Alert, error) {
+	alert, err := s.alertRepo.FindByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NewNotFoundError("alert not found")
+		}
+		return nil, err
+	}
+	return alert, nil
+}
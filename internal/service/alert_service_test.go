@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupAlertServiceTestDB(t *testing.T) (*gorm.DB, *models.User, *models.Account) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// Create a test account
+	account := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create test account: %v", err)
+	}
+
+	return db, user, account
+}
+
+// alertExpenses returns expenses numbered from 1, in the order of the dates and amounts
+func alertExpenses(accountID uint, description string, dates []string, amounts []float64) []models.Transaction {
+	expenses := make([]models.Transaction, len(dates))
+	for i, date := range dates {
+		expenses[i] = models.Transaction{AccountID: accountID, Description: description, Amount: amounts[i], Type: models.TransactionTypeExpense, Date: forecastDate(date)}
+		expenses[i].ID = uint(i + 1)
+	}
+	return expenses
+}
+
+func TestAlertService_CategorySpikes(t *testing.T) {
+	db, user, account := setupAlertServiceTestDB(t)
+	transactionRepo := repository.NewTransactionRepository(db)
+	service := &alertService{statisticsRepo: repository.NewStatisticsRepository(db)}
+
+	spend := func(category string, amounts map[string]float64) {
+		record := &models.Category{Name: category, Type: models.TransactionTypeExpense, UserID: user.ID}
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+		for date, amount := range amounts {
+			tx := &models.Transaction{AccountID: account.ID, Description: category, Amount: amount, Type: models.TransactionTypeExpense, Date: forecastDate(date)}
+			if err := transactionRepo.Create(tx); err != nil {
+				t.Fatalf("Failed to create transaction: %v", err)
+			}
+			if err := transactionRepo.AssociateCategories(tx.ID, []uint{record.ID}); err != nil {
+				t.Fatalf("Failed to associate categories: %v", err)
+			}
+		}
+	}
+
+	// Twice the 100 spent on average from July to September, and 200 more
+	spend("Mercado", map[string]float64{"2026-07-10": 100, "2026-08-10": 80, "2026-09-10": 120, "2026-10-05": 150, "2026-10-15": 150})
+	// Only 1.5 times its average
+	spend("Casa", map[string]float64{"2026-07-03": 100, "2026-08-03": 100, "2026-09-03": 100, "2026-10-03": 150})
+	// Three times its average, but only 40 more
+	spend("Café", map[string]float64{"2026-07-20": 20, "2026-08-20": 20, "2026-09-20": 20, "2026-10-12": 60})
+	// Nothing to compare with
+	spend("Viagem", map[string]float64{"2026-10-01": 2000})
+	// Months before the trailing average and days after today don't count
+	spend("Farmácia", map[string]float64{"2026-06-10": 900, "2026-07-10": 30, "2026-08-10": 30, "2026-09-10": 30, "2026-10-25": 500})
+
+	alerts, err := service.categorySpikes(context.Background(), user.ID, forecastDate("2026-10-18"), time.UTC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %+v", alerts)
+	}
+	alert := alerts[0]
+	if alert.Type != models.AlertTypeCategorySpike || alert.Key != "category_spike:2026-10:Mercado" || alert.Subject != "Mercado" {
+		t.Errorf("Unexpected alert: %+v", alert)
+	}
+	if alert.Amount != 300 || alert.Baseline != 100 || alert.UserID != user.ID {
+		t.Errorf("Expected 300 against an average of 100, got %+v", alert)
+	}
+}
+
+func TestPriceIncreases(t *testing.T) {
+	since := forecastDate("2026-09-18")
+
+	tests := []struct {
+		name     string
+		expenses []models.Transaction
+		expected bool
+	}{
+		{
+			name:     "charged more after two months at the old price",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-08-10", "2026-09-10", "2026-10-10"}, []float64{39.9, 39.9, 44.9}),
+			expected: true,
+		},
+		{
+			name:     "only one month at the old price",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-08-10", "2026-09-10", "2026-10-10"}, []float64{29.9, 39.9, 44.9}),
+		},
+		{
+			name:     "charged less",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-08-10", "2026-09-10", "2026-10-10"}, []float64{44.9, 44.9, 39.9}),
+		},
+		{
+			name:     "same price",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-08-10", "2026-09-10", "2026-10-10"}, []float64{39.9, 39.9, 39.9}),
+		},
+		{
+			name:     "increase isn't recent",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-06-10", "2026-07-10", "2026-08-10"}, []float64{39.9, 39.9, 44.9}),
+		},
+		{
+			name:     "a month skipped before the increase",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-07-10", "2026-08-10", "2026-10-10"}, []float64{39.9, 39.9, 44.9}),
+		},
+		{
+			name:     "a month skipped at the old price",
+			expenses: alertExpenses(1, "Netflix", []string{"2026-07-10", "2026-09-10", "2026-10-10"}, []float64{39.9, 39.9, 44.9}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := priceIncreases(1, tt.expenses, since)
+			if !tt.expected {
+				if len(alerts) != 0 {
+					t.Errorf("Expected no alerts, got %+v", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("Expected 1 alert, got %+v", alerts)
+			}
+			last, previous := tt.expenses[len(tt.expenses)-1], tt.expenses[len(tt.expenses)-2]
+			alert := alerts[0]
+			if alert.Type != models.AlertTypeSubscriptionPriceIncrease || alert.TransactionID == nil || *alert.TransactionID != last.ID {
+				t.Errorf("Expected an alert for transaction %d, got %+v", last.ID, alert)
+			}
+			if alert.Amount != last.Amount || alert.Baseline != previous.Amount {
+				t.Errorf("Expected %.2f up from %.2f, got %+v", last.Amount, previous.Amount, alert)
+			}
+		})
+	}
+
+	// Charges of different accounts aren't the same charge
+	expenses := alertExpenses(1, "Netflix", []string{"2026-08-10", "2026-09-10", "2026-10-10"}, []float64{39.9, 39.9, 44.9})
+	expenses[2].AccountID = 2
+	if alerts := priceIncreases(1, expenses, since); len(alerts) != 0 {
+		t.Errorf("Expected no alerts across accounts, got %+v", alerts)
+	}
+}
+
+func TestLargeExpenses(t *testing.T) {
+	since := forecastDate("2026-09-18")
+	// Older expenses with a mean of 100 and a standard deviation of about 14.14, so anything
+	// above about 142.43 is large
+	older := []float64{80, 90, 100, 110, 120, 80, 90, 100, 110, 120}
+	history := func(count int, recent ...float64) []models.Transaction {
+		dates := make([]string, 0, count+len(recent))
+		amounts := make([]float64, 0, count+len(recent))
+		for i := 0; i < count; i++ {
+			dates = append(dates, "2026-08-01")
+			amounts = append(amounts, older[i])
+		}
+		for _, amount := range recent {
+			dates = append(dates, "2026-10-01")
+			amounts = append(amounts, amount)
+		}
+		return alertExpenses(1, "Compra", dates, amounts)
+	}
+
+	tests := []struct {
+		name     string
+		expenses []models.Transaction
+		expected []float64
+	}{
+		{"above the threshold", history(10, 150, 140), []float64{150}},
+		{"at most the threshold", history(10, 142, 100), nil},
+		{"too few earlier expenses", history(9, 1000), nil},
+		{"large expenses before since are only samples", append(history(10), alertExpenses(1, "TV", []string{"2026-09-01"}, []float64{1000})...), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := largeExpenses(1, tt.expenses, since)
+			if len(alerts) != len(tt.expected) {
+				t.Fatalf("Expected %d alerts, got %+v", len(tt.expected), alerts)
+			}
+			for i, alert := range alerts {
+				if alert.Type != models.AlertTypeLargeExpense || alert.Amount != tt.expected[i] || alert.Baseline != 100 || alert.TransactionID == nil {
+					t.Errorf("Expected a large expense of %.2f against 100, got %+v", tt.expected[i], alert)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/backup"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/merchants"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+type BackupService interface {
+	// Backup builds an archive of everything the user owns
+	Backup(ctx context.Context, userID uint) (*backup.Archive, error)
+	// Restore adds the archive's records to the user's data. With dryRun the archive is
+	// checked and restored in a transaction that is rolled back, so nothing is saved.
+	Restore(ctx context.Context, userID uint, archive *backup.Archive, dryRun bool) (*repository.RestoreResult, error)
+}
+
+type backupService struct {
+	backupRepo repository.BackupRepository
+	now        func() time.Time
+}
+
+func NewBackupService(backupRepo repository.BackupRepository) BackupService {
+	return &backupService{backupRepo: backupRepo, now: time.Now}
+}
+
+func (s *backupService) Backup(ctx context.Context, userID uint) (*backup.Archive, error) {
+	data, err := s.backupRepo.Load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return archiveFromData(data, s.now()), nil
+}
+
+func (s *backupService) Restore(ctx context.Context, userID uint, archive *backup.Archive, dryRun bool) (*repository.RestoreResult, error) {
+	if problems := archive.Validate(); len(problems) > 0 {
+		return nil, errors.NewValidationError("invalid archive: " + strings.Join(problems, "; "))
+	}
+	return s.backupRepo.Restore(ctx, userID, dataFromArchive(archive), dryRun)
+}
+
+func archiveFromData(data *repository.UserData, now time.Time) *backup.Archive {
+	archive := &backup.Archive{
+		Version:       backup.Version,
+		ExportedAt:    now.UTC(),
+		Accounts:      make([]backup.Account, len(data.Accounts)),
+		Categories:    make([]backup.Category, len(data.Categories)),
+		Tags:          make([]backup.Tag, len(data.Tags)),
+		Merchants:     make([]backup.Merchant, len(data.Merchants)),
+		Transactions:  make([]backup.Transaction, len(data.Transactions)),
+		Rules:         make([]backup.Rule, len(data.Rules)),
+		Subscriptions: make([]backup.Subscription, len(data.Subscriptions)),
+		Shares:        make([]backup.Share, len(data.Shares)),
+	}
+
+	for i, account := range data.Accounts {
+		archive.Accounts[i] = backup.Account{
+			ID:                  account.ID,
+			Name:                account.Name,
+			Type:                account.Type,
+			InitialBalance:      account.InitialBalance,
+			Balance:             account.Balance,
+			Color:               account.Color,
+			StatementClosingDay: account.StatementClosingDay,
+			PaymentDueDay:       account.PaymentDueDay,
+			PaymentAccountID:    account.PaymentAccountID,
+			CreatedAt:           account.CreatedAt,
+		}
+	}
+	for i, category := range data.Categories {
+		archive.Categories[i] = backup.Category{
+			ID:          category.ID,
+			Name:        category.Name,
+			Description: category.Description,
+			Deductible:  category.Deductible,
+			Type:        category.Type,
+			ParentID:    category.ParentID,
+		}
+	}
+	for i, tag := range data.Tags {
+		archive.Tags[i] = backup.Tag{ID: tag.ID, Name: tag.Name, Color: tag.Color}
+	}
+	for i, merchant := range data.Merchants {
+		archive.Merchants[i] = backup.Merchant{ID: merchant.ID, Name: merchant.Name}
+		for _, alias := range merchant.Aliases {
+			archive.Merchants[i].Aliases = append(archive.Merchants[i].Aliases, alias.Pattern)
+		}
+	}
+	for i, transaction := range data.Transactions {
+		archived := backup.Transaction{
+			ID:                    transaction.ID,
+			Date:                  transaction.Date,
+			Amount:                transaction.Amount,
+			Type:                  transaction.Type,
+			Description:           transaction.Description,
+			AccountID:             transaction.AccountID,
+			MerchantID:            transaction.MerchantID,
+			AttachedTransactionID: transaction.AttachedTransactionID,
+			AttachmentType:        transaction.AttachmentType,
+			CreatedAt:             transaction.CreatedAt,
+		}
+		for _, category := range transaction.Categories {
+			archived.CategoryIDs = append(archived.CategoryIDs, category.ID)
+		}
+		for _, tag := range transaction.Tags {
+			archived.TagIDs = append(archived.TagIDs, tag.ID)
+		}
+		for _, split := range transaction.Splits {
+			archived.Splits = append(archived.Splits, backup.Split{CategoryID: split.CategoryID, Amount: split.Amount, Memo: split.Memo})
+		}
+		archive.Transactions[i] = archived
+	}
+	for i, rule := range data.Rules {
+		archive.Rules[i] = backup.Rule{
+			Name:            rule.Name,
+			Type:            rule.Type,
+			Value:           rule.Value,
+			TransactionType: rule.TransactionType,
+			CategoryID:      rule.CategoryDst,
+			Active:          rule.Active,
+		}
+		for _, tag := range rule.Tags {
+			archive.Rules[i].TagIDs = append(archive.Rules[i].TagIDs, tag.ID)
+		}
+	}
+	for i, subscription := range data.Subscriptions {
+		archive.Subscriptions[i] = backup.Subscription{
+			Key:             subscription.Key,
+			Name:            subscription.Name,
+			AccountID:       subscription.AccountID,
+			Cadence:         subscription.Cadence,
+			Amount:          subscription.Amount,
+			Charges:         subscription.Charges,
+			FirstChargeDate: subscription.FirstChargeDate,
+			LastChargeDate:  subscription.LastChargeDate,
+			Status:          subscription.Status,
+			CancelledAt:     subscription.CancelledAt,
+		}
+	}
+	for i, share := range data.Shares {
+		archive.Shares[i] = backup.Share{
+			AccountID:       share.AccountID,
+			Email:           share.SharedUser.Email,
+			PermissionLevel: share.PermissionLevel,
+			SharedAt:        share.SharedAt,
+		}
+	}
+	return archive
+}
+
+// dataFromArchive builds the records to restore. IDs are still the archive's, the repository
+// replaces them as the records are created.
+func dataFromArchive(archive *backup.Archive) *repository.UserData {
+	data := &repository.UserData{
+		Accounts:      make([]models.Account, len(archive.Accounts)),
+		Categories:    make([]models.Category, len(archive.Categories)),
+		Tags:          make([]models.Tag, len(archive.Tags)),
+		Merchants:     make([]models.Merchant, len(archive.Merchants)),
+		Transactions:  make([]models.Transaction, len(archive.Transactions)),
+		Rules:         make([]models.CategorizationRule, len(archive.Rules)),
+		Subscriptions: make([]models.Subscription, len(archive.Subscriptions)),
+		Shares:        make([]models.AccountShare, len(archive.Shares)),
+	}
+
+	for i, archived := range archive.Accounts {
+		account := models.Account{
+			Name:                archived.Name,
+			Type:                archived.Type,
+			InitialBalance:      archived.InitialBalance,
+			Balance:             archived.Balance,
+			Color:               archived.Color,
+			StatementClosingDay: archived.StatementClosingDay,
+			PaymentDueDay:       archived.PaymentDueDay,
+			PaymentAccountID:    archived.PaymentAccountID,
+		}
+		account.ID = archived.ID
+		account.CreatedAt = archived.CreatedAt
+		data.Accounts[i] = account
+	}
+	for i, archived := range archive.Categories {
+		category := models.Category{
+			Name:        archived.Name,
+			Description: archived.Description,
+			Deductible:  archived.Deductible,
+			Type:        archived.Type,
+			ParentID:    archived.ParentID,
+		}
+		category.ID = archived.ID
+		data.Categories[i] = category
+	}
+	for i, archived := range archive.Tags {
+		data.Tags[i] = models.Tag{ID: archived.ID, Name: archived.Name, Color: archived.Color}
+	}
+	for i, archived := range archive.Merchants {
+		merchant := models.Merchant{ID: archived.ID, Key: merchants.Key(archived.Name), Name: archived.Name}
+		for _, pattern := range archived.Aliases {
+			merchant.Aliases = append(merchant.Aliases, models.MerchantAlias{Pattern: pattern})
+		}
+		data.Merchants[i] = merchant
+	}
+	for i, archived := range archive.Transactions {
+		transaction := models.Transaction{
+			Date:                  archived.Date,
+			Amount:                archived.Amount,
+			Type:                  archived.Type,
+			Description:           archived.Description,
+			AccountID:             archived.AccountID,
+			MerchantID:            archived.MerchantID,
+			AttachedTransactionID: archived.AttachedTransactionID,
+			AttachmentType:        archived.AttachmentType,
+		}
+		transaction.ID = archived.ID
+		transaction.CreatedAt = archived.CreatedAt
+		for _, id := range archived.CategoryIDs {
+			category := &models.Category{}
+			category.ID = id
+			transaction.Categories = append(transaction.Categories, category)
+		}
+		for _, id := range archived.TagIDs {
+			transaction.Tags = append(transaction.Tags, &models.Tag{ID: id})
+		}
+		for _, split := range archived.Splits {
+			transaction.Splits = append(transaction.Splits, models.TransactionSplit{CategoryID: split.CategoryID, Amount: split.Amount, Memo: split.Memo})
+		}
+		data.Transactions[i] = transaction
+	}
+	for i, archived := range archive.Rules {
+		rule := models.CategorizationRule{
+			Name:            archived.Name,
+			Type:            archived.Type,
+			Value:           archived.Value,
+			TransactionType: archived.TransactionType,
+			CategoryDst:     archived.CategoryID,
+			Active:          archived.Active,
+		}
+		for _, id := range archived.TagIDs {
+			rule.Tags = append(rule.Tags, models.Tag{ID: id})
+		}
+		data.Rules[i] = rule
+	}
+	for i, archived := range archive.Subscriptions {
+		data.Subscriptions[i] = models.Subscription{
+			Key:             archived.Key,
+			Name:            archived.Name,
+			AccountID:       archived.AccountID,
+			Cadence:         archived.Cadence,
+			Amount:          archived.Amount,
+			Charges:         archived.Charges,
+			FirstChargeDate: archived.FirstChargeDate,
+			LastChargeDate:  archived.LastChargeDate,
+			Status:          archived.Status,
+			CancelledAt:     archived.CancelledAt,
+		}
+	}
+	for i, archived := range archive.Shares {
+		data.Shares[i] = models.AccountShare{
+			AccountID:       archived.AccountID,
+			SharedUser:      models.User{Email: archived.Email},
+			PermissionLevel: archived.PermissionLevel,
+			SharedAt:        archived.SharedAt,
+		}
+	}
+	return data
+}
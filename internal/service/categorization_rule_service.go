@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/repository"
 )
@@ -16,11 +17,12 @@ type CategorizationRuleService interface {
 }
 
 type categorizationRuleService struct {
-	repo repository.CategorizationRuleRepository
+	repo       repository.CategorizationRuleRepository
+	tagService TagService
 }
 
-func NewCategorizationRuleService(repo repository.CategorizationRuleRepository) CategorizationRuleService {
-	return &categorizationRuleService{repo: repo}
+func NewCategorizationRuleService(repo repository.CategorizationRuleRepository, tagService TagService) CategorizationRuleService {
+	return &categorizationRuleService{repo: repo, tagService: tagService}
 }
 
 func (s *categorizationRuleService) ListRules(ctx context.Context, userID uint) ([]models.CategorizationRule, error) {
@@ -32,13 +34,40 @@ func (s *categorizationRuleService) GetRuleByID(ctx context.Context, id uint, us
 }
 
 func (s *categorizationRuleService) CreateRule(ctx context.Context, rule *models.CategorizationRule) error {
+	if err := s.resolveTags(ctx, rule); err != nil {
+		return err
+	}
 	return s.repo.Create(ctx, rule)
 }
 
 func (s *categorizationRuleService) UpdateRule(ctx context.Context, rule *models.CategorizationRule) error {
+	if err := s.resolveTags(ctx, rule); err != nil {
+		return err
+	}
 	return s.repo.Update(ctx, rule)
 }
 
+// resolveTags loads the rule's tags from the user's tags and makes sure the rule does something
+func (s *categorizationRuleService) resolveTags(ctx context.Context, rule *models.CategorizationRule) error {
+	if rule.CategoryDst == 0 && len(rule.Tags) == 0 {
+		return errors.NewValidationError("rule must set a category or at least one tag")
+	}
+	if len(rule.Tags) == 0 {
+		return nil
+	}
+
+	tagIDs := make([]uint, len(rule.Tags))
+	for i, tag := range rule.Tags {
+		tagIDs[i] = tag.ID
+	}
+	tags, err := s.tagService.ResolveTags(ctx, rule.UserID, tagIDs)
+	if err != nil {
+		return err
+	}
+	rule.Tags = tags
+	return nil
+}
+
 func (s *categorizationRuleService) DeleteRule(ctx context.Context, id uint, userID uint) error {
 	return s.repo.Delete(ctx, id, userID)
 }
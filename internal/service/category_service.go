@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
-	"errors"
+	stdErrors "errors"
 
 	"gorm.io/gorm"
 
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
 )
 
 type CategoryService interface {
@@ -14,7 +16,9 @@ type CategoryService interface {
 	CreateCategory(ctx context.Context, category *models.Category) error
 	GetCategoryByID(ctx context.Context, id string, userID uint) (*models.Category, error)
 	UpdateCategory(ctx context.Context, category *models.Category) error
-	DeleteCategory(ctx context.Context, id string, userID uint) error
+	DeleteCategory(ctx context.Context, id string, userID uint, replacementID *uint) error
+	MergeCategories(ctx context.Context, userID uint, sourceID, targetID uint) error
+	ApplyTemplate(ctx context.Context, userID uint, name string) (*ApplyTemplateResult, error)
 }
 
 type categoryService struct {
@@ -44,7 +48,11 @@ func (s *categoryService) CreateCategory(ctx context.Context, category *models.C
 	}
 
 	if count > 0 {
-		return errors.New("category with this name and type already exists")
+		return stdErrors.New("category with this name and type already exists")
+	}
+
+	if err := s.validateParent(ctx, category); err != nil {
+		return err
 	}
 
 	return s.db.WithContext(ctx).Create(category).Error
@@ -59,9 +67,248 @@ func (s *categoryService) GetCategoryByID(ctx context.Context, id string, userID
 }
 
 func (s *categoryService) UpdateCategory(ctx context.Context, category *models.Category) error {
+	if err := s.validateParent(ctx, category); err != nil {
+		return err
+	}
 	return s.db.WithContext(ctx).Save(category).Error
 }
 
-func (s *categoryService) DeleteCategory(ctx context.Context, id string, userID uint) error {
-	return s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Category{}).Error
+// DeleteCategory deletes a category. Categories still used by transactions or
+// categorization rules require a replacement category to move them to.
+func (s *categoryService) DeleteCategory(ctx context.Context, id string, userID uint, replacementID *uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&category).Error; err != nil {
+			return err
+		}
+
+		if replacementID != nil {
+			return mergeCategory(ctx, tx, category, *replacementID)
+		}
+
+		usage, err := repository.NewCategoryRepository(tx).CountUsage(ctx, category.ID)
+		if err != nil {
+			return err
+		}
+		if usage > 0 {
+			return errors.ErrCategoryInUse
+		}
+
+		// Move the subcategories up one level so they are not left pointing at a deleted parent
+		if err := tx.Model(&models.Category{}).
+			Where("parent_id = ? AND user_id = ?", category.ID, userID).
+			Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&category).Error
+	})
+}
+
+// MergeCategories moves everything that uses the source category to the target category
+// and deletes the source
+func (s *categoryService) MergeCategories(ctx context.Context, userID uint, sourceID, targetID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var source models.Category
+		if err := tx.Where("id = ? AND user_id = ?", sourceID, userID).First(&source).Error; err != nil {
+			return err
+		}
+		return mergeCategory(ctx, tx, source, targetID)
+	})
+}
+
+// mergeCategory reassigns the source category to the target inside the given transaction
+// and deletes the source
+func mergeCategory(ctx context.Context, tx *gorm.DB, source models.Category, targetID uint) error {
+	if source.ID == targetID {
+		return errors.NewValidationError("cannot merge a category into itself")
+	}
+
+	var target models.Category
+	if err := tx.Where("id = ? AND user_id = ?", targetID, source.UserID).First(&target).Error; err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NewValidationError("replacement category not found")
+		}
+		return err
+	}
+	if target.Type != source.Type {
+		return errors.NewValidationError("replacement category must have the same type")
+	}
+
+	// The target would end up as a subcategory of itself if it lives under the source
+	var categories []models.Category
+	if err := tx.Where("user_id = ?", source.UserID).Find(&categories).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	current := target
+	for depth := 0; depth <= len(categories) && current.ParentID != nil; depth++ {
+		if *current.ParentID == source.ID {
+			return errors.NewValidationError("cannot merge a category into one of its subcategories")
+		}
+		parent, ok := byID[*current.ParentID]
+		if !ok {
+			break
+		}
+		current = parent
+	}
+
+	if err := repository.NewCategoryRepository(tx).Reassign(ctx, source.ID, target.ID); err != nil {
+		return err
+	}
+
+	return tx.Delete(&source).Error
+}
+
+// ApplyTemplate seeds the user's categories and categorization rules from a template.
+// Categories that already exist are reused (soft-deleted ones are restored) and rules
+// that already exist are skipped, so applying a template again doesn't duplicate anything.
+func (s *categoryService) ApplyTemplate(ctx context.Context, userID uint, name string) (*ApplyTemplateResult, error) {
+	template, ok := FindCategoryTemplate(name)
+	if !ok {
+		return nil, errors.NewValidationError("category template not found")
+	}
+
+	result := &ApplyTemplateResult{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := make(map[string]uint, len(template.Categories))
+		key := func(name string, transactionType models.TransactionType) string {
+			return string(transactionType) + ":" + name
+		}
+
+		// Parents are listed before their children, so their IDs are already known here
+		for _, tc := range template.Categories {
+			var parentID *uint
+			if tc.Parent != "" {
+				if id, ok := ids[key(tc.Parent, tc.Type)]; ok {
+					parentID = &id
+				}
+			}
+
+			var category models.Category
+			found := tx.Unscoped().
+				Where("user_id = ? AND name = ? AND type = ?", userID, tc.Name, tc.Type).
+				Limit(1).
+				Find(&category)
+			if found.Error != nil {
+				return found.Error
+			}
+
+			if found.RowsAffected == 0 {
+				category = models.Category{
+					Name:       tc.Name,
+					Type:       tc.Type,
+					Deductible: tc.Deductible,
+					UserID:     userID,
+					ParentID:   parentID,
+				}
+				if err := tx.Create(&category).Error; err != nil {
+					return err
+				}
+				result.CategoriesCreated++
+			} else if category.DeletedAt.Valid {
+				if err := tx.Unscoped().Model(&category).Update("deleted_at", nil).Error; err != nil {
+					return err
+				}
+			}
+			ids[key(tc.Name, tc.Type)] = category.ID
+		}
+
+		for _, tr := range template.Rules {
+			categoryID, ok := ids[key(tr.Category, tr.Type)]
+			if !ok {
+				continue
+			}
+
+			var count int64
+			if err := tx.Model(&models.CategorizationRule{}).
+				Where("user_id = ? AND value = ? AND category_dst = ?", userID, tr.Pattern, categoryID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			rule := models.CategorizationRule{
+				UserID:          userID,
+				Name:            tr.Name,
+				Type:            "regex",
+				Value:           tr.Pattern,
+				TransactionType: string(tr.Type),
+				CategoryDst:     categoryID,
+				Active:          true,
+			}
+			if err := tx.Create(&rule).Error; err != nil {
+				return err
+			}
+			result.RulesCreated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validateParent checks that the category's parent belongs to the same user, has the
+// same type and that setting it would not create a cycle in the category tree
+func (s *categoryService) validateParent(ctx context.Context, category *models.Category) error {
+	if category.ParentID == nil {
+		return nil
+	}
+	if category.ID != 0 && *category.ParentID == category.ID {
+		return errors.NewValidationError("a category cannot be its own parent")
+	}
+
+	var parent models.Category
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", *category.ParentID, category.UserID).
+		First(&parent).Error; err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NewValidationError("parent category not found")
+		}
+		return err
+	}
+	if parent.Type != category.Type {
+		return errors.NewValidationError("parent category must have the same type")
+	}
+
+	// New categories have no descendants, so they can't close a cycle
+	if category.ID == 0 {
+		return nil
+	}
+
+	categories, err := s.ListCategories(ctx, category.UserID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	// Walk up from the new parent; reaching the category itself means it would become its own ancestor
+	current := parent
+	for depth := 0; depth <= len(categories); depth++ {
+		if current.ID == category.ID {
+			return errors.NewValidationError("category cannot be moved under one of its subcategories")
+		}
+		if current.ParentID == nil {
+			return nil
+		}
+		next, ok := byID[*current.ParentID]
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+
+	return errors.NewValidationError("category tree contains a cycle")
 }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+func setupCategoryServiceTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Tag{}, &models.CategorizationRule{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestCategoryService_ValidateParent(t *testing.T) {
+	db, user := setupCategoryServiceTestDB(t)
+	service := NewCategoryService(db)
+	ctx := context.Background()
+
+	// Alimentação > Mercado > Hortifruti
+	food := &models.Category{Name: "Alimentação", Type: models.TransactionTypeExpense, UserID: user.ID}
+	if err := service.CreateCategory(ctx, food); err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	groceries := &models.Category{Name: "Mercado", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &food.ID}
+	if err := service.CreateCategory(ctx, groceries); err != nil {
+		t.Fatalf("Failed to create child category: %v", err)
+	}
+	produce := &models.Category{Name: "Hortifruti", Type: models.TransactionTypeExpense, UserID: user.ID, ParentID: &groceries.ID}
+	if err := service.CreateCategory(ctx, produce); err != nil {
+		t.Fatalf("Failed to create grandchild category: %v", err)
+	}
+
+	salary := &models.Category{Name: "Salário", Type: models.TransactionTypeIncome, UserID: user.ID}
+	if err := service.CreateCategory(ctx, salary); err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	otherUsers := &models.Category{Name: "Casa", Type: models.TransactionTypeExpense, UserID: user.ID + 1}
+	if err := db.Create(otherUsers).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		category models.Category
+		parentID uint
+	}{
+		{"own parent", *food, food.ID},
+		{"child as parent", *food, groceries.ID},
+		{"grandchild as parent", *food, produce.ID},
+		{"parent of another type", *groceries, salary.ID},
+		{"parent of another user", *groceries, otherUsers.ID},
+		{"unknown parent", *groceries, 9999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category := tt.category
+			parentID := tt.parentID
+			category.ParentID = &parentID
+
+			err := service.UpdateCategory(ctx, &category)
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected a validation error, got %v", err)
+			}
+		})
+	}
+
+	// Moving a category under one that isn't its descendant is allowed
+	produce.ParentID = &food.ID
+	if err := service.UpdateCategory(ctx, produce); err != nil {
+		t.Errorf("Expected the category to move, got %v", err)
+	}
+	groceries.ParentID = &produce.ID
+	if err := service.UpdateCategory(ctx, groceries); err != nil {
+		t.Errorf("Expected the former parent to move under its former child, got %v", err)
+	}
+}
+
+func TestCategoryService_ApplyTemplate(t *testing.T) {
+	db, user := setupCategoryServiceTestDB(t)
+	service := NewCategoryService(db)
+	ctx := context.Background()
+	template, _ := FindCategoryTemplate(DefaultCategoryTemplate)
+
+	// The user already has one of the categories, and deleted another one
+	groceries := &models.Category{Name: "Mercado", Type: models.TransactionTypeExpense, UserID: user.ID}
+	if err := service.CreateCategory(ctx, groceries); err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	taxes := &models.Category{Name: "Impostos", Type: models.TransactionTypeExpense, UserID: user.ID}
+	if err := service.CreateCategory(ctx, taxes); err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	if err := db.Delete(taxes).Error; err != nil {
+		t.Fatalf("Failed to delete category: %v", err)
+	}
+
+	result, err := service.ApplyTemplate(ctx, user.ID, DefaultCategoryTemplate)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.CategoriesCreated != len(template.Categories)-2 || result.RulesCreated != len(template.Rules) {
+		t.Errorf("Expected %d categories and %d rules, got %+v", len(template.Categories)-2, len(template.Rules), result)
+	}
+	var categories int64
+	db.Model(&models.Category{}).Where("user_id = ?", user.ID).Count(&categories)
+	if categories != int64(len(template.Categories)) {
+		t.Errorf("Expected %d categories, got %d", len(template.Categories), categories)
+	}
+
+	// Applying it again creates nothing
+	result, err = service.ApplyTemplate(ctx, user.ID, DefaultCategoryTemplate)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.CategoriesCreated != 0 || result.RulesCreated != 0 {
+		t.Errorf("Expected nothing to be created again, got %+v", result)
+	}
+	var rules int64
+	db.Model(&models.Category{}).Where("user_id = ?", user.ID).Count(&categories)
+	db.Model(&models.CategorizationRule{}).Where("user_id = ?", user.ID).Count(&rules)
+	if categories != int64(len(template.Categories)) || rules != int64(len(template.Rules)) {
+		t.Errorf("Expected %d categories and %d rules, got %d and %d", len(template.Categories), len(template.Rules), categories, rules)
+	}
+
+	// Unknown templates aren't applied
+	if _, err := service.ApplyTemplate(ctx, user.ID, "unknown"); err == nil {
+		t.Error("Expected an unknown template to fail")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import "github.com/LeonardsonCC/dinheiros/internal/models"
+
+const (
+	// DefaultCategoryTemplate is applied to new users that don't pick a template
+	DefaultCategoryTemplate = "pt-BR"
+	// NoCategoryTemplate opts out of seeding categories at registration
+	NoCategoryTemplate = "none"
+)
+
+// CategoryTemplate is a starter set of categories and categorization rules
+type CategoryTemplate struct {
+	Name       string             `json:"name"`
+	Categories []TemplateCategory `json:"categories"`
+	Rules      []TemplateRule     `json:"rules"`
+}
+
+// TemplateCategory is a category created by a template. Parent references the
+// name of another category of the same template and type.
+type TemplateCategory struct {
+	Name       string                 `json:"name"`
+	Type       models.TransactionType `json:"type"`
+	Parent     string                 `json:"parent,omitempty"`
+	Deductible bool                   `json:"deductible,omitempty"`
+}
+
+// TemplateRule is a regex categorization rule pointing at a template category
+type TemplateRule struct {
+	Name     string                 `json:"name"`
+	Pattern  string                 `json:"pattern"`
+	Type     models.TransactionType `json:"type"`
+	Category string                 `json:"category"`
+}
+
+// ApplyTemplateResult reports what applying a template changed
+type ApplyTemplateResult struct {
+	CategoriesCreated int `json:"categories_created"`
+	RulesCreated      int `json:"rules_created"`
+}
+
+var categoryTemplates = []CategoryTemplate{
+	{
+		Name: "pt-BR",
+		Categories: []TemplateCategory{
+			{Name: "Salário", Type: models.TransactionTypeIncome},
+			{Name: "Freelance", Type: models.TransactionTypeIncome},
+			{Name: "Rendimentos", Type: models.TransactionTypeIncome},
+			{Name: "Reembolsos", Type: models.TransactionTypeIncome},
+			{Name: "Pix recebido", Type: models.TransactionTypeIncome},
+			{Name: "Alimentação", Type: models.TransactionTypeExpense},
+			{Name: "Mercado", Type: models.TransactionTypeExpense, Parent: "Alimentação"},
+			{Name: "Restaurantes e delivery", Type: models.TransactionTypeExpense, Parent: "Alimentação"},
+			{Name: "Transporte", Type: models.TransactionTypeExpense},
+			{Name: "Aplicativos de transporte", Type: models.TransactionTypeExpense, Parent: "Transporte"},
+			{Name: "Combustível", Type: models.TransactionTypeExpense, Parent: "Transporte"},
+			{Name: "Moradia", Type: models.TransactionTypeExpense},
+			{Name: "Saúde", Type: models.TransactionTypeExpense, Deductible: true},
+			{Name: "Educação", Type: models.TransactionTypeExpense, Deductible: true},
+			{Name: "Lazer", Type: models.TransactionTypeExpense},
+			{Name: "Assinaturas", Type: models.TransactionTypeExpense},
+			{Name: "Compras", Type: models.TransactionTypeExpense},
+			{Name: "Pix enviado", Type: models.TransactionTypeExpense},
+			{Name: "Tarifas bancárias", Type: models.TransactionTypeExpense},
+			{Name: "Impostos", Type: models.TransactionTypeExpense},
+		},
+		Rules: []TemplateRule{
+			{Name: "iFood", Pattern: `(?i)ifood`, Type: models.TransactionTypeExpense, Category: "Restaurantes e delivery"},
+			{Name: "Uber", Pattern: `(?i)\buber\b`, Type: models.TransactionTypeExpense, Category: "Aplicativos de transporte"},
+			{Name: "99", Pattern: `(?i)\b99\s*(app|pop|taxi)`, Type: models.TransactionTypeExpense, Category: "Aplicativos de transporte"},
+			{Name: "Pix enviado", Pattern: `(?i)pix\s*(enviado|transf)`, Type: models.TransactionTypeExpense, Category: "Pix enviado"},
+			{Name: "Pix recebido", Pattern: `(?i)pix\s*recebido`, Type: models.TransactionTypeIncome, Category: "Pix recebido"},
+			{Name: "Tarifas bancárias", Pattern: `(?i)(tarifa|anuidade|\biof\b|juros)`, Type: models.TransactionTypeExpense, Category: "Tarifas bancárias"},
+		},
+	},
+	{
+		Name: "en",
+		Categories: []TemplateCategory{
+			{Name: "Salary", Type: models.TransactionTypeIncome},
+			{Name: "Freelance", Type: models.TransactionTypeIncome},
+			{Name: "Investment income", Type: models.TransactionTypeIncome},
+			{Name: "Refunds", Type: models.TransactionTypeIncome},
+			{Name: "Pix received", Type: models.TransactionTypeIncome},
+			{Name: "Food", Type: models.TransactionTypeExpense},
+			{Name: "Groceries", Type: models.TransactionTypeExpense, Parent: "Food"},
+			{Name: "Restaurants and delivery", Type: models.TransactionTypeExpense, Parent: "Food"},
+			{Name: "Transportation", Type: models.TransactionTypeExpense},
+			{Name: "Ride hailing", Type: models.TransactionTypeExpense, Parent: "Transportation"},
+			{Name: "Fuel", Type: models.TransactionTypeExpense, Parent: "Transportation"},
+			{Name: "Housing", Type: models.TransactionTypeExpense},
+			{Name: "Health", Type: models.TransactionTypeExpense},
+			{Name: "Education", Type: models.TransactionTypeExpense},
+			{Name: "Entertainment", Type: models.TransactionTypeExpense},
+			{Name: "Subscriptions", Type: models.TransactionTypeExpense},
+			{Name: "Shopping", Type: models.TransactionTypeExpense},
+			{Name: "Pix sent", Type: models.TransactionTypeExpense},
+			{Name: "Bank fees", Type: models.TransactionTypeExpense},
+			{Name: "Taxes", Type: models.TransactionTypeExpense},
+		},
+		Rules: []TemplateRule{
+			{Name: "iFood", Pattern: `(?i)ifood`, Type: models.TransactionTypeExpense, Category: "Restaurants and delivery"},
+			{Name: "Uber", Pattern: `(?i)\buber\b`, Type: models.TransactionTypeExpense, Category: "Ride hailing"},
+			{Name: "99", Pattern: `(?i)\b99\s*(app|pop|taxi)`, Type: models.TransactionTypeExpense, Category: "Ride hailing"},
+			{Name: "Pix sent", Pattern: `(?i)pix\s*(enviado|transf)`, Type: models.TransactionTypeExpense, Category: "Pix sent"},
+			{Name: "Pix received", Pattern: `(?i)pix\s*recebido`, Type: models.TransactionTypeIncome, Category: "Pix received"},
+			{Name: "Bank fees", Pattern: `(?i)(tarifa|anuidade|\biof\b|juros)`, Type: models.TransactionTypeExpense, Category: "Bank fees"},
+		},
+	},
+}
+
+// CategoryTemplates returns the available category templates
+func CategoryTemplates() []CategoryTemplate {
+	return categoryTemplates
+}
+
+// FindCategoryTemplate returns the template with the given name
+func FindCategoryTemplate(name string) (*CategoryTemplate, bool) {
+	for i := range categoryTemplates {
+		if categoryTemplates[i].Name == name {
+			return &categoryTemplates[i], true
+		}
+	}
+	return nil, false
+}
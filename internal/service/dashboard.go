@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	repo "github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+const (
+	DefaultDashboardRecentLimit = 5
+	MaxDashboardRecentLimit     = 50
+	// dashboardTopLimit is how many categories and merchants are ranked in the summary
+	dashboardTopLimit = 5
+)
+
+type DashboardPeriodType string
+
+const (
+	// DashboardPeriodMonth is a calendar month, the current one by default
+	DashboardPeriodMonth DashboardPeriodType = "month"
+	// DashboardPeriodCustom is an arbitrary date range
+	DashboardPeriodCustom DashboardPeriodType = "custom"
+	// DashboardPeriodCreditCardCycle is the billing cycle of a credit card, from the day after
+	// one statement closing day up to the next
+	DashboardPeriodCreditCardCycle DashboardPeriodType = "credit_card_cycle"
+)
+
+// DashboardPeriod selects the period summarized by the dashboard. Date selects the month or
+// the credit card cycle containing it and defaults to today in Location.
+type DashboardPeriod struct {
+	Type        DashboardPeriodType
+	Date        *time.Time
+	StartDate   *time.Time
+	EndDate     *time.Time
+	AccountID   uint
+	RecentLimit int
+	Location    *time.Location
+}
+
+type DashboardTotals struct {
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"`
+	Net      float64 `json:"net"`
+}
+
+// DashboardComparison holds the totals of an earlier period and how the current period changed
+// relative to it. Percentages are nil when the earlier value is zero.
+type DashboardComparison struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	DashboardTotals
+	IncomeChange          float64  `json:"incomeChange"`
+	ExpensesChange        float64  `json:"expensesChange"`
+	NetChange             float64  `json:"netChange"`
+	IncomeChangePercent   *float64 `json:"incomeChangePercent"`
+	ExpensesChangePercent *float64 `json:"expensesChangePercent"`
+	NetChangePercent      *float64 `json:"netChangePercent"`
+}
+
+type DashboardRankItem struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+type DashboardSummary struct {
+	Period       DashboardPeriodType
+	StartDate    time.Time
+	EndDate      time.Time
+	TotalBalance float64
+	DashboardTotals
+	PreviousPeriod     DashboardComparison
+	LastYear           DashboardComparison
+	TopCategories      []DashboardRankItem
+	TopMerchants       []DashboardRankItem
+	RecentTransactions []models.Transaction
+}
+
+func (s *transactionService) GetDashboardSummary(userID uint, period DashboardPeriod) (*DashboardSummary, error) {
+	start, end, previousStart, previousEnd, err := s.resolveDashboardPeriod(userID, &period)
+	if err != nil {
+		return nil, err
+	}
+	// A credit card cycle summarizes the card's bill, other periods every account
+	var accountIDs []uint
+	if period.Type == DashboardPeriodCreditCardCycle {
+		accountIDs = []uint{period.AccountID}
+	}
+
+	recentLimit := period.RecentLimit
+	if recentLimit <= 0 {
+		recentLimit = DefaultDashboardRecentLimit
+	}
+	if recentLimit > MaxDashboardRecentLimit {
+		recentLimit = MaxDashboardRecentLimit
+	}
+
+	totalBalance, income, expenses, recent, err := s.transactionRepo.GetDashboardSummary(userID, accountIDs, start, end, recentLimit)
+	if err != nil {
+		return nil, err
+	}
+	summary := &DashboardSummary{
+		Period:             period.Type,
+		StartDate:          start,
+		EndDate:            end,
+		TotalBalance:       totalBalance,
+		DashboardTotals:    newDashboardTotals(income, expenses),
+		RecentTransactions: recent,
+	}
+
+	summary.PreviousPeriod, err = s.compareDashboardPeriod(userID, accountIDs, summary.DashboardTotals, previousStart, previousEnd)
+	if err != nil {
+		return nil, err
+	}
+	summary.LastYear, err = s.compareDashboardPeriod(userID, accountIDs, summary.DashboardTotals, sameDateLastYear(start), sameDateLastYear(end))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	filter := repo.StatisticsFilter{
+		UserID:           userID,
+		StartDate:        &start,
+		EndDate:          &end,
+		AccountIDs:       accountIDs,
+		Types:            []models.TransactionType{models.TransactionTypeExpense},
+		ExcludeTransfers: true,
+		Location:         period.Location,
+	}
+	categories, err := s.statisticsRepo.AmountByCategory(ctx, filter, true)
+	if err != nil {
+		return nil, err
+	}
+	summary.TopCategories = topDashboardItems(categories)
+
+	merchants, err := s.statisticsRepo.AmountByMerchant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	summary.TopMerchants = topDashboardItems(merchants)
+
+	return summary, nil
+}
+
+// resolveDashboardPeriod returns the dates of the period and of the previous equivalent period
+func (s *transactionService) resolveDashboardPeriod(userID uint, period *DashboardPeriod) (start, end, previousStart, previousEnd time.Time, err error) {
+	if period.Type == "" {
+		period.Type = DashboardPeriodMonth
+	}
+	loc := period.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	now := time.Now().In(loc)
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if period.Date != nil {
+		date = *period.Date
+	}
+
+	switch period.Type {
+	case DashboardPeriodMonth:
+		start = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+		end = start.AddDate(0, 1, -1)
+		previousStart = start.AddDate(0, -1, 0)
+		previousEnd = start.AddDate(0, 0, -1)
+
+	case DashboardPeriodCustom:
+		if period.StartDate == nil || period.EndDate == nil {
+			err = errors.NewValidationError("startDate and endDate are required for a custom period")
+			return
+		}
+		start, end = *period.StartDate, *period.EndDate
+		if end.Before(start) {
+			err = errors.NewValidationError("startDate must be before endDate")
+			return
+		}
+		days := int(math.Round(end.Sub(start).Hours()/24)) + 1
+		previousEnd = start.AddDate(0, 0, -1)
+		previousStart = start.AddDate(0, 0, -days)
+
+	case DashboardPeriodCreditCardCycle:
+		account, findErr := s.accountRepo.FindByID(period.AccountID, userID)
+		if findErr != nil {
+			err = errors.NewValidationError("credit card account not found")
+			return
+		}
+		if account.Type != models.AccountTypeCredit || account.StatementClosingDay == nil {
+			err = errors.NewValidationError("account has no credit card billing cycle")
+			return
+		}
+		closingDay := *account.StatementClosingDay
+		closing := dateInMonth(date.Year(), date.Month(), closingDay, date.Location())
+		if closing.Before(date) {
+			closing = dateInMonth(date.Year(), date.Month()+1, closingDay, date.Location())
+		}
+		previousClosing := dateInMonth(closing.Year(), closing.Month()-1, closingDay, closing.Location())
+		start = previousClosing.AddDate(0, 0, 1)
+		end = closing
+		previousStart = dateInMonth(previousClosing.Year(), previousClosing.Month()-1, closingDay, previousClosing.Location()).AddDate(0, 0, 1)
+		previousEnd = previousClosing
+
+	default:
+		err = errors.NewValidationError("invalid period, expected month, custom or credit_card_cycle")
+	}
+	return
+}
+
+func (s *transactionService) compareDashboardPeriod(userID uint, accountIDs []uint, current DashboardTotals, start, end time.Time) (DashboardComparison, error) {
+	income, expenses, err := s.transactionRepo.GetPeriodTotals(userID, accountIDs, start, end)
+	if err != nil {
+		return DashboardComparison{}, err
+	}
+	totals := newDashboardTotals(income, expenses)
+	return DashboardComparison{
+		StartDate:             start.Format("2006-01-02"),
+		EndDate:               end.Format("2006-01-02"),
+		DashboardTotals:       totals,
+		IncomeChange:          roundCents(current.Income - totals.Income),
+		ExpensesChange:        roundCents(current.Expenses - totals.Expenses),
+		NetChange:             roundCents(current.Net - totals.Net),
+		IncomeChangePercent:   changePercent(current.Income, totals.Income),
+		ExpensesChangePercent: changePercent(current.Expenses, totals.Expenses),
+		NetChangePercent:      changePercent(current.Net, totals.Net),
+	}, nil
+}
+
+// sameDateLastYear returns the date a year earlier, on the last day of the month when it
+// doesn't have the day, like February 29th
+func sameDateLastYear(date time.Time) time.Time {
+	return dateInMonth(date.Year()-1, date.Month(), date.Day(), date.Location())
+}
+
+func newDashboardTotals(income, expenses float64) DashboardTotals {
+	return DashboardTotals{Income: roundCents(income), Expenses: roundCents(expenses), Net: roundCents(income - expenses)}
+}
+
+func changePercent(current, previous float64) *float64 {
+	if toCents(previous) == 0 {
+		return nil
+	}
+	percent := math.Round((current-previous)/math.Abs(previous)*10000) / 100
+	return &percent
+}
+
+// topDashboardItems returns the labels with the highest amounts
+func topDashboardItems(rows []repo.StatisticsRow) []DashboardRankItem {
+	byLabel := make(map[string]float64)
+	for _, row := range rows {
+		byLabel[row.Label] += row.Amount
+	}
+	items := make([]DashboardRankItem, 0, len(byLabel))
+	for label, amount := range byLabel {
+		items = append(items, DashboardRankItem{Name: label, Amount: roundCents(amount)})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Amount != items[j].Amount {
+			return items[i].Amount > items[j].Amount
+		}
+		return items[i].Name < items[j].Name
+	})
+	if len(items) > dashboardTopLimit {
+		items = items[:dashboardTopLimit]
+	}
+	return items
+}
+
+func roundCents(amount float64) float64 {
+	return float64(toCents(amount)) / 100
+}
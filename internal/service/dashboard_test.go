@@ -0,0 +1,163 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupDashboardServiceTestDB(t *testing.T) (*gorm.DB, *models.User, *transactionService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	service := &transactionService{
+		transactionRepo: repository.NewTransactionRepository(db),
+		accountRepo:     repository.NewAccountRepository(db),
+		statisticsRepo:  repository.NewStatisticsRepository(db),
+	}
+	return db, user, service
+}
+
+// dashboardExpense creates an expense in the category, which is created when the user doesn't
+// have it yet
+func dashboardExpense(t *testing.T, db *gorm.DB, account *models.Account, date string, amount float64, category string) {
+	var record models.Category
+	if err := db.Where(models.Category{Name: category, Type: models.TransactionTypeExpense, UserID: account.UserID}).FirstOrCreate(&record).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	transactionRepo := repository.NewTransactionRepository(db)
+	tx := &models.Transaction{AccountID: account.ID, Description: category, Amount: amount, Type: models.TransactionTypeExpense, Date: forecastDate(date)}
+	if err := transactionRepo.Create(tx); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if err := transactionRepo.AssociateCategories(tx.ID, []uint{record.ID}); err != nil {
+		t.Fatalf("Failed to associate categories: %v", err)
+	}
+}
+
+func TestTransactionService_GetDashboardSummary_CreditCardCycle(t *testing.T) {
+	db, user, service := setupDashboardServiceTestDB(t)
+
+	checking := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	closingDay := 10
+	card := &models.Account{Name: "Nubank", Type: models.AccountTypeCredit, UserID: user.ID, StatementClosingDay: &closingDay}
+	for _, account := range []*models.Account{checking, card} {
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	// The cycle of October 18th closes on November 10th
+	dashboardExpense(t, db, card, "2026-10-12", 300, "Mercado")
+	dashboardExpense(t, db, card, "2026-10-05", 100, "Mercado")
+	dashboardExpense(t, db, checking, "2026-10-15", 1000, "Aluguel")
+
+	date := forecastDate("2026-10-18")
+	summary, err := service.GetDashboardSummary(user.ID, DashboardPeriod{Type: DashboardPeriodCreditCardCycle, AccountID: card.ID, Date: &date, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !summary.StartDate.Equal(forecastDate("2026-10-11")) || !summary.EndDate.Equal(forecastDate("2026-11-10")) {
+		t.Fatalf("Expected the cycle from October 11th to November 10th, got %v to %v", summary.StartDate, summary.EndDate)
+	}
+
+	// Only the card's bill is summarized
+	if summary.Expenses != 300 || summary.PreviousPeriod.Expenses != 100 {
+		t.Errorf("Expected 300 in the cycle and 100 in the previous one, got %+v and %+v", summary.DashboardTotals, summary.PreviousPeriod.DashboardTotals)
+	}
+	if len(summary.RecentTransactions) != 1 || summary.RecentTransactions[0].AccountID != card.ID {
+		t.Errorf("Expected only the card's transaction, got %+v", summary.RecentTransactions)
+	}
+	if len(summary.TopCategories) != 1 || summary.TopCategories[0] != (DashboardRankItem{Name: "Mercado", Amount: 300}) {
+		t.Errorf("Expected only the card's category, got %+v", summary.TopCategories)
+	}
+}
+
+func TestTransactionService_GetDashboardSummary_SharedAccounts(t *testing.T) {
+	db, user, service := setupDashboardServiceTestDB(t)
+
+	owner := &models.User{Name: "Owner", Email: "owner@example.com", Password: "hashedpassword"}
+	if err := db.Create(owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	checking := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	shared := &models.Account{Name: "Conta Conjunta", Type: models.AccountTypeChecking, UserID: owner.ID}
+	for _, account := range []*models.Account{checking, shared} {
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+	share := &models.AccountShare{AccountID: shared.ID, OwnerUserID: owner.ID, SharedUserID: user.ID, PermissionLevel: models.PermissionRead, SharedAt: time.Now()}
+	if err := db.Create(share).Error; err != nil {
+		t.Fatalf("Failed to share account: %v", err)
+	}
+
+	dashboardExpense(t, db, checking, "2026-10-05", 200, "Mercado")
+	dashboardExpense(t, db, shared, "2026-10-06", 50, "Padaria")
+
+	date := forecastDate("2026-10-18")
+	summary, err := service.GetDashboardSummary(user.ID, DashboardPeriod{Date: &date, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The totals and the categories cover the same accounts
+	var categories float64
+	for _, item := range summary.TopCategories {
+		categories += item.Amount
+	}
+	if summary.Expenses != 250 || categories != 250 {
+		t.Errorf("Expected 250 in the totals and categories, got %.2f and %+v", summary.Expenses, summary.TopCategories)
+	}
+	if len(summary.RecentTransactions) != 2 {
+		t.Errorf("Expected the transactions of both accounts, got %+v", summary.RecentTransactions)
+	}
+}
+
+func TestTransactionService_GetDashboardSummary_LastYearOfLeapDay(t *testing.T) {
+	db, user, service := setupDashboardServiceTestDB(t)
+
+	account := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	dashboardExpense(t, db, account, "2027-02-28", 40, "Mercado")
+	dashboardExpense(t, db, account, "2027-03-01", 70, "Mercado")
+
+	date := forecastDate("2028-02-10")
+	summary, err := service.GetDashboardSummary(user.ID, DashboardPeriod{Date: &date, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lastYear := summary.LastYear
+	if lastYear.StartDate != "2027-02-01" || lastYear.EndDate != "2027-02-28" {
+		t.Errorf("Expected February 2027, got %s to %s", lastYear.StartDate, lastYear.EndDate)
+	}
+	if lastYear.Expenses != 40 {
+		t.Errorf("Expected 40 spent in February 2027, got %.2f", lastYear.Expenses)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/LeonardsonCC/dinheiros/internal/export"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// exportBatchSize is how many transactions are loaded from the database at a time
+const exportBatchSize = 500
+
+type ExportService interface {
+	// ExportTransactions writes the user's transactions matching the filter and closes the
+	// writer. It stops when the context is done, leaving the export incomplete.
+	ExportTransactions(ctx context.Context, userID uint, filter repository.TransactionFilter, writer export.Writer) error
+}
+
+type exportService struct {
+	transactionRepo repository.TransactionRepository
+	accountRepo     repository.AccountRepository
+}
+
+func NewExportService(transactionRepo repository.TransactionRepository, accountRepo repository.AccountRepository) ExportService {
+	return &exportService{transactionRepo: transactionRepo, accountRepo: accountRepo}
+}
+
+func (s *exportService) ExportTransactions(ctx context.Context, userID uint, filter repository.TransactionFilter, writer export.Writer) error {
+	write := func(transactions []models.Transaction) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for i := range transactions {
+			if err := writer.Write(&transactions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	accountWriter, ok := writer.(export.AccountWriter)
+	if !ok {
+		if err := s.transactionRepo.FindInBatches(userID, filter, exportBatchSize, write); err != nil {
+			return err
+		}
+		return writer.Close()
+	}
+
+	accounts, err := s.accountRepo.FindByUserIDIncludingShared(userID)
+	if err != nil {
+		return err
+	}
+	selected := make(map[uint]bool, len(filter.AccountIDs))
+	for _, id := range filter.AccountIDs {
+		selected[id] = true
+	}
+	// Formats grouping by account write the credit cards after the other accounts
+	sort.SliceStable(accounts, func(i, j int) bool {
+		iCredit, jCredit := accounts[i].Type == models.AccountTypeCredit, accounts[j].Type == models.AccountTypeCredit
+		if iCredit != jCredit {
+			return jCredit
+		}
+		return accounts[i].ID < accounts[j].ID
+	})
+
+	for _, account := range accounts {
+		if len(selected) > 0 && !selected[account.ID] {
+			continue
+		}
+		accountFilter := filter
+		accountFilter.AccountIDs = []uint{account.ID}
+		first, last, found, err := s.transactionRepo.DateRange(userID, accountFilter)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		if err := accountWriter.StartAccount(account, first, last); err != nil {
+			return err
+		}
+		if err := s.transactionRepo.FindInBatches(userID, accountFilter, exportBatchSize, write); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
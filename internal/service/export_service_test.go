@@ -0,0 +1,263 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/dto"
+	"github.com/LeonardsonCC/dinheiros/internal/export"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// exportTestData are the transactions of the export tests: groceries on the card and the
+// payment of the card's bill from the checking account
+type exportTestData struct {
+	checking, card    *models.Account
+	groceries         *models.Transaction
+	outbound, inbound *models.Transaction
+}
+
+func setupExportServiceTestDB(t *testing.T) (*gorm.DB, *models.User, ExportService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	service := NewExportService(repository.NewTransactionRepository(db), repository.NewAccountRepository(db))
+	return db, user, service
+}
+
+func createExportTestData(t *testing.T, db *gorm.DB, user *models.User) *exportTestData {
+	transactionRepo := repository.NewTransactionRepository(db)
+	// The card is created first, but formats grouping by account still write it last
+	data := &exportTestData{
+		card:     &models.Account{Name: "Cartão", Type: models.AccountTypeCredit, UserID: user.ID},
+		checking: &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID},
+	}
+	for _, account := range []*models.Account{data.card, data.checking} {
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+	category := &models.Category{Name: "Mercado", Type: models.TransactionTypeExpense, UserID: user.ID}
+	if err := db.Create(category).Error; err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	outbound, inbound := models.AttachmentTypeOutboundTransfer, models.AttachmentTypeInboundTransfer
+	data.groceries = &models.Transaction{AccountID: data.card.ID, Description: "COMPRA CARTAO 1234 PADARIA", Amount: 80, Type: models.TransactionTypeExpense, Date: forecastDate("2026-10-05")}
+	data.outbound = &models.Transaction{AccountID: data.checking.ID, Description: "Pagamento da fatura", Amount: 300, Type: models.TransactionTypeExpense, Date: forecastDate("2026-10-10"), AttachmentType: &outbound}
+	data.inbound = &models.Transaction{AccountID: data.card.ID, Description: "Pagamento da fatura", Amount: 300, Type: models.TransactionTypeIncome, Date: forecastDate("2026-10-10"), AttachmentType: &inbound}
+	for _, transaction := range []*models.Transaction{data.groceries, data.outbound, data.inbound} {
+		if err := transactionRepo.Create(transaction); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+	if err := transactionRepo.AssociateCategories(data.groceries.ID, []uint{category.ID}); err != nil {
+		t.Fatalf("Failed to associate categories: %v", err)
+	}
+	for _, pair := range [][2]*models.Transaction{{data.outbound, data.inbound}, {data.inbound, data.outbound}} {
+		if err := db.Model(pair[0]).Update("attached_transaction_id", pair[1].ID).Error; err != nil {
+			t.Fatalf("Failed to attach transactions: %v", err)
+		}
+	}
+	return data
+}
+
+func exportTransactions(t *testing.T, service ExportService, userID uint, format export.Format) []byte {
+	var out bytes.Buffer
+	writer, err := export.NewWriter(format, &out, time.UTC)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := service.ExportTransactions(context.Background(), userID, repository.TransactionFilter{}, writer); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestExportService_CSV(t *testing.T) {
+	db, user, service := setupExportServiceTestDB(t)
+	data := createExportTestData(t, db, user)
+
+	records, err := csv.NewReader(bytes.NewReader(exportTransactions(t, service, user.ID, export.FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected a header and 3 rows, got %v", records)
+	}
+	column := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	rows := make(map[string][]string, len(records)-1)
+	for _, record := range records[1:] {
+		rows[record[column["id"]]] = record
+	}
+	row := func(transaction *models.Transaction) []string {
+		record, ok := rows[strconv.FormatUint(uint64(transaction.ID), 10)]
+		if !ok {
+			t.Fatalf("Expected a row for transaction %d, got %v", transaction.ID, records)
+		}
+		return record
+	}
+
+	groceries := row(data.groceries)
+	if groceries[column["account"]] != "Cartão" || groceries[column["categories"]] != "Mercado" || groceries[column["attachment_type"]] != "" {
+		t.Errorf("Expected the card's groceries, got %v", groceries)
+	}
+	outbound := row(data.outbound)
+	if outbound[column["account"]] != "Conta Corrente" || outbound[column["attached_transaction_id"]] != strconv.FormatUint(uint64(data.inbound.ID), 10) ||
+		outbound[column["attachment_type"]] != "outbound_transfer" || outbound[column["attached_account"]] != "Cartão" {
+		t.Errorf("Expected the payment to the card, got %v", outbound)
+	}
+	inbound := row(data.inbound)
+	if inbound[column["attachment_type"]] != "inbound_transfer" || inbound[column["attached_account"]] != "Conta Corrente" {
+		t.Errorf("Expected the payment from the checking account, got %v", inbound)
+	}
+}
+
+func TestExportService_OFX(t *testing.T) {
+	db, user, service := setupExportServiceTestDB(t)
+	data := createExportTestData(t, db, user)
+
+	ofx := string(exportTransactions(t, service, user.ID, export.FormatOFX))
+	checking := strings.Index(ofx, "<BANKACCTFROM><BANKID>0000</BANKID><ACCTID>"+strconv.FormatUint(uint64(data.checking.ID), 10)+"</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE>")
+	card := strings.Index(ofx, "<CCACCTFROM><ACCTID>"+strconv.FormatUint(uint64(data.card.ID), 10)+"</ACCTID></CCACCTFROM>")
+	if checking < 0 || card < checking {
+		t.Fatalf("Expected the checking account's statement before the card's, got:\n%s", ofx)
+	}
+
+	// Each transaction is in its account's statement, and transfers are XFER
+	statement := func(transaction *models.Transaction) (string, int) {
+		start := strings.Index(ofx, "<FITID>"+strconv.FormatUint(uint64(transaction.ID), 10)+"</FITID>")
+		if start < 0 {
+			t.Fatalf("Expected transaction %d in the export, got:\n%s", transaction.ID, ofx)
+		}
+		lineStart := strings.LastIndex(ofx[:start], "\n") + 1
+		return ofx[lineStart : start+strings.Index(ofx[start:], "\n")], start
+	}
+	groceries, at := statement(data.groceries)
+	if !strings.Contains(groceries, "<TRNTYPE>DEBIT</TRNTYPE>") || !strings.Contains(groceries, "<TRNAMT>-80.00</TRNAMT>") || at < card {
+		t.Errorf("Expected a debit in the card's statement, got %s", groceries)
+	}
+	outbound, at := statement(data.outbound)
+	if !strings.Contains(outbound, "<TRNTYPE>XFER</TRNTYPE>") || !strings.Contains(outbound, "<TRNAMT>-300.00</TRNAMT>") || at > card {
+		t.Errorf("Expected a transfer out of the checking account, got %s", outbound)
+	}
+	inbound, at := statement(data.inbound)
+	if !strings.Contains(inbound, "<TRNTYPE>XFER</TRNTYPE>") || !strings.Contains(inbound, "<TRNAMT>300.00</TRNAMT>") || at < card {
+		t.Errorf("Expected a transfer into the card, got %s", inbound)
+	}
+}
+
+func TestExportService_NDJSON(t *testing.T) {
+	db, user, service := setupExportServiceTestDB(t)
+	data := createExportTestData(t, db, user)
+
+	transactions := make(map[uint]dto.TransactionResponse)
+	scanner := bufio.NewScanner(bytes.NewReader(exportTransactions(t, service, user.ID, export.FormatNDJSON)))
+	for scanner.Scan() {
+		var transaction dto.TransactionResponse
+		if err := json.Unmarshal(scanner.Bytes(), &transaction); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", scanner.Text(), err)
+		}
+		transactions[transaction.ID] = transaction
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("Expected 3 transactions, got %+v", transactions)
+	}
+
+	groceries := transactions[data.groceries.ID]
+	if groceries.Account.Name != "Cartão" || len(groceries.Categories) != 1 || groceries.Categories[0].Name != "Mercado" || groceries.AttachmentType != nil {
+		t.Errorf("Expected the card's groceries, got %+v", groceries)
+	}
+	outbound := transactions[data.outbound.ID]
+	if outbound.Account.Name != "Conta Corrente" || outbound.AttachmentType == nil || *outbound.AttachmentType != "outbound_transfer" ||
+		outbound.AttachedTransaction == nil || outbound.AttachedTransaction.ID != data.inbound.ID || outbound.AttachedTransaction.Account.Name != "Cartão" {
+		t.Errorf("Expected the payment to the card, got %+v", outbound)
+	}
+	inbound := transactions[data.inbound.ID]
+	if inbound.AttachmentType == nil || *inbound.AttachmentType != "inbound_transfer" || inbound.AttachedTransaction == nil || inbound.AttachedTransaction.Account.Name != "Conta Corrente" {
+		t.Errorf("Expected the payment from the checking account, got %+v", inbound)
+	}
+}
+
+func TestExportService_Batches(t *testing.T) {
+	db, user, service := setupExportServiceTestDB(t)
+
+	accounts := []*models.Account{
+		{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID},
+		{Name: "Cartão", Type: models.AccountTypeCredit, UserID: user.ID},
+	}
+	for _, account := range accounts {
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+	// One more than a batch in the checking account, and a few on the card
+	count := map[uint]int{accounts[0].ID: exportBatchSize + 1, accounts[1].ID: 3}
+	var transactions []*models.Transaction
+	for _, account := range accounts {
+		for i := 0; i < count[account.ID]; i++ {
+			transactions = append(transactions, &models.Transaction{AccountID: account.ID, Description: "Compra " + strconv.Itoa(i), Amount: 1, Type: models.TransactionTypeExpense, Date: forecastDate("2026-10-01").AddDate(0, 0, i%28)})
+		}
+	}
+	if err := db.CreateInBatches(transactions, 100).Error; err != nil {
+		t.Fatalf("Failed to create transactions: %v", err)
+	}
+	total := len(transactions)
+
+	// Without grouping by account
+	lines := strings.Split(strings.TrimSpace(string(exportTransactions(t, service, user.ID, export.FormatNDJSON))), "\n")
+	ids := make(map[uint]bool, len(lines))
+	for _, line := range lines {
+		var transaction dto.TransactionResponse
+		if err := json.Unmarshal([]byte(line), &transaction); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", line, err)
+		}
+		ids[transaction.ID] = true
+	}
+	if len(lines) != total || len(ids) != total {
+		t.Errorf("Expected %d different transactions, got %d lines with %d transactions", total, len(lines), len(ids))
+	}
+
+	// Grouping by account
+	ofx := string(exportTransactions(t, service, user.ID, export.FormatOFX))
+	if written := strings.Count(ofx, "<STMTTRN>"); written != total {
+		t.Errorf("Expected %d transactions, got %d", total, written)
+	}
+	checking := ofx[:strings.Index(ofx, "<CCACCTFROM>")]
+	if written := strings.Count(checking, "<STMTTRN>"); written != exportBatchSize+1 {
+		t.Errorf("Expected %d transactions in the checking account, got %d", exportBatchSize+1, written)
+	}
+}
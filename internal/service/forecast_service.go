@@ -0,0 +1,322 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+const (
+	DefaultForecastDays = 30
+	MaxForecastDays     = 365
+	// forecastHistoryMonths is how far back the history is searched for recurring transactions
+	forecastHistoryMonths = 6
+)
+
+type ForecastEventSource string
+
+const (
+	// ForecastEventScheduled is a transaction already registered with a future date
+	ForecastEventScheduled ForecastEventSource = "scheduled"
+	// ForecastEventRecurring is the next occurrence of a recurring transaction
+	ForecastEventRecurring ForecastEventSource = "recurring"
+	// ForecastEventCreditCardBill is a credit card bill paid from its payment account
+	ForecastEventCreditCardBill ForecastEventSource = "credit_card_bill"
+)
+
+// ForecastEvent is a projected change to an account balance. Amount is signed.
+type ForecastEvent struct {
+	Date        string              `json:"date"`
+	AccountID   uint                `json:"account_id"`
+	Description string              `json:"description"`
+	Amount      float64             `json:"amount"`
+	Source      ForecastEventSource `json:"source"`
+}
+
+// AccountForecast is the projected end of day balance of an account, aligned with Forecast.Labels.
+// FirstNegativeDate is only reported for accounts that aren't credit cards.
+type AccountForecast struct {
+	AccountID         uint               `json:"account_id"`
+	Name              string             `json:"name"`
+	Type              models.AccountType `json:"type"`
+	CurrentBalance    float64            `json:"current_balance"`
+	Balances          []float64          `json:"balances"`
+	FirstNegativeDate *string            `json:"first_negative_date"`
+}
+
+type Forecast struct {
+	Labels   []string          `json:"labels"`
+	Accounts []AccountForecast `json:"accounts"`
+	Events   []ForecastEvent   `json:"events"`
+}
+
+type ForecastService interface {
+	// Forecast projects the balance of every account of the user for the next days of loc
+	Forecast(userID uint, days int, loc *time.Location) (*Forecast, error)
+}
+
+type forecastService struct {
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	now             func() time.Time
+}
+
+func NewForecastService(accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository) ForecastService {
+	return &forecastService{accountRepo: accountRepo, transactionRepo: transactionRepo, now: time.Now}
+}
+
+// billCycle tracks the last closed bill of a credit card that is still waiting for its due date
+type billCycle struct {
+	pending bool
+	closed  float64 // card balance when the bill closed
+	paid    float64 // payments received by the card since then
+}
+
+func (s *forecastService) Forecast(userID uint, days int, loc *time.Location) (*Forecast, error) {
+	if days <= 0 || days > MaxForecastDays {
+		return nil, errors.NewValidationError("days must be between 1 and 365")
+	}
+
+	accounts, err := s.accountRepo.FindByUserIDIncludingShared(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+	now := s.now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	endOfToday := today.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	horizon := today.AddDate(0, 0, days)
+	historyStart := today.AddDate(0, -forecastHistoryMonths, 0)
+
+	accountIDs := make([]uint, len(accounts))
+	accountsByID := make(map[uint]*models.Account, len(accounts))
+	for i := range accounts {
+		accountIDs[i] = accounts[i].ID
+		accountsByID[accounts[i].ID] = &accounts[i]
+	}
+
+	transactions := make([]models.Transaction, 0)
+	if len(accountIDs) > 0 {
+		found, _, err := s.transactionRepo.FindByUserID(userID, nil, accountIDs, nil, nil, nil, "", nil, nil, &historyStart, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range found {
+			if _, ok := accountsByID[tx.AccountID]; ok {
+				tx.Date = tx.Date.In(loc)
+				transactions = append(transactions, tx)
+			}
+		}
+	}
+
+	// Account balances already include future dated transactions, so take them out of the
+	// current balance and add them back on their date
+	balances := make(map[uint]float64, len(accounts))
+	for _, account := range accounts {
+		balances[account.ID] = account.Balance
+	}
+	eventsByDay := make(map[string][]ForecastEvent)
+	scheduled := make(map[string]bool)
+	accountByTransaction := make(map[uint]uint, len(transactions))
+	for _, tx := range transactions {
+		accountByTransaction[tx.ID] = tx.AccountID
+		if !tx.Date.After(endOfToday) {
+			continue
+		}
+		amount := balanceEffect(tx)
+		balances[tx.AccountID] -= amount
+		if !tx.Date.Before(horizon.AddDate(0, 0, 1)) {
+			continue
+		}
+		day := tx.Date.Format("2006-01-02")
+		eventsByDay[day] = append(eventsByDay[day], ForecastEvent{
+			Date:        day,
+			AccountID:   tx.AccountID,
+			Description: tx.Description,
+			Amount:      amount,
+			Source:      ForecastEventScheduled,
+		})
+		scheduled[recurringMonthKey(tx.AccountID, tx.Description, tx.Date)] = true
+	}
+
+	// Credit card payments are projected from the bills, so transfers to and from credit
+	// cards are left out of the recurring transactions
+	history := make([]models.Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.AttachedTransactionID != nil {
+			other, ok := accountsByID[accountByTransaction[*tx.AttachedTransactionID]]
+			if ok && other.Type == models.AccountTypeCredit {
+				continue
+			}
+			if accountsByID[tx.AccountID].Type == models.AccountTypeCredit {
+				continue
+			}
+		}
+		history = append(history, tx)
+	}
+	for _, pattern := range DetectRecurring(history, endOfToday) {
+		for _, date := range pattern.NextDates(endOfToday, horizon) {
+			if scheduled[recurringMonthKey(pattern.AccountID, pattern.Description, date)] {
+				continue
+			}
+			amount := pattern.Amount
+			if pattern.Type == models.TransactionTypeExpense {
+				amount = -amount
+			}
+			day := date.Format("2006-01-02")
+			eventsByDay[day] = append(eventsByDay[day], ForecastEvent{
+				Date:        day,
+				AccountID:   pattern.AccountID,
+				Description: pattern.Description,
+				Amount:      amount,
+				Source:      ForecastEventRecurring,
+			})
+		}
+	}
+
+	cards := make([]*models.Account, 0)
+	cycles := make(map[uint]*billCycle)
+	for i := range accounts {
+		card := &accounts[i]
+		if !hasBillingCycle(card, accountsByID) {
+			continue
+		}
+		cards = append(cards, card)
+		cycles[card.ID] = openBillCycle(card, transactions, balances[card.ID], today, endOfToday)
+	}
+
+	forecast := &Forecast{Labels: make([]string, 0, days), Events: make([]ForecastEvent, 0)}
+	curves := make(map[uint][]float64, len(accounts))
+	firstNegative := make(map[uint]string)
+	for _, account := range accounts {
+		if account.Type != models.AccountTypeCredit && toCents(balances[account.ID]) < 0 {
+			firstNegative[account.ID] = today.Format("2006-01-02")
+		}
+	}
+	current := make(map[uint]float64, len(balances))
+	for id, balance := range balances {
+		current[id] = balance
+	}
+
+	for i := 1; i <= days; i++ {
+		date := today.AddDate(0, 0, i)
+		day := date.Format("2006-01-02")
+		forecast.Labels = append(forecast.Labels, day)
+
+		events := eventsByDay[day]
+		for _, event := range events {
+			balances[event.AccountID] += event.Amount
+			if cycle, ok := cycles[event.AccountID]; ok && cycle.pending && event.Amount > 0 {
+				cycle.paid += event.Amount
+			}
+		}
+
+		for _, card := range cards {
+			cycle := cycles[card.ID]
+			if cycle.pending && date.Equal(dateInMonth(date.Year(), date.Month(), *card.PaymentDueDay, date.Location())) {
+				if bill := -cycle.closed - cycle.paid; toCents(bill) > 0 {
+					payment := []ForecastEvent{
+						{Date: day, AccountID: *card.PaymentAccountID, Description: card.Name, Amount: -bill, Source: ForecastEventCreditCardBill},
+						{Date: day, AccountID: card.ID, Description: card.Name, Amount: bill, Source: ForecastEventCreditCardBill},
+					}
+					for _, event := range payment {
+						balances[event.AccountID] += event.Amount
+					}
+					events = append(events, payment...)
+				}
+				cycle.pending = false
+			}
+			if date.Equal(dateInMonth(date.Year(), date.Month(), *card.StatementClosingDay, date.Location())) {
+				*cycle = billCycle{pending: true, closed: balances[card.ID]}
+			}
+		}
+
+		forecast.Events = append(forecast.Events, events...)
+		for _, account := range accounts {
+			balance := math.Round(balances[account.ID]*100) / 100
+			curves[account.ID] = append(curves[account.ID], balance)
+			if _, ok := firstNegative[account.ID]; !ok && account.Type != models.AccountTypeCredit && toCents(balance) < 0 {
+				firstNegative[account.ID] = day
+			}
+		}
+	}
+
+	forecast.Accounts = make([]AccountForecast, 0, len(accounts))
+	for _, account := range accounts {
+		result := AccountForecast{
+			AccountID:      account.ID,
+			Name:           account.Name,
+			Type:           account.Type,
+			CurrentBalance: math.Round(current[account.ID]*100) / 100,
+			Balances:       curves[account.ID],
+		}
+		if day, ok := firstNegative[account.ID]; ok {
+			result.FirstNegativeDate = &day
+		}
+		forecast.Accounts = append(forecast.Accounts, result)
+	}
+	sort.SliceStable(forecast.Events, func(i, j int) bool { return forecast.Events[i].Date < forecast.Events[j].Date })
+
+	return forecast, nil
+}
+
+// openBillCycle finds the bill closed before today that isn't due yet, if any
+func openBillCycle(card *models.Account, transactions []models.Transaction, balance float64, today, endOfToday time.Time) *billCycle {
+	closing := dateInMonth(today.Year(), today.Month(), *card.StatementClosingDay, today.Location())
+	if closing.After(today) {
+		closing = dateInMonth(today.Year(), today.Month()-1, *card.StatementClosingDay, today.Location())
+	}
+	due := dateInMonth(closing.Year(), closing.Month(), *card.PaymentDueDay, closing.Location())
+	if !due.After(closing) {
+		due = dateInMonth(closing.Year(), closing.Month()+1, *card.PaymentDueDay, closing.Location())
+	}
+	if !due.After(today) {
+		return &billCycle{}
+	}
+
+	// Walk the card balance back to the end of the closing day
+	cycle := &billCycle{pending: true, closed: balance}
+	endOfClosing := closing.AddDate(0, 0, 1)
+	for _, tx := range transactions {
+		if tx.AccountID != card.ID || tx.Date.Before(endOfClosing) || tx.Date.After(endOfToday) {
+			continue
+		}
+		amount := balanceEffect(tx)
+		cycle.closed -= amount
+		if amount > 0 {
+			cycle.paid += amount
+		}
+	}
+	return cycle
+}
+
+func hasBillingCycle(account *models.Account, accountsByID map[uint]*models.Account) bool {
+	if account.Type != models.AccountTypeCredit || account.StatementClosingDay == nil || account.PaymentDueDay == nil || account.PaymentAccountID == nil {
+		return false
+	}
+	_, ok := accountsByID[*account.PaymentAccountID]
+	return ok
+}
+
+// balanceEffect returns how a transaction changes its account balance
+func balanceEffect(tx models.Transaction) float64 {
+	switch tx.Type {
+	case models.TransactionTypeIncome, models.TransactionTypeInitial:
+		return tx.Amount
+	case models.TransactionTypeExpense:
+		return -tx.Amount
+	}
+	return 0
+}
+
+func recurringMonthKey(accountID uint, description string, date time.Time) string {
+	return strconv.FormatUint(uint64(accountID), 10) + "|" + date.Format("2006-01") + "|" + normalizeRecurringDescription(description)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupForecastServiceTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func forecastDate(date string) time.Time {
+	parsed, _ := time.Parse("2006-01-02", date)
+	return parsed
+}
+
+func TestOpenBillCycle(t *testing.T) {
+	today := forecastDate("2026-10-18")
+	endOfToday := today.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	card := func(closingDay, dueDay int) *models.Account {
+		account := &models.Account{Type: models.AccountTypeCredit, StatementClosingDay: &closingDay, PaymentDueDay: &dueDay}
+		account.ID = 1
+		return account
+	}
+	expense := func(date string, amount float64) models.Transaction {
+		return models.Transaction{AccountID: 1, Type: models.TransactionTypeExpense, Amount: amount, Date: forecastDate(date)}
+	}
+	payment := func(date string, amount float64) models.Transaction {
+		return models.Transaction{AccountID: 1, Type: models.TransactionTypeIncome, Amount: amount, Date: forecastDate(date)}
+	}
+
+	tests := []struct {
+		name         string
+		card         *models.Account
+		transactions []models.Transaction
+		balance      float64
+		expected     billCycle
+	}{
+		{
+			// Closed on the 10th, due on the 20th: spending since the closing isn't on the bill
+			name:         "closed this month and not due yet",
+			card:         card(10, 20),
+			transactions: []models.Transaction{expense("2026-10-08", 200), expense("2026-10-12", 300), expense("2026-10-10", 100)},
+			balance:      -600,
+			expected:     billCycle{pending: true, closed: -300},
+		},
+		{
+			name:         "payments since the closing are partial payments",
+			card:         card(10, 20),
+			transactions: []models.Transaction{expense("2026-10-12", 300), payment("2026-10-15", 50)},
+			balance:      -450,
+			expected:     billCycle{pending: true, closed: -200, paid: 50},
+		},
+		{
+			name:     "closed this month and already due",
+			card:     card(5, 12),
+			balance:  -300,
+			expected: billCycle{},
+		},
+		{
+			// The closing day is after today, so the last bill closed on September 25th and was
+			// due on October 5th
+			name:     "closing after today",
+			card:     card(25, 5),
+			balance:  -300,
+			expected: billCycle{},
+		},
+		{
+			// Due days before the closing day fall in the next month: closed on September 25th,
+			// due on October 20th
+			name:         "due day rolls over to the next month",
+			card:         card(25, 20),
+			transactions: []models.Transaction{expense("2026-09-20", 100), expense("2026-10-01", 80)},
+			balance:      -180,
+			expected:     billCycle{pending: true, closed: -100},
+		},
+		{
+			name:     "due today",
+			card:     card(10, 18),
+			balance:  -300,
+			expected: billCycle{},
+		},
+		{
+			name:         "transactions of other accounts and the future are ignored",
+			card:         card(10, 20),
+			transactions: []models.Transaction{{AccountID: 2, Type: models.TransactionTypeExpense, Amount: 70, Date: forecastDate("2026-10-12")}, expense("2026-10-25", 90)},
+			balance:      -100,
+			expected:     billCycle{pending: true, closed: -100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycle := openBillCycle(tt.card, tt.transactions, tt.balance, today, endOfToday)
+			if cycle.pending != tt.expected.pending || toCents(cycle.closed) != toCents(tt.expected.closed) || toCents(cycle.paid) != toCents(tt.expected.paid) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *cycle)
+			}
+		})
+	}
+}
+
+func TestForecastService_Forecast(t *testing.T) {
+	db, user := setupForecastServiceTestDB(t)
+	service := NewForecastService(repository.NewAccountRepository(db), repository.NewTransactionRepository(db)).(*forecastService)
+	service.now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	// The balance includes the expense scheduled for October 25th
+	checking := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID, Balance: 400}
+	if err := db.Create(checking).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	closingDay, dueDay := 10, 20
+	card := &models.Account{
+		Name:                "Nubank",
+		Type:                models.AccountTypeCredit,
+		UserID:              user.ID,
+		Balance:             -450,
+		StatementClosingDay: &closingDay,
+		PaymentDueDay:       &dueDay,
+		PaymentAccountID:    &checking.ID,
+	}
+	if err := db.Create(card).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	transactions := []models.Transaction{
+		// Rent every 5th, projected on November 5th
+		{AccountID: checking.ID, Type: models.TransactionTypeExpense, Amount: 800, Description: "Aluguel", Date: forecastDate("2026-08-05")},
+		{AccountID: checking.ID, Type: models.TransactionTypeExpense, Amount: 800, Description: "Aluguel", Date: forecastDate("2026-09-05")},
+		{AccountID: checking.ID, Type: models.TransactionTypeExpense, Amount: 800, Description: "Aluguel", Date: forecastDate("2026-10-05")},
+		{AccountID: checking.ID, Type: models.TransactionTypeExpense, Amount: 100, Description: "Dentista", Date: forecastDate("2026-10-25")},
+		// A 200 bill closed on October 10th, partially paid, then 300 spent on the next one
+		{AccountID: card.ID, Type: models.TransactionTypeExpense, Amount: 200, Description: "Mercado", Date: forecastDate("2026-10-01")},
+		{AccountID: card.ID, Type: models.TransactionTypeExpense, Amount: 300, Description: "Viagem", Date: forecastDate("2026-10-12")},
+		{AccountID: card.ID, Type: models.TransactionTypeIncome, Amount: 50, Description: "Pagamento parcial", Date: forecastDate("2026-10-15")},
+	}
+	if err := db.Create(&transactions).Error; err != nil {
+		t.Fatalf("Failed to create transactions: %v", err)
+	}
+
+	forecast, err := service.Forecast(user.ID, 30, time.UTC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(forecast.Labels) != 30 || forecast.Labels[0] != "2026-10-19" {
+		t.Fatalf("Expected 30 days from tomorrow, got %v", forecast.Labels)
+	}
+
+	balanceOn := func(account AccountForecast, day string) float64 {
+		for i, label := range forecast.Labels {
+			if label == day {
+				return account.Balances[i]
+			}
+		}
+		t.Fatalf("No balance on %s", day)
+		return 0
+	}
+	var checkingForecast, cardForecast AccountForecast
+	for _, account := range forecast.Accounts {
+		switch account.AccountID {
+		case checking.ID:
+			checkingForecast = account
+		case card.ID:
+			cardForecast = account
+		}
+	}
+
+	if checkingForecast.CurrentBalance != 500 {
+		t.Errorf("Expected the scheduled expense out of the current balance, got %v", checkingForecast.CurrentBalance)
+	}
+	// The rest of the closed bill is paid on its due date
+	if balanceOn(checkingForecast, "2026-10-20") != 350 || balanceOn(cardForecast, "2026-10-20") != -300 {
+		t.Errorf("Expected the 150 left of the bill to be paid on the 20th, got %v and %v", balanceOn(checkingForecast, "2026-10-20"), balanceOn(cardForecast, "2026-10-20"))
+	}
+	if balanceOn(checkingForecast, "2026-10-25") != 250 || balanceOn(checkingForecast, "2026-11-05") != -550 {
+		t.Errorf("Expected the scheduled expense and the rent, got %v", checkingForecast.Balances)
+	}
+	if checkingForecast.FirstNegativeDate == nil || *checkingForecast.FirstNegativeDate != "2026-11-05" {
+		t.Errorf("Expected the first negative date to be 2026-11-05, got %v", checkingForecast.FirstNegativeDate)
+	}
+	if cardForecast.FirstNegativeDate != nil {
+		t.Errorf("Expected no first negative date for credit cards, got %v", *cardForecast.FirstNegativeDate)
+	}
+}
+
+func TestForecastService_FirstNegativeDateToday(t *testing.T) {
+	db, user := setupForecastServiceTestDB(t)
+	service := NewForecastService(repository.NewAccountRepository(db), repository.NewTransactionRepository(db)).(*forecastService)
+	service.now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	overdrawn := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID, Balance: -10}
+	if err := db.Create(overdrawn).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	forecast, err := service.Forecast(user.ID, 7, time.UTC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if date := forecast.Accounts[0].FirstNegativeDate; date == nil || *date != "2026-10-18" {
+		t.Errorf("Expected an account already negative to be negative today, got %v", date)
+	}
+
+	if _, err := service.Forecast(user.ID, 0, time.UTC); err == nil {
+		t.Error("Expected an error for 0 days")
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/oidc"
+)
+
+// GoogleJWKSURL is where Google publishes the keys that sign its ID tokens
+const GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// googleIssuers are the issuers of Google ID tokens, which have been sent with and without
+// the scheme
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// ExternalIdentity is a user's account at an identity provider, as its verified ID token
+// tells
+type ExternalIdentity struct {
+	Provider string
+	// Subject is the provider's ID of the account
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// IdentityVerifier verifies the ID tokens of an identity provider
+type IdentityVerifier interface {
+	// Verify returns the identity of the ID token, or an error wrapping
+	// errors.ErrUnauthorized when the token isn't valid or its email isn't verified
+	Verify(ctx context.Context, idToken string) (*ExternalIdentity, error)
+}
+
+type googleVerifier struct {
+	verifier *oidc.Verifier
+}
+
+// NewGoogleVerifier creates an IdentityVerifier of the Google Sign-In credentials issued for
+// the client ID, whose signatures are checked with the key set
+func NewGoogleVerifier(clientID string, keySet oidc.KeySet) IdentityVerifier {
+	return &googleVerifier{verifier: oidc.NewVerifier(keySet, clientID, googleIssuers...)}
+}
+
+func (v *googleVerifier) Verify(ctx context.Context, idToken string) (*ExternalIdentity, error) {
+	token, err := v.verifier.Verify(ctx, idToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrUnauthorized, err)
+	}
+	// Only a verified email is known to belong to whoever logged in
+	if token.Email == "" || !token.EmailVerified {
+		return nil, fmt.Errorf("%w: email not verified", errors.ErrUnauthorized)
+	}
+	return &ExternalIdentity{
+		Provider:      models.IdentityProviderGoogle,
+		Subject:       token.Subject,
+		Email:         token.Email,
+		EmailVerified: token.EmailVerified,
+		Name:          token.Name,
+	}, nil
+}
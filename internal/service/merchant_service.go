@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/merchants"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+type MerchantService interface {
+	ListMerchants(ctx context.Context, userID uint) ([]models.Merchant, error)
+	// CreateAlias names the merchant of every description containing pattern and assigns the
+	// merchants of the user's transactions again
+	CreateAlias(ctx context.Context, userID uint, pattern string, name string) (*models.MerchantAlias, error)
+	DeleteAlias(ctx context.Context, id uint, userID uint) error
+	// Normalize assigns the merchants of all the user's transactions again, returning how many
+	// transactions changed merchant
+	Normalize(ctx context.Context, userID uint) (int, error)
+}
+
+type merchantService struct {
+	merchantRepo    repository.MerchantRepository
+	transactionRepo repository.TransactionRepository
+}
+
+func NewMerchantService(merchantRepo repository.MerchantRepository, transactionRepo repository.TransactionRepository) MerchantService {
+	return &merchantService{merchantRepo: merchantRepo, transactionRepo: transactionRepo}
+}
+
+func (s *merchantService) ListMerchants(ctx context.Context, userID uint) ([]models.Merchant, error) {
+	return s.merchantRepo.FindByUserID(ctx, userID)
+}
+
+func (s *merchantService) CreateAlias(ctx context.Context, userID uint, pattern string, name string) (*models.MerchantAlias, error) {
+	pattern, name = strings.TrimSpace(pattern), strings.TrimSpace(name)
+	if merchants.Key(pattern) == "" || name == "" {
+		return nil, errors.NewValidationError("pattern and name are required")
+	}
+
+	aliases, err := s.merchantRepo.FindAliases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		if merchants.Key(alias.Pattern) == merchants.Key(pattern) {
+			return nil, errors.NewValidationError("an alias with this pattern already exists")
+		}
+	}
+
+	merchant := &models.Merchant{UserID: userID, Key: merchants.Key(name), Name: name}
+	if err := s.merchantRepo.FindOrCreate(ctx, merchant); err != nil {
+		return nil, err
+	}
+	// The alias spells the name the way the user wants it, e.g. "iFood" for a detected "Ifood"
+	if merchant.Name != name {
+		merchant.Name = name
+		if err := s.merchantRepo.Update(ctx, merchant); err != nil {
+			return nil, err
+		}
+	}
+	alias := &models.MerchantAlias{UserID: userID, MerchantID: merchant.ID, Pattern: pattern}
+	if err := s.merchantRepo.CreateAlias(ctx, alias); err != nil {
+		return nil, err
+	}
+	alias.Merchant = merchant
+
+	if _, err := s.Normalize(ctx, userID); err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+func (s *merchantService) DeleteAlias(ctx context.Context, id uint, userID uint) error {
+	if err := s.merchantRepo.DeleteAlias(ctx, id, userID); err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NewNotFoundError("alias not found")
+		}
+		return err
+	}
+	_, err := s.Normalize(ctx, userID)
+	return err
+}
+
+// Normalize only assigns the transactions of the user's own accounts. Transactions of accounts
+// shared with the user keep the merchants of the account owner.
+func (s *merchantService) Normalize(ctx context.Context, userID uint) (int, error) {
+	assigner, err := newMerchantAssigner(ctx, s.merchantRepo, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	changes := make(map[uint]*uint)
+	for _, tx := range transactions {
+		if tx.Account.UserID != userID {
+			continue
+		}
+		merchantID, err := assigner.merchantID(ctx, tx.Description)
+		if err != nil {
+			return 0, err
+		}
+		if !sameMerchant(merchantID, tx.MerchantID) {
+			changes[tx.ID] = merchantID
+		}
+	}
+
+	if err := s.merchantRepo.SetTransactionMerchants(ctx, changes); err != nil {
+		return 0, err
+	}
+	if err := s.merchantRepo.DeleteUnused(ctx, userID); err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
+
+// merchantAssigner finds the merchants of a user's descriptions, creating the merchants the
+// user doesn't have yet
+type merchantAssigner struct {
+	merchantRepo repository.MerchantRepository
+	userID       uint
+	normalizer   *merchants.Normalizer
+	ids          map[string]uint
+}
+
+func newMerchantAssigner(ctx context.Context, merchantRepo repository.MerchantRepository, userID uint) (*merchantAssigner, error) {
+	normalizer, err := merchantNormalizer(ctx, merchantRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &merchantAssigner{merchantRepo: merchantRepo, userID: userID, normalizer: normalizer, ids: make(map[string]uint)}, nil
+}
+
+// merchantID returns nil for descriptions without a name in them
+func (a *merchantAssigner) merchantID(ctx context.Context, description string) (*uint, error) {
+	name := a.normalizer.Name(description)
+	key := merchants.Key(name)
+	if key == "" {
+		return nil, nil
+	}
+	if id, ok := a.ids[key]; ok {
+		return &id, nil
+	}
+	merchant := &models.Merchant{UserID: a.userID, Key: key, Name: name}
+	if err := a.merchantRepo.FindOrCreate(ctx, merchant); err != nil {
+		return nil, err
+	}
+	a.ids[key] = merchant.ID
+	return &merchant.ID, nil
+}
+
+// merchantNormalizer returns a normalizer with the user's aliases
+func merchantNormalizer(ctx context.Context, merchantRepo repository.MerchantRepository, userID uint) (*merchants.Normalizer, error) {
+	aliases, err := merchantRepo.FindAliases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	normalizerAliases := make([]merchants.Alias, 0, len(aliases))
+	for _, alias := range aliases {
+		if alias.Merchant != nil {
+			normalizerAliases = append(normalizerAliases, merchants.Alias{Pattern: alias.Pattern, Name: alias.Merchant.Name})
+		}
+	}
+	return merchants.NewNormalizer(normalizerAliases), nil
+}
+
+func sameMerchant(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupMerchantServiceTestDB(t *testing.T) (*gorm.DB, *models.Account, MerchantService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{}, &models.MerchantAlias{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user and account
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	account := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create test account: %v", err)
+	}
+
+	service := NewMerchantService(repository.NewMerchantRepository(db), repository.NewTransactionRepository(db))
+	return db, account, service
+}
+
+func TestMerchantService_Normalize(t *testing.T) {
+	db, account, service := setupMerchantServiceTestDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		// builtIn is the merchant from the Brazilian payment prefixes alone
+		builtIn string
+		// aliased is the merchant once the user has aliases
+		aliased string
+	}{
+		{"PIX ENVIADO FULANO DE TAL", "Fulano de Tal", "Fulano de Tal"},
+		{"IFOOD *IFOOD", "Ifood", "iFood"},
+		{"COMPRA CARTAO 1234 IFOOD MERCADO", "Ifood Mercado", "iFood"},
+		{"COMPRA CARTAO 1234 PADARIA", "Padaria", "Padaria"},
+		{"COMPRA CARTAO 5678 PADARIA PAO QUENTE", "Padaria Pao Quente", "Padaria Pão Quente"},
+		{"123456", "", ""},
+	}
+	transactions := make([]*models.Transaction, len(tests))
+	for i, tt := range tests {
+		transactions[i] = &models.Transaction{AccountID: account.ID, Description: tt.description, Amount: 10, Type: models.TransactionTypeExpense, Date: time.Now()}
+		if err := db.Create(transactions[i]).Error; err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+	check := func(t *testing.T, aliased bool) {
+		for i, tt := range tests {
+			var stored models.Transaction
+			if err := db.Preload("Merchant").First(&stored, transactions[i].ID).Error; err != nil {
+				t.Fatalf("Failed to find transaction: %v", err)
+			}
+			want := tt.builtIn
+			if aliased {
+				want = tt.aliased
+			}
+			got := ""
+			if stored.Merchant != nil {
+				got = stored.Merchant.Name
+			}
+			if got != want {
+				t.Errorf("Merchant of %q = %q, want %q", tt.description, got, want)
+			}
+			if stored.Description != tt.description {
+				t.Errorf("Expected the raw description %q to be kept, got %q", tt.description, stored.Description)
+			}
+		}
+	}
+
+	changed, err := service.Normalize(ctx, account.UserID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if changed != len(tests)-1 {
+		t.Errorf("Expected %d transactions to get a merchant, got %d", len(tests)-1, changed)
+	}
+	check(t, false)
+
+	// The user's aliases win over the built-in names
+	alias, err := service.CreateAlias(ctx, account.UserID, "ifood", "iFood")
+	if err != nil {
+		t.Fatalf("Failed to create alias: %v", err)
+	}
+	if _, err := service.CreateAlias(ctx, account.UserID, "padaria pão", "Padaria Pão Quente"); err != nil {
+		t.Fatalf("Failed to create alias: %v", err)
+	}
+	check(t, true)
+	if _, err := service.CreateAlias(ctx, account.UserID, "PADARIA PAO", "Outra Padaria"); err == nil {
+		t.Error("Expected a pattern the user already has to be refused")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+
+	// Normalizing again changes nothing
+	if changed, err := service.Normalize(ctx, account.UserID); err != nil || changed != 0 {
+		t.Errorf("Expected no changes, got %d and %v", changed, err)
+	}
+
+	// Without the alias, other descriptions get their built-in names back, while the
+	// merchant keeps the user's spelling
+	if err := service.DeleteAlias(ctx, alias.ID, account.UserID); err != nil {
+		t.Fatalf("Failed to delete alias: %v", err)
+	}
+	names := make(map[string]bool)
+	merchants, err := service.ListMerchants(ctx, account.UserID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, merchant := range merchants {
+		names[merchant.Name] = true
+	}
+	if len(names) != 5 || !names["iFood"] || !names["Ifood Mercado"] {
+		t.Errorf("Expected iFood and Ifood Mercado, got %v", names)
+	}
+}
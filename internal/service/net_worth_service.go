@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// NetWorthQuery selects the range and bucket size of the net worth history. Without a range
+// the last 30 days or 12 months up to today are returned. Buckets are days or months of
+// Location, or UTC when it's nil.
+type NetWorthQuery struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	Period    repository.StatisticsPeriod
+	Location  *time.Location
+}
+
+// NetWorthAccount is the balance of an account at the end of every bucket
+type NetWorthAccount struct {
+	AccountID uint               `json:"account_id"`
+	Name      string             `json:"name"`
+	Type      models.AccountType `json:"type"`
+	Liability bool               `json:"liability"`
+	IsActive  bool               `json:"is_active"`
+	Balances  []float64          `json:"balances"`
+}
+
+// NetWorth is a balance history aligned with Labels. Liabilities are the amounts owed on
+// credit cards, so NetWorth is Assets minus Liabilities.
+type NetWorth struct {
+	Labels      []string          `json:"labels"`
+	Assets      []float64         `json:"assets"`
+	Liabilities []float64         `json:"liabilities"`
+	NetWorth    []float64         `json:"net_worth"`
+	Accounts    []NetWorthAccount `json:"accounts"`
+}
+
+type NetWorthService interface {
+	// NetWorthHistory reconstructs the balance of every account of the user over time
+	NetWorthHistory(ctx context.Context, userID uint, query NetWorthQuery) (*NetWorth, error)
+}
+
+type netWorthService struct {
+	accountRepo    repository.AccountRepository
+	statisticsRepo repository.StatisticsRepository
+	now            func() time.Time
+}
+
+func NewNetWorthService(accountRepo repository.AccountRepository, statisticsRepo repository.StatisticsRepository) NetWorthService {
+	return &netWorthService{accountRepo: accountRepo, statisticsRepo: statisticsRepo, now: time.Now}
+}
+
+func (s *netWorthService) NetWorthHistory(ctx context.Context, userID uint, query NetWorthQuery) (*NetWorth, error) {
+	period := query.Period
+	if period == "" {
+		period = repository.StatisticsPeriodMonth
+	}
+	if period != repository.StatisticsPeriodDay && period != repository.StatisticsPeriodMonth {
+		return nil, errors.NewValidationError("invalid period, expected day or month")
+	}
+
+	loc := query.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := s.now().In(loc)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if query.EndDate != nil {
+		end = *query.EndDate
+	}
+	var start time.Time
+	switch {
+	case query.StartDate != nil:
+		start = *query.StartDate
+	case period == repository.StatisticsPeriodDay:
+		start = end.AddDate(0, 0, -29)
+	default:
+		start = end.AddDate(0, -11, 0)
+	}
+	if start.After(end) {
+		return nil, errors.NewValidationError("start date must be before end date")
+	}
+	labels := periodLabels(start, end, period, loc)
+
+	accounts, err := s.accountRepo.FindByUserIDIncludingSharedAndDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	accountIDs := make([]uint, len(accounts))
+	for i, account := range accounts {
+		accountIDs[i] = account.ID
+	}
+
+	rows, err := s.statisticsRepo.BalanceChanges(ctx, accountIDs, &end, period, loc)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[uint][]repository.BalanceChangeRow, len(accounts))
+	for _, row := range rows {
+		changes[row.AccountID] = append(changes[row.AccountID], row)
+	}
+
+	result := &NetWorth{
+		Labels:      labels,
+		Assets:      make([]float64, len(labels)),
+		Liabilities: make([]float64, len(labels)),
+		NetWorth:    make([]float64, len(labels)),
+		Accounts:    make([]NetWorthAccount, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		created := periodLabel(account.CreatedAt, period, loc)
+		deleted := ""
+		if account.DeletedAt.Valid {
+			deleted = periodLabel(account.DeletedAt.Time, period, loc)
+		}
+
+		history := NetWorthAccount{
+			AccountID: account.ID,
+			Name:      account.Name,
+			Type:      account.Type,
+			Liability: account.Type == models.AccountTypeCredit,
+			IsActive:  !account.DeletedAt.Valid,
+			Balances:  make([]float64, len(labels)),
+		}
+
+		// Rows are sorted by label, so the balance is accumulated while walking the buckets
+		balance := 0.0
+		next := 0
+		accountRows := changes[account.ID]
+		for i, label := range labels {
+			for next < len(accountRows) && accountRows[next].Label <= label {
+				balance += accountRows[next].Amount
+				next++
+			}
+			if deleted != "" && label >= deleted {
+				continue
+			}
+			value := balance
+			if label >= created {
+				value += account.InitialBalance
+			}
+			value = math.Round(value*100) / 100
+			history.Balances[i] = value
+
+			if history.Liability {
+				result.Liabilities[i] -= value
+			} else {
+				result.Assets[i] += value
+			}
+		}
+		result.Accounts = append(result.Accounts, history)
+	}
+
+	for i := range labels {
+		result.Assets[i] = math.Round(result.Assets[i]*100) / 100
+		result.Liabilities[i] = math.Round(result.Liabilities[i]*100) / 100
+		result.NetWorth[i] = math.Round((result.Assets[i]-result.Liabilities[i])*100) / 100
+	}
+	return result, nil
+}
+
+// periodLabels returns the label of every day or month from start to end, inclusive
+func periodLabels(start, end time.Time, period repository.StatisticsPeriod, loc *time.Location) []string {
+	labels := make([]string, 0)
+	start = start.In(loc)
+	if period == repository.StatisticsPeriodMonth {
+		month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, loc)
+		for !month.After(end) {
+			labels = append(labels, periodLabel(month, period, loc))
+			month = month.AddDate(0, 1, 0)
+		}
+		return labels
+	}
+
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	for !day.After(end) {
+		labels = append(labels, periodLabel(day, period, loc))
+		day = day.AddDate(0, 0, 1)
+	}
+	return labels
+}
+
+// periodLabel formats a date the same way the database buckets it
+func periodLabel(date time.Time, period repository.StatisticsPeriod, loc *time.Location) string {
+	if period == repository.StatisticsPeriodMonth {
+		return date.In(loc).Format("2006-01")
+	}
+	return date.In(loc).Format("2006-01-02")
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/oidc"
+)
+
+// oidcLoginDuration is how long users have to log in at the provider and come back
+const oidcLoginDuration = 10 * time.Minute
+
+// OIDCProvider is an OpenID Connect provider users can log in with, such as a self-hosted
+// Keycloak or Authentik
+type OIDCProvider struct {
+	// ID names the provider in URLs and in the identities it links to users
+	ID string
+	// Name is shown to users on the login button
+	Name   string
+	Client *oidc.Client
+}
+
+// OIDCAuthorization is a started login with a provider: the URL the user logs in at and the
+// state token the browser keeps until the provider sends them back
+type OIDCAuthorization struct {
+	URL        string
+	StateToken string
+	ExpiresAt  time.Time
+}
+
+type OIDCLoginService interface {
+	// Providers returns the configured providers
+	Providers() []OIDCProvider
+	// Authorize starts a login with the provider, for a new user registered by it to be
+	// seeded with the category template. An unknown provider returns a NotFoundError.
+	Authorize(ctx context.Context, providerID, categoryTemplate string) (*OIDCAuthorization, error)
+	// Login completes a login with the provider with the authorization code and state it sent
+	// the user back with, which must match the state token of the authorization. The
+	// provider's identity logs in like UserService.LoginWithIdentity. Invalid states, codes
+	// and ID tokens return errors wrapping errors.ErrUnauthorized.
+	Login(ctx context.Context, providerID, code, state, stateToken string, client SessionClient) (*LoginResult, error)
+}
+
+type oidcLoginService struct {
+	providers   []OIDCProvider
+	userService UserService
+	jwtManager  *auth.JWTManager
+}
+
+func NewOIDCLoginService(providers []OIDCProvider, userService UserService, jwtManager *auth.JWTManager) OIDCLoginService {
+	return &oidcLoginService{
+		providers:   providers,
+		userService: userService,
+		jwtManager:  jwtManager,
+	}
+}
+
+func (s *oidcLoginService) Providers() []OIDCProvider {
+	return s.providers
+}
+
+func (s *oidcLoginService) provider(id string) (*OIDCProvider, error) {
+	for i := range s.providers {
+		if s.providers[i].ID == id {
+			return &s.providers[i], nil
+		}
+	}
+	return nil, errors.NewNotFoundError("login provider not found")
+}
+
+func (s *oidcLoginService) Authorize(ctx context.Context, providerID, categoryTemplate string) (*OIDCAuthorization, error) {
+	provider, err := s.provider(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := auth.OIDCStateClaims{Provider: provider.ID, CategoryTemplate: categoryTemplate}
+	for _, value := range []*string{&claims.State, &claims.Nonce, &claims.CodeVerifier} {
+		if *value, err = oidc.RandomValue(); err != nil {
+			return nil, fmt.Errorf("generating login state: %w", err)
+		}
+	}
+
+	url, err := provider.Client.AuthCodeURL(ctx, claims.State, claims.Nonce, claims.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("starting %s login: %w", provider.ID, err)
+	}
+	stateToken, expiresAt, err := s.jwtManager.GenerateOIDCStateToken(claims, oidcLoginDuration)
+	if err != nil {
+		return nil, fmt.Errorf("generating login state: %w", err)
+	}
+
+	return &OIDCAuthorization{URL: url, StateToken: stateToken, ExpiresAt: expiresAt}, nil
+}
+
+func (s *oidcLoginService) Login(ctx context.Context, providerID, code, state, stateToken string, client SessionClient) (*LoginResult, error) {
+	provider, err := s.provider(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The state token ties the callback to the login this browser started, so logins can't be
+	// forced on the user with someone else's code
+	claims, err := s.jwtManager.VerifyOIDCStateToken(stateToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid login state: %v", errors.ErrUnauthorized, err)
+	}
+	if claims.Provider != provider.ID || claims.State != state {
+		return nil, fmt.Errorf("%w: login state mismatch", errors.ErrUnauthorized)
+	}
+
+	token, err := provider.Client.Exchange(ctx, code, claims.CodeVerifier, claims.Nonce)
+	if err != nil {
+		if stdErrors.Is(err, oidc.ErrExchangeFailed) || stdErrors.Is(err, oidc.ErrInvalidToken) {
+			return nil, fmt.Errorf("%w: %v", errors.ErrUnauthorized, err)
+		}
+		return nil, fmt.Errorf("completing %s login: %w", provider.ID, err)
+	}
+
+	log.Printf("[OIDCLoginService] Login: %s identity authenticated", provider.ID)
+	return s.userService.LoginWithIdentity(&ExternalIdentity{
+		Provider:      provider.ID,
+		Subject:       token.Subject,
+		Email:         token.Email,
+		EmailVerified: token.EmailVerified,
+		Name:          token.Name,
+	}, claims.CategoryTemplate, client)
+}
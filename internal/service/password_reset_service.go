@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/mailer"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+const (
+	// passwordResetTokenDuration is how long an emailed reset link works
+	passwordResetTokenDuration = time.Hour
+	// passwordResetSendTimeout is how long sending a reset email can take
+	passwordResetSendTimeout = time.Minute
+)
+
+type PasswordResetService interface {
+	// Request emails a reset link to the user with the email, if there's one, replacing the
+	// links sent before. Whether there's such a user isn't returned, so the response to the
+	// client can't tell which emails are registered.
+	Request(ctx context.Context, email string) error
+	// Reset sets a new password for the user of the reset token, which can only be used once,
+	// unlocks their logins and revokes all their sessions. An unknown, used or expired token
+	// returns a ValidationError.
+	Reset(ctx context.Context, token, newPassword string, client SessionClient) error
+}
+
+type passwordResetService struct {
+	userRepo       repository.UserRepository
+	tokenRepo      repository.PasswordResetTokenRepository
+	auditLogRepo   repository.AuditLogRepository
+	sessionService SessionService
+	mailer         mailer.Mailer
+	// resetURL is the frontend page the emailed link opens, with the token in its query
+	resetURL string
+	now      func() time.Time
+}
+
+func NewPasswordResetService(userRepo repository.UserRepository, tokenRepo repository.PasswordResetTokenRepository, auditLogRepo repository.AuditLogRepository, sessionService SessionService, mailer mailer.Mailer, resetURL string) PasswordResetService {
+	return &passwordResetService{
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		auditLogRepo:   auditLogRepo,
+		sessionService: sessionService,
+		mailer:         mailer,
+		resetURL:       resetURL,
+		now:            time.Now,
+	}
+}
+
+func (s *passwordResetService) Request(ctx context.Context, email string) error {
+	// Unknown emails generate a token and write to the tokens too, cleaning up the expired
+	// ones, so the response takes about as long for registered emails as for unknown ones
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hash := auth.HashToken(token)
+	now := s.now()
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			if err := s.tokenRepo.DeleteExpired(ctx, now); err != nil {
+				log.Printf("[PasswordResetService] Request: Error deleting expired reset tokens: %v", err)
+			}
+			return nil
+		}
+		return err
+	}
+
+	resetToken := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(passwordResetTokenDuration),
+	}
+	if err := s.tokenRepo.Replace(ctx, resetToken); err != nil {
+		return err
+	}
+
+	// The email is sent in the background, since sending it takes much longer than anything
+	// unknown emails could do
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Dinheiros password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your Dinheiros account. Open the link below to choose a new password:\n\n"+
+			"%s\n\n"+
+			"The link works once, for the next %d minutes. If you didn't ask for it, you can ignore this email and your password won't change.\n",
+			user.Name, s.resetLink(token), int(passwordResetTokenDuration.Minutes())),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("[PasswordResetService] Request: Error sending reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// resetLink returns the link to the frontend page that resets the password with the token
+func (s *passwordResetService) resetLink(token string) string {
+	return s.resetURL + "?token=" + url.QueryEscape(token)
+}
+
+func (s *passwordResetService) Reset(ctx context.Context, token, newPassword string, client SessionClient) error {
+	now := s.now()
+	resetToken, err := s.tokenRepo.Use(ctx, auth.HashToken(token), now)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return errors.NewValidationError("invalid or expired reset token")
+		}
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(resetToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Whoever has the link controls the email, so the logins locked by wrong passwords are
+	// unlocked too
+	user.Password = newPassword
+	if err := user.HashPassword(); err != nil {
+		return stdErrors.New("error hashing new password")
+	}
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return stdErrors.New("error updating password")
+	}
+
+	if _, err := s.sessionService.RevokeAll(ctx, user.ID, 0); err != nil {
+		log.Printf("[PasswordResetService] Reset: Error revoking sessions for user %d: %v", user.ID, err)
+		return stdErrors.New("error revoking sessions")
+	}
+
+	entry := &models.AuditLog{
+		UserID:    user.ID,
+		Action:    models.AuditActionPasswordReset,
+		Details:   "password reset with an emailed link",
+		UserAgent: truncateUserAgent(client.UserAgent),
+		IPAddress: client.IPAddress,
+	}
+	if err := s.auditLogRepo.Create(ctx, entry); err != nil {
+		log.Printf("[PasswordResetService] Reset: Error saving audit entry for user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/mailer"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// recordingMailer hands the emails it's asked to send to the test
+type recordingMailer struct {
+	sent chan mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// resetLinkToken returns the token of the reset link in an email
+func (m *recordingMailer) resetLinkToken(t *testing.T) string {
+	select {
+	case msg := <-m.sent:
+		link := regexp.MustCompile(`https?://\S+`).FindString(msg.Body)
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Query().Get("token") == "" {
+			t.Fatalf("Expected a reset link in the email, got %q", msg.Body)
+		}
+		return parsed.Query().Get("token")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a reset email")
+		return ""
+	}
+}
+
+func setupPasswordResetServiceTestDB(t *testing.T) (*gorm.DB, *models.User, *passwordResetService, *recordingMailer) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.AuditLog{}, &models.PasswordResetToken{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{Name: "Test User", Email: "test@example.com", Password: "old-password"}
+	if err := user.HashPassword(); err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	sessionService := NewSessionService(sessionRepo, userRepo, auth.NewJWTManager("test-secret", 15*time.Minute), time.Hour)
+	recorder := &recordingMailer{sent: make(chan mailer.Message, 10)}
+	service := NewPasswordResetService(userRepo, repository.NewPasswordResetTokenRepository(db), repository.NewAuditLogRepository(db), sessionService, recorder, "http://localhost:8080/reset-password").(*passwordResetService)
+
+	return db, user, service, recorder
+}
+
+func TestPasswordResetService_RequestAndReset(t *testing.T) {
+	db, user, service, recorder := setupPasswordResetServiceTestDB(t)
+	ctx := context.Background()
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	// Logged in elsewhere and locked by wrong passwords
+	if _, err := service.sessionService.Start(ctx, user, client, false); err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	lockedUntil := time.Now().Add(time.Hour)
+	if err := db.Model(user).Updates(map[string]interface{}{"failed_login_attempts": 5, "locked_until": lockedUntil}).Error; err != nil {
+		t.Fatalf("Failed to lock user: %v", err)
+	}
+
+	if err := service.Request(ctx, user.Email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	token := recorder.resetLinkToken(t)
+
+	if err := service.Reset(ctx, token, "new-password", client); err != nil {
+		t.Fatalf("Expected the reset to work, got %v", err)
+	}
+	stored, err := repository.NewUserRepository(db).FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to find user: %v", err)
+	}
+	if stored.CheckPassword("new-password") != nil {
+		t.Error("Expected the new password to be set")
+	}
+	if stored.FailedLoginAttempts != 0 || stored.LockedUntil != nil {
+		t.Errorf("Expected the logins to be unlocked, got %d attempts locked until %v", stored.FailedLoginAttempts, stored.LockedUntil)
+	}
+	if sessions, _ := service.sessionService.List(ctx, user.ID); len(sessions) != 0 {
+		t.Errorf("Expected the sessions to be revoked, got %+v", sessions)
+	}
+
+	// The link only works once
+	var validation *errors.ValidationError
+	if err := service.Reset(ctx, token, "another-password", client); !stdErrors.As(err, &validation) {
+		t.Errorf("Expected a used link to be invalid, got %v", err)
+	}
+}
+
+func TestPasswordResetService_RequestReplacesLinks(t *testing.T) {
+	db, user, service, recorder := setupPasswordResetServiceTestDB(t)
+	ctx := context.Background()
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	if err := service.Request(ctx, user.Email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	first := recorder.resetLinkToken(t)
+	if err := service.Request(ctx, user.Email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second := recorder.resetLinkToken(t)
+
+	if err := service.Reset(ctx, first, "new-password", client); err == nil {
+		t.Error("Expected the first link to be replaced")
+	}
+	if err := service.Reset(ctx, second, "new-password", client); err != nil {
+		t.Errorf("Expected the latest link to work, got %v", err)
+	}
+
+	// Unknown emails get no email, and clean up the used and expired links
+	if err := service.Request(ctx, "unknown@example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case msg := <-recorder.sent:
+		t.Errorf("Expected no email to unknown addresses, got %+v", msg)
+	default:
+	}
+	var tokens int64
+	db.Model(&models.PasswordResetToken{}).Count(&tokens)
+	if tokens != 0 {
+		t.Errorf("Expected the used link to be cleaned up, got %d links", tokens)
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, which tells them apart from
+// the JWT access tokens sent in the same header
+const PersonalAccessTokenPrefix = "dnh_"
+
+const (
+	maxTokenNameLength = 100
+	// tokenDisplayLength is how much of a token is kept to show in the list of tokens
+	tokenDisplayLength = len(PersonalAccessTokenPrefix) + 8
+	// tokenTouchInterval is how often the last use of a token is saved, so scripts making many
+	// requests don't write to the database on each one
+	tokenTouchInterval = time.Minute
+)
+
+// CreatePersonalAccessToken is what a new personal access token gives access to
+type CreatePersonalAccessToken struct {
+	Name   string
+	Scopes []models.TokenScope
+	// AccountID restricts the token to one account the user can access when set
+	AccountID *uint
+	// ExpiresAt is nil for tokens that don't expire
+	ExpiresAt *time.Time
+}
+
+type PersonalAccessTokenService interface {
+	// Create creates a token for the user, returning it with the token itself, which can't be
+	// retrieved again
+	Create(ctx context.Context, userID uint, input CreatePersonalAccessToken) (*models.PersonalAccessToken, string, error)
+	// List returns the user's tokens, including expired ones, newest first
+	List(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error)
+	Revoke(ctx context.Context, userID, tokenID uint) error
+	// Authenticate returns the personal access token, recording that it was used, or
+	// errors.ErrUnauthorized when it doesn't exist or expired
+	Authenticate(ctx context.Context, token string) (*models.PersonalAccessToken, error)
+}
+
+type personalAccessTokenService struct {
+	tokenRepo   repository.PersonalAccessTokenRepository
+	accountRepo repository.AccountRepository
+	now         func() time.Time
+}
+
+func NewPersonalAccessTokenService(tokenRepo repository.PersonalAccessTokenRepository, accountRepo repository.AccountRepository) PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		tokenRepo:   tokenRepo,
+		accountRepo: accountRepo,
+		now:         time.Now,
+	}
+}
+
+func (s *personalAccessTokenService) Create(ctx context.Context, userID uint, input CreatePersonalAccessToken) (*models.PersonalAccessToken, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, "", errors.NewValidationError("name is required")
+	}
+	if len(name) > maxTokenNameLength {
+		return nil, "", errors.NewValidationError(fmt.Sprintf("name must have at most %d characters", maxTokenNameLength))
+	}
+
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := s.now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, "", errors.NewValidationError("expires_at must be in the future")
+	}
+	if input.AccountID != nil {
+		hasAccess, err := s.accountRepo.HasAccess(*input.AccountID, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		if !hasAccess {
+			return nil, "", errors.NewNotFoundError("account not found")
+		}
+	}
+
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := PersonalAccessTokenPrefix + secret
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(plain),
+		Prefix:    plain[:tokenDisplayLength],
+		AccountID: input.AccountID,
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, "", err
+	}
+	return token, plain, nil
+}
+
+func (s *personalAccessTokenService) List(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error) {
+	return s.tokenRepo.FindByUserID(ctx, userID)
+}
+
+func (s *personalAccessTokenService) Revoke(ctx context.Context, userID, tokenID uint) error {
+	if err := s.tokenRepo.Delete(ctx, userID, tokenID); err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return errors.NewNotFoundError("token not found")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *personalAccessTokenService) Authenticate(ctx context.Context, plain string) (*models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(plain, PersonalAccessTokenPrefix) {
+		return nil, errors.ErrUnauthorized
+	}
+	token, err := s.tokenRepo.FindByHash(ctx, auth.HashToken(plain))
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, errors.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	now := s.now()
+	if token.Expired(now) {
+		return nil, errors.ErrUnauthorized
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= tokenTouchInterval {
+		if err := s.tokenRepo.Touch(ctx, token.ID, now); err != nil {
+			log.Printf("[PersonalAccessTokenService] Error recording use of token %d: %v", token.ID, err)
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
+
+// normalizeScopes validates the scopes, dropping duplicates and keeping the order of
+// models.TokenScopes
+func normalizeScopes(scopes []models.TokenScope) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.NewValidationError("at least one scope is required")
+	}
+	requested := make(map[models.TokenScope]bool, len(scopes))
+	for _, scope := range scopes {
+		requested[scope] = true
+	}
+
+	normalized := make([]string, 0, len(requested))
+	for _, scope := range models.TokenScopes {
+		if requested[scope] {
+			normalized = append(normalized, string(scope))
+			delete(requested, scope)
+		}
+	}
+	for scope := range requested {
+		return nil, errors.NewValidationError(fmt.Sprintf("unknown scope %q", scope))
+	}
+	return normalized, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupPersonalAccessTokenServiceTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.PersonalAccessToken{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestPersonalAccessTokenService_Authenticate(t *testing.T) {
+	db, user := setupPersonalAccessTokenServiceTestDB(t)
+	tokenRepo := repository.NewPersonalAccessTokenRepository(db)
+	service := NewPersonalAccessTokenService(tokenRepo, repository.NewAccountRepository(db)).(*personalAccessTokenService)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expiresAt := now.Add(time.Hour)
+	created, plain, err := service.Create(ctx, user.ID, CreatePersonalAccessToken{Name: "Script", Scopes: []models.TokenScope{models.ScopeReadTransactions}, ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	lastUsedAt := func() *time.Time {
+		stored, err := tokenRepo.FindByHash(ctx, created.TokenHash)
+		if err != nil {
+			t.Fatalf("Failed to find token: %v", err)
+		}
+		return stored.LastUsedAt
+	}
+	if lastUsedAt() != nil {
+		t.Fatal("Expected a new token not to be used yet")
+	}
+
+	// The first use is recorded
+	token, err := service.Authenticate(ctx, plain)
+	if err != nil {
+		t.Fatalf("Expected the token to authenticate, got %v", err)
+	}
+	if token.ID != created.ID || token.LastUsedAt == nil || !token.LastUsedAt.Equal(now) {
+		t.Errorf("Expected the token used now, got %+v", token)
+	}
+	if used := lastUsedAt(); used == nil || !used.Equal(now) {
+		t.Errorf("Expected the use to be saved, got %v", used)
+	}
+
+	// Uses within a minute aren't saved again
+	first := now
+	now = now.Add(30 * time.Second)
+	if _, err := service.Authenticate(ctx, plain); err != nil {
+		t.Fatalf("Expected the token to authenticate, got %v", err)
+	}
+	if used := lastUsedAt(); used == nil || !used.Equal(first) {
+		t.Errorf("Expected the last use to stay at %v, got %v", first, used)
+	}
+	now = first.Add(tokenTouchInterval)
+	if _, err := service.Authenticate(ctx, plain); err != nil {
+		t.Fatalf("Expected the token to authenticate, got %v", err)
+	}
+	if used := lastUsedAt(); used == nil || !used.Equal(now) {
+		t.Errorf("Expected the last use to move to %v, got %v", now, used)
+	}
+
+	// Expired and unknown tokens are unauthorized
+	used := now
+	now = expiresAt
+	if _, err := service.Authenticate(ctx, plain); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Errorf("Expected the expired token to be unauthorized, got %v", err)
+	}
+	if stored := lastUsedAt(); stored == nil || !stored.Equal(used) {
+		t.Errorf("Expected the expired token's use not to be recorded, got %v", stored)
+	}
+	if _, err := service.Authenticate(ctx, PersonalAccessTokenPrefix+"unknown"); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Errorf("Expected an unknown token to be unauthorized, got %v", err)
+	}
+
+	// Tokens can't be created already expired
+	var validation *errors.ValidationError
+	if _, _, err := service.Create(ctx, user.ID, CreatePersonalAccessToken{Name: "Old", Scopes: []models.TokenScope{models.ScopeReadTransactions}, ExpiresAt: &now}); !stdErrors.As(err, &validation) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/backup"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// PrivacyService implements the rights of data subjects under the LGPD: getting a copy of
+// their personal data and having it deleted
+type PrivacyService interface {
+	ExportPersonalData(ctx context.Context, userID uint) (*backup.PersonalData, error)
+	// DeleteAccount permanently removes the user and everything they own once their password
+	// is confirmed, returning errors.ErrUnauthorized when it isn't. Their tokens stop working,
+	// since the user they were issued for no longer exists.
+	DeleteAccount(ctx context.Context, userID uint, password string) error
+}
+
+type privacyService struct {
+	userRepo         repository.UserRepository
+	accountShareRepo *repository.AccountShareRepository
+	alertRepo        repository.AlertRepository
+	sessionRepo      repository.SessionRepository
+	tokenRepo        repository.PersonalAccessTokenRepository
+	auditLogRepo     repository.AuditLogRepository
+	identityRepo     repository.UserIdentityRepository
+	backupService    BackupService
+	now              func() time.Time
+}
+
+func NewPrivacyService(userRepo repository.UserRepository, accountShareRepo *repository.AccountShareRepository, alertRepo repository.AlertRepository, sessionRepo repository.SessionRepository, tokenRepo repository.PersonalAccessTokenRepository, auditLogRepo repository.AuditLogRepository, identityRepo repository.UserIdentityRepository, backupService BackupService) PrivacyService {
+	return &privacyService{userRepo: userRepo, accountShareRepo: accountShareRepo, alertRepo: alertRepo, sessionRepo: sessionRepo, tokenRepo: tokenRepo, auditLogRepo: auditLogRepo, identityRepo: identityRepo, backupService: backupService, now: time.Now}
+}
+
+func (s *privacyService) ExportPersonalData(ctx context.Context, userID uint) (*backup.PersonalData, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.backupService.Backup(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	timezone := user.Timezone
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
+
+	data := &backup.PersonalData{
+		ExportedAt: s.now().UTC(),
+		Profile: backup.Profile{
+			ID:               user.ID,
+			Name:             user.Name,
+			Email:            user.Email,
+			Timezone:         timezone,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+			TwoFactorEnabled: user.TwoFactorEnabled,
+		},
+		Data:         archive,
+		SharedWithMe: []backup.ReceivedShare{},
+		Invitations:  []backup.Invitation{},
+		Alerts:       []backup.Alert{},
+		Sessions:     []backup.Session{},
+		Tokens:       []backup.Token{},
+		AuditLog:     []backup.AuditEntry{},
+		Identities:   []backup.Identity{},
+	}
+
+	shares, err := s.accountShareRepo.GetSharesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		data.SharedWithMe = append(data.SharedWithMe, backup.ReceivedShare{
+			AccountName:     share.Account.Name,
+			OwnerName:       share.OwnerUser.Name,
+			OwnerEmail:      share.OwnerUser.Email,
+			PermissionLevel: share.PermissionLevel,
+			SharedAt:        share.SharedAt,
+		})
+	}
+
+	invitations, err := s.accountShareRepo.GetInvitationsByUser(userID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, invitation := range invitations {
+		data.Invitations = append(data.Invitations, backup.Invitation{
+			AccountName:     invitation.Account.Name,
+			OwnerEmail:      invitation.OwnerUser.Email,
+			InvitedEmail:    invitation.InvitedEmail,
+			PermissionLevel: invitation.PermissionLevel,
+			Status:          invitation.Status,
+			CreatedAt:       invitation.CreatedAt,
+			ExpiresAt:       invitation.ExpiresAt,
+			AcceptedAt:      invitation.AcceptedAt,
+		})
+	}
+
+	alerts, err := s.alertRepo.FindByUserID(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		data.Alerts = append(data.Alerts, backup.Alert{
+			Type:          alert.Type,
+			Message:       alert.Message,
+			Subject:       alert.Subject,
+			TransactionID: alert.TransactionID,
+			Amount:        alert.Amount,
+			Baseline:      alert.Baseline,
+			ReadAt:        alert.ReadAt,
+			DismissedAt:   alert.DismissedAt,
+			CreatedAt:     alert.CreatedAt,
+		})
+	}
+
+	sessions, err := s.sessionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		data.Sessions = append(data.Sessions, backup.Session{
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+
+	tokens, err := s.tokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		data.Tokens = append(data.Tokens, backup.Token{
+			Name:       token.Name,
+			Prefix:     token.Prefix,
+			Scopes:     token.ScopeList(),
+			AccountID:  token.AccountID,
+			ExpiresAt:  token.ExpiresAt,
+			LastUsedAt: token.LastUsedAt,
+			CreatedAt:  token.CreatedAt,
+		})
+	}
+
+	entries, err := s.auditLogRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data.AuditLog = append(data.AuditLog, backup.AuditEntry{
+			Action:    entry.Action,
+			Details:   entry.Details,
+			UserAgent: entry.UserAgent,
+			IPAddress: entry.IPAddress,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	identities, err := s.identityRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		data.Identities = append(data.Identities, backup.Identity{
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			LastLoginAt: identity.LastLoginAt,
+			CreatedAt:   identity.CreatedAt,
+		})
+	}
+	return data, nil
+}
+
+func (s *privacyService) DeleteAccount(ctx context.Context, userID uint, password string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := user.CheckPassword(password); err != nil {
+		return errors.ErrUnauthorized
+	}
+	if err := s.userRepo.DeleteWithData(userID); err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return errors.NewNotFoundError("user not found")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *privacyService) findUser(userID uint) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, errors.NewNotFoundError("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+const (
+	// minRecurringMonths is how many consecutive months a transaction must repeat to be recurring
+	minRecurringMonths = 3
+	// recurringActiveDays is how recent the last occurrence must be for a pattern to still be active
+	recurringActiveDays = 45
+)
+
+// RecurringPattern is a transaction that repeats every month on the same account with
+// the same description and amount
+type RecurringPattern struct {
+	AccountID   uint
+	Description string
+	Type        models.TransactionType
+	Amount      float64
+	DayOfMonth  int
+	Occurrences int
+	LastDate    time.Time
+}
+
+// DetectRecurring finds the monthly patterns in a transaction history that are still active at asOf.
+// Initial balance entries and transactions after asOf are ignored.
+func DetectRecurring(transactions []models.Transaction, asOf time.Time) []RecurringPattern {
+	type recurringKey struct {
+		accountID   uint
+		description string
+		txType      models.TransactionType
+		cents       int64
+	}
+
+	groups := make(map[recurringKey][]models.Transaction)
+	for _, tx := range transactions {
+		if tx.Type == models.TransactionTypeInitial || tx.Date.After(asOf) {
+			continue
+		}
+		key := recurringKey{
+			accountID:   tx.AccountID,
+			description: normalizeRecurringDescription(tx.Description),
+			txType:      tx.Type,
+			cents:       toCents(tx.Amount),
+		}
+		if key.description == "" {
+			continue
+		}
+		groups[key] = append(groups[key], tx)
+	}
+
+	patterns := make([]RecurringPattern, 0)
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+		last := group[len(group)-1]
+		if asOf.Sub(last.Date) > recurringActiveDays*24*time.Hour {
+			continue
+		}
+		if months := consecutiveMonths(group); months < minRecurringMonths {
+			continue
+		}
+		patterns = append(patterns, RecurringPattern{
+			AccountID:   last.AccountID,
+			Description: last.Description,
+			Type:        last.Type,
+			Amount:      last.Amount,
+			DayOfMonth:  last.Date.Day(),
+			Occurrences: len(group),
+			LastDate:    last.Date,
+		})
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].AccountID != patterns[j].AccountID {
+			return patterns[i].AccountID < patterns[j].AccountID
+		}
+		return patterns[i].Description < patterns[j].Description
+	})
+	return patterns
+}
+
+// NextDates returns the dates of the pattern after from and up to until, inclusive
+func (p RecurringPattern) NextDates(from, until time.Time) []time.Time {
+	var dates []time.Time
+	year, month, _ := p.LastDate.Date()
+	for i := 1; ; i++ {
+		date := dateInMonth(year, month+time.Month(i), p.DayOfMonth, p.LastDate.Location())
+		if date.After(until) {
+			return dates
+		}
+		if date.After(from) {
+			dates = append(dates, date)
+		}
+	}
+}
+
+// consecutiveMonths returns the longest run of consecutive months, ending at the most
+// recent transaction, in which the sorted transactions occur
+func consecutiveMonths(sorted []models.Transaction) int {
+	run := 1
+	lastMonth := monthIndex(sorted[len(sorted)-1].Date)
+	for i := len(sorted) - 2; i >= 0; i-- {
+		month := monthIndex(sorted[i].Date)
+		switch lastMonth - month {
+		case 0:
+			continue
+		case 1:
+			run++
+			lastMonth = month
+		default:
+			return run
+		}
+	}
+	return run
+}
+
+func monthIndex(date time.Time) int {
+	return date.Year()*12 + int(date.Month())
+}
+
+// dateInMonth returns the given day of the month, moved back to the last day for shorter months
+func dateInMonth(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, loc)
+}
+
+func normalizeRecurringDescription(description string) string {
+	return strings.Join(strings.Fields(strings.ToLower(description)), " ")
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+)
+
+func recurringTransaction(accountID uint, description string, amount float64, date string) models.Transaction {
+	parsed, _ := time.Parse("2006-01-02", date)
+	return models.Transaction{AccountID: accountID, Description: description, Amount: amount, Type: models.TransactionTypeExpense, Date: parsed}
+}
+
+func TestDetectRecurring(t *testing.T) {
+	asOf := time.Date(2026, 10, 18, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		transactions []models.Transaction
+		expected     []string
+	}{
+		{
+			name: "three consecutive months",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Aluguel", 1500, "2026-08-05"),
+				recurringTransaction(1, "Aluguel", 1500, "2026-09-05"),
+				recurringTransaction(1, "Aluguel", 1500, "2026-10-05"),
+			},
+			expected: []string{"Aluguel"},
+		},
+		{
+			name: "descriptions compared without case and extra spaces",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "NETFLIX  COM", 55.9, "2026-08-10"),
+				recurringTransaction(1, "netflix com", 55.9, "2026-09-10"),
+				recurringTransaction(1, "Netflix Com", 55.9, "2026-10-10"),
+			},
+			expected: []string{"Netflix Com"},
+		},
+		{
+			name: "only two months",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Aluguel", 1500, "2026-09-05"),
+				recurringTransaction(1, "Aluguel", 1500, "2026-10-05"),
+			},
+		},
+		{
+			name: "a missed month breaks the run",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Academia", 99, "2026-06-03"),
+				recurringTransaction(1, "Academia", 99, "2026-07-03"),
+				recurringTransaction(1, "Academia", 99, "2026-09-03"),
+				recurringTransaction(1, "Academia", 99, "2026-10-03"),
+			},
+		},
+		{
+			name: "stopped more than 45 days ago",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Academia", 99, "2026-06-01"),
+				recurringTransaction(1, "Academia", 99, "2026-07-01"),
+				recurringTransaction(1, "Academia", 99, "2026-08-01"),
+			},
+		},
+		{
+			name: "amounts differ",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Luz", 180.3, "2026-08-15"),
+				recurringTransaction(1, "Luz", 201.75, "2026-09-15"),
+				recurringTransaction(1, "Luz", 176.4, "2026-10-15"),
+			},
+		},
+		{
+			name: "different accounts",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Aluguel", 1500, "2026-08-05"),
+				recurringTransaction(2, "Aluguel", 1500, "2026-09-05"),
+				recurringTransaction(1, "Aluguel", 1500, "2026-10-05"),
+			},
+		},
+		{
+			name: "transactions after asOf are ignored",
+			transactions: []models.Transaction{
+				recurringTransaction(1, "Aluguel", 1500, "2026-09-05"),
+				recurringTransaction(1, "Aluguel", 1500, "2026-10-05"),
+				recurringTransaction(1, "Aluguel", 1500, "2026-11-05"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := DetectRecurring(tt.transactions, asOf)
+			if len(patterns) != len(tt.expected) {
+				t.Fatalf("Expected %d patterns, got %+v", len(tt.expected), patterns)
+			}
+			for i, pattern := range patterns {
+				if pattern.Description != tt.expected[i] {
+					t.Errorf("Expected pattern %q, got %q", tt.expected[i], pattern.Description)
+				}
+			}
+		})
+	}
+
+	// Initial balances never recur
+	initial := []models.Transaction{
+		recurringTransaction(1, "Saldo inicial", 100, "2026-08-01"),
+		recurringTransaction(1, "Saldo inicial", 100, "2026-09-01"),
+		recurringTransaction(1, "Saldo inicial", 100, "2026-10-01"),
+	}
+	for i := range initial {
+		initial[i].Type = models.TransactionTypeInitial
+	}
+	if patterns := DetectRecurring(initial, asOf); len(patterns) != 0 {
+		t.Errorf("Expected initial balances to be ignored, got %+v", patterns)
+	}
+}
+
+func TestRecurringPattern_NextDates(t *testing.T) {
+	day := func(date string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", date)
+		return parsed
+	}
+
+	tests := []struct {
+		name       string
+		dayOfMonth int
+		lastDate   string
+		from       string
+		until      string
+		expected   []string
+	}{
+		{"next months", 5, "2026-10-05", "2026-10-18", "2026-12-31", []string{"2026-11-05", "2026-12-05"}},
+		{"moved back in shorter months", 31, "2026-12-31", "2027-01-01", "2027-04-30", []string{"2027-01-31", "2027-02-28", "2027-03-31", "2027-04-30"}},
+		{"until is inclusive", 10, "2026-10-10", "2026-10-18", "2026-11-10", []string{"2026-11-10"}},
+		{"dates up to from are skipped", 10, "2026-08-10", "2026-10-10", "2026-11-30", []string{"2026-11-10"}},
+		{"nothing before until", 25, "2026-10-25", "2026-10-25", "2026-11-20", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := RecurringPattern{DayOfMonth: tt.dayOfMonth, LastDate: day(tt.lastDate)}
+			dates := pattern.NextDates(day(tt.from), day(tt.until))
+			if len(dates) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, dates)
+			}
+			for i, date := range dates {
+				if date.Format("2006-01-02") != tt.expected[i] {
+					t.Errorf("Expected %s, got %s", tt.expected[i], date.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
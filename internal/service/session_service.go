@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"log"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+// maxUserAgentLength is the size of the user agent column
+const maxUserAgentLength = 512
+
+// SessionClient is the device a session was started or refreshed from
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+type SessionService interface {
+	// Start opens a session for the user on the client and returns its tokens. With
+	// twoFactorVerified the user just entered a 2FA code, which counts as a recent verification.
+	Start(ctx context.Context, user *models.User, client SessionClient, twoFactorVerified bool) (*auth.TokenPair, error)
+	// Refresh exchanges a refresh token for new tokens, replacing the refresh token. Using a
+	// refresh token that was already exchanged revokes its session, since someone else has a
+	// copy of it. Invalid refresh tokens return errors.ErrUnauthorized.
+	Refresh(ctx context.Context, refreshToken string, client SessionClient) (*auth.TokenPair, *models.User, error)
+	// Verify returns the user's session, or errors.ErrUnauthorized unless it's active
+	Verify(ctx context.Context, userID, sessionID uint) (*models.Session, error)
+	// List returns the user's active sessions, most recently used first
+	List(ctx context.Context, userID uint) ([]models.Session, error)
+	Revoke(ctx context.Context, userID, sessionID uint) error
+	// RevokeAll revokes the user's sessions except exceptSessionID, which is 0 to revoke all
+	// of them, and returns how many were revoked
+	RevokeAll(ctx context.Context, userID, exceptSessionID uint) (int64, error)
+}
+
+type sessionService struct {
+	sessionRepo          repository.SessionRepository
+	userRepo             repository.UserRepository
+	jwtManager           *auth.JWTManager
+	refreshTokenDuration time.Duration
+	now                  func() time.Time
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, userRepo repository.UserRepository, jwtManager *auth.JWTManager, refreshTokenDuration time.Duration) SessionService {
+	return &sessionService{
+		sessionRepo:          sessionRepo,
+		userRepo:             userRepo,
+		jwtManager:           jwtManager,
+		refreshTokenDuration: refreshTokenDuration,
+		now:                  time.Now,
+	}
+}
+
+func (s *sessionService) Start(ctx context.Context, user *models.User, client SessionClient, twoFactorVerified bool) (*auth.TokenPair, error) {
+	now := s.now()
+	// Expired sessions are no longer listed, so they are cleaned up as new ones start
+	if err := s.sessionRepo.DeleteExpired(ctx, user.ID, now); err != nil {
+		log.Printf("[SessionService] Error deleting expired sessions for user %d: %v", user.ID, err)
+	}
+
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: auth.HashToken(refreshToken),
+		UserAgent:        truncateUserAgent(client.UserAgent),
+		IPAddress:        client.IPAddress,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.refreshTokenDuration),
+	}
+	if twoFactorVerified {
+		session.TwoFactorVerifiedAt = &now
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.tokens(user, session, refreshToken)
+}
+
+func (s *sessionService) Refresh(ctx context.Context, refreshToken string, client SessionClient) (*auth.TokenPair, *models.User, error) {
+	hash := auth.HashToken(refreshToken)
+	session, err := s.sessionRepo.FindByTokenHash(ctx, hash)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, nil, errors.ErrUnauthorized
+		}
+		return nil, nil, err
+	}
+
+	now := s.now()
+	if !session.Active(now) {
+		return nil, nil, errors.ErrUnauthorized
+	}
+	if session.RefreshTokenHash != hash {
+		log.Printf("[SessionService] Refresh token of session %d was reused, revoking it", session.ID)
+		if err := s.sessionRepo.Revoke(ctx, session.UserID, session.ID, now); err != nil && !stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.ErrUnauthorized
+	}
+
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, nil, errors.ErrUnauthorized
+		}
+		return nil, nil, err
+	}
+
+	newToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	session.PreviousTokenHash = hash
+	session.RefreshTokenHash = auth.HashToken(newToken)
+	session.UserAgent = truncateUserAgent(client.UserAgent)
+	session.IPAddress = client.IPAddress
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshTokenDuration)
+	if err := s.sessionRepo.Rotate(ctx, session, hash); err != nil {
+		// A concurrent refresh with the same token won, or the session was revoked meanwhile
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, nil, errors.ErrUnauthorized
+		}
+		return nil, nil, err
+	}
+
+	tokens, err := s.tokens(user, session, newToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+func (s *sessionService) Verify(ctx context.Context, userID, sessionID uint) (*models.Session, error) {
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, errors.ErrUnauthorized
+		}
+		return nil, err
+	}
+	if session.UserID != userID || !session.Active(s.now()) {
+		return nil, errors.ErrUnauthorized
+	}
+	return session, nil
+}
+
+func (s *sessionService) List(ctx context.Context, userID uint) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	active := make([]models.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Active(now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userID, sessionID uint) error {
+	if err := s.sessionRepo.Revoke(ctx, userID, sessionID, s.now()); err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return errors.NewNotFoundError("session not found")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userID, exceptSessionID uint) (int64, error) {
+	return s.sessionRepo.RevokeAll(ctx, userID, exceptSessionID, s.now())
+}
+
+func (s *sessionService) tokens(user *models.User, session *models.Session, refreshToken string) (*auth.TokenPair, error) {
+	accessToken, expiresAt, err := s.jwtManager.GenerateToken(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  expiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+var testJWTManager = auth.NewJWTManager("test-secret", 15*time.Minute)
+
+func setupSessionServiceTestDB(t *testing.T) (*models.User, *sessionService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Session{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	service := NewSessionService(repository.NewSessionRepository(db), repository.NewUserRepository(db), testJWTManager, time.Hour).(*sessionService)
+	return user, service
+}
+
+// tokenSessionID returns the session an access token was issued for
+func tokenSessionID(t *testing.T, tokens *auth.TokenPair) uint {
+	claims, err := testJWTManager.VerifyToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to verify access token: %v", err)
+	}
+	return claims.SessionID
+}
+
+func TestSessionService_Refresh(t *testing.T) {
+	user, service := setupSessionServiceTestDB(t)
+	ctx := context.Background()
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	started, err := service.Start(ctx, user, client, false)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := tokenSessionID(t, started)
+
+	// Refreshing replaces the refresh token and keeps the session
+	refreshed, refreshedUser, err := service.Refresh(ctx, started.RefreshToken, SessionClient{UserAgent: "other", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Expected the refresh to work, got %v", err)
+	}
+	if refreshedUser.ID != user.ID || refreshed.RefreshToken == started.RefreshToken || tokenSessionID(t, refreshed) != sessionID {
+		t.Fatalf("Expected new tokens for the same session, got %+v", refreshed)
+	}
+	session, err := service.Verify(ctx, user.ID, sessionID)
+	if err != nil {
+		t.Fatalf("Expected the session to be active, got %v", err)
+	}
+	if session.UserAgent != "other" || session.IPAddress != "10.0.0.1" {
+		t.Errorf("Expected the session to be used from the new client, got %+v", session)
+	}
+	if _, _, err := service.Refresh(ctx, refreshed.RefreshToken, client); err != nil {
+		t.Fatalf("Expected the new refresh token to work, got %v", err)
+	}
+	if _, _, err := service.Refresh(ctx, "unknown", client); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Errorf("Expected an unknown refresh token to be unauthorized, got %v", err)
+	}
+}
+
+func TestSessionService_RefreshReuseRevokesSession(t *testing.T) {
+	user, service := setupSessionServiceTestDB(t)
+	ctx := context.Background()
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	started, err := service.Start(ctx, user, client, false)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	other, err := service.Start(ctx, user, client, false)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	refreshed, _, err := service.Refresh(ctx, started.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("Expected the refresh to work, got %v", err)
+	}
+
+	// Someone else has a copy of the exchanged token
+	if _, _, err := service.Refresh(ctx, started.RefreshToken, client); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Fatalf("Expected the reused token to be unauthorized, got %v", err)
+	}
+	if _, _, err := service.Refresh(ctx, refreshed.RefreshToken, client); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Errorf("Expected the latest token of the session to be revoked too, got %v", err)
+	}
+	if _, err := service.Verify(ctx, user.ID, tokenSessionID(t, refreshed)); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Errorf("Expected the session's access tokens to be revoked, got %v", err)
+	}
+
+	// The user's other sessions aren't affected
+	if _, err := service.Verify(ctx, user.ID, tokenSessionID(t, other)); err != nil {
+		t.Errorf("Expected the other session to stay active, got %v", err)
+	}
+}
+
+func TestSessionService_RevokeAll(t *testing.T) {
+	user, service := setupSessionServiceTestDB(t)
+	ctx := context.Background()
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	var sessionIDs []uint
+	for i := 0; i < 3; i++ {
+		tokens, err := service.Start(ctx, user, client, false)
+		if err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+		sessionIDs = append(sessionIDs, tokenSessionID(t, tokens))
+	}
+	current := sessionIDs[1]
+
+	revoked, err := service.RevokeAll(ctx, user.ID, current)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("Expected 2 revoked sessions, got %d", revoked)
+	}
+	sessions, err := service.List(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != current {
+		t.Errorf("Expected only the current session to stay active, got %+v", sessions)
+	}
+
+	// Without a current session, all of them are revoked
+	if revoked, err := service.RevokeAll(ctx, user.ID, 0); err != nil || revoked != 1 {
+		t.Errorf("Expected the last session to be revoked, got %d and %v", revoked, err)
+	}
+	if _, err := service.Verify(ctx, user.ID, current); !stdErrors.Is(err, errors.ErrUnauthorized) {
+		t.Errorf("Expected the current session to be revoked, got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+const (
+	// subscriptionHistoryMonths is how far back the history is searched, long enough to see a
+	// yearly subscription charge twice
+	subscriptionHistoryMonths = 25
+	// subscriptionAmountTolerance is how far a charge may be from the usual amount of the subscription
+	subscriptionAmountTolerance = 0.25
+	// subscriptionRegularShare is the share of the intervals between charges that must match the cadence
+	subscriptionRegularShare = 0.8
+)
+
+// subscriptionCadences are the supported billing intervals, in days, with how many charges
+// are needed to trust them
+var subscriptionCadences = []struct {
+	cadence    models.SubscriptionCadence
+	minDays    int
+	maxDays    int
+	minCharges int
+}{
+	{models.SubscriptionCadenceWeekly, 6, 8, 4},
+	{models.SubscriptionCadenceMonthly, 26, 35, 3},
+	{models.SubscriptionCadenceYearly, 350, 380, 2},
+}
+
+// installmentPattern matches purchases paid in installments, which repeat monthly with the
+// same amount but aren't subscriptions
+var installmentPattern = regexp.MustCompile(`(?i)\bparc(ela)?\b|\b\d{1,2}\s*/\s*\d{1,2}\s*$`)
+
+// SubscriptionPriceChange is a charge billed at a different amount than the one before it
+type SubscriptionPriceChange struct {
+	Date string  `json:"date"`
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+}
+
+// DetectedSubscription is a subscription found in the transactions with its price history
+type DetectedSubscription struct {
+	models.Subscription
+	PriceChanges []SubscriptionPriceChange
+}
+
+// SubscriptionSummary is a stored subscription with its estimated cost. Active tells whether
+// it was charged within its cadence. PriceChanges is only known for subscriptions that are
+// still in the searched history.
+type SubscriptionSummary struct {
+	models.Subscription
+	MonthlyCost  float64                   `json:"monthly_cost"`
+	YearlyCost   float64                   `json:"yearly_cost"`
+	Active       bool                      `json:"active"`
+	PriceChanges []SubscriptionPriceChange `json:"price_changes"`
+}
+
+type SubscriptionService interface {
+	// ListSubscriptions returns the user's stored subscriptions, with the price changes found
+	// in the expenses. It doesn't store anything, new subscriptions only show up after
+	// RefreshSubscriptions. Ignored subscriptions are only returned when includeIgnored is set.
+	ListSubscriptions(ctx context.Context, userID uint, loc *time.Location, includeIgnored bool) ([]SubscriptionSummary, error)
+	// RefreshSubscriptions detects the user's subscriptions in the expenses, stores the new
+	// ones, updates the charges of the known ones and returns all of them like ListSubscriptions
+	RefreshSubscriptions(ctx context.Context, userID uint, loc *time.Location, includeIgnored bool) ([]SubscriptionSummary, error)
+	// UpdateStatus confirms, ignores or cancels a subscription. The returned summary has no
+	// price changes, they are only known while detecting.
+	UpdateStatus(ctx context.Context, id uint, userID uint, status models.SubscriptionStatus, loc *time.Location) (*SubscriptionSummary, error)
+}
+
+type subscriptionService struct {
+	subscriptionRepo repository.SubscriptionRepository
+	transactionRepo  repository.TransactionRepository
+	now              func() time.Time
+}
+
+func NewSubscriptionService(subscriptionRepo repository.SubscriptionRepository, transactionRepo repository.TransactionRepository) SubscriptionService {
+	return &subscriptionService{subscriptionRepo: subscriptionRepo, transactionRepo: transactionRepo, now: time.Now}
+}
+
+func (s *subscriptionService) ListSubscriptions(ctx context.Context, userID uint, loc *time.Location, includeIgnored bool) ([]SubscriptionSummary, error) {
+	today := s.today(loc)
+	detected, err := s.detect(userID, today)
+	if err != nil {
+		return nil, err
+	}
+	return s.summaries(ctx, userID, today, detected, includeIgnored)
+}
+
+func (s *subscriptionService) RefreshSubscriptions(ctx context.Context, userID uint, loc *time.Location, includeIgnored bool) ([]SubscriptionSummary, error) {
+	today := s.today(loc)
+	detected, err := s.detect(userID, today)
+	if err != nil {
+		return nil, err
+	}
+
+	detectedSubscriptions := make([]models.Subscription, len(detected))
+	for i, subscription := range detected {
+		detectedSubscriptions[i] = subscription.Subscription
+	}
+	if err := s.subscriptionRepo.SaveDetected(ctx, userID, detectedSubscriptions); err != nil {
+		return nil, err
+	}
+	return s.summaries(ctx, userID, today, detected, includeIgnored)
+}
+
+// detect finds the subscriptions in the user's expenses of the searched history
+func (s *subscriptionService) detect(userID uint, today time.Time) ([]DetectedSubscription, error) {
+	historyStart := today.AddDate(0, -subscriptionHistoryMonths, 0)
+	found, _, err := s.transactionRepo.FindByUserID(userID, []models.TransactionType{models.TransactionTypeExpense}, nil, nil, nil, nil, "", nil, nil, &historyStart, &today, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	expenses := make([]models.Transaction, 0, len(found))
+	for _, tx := range found {
+		if tx.AttachedTransactionID != nil {
+			continue
+		}
+		tx.Date = tx.Date.In(today.Location())
+		expenses = append(expenses, tx)
+	}
+	return DetectSubscriptions(expenses), nil
+}
+
+// summaries summarizes the stored subscriptions with the price changes of the detected ones
+func (s *subscriptionService) summaries(ctx context.Context, userID uint, today time.Time, detected []DetectedSubscription, includeIgnored bool) ([]SubscriptionSummary, error) {
+	priceChanges := make(map[string][]SubscriptionPriceChange, len(detected))
+	for _, subscription := range detected {
+		priceChanges[subscription.Key] = subscription.PriceChanges
+	}
+
+	stored, err := s.subscriptionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]SubscriptionSummary, 0, len(stored))
+	for _, subscription := range stored {
+		if subscription.Status == models.SubscriptionStatusIgnored && !includeIgnored {
+			continue
+		}
+		summaries = append(summaries, summarizeSubscription(subscription, today, priceChanges[subscription.Key]))
+	}
+	return summaries, nil
+}
+
+func (s *subscriptionService) UpdateStatus(ctx context.Context, id uint, userID uint, status models.SubscriptionStatus, loc *time.Location) (*SubscriptionSummary, error) {
+	switch status {
+	case models.SubscriptionStatusConfirmed, models.SubscriptionStatusIgnored, models.SubscriptionStatusCancelled:
+	default:
+		return nil, errors.NewValidationError("invalid status, expected confirmed, ignored or cancelled")
+	}
+
+	subscription, err := s.subscriptionRepo.FindByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NewNotFoundError("subscription not found")
+		}
+		return nil, err
+	}
+
+	if status == models.SubscriptionStatusCancelled {
+		if subscription.CancelledAt == nil {
+			now := s.now()
+			subscription.CancelledAt = &now
+		}
+	} else {
+		subscription.CancelledAt = nil
+	}
+	subscription.Status = status
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return nil, err
+	}
+	summary := summarizeSubscription(*subscription, s.today(loc), nil)
+	return &summary, nil
+}
+
+func (s *subscriptionService) today(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.Local
+	}
+	now := s.now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+}
+
+func summarizeSubscription(subscription models.Subscription, today time.Time, changes []SubscriptionPriceChange) SubscriptionSummary {
+	if changes == nil {
+		changes = []SubscriptionPriceChange{}
+	}
+	monthly := monthlyCost(subscription.Amount, subscription.Cadence)
+	return SubscriptionSummary{
+		Subscription: subscription,
+		MonthlyCost:  roundCents(monthly),
+		YearlyCost:   roundCents(monthly * 12),
+		Active:       subscriptionActive(subscription, today),
+		PriceChanges: changes,
+	}
+}
+
+// DetectSubscriptions groups expenses by their normalized description and keeps the groups
+// charged at a regular weekly, monthly or yearly interval with similar amounts
+func DetectSubscriptions(expenses []models.Transaction) []DetectedSubscription {
+	groups := make(map[string][]models.Transaction)
+	for _, tx := range expenses {
+		if tx.Type != models.TransactionTypeExpense || installmentPattern.MatchString(tx.Description) {
+			continue
+		}
+		key := normalizeSubscriptionDescription(tx.Description)
+		if key == "" {
+			continue
+		}
+		groups[key] = append(groups[key], tx)
+	}
+
+	subscriptions := make([]DetectedSubscription, 0)
+	for key, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+
+		cadence, ok := detectCadence(group)
+		if !ok || !similarAmounts(group) {
+			continue
+		}
+
+		last := group[len(group)-1]
+		changes := make([]SubscriptionPriceChange, 0)
+		for i := 1; i < len(group); i++ {
+			if toCents(group[i].Amount) != toCents(group[i-1].Amount) {
+				changes = append(changes, SubscriptionPriceChange{
+					Date: group[i].Date.Format("2006-01-02"),
+					From: group[i-1].Amount,
+					To:   group[i].Amount,
+				})
+			}
+		}
+		subscriptions = append(subscriptions, DetectedSubscription{
+			Subscription: models.Subscription{
+				Key:             key,
+				Name:            last.Description,
+				AccountID:       last.AccountID,
+				Cadence:         cadence,
+				Amount:          last.Amount,
+				Charges:         len(group),
+				FirstChargeDate: group[0].Date,
+				LastChargeDate:  last.Date,
+			},
+			PriceChanges: changes,
+		})
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].Key < subscriptions[j].Key })
+	return subscriptions
+}
+
+// detectCadence returns the cadence most of the intervals between the sorted charges match
+func detectCadence(sorted []models.Transaction) (models.SubscriptionCadence, bool) {
+	intervals := make([]int, 0, len(sorted)-1)
+	for i := 1; i < len(sorted); i++ {
+		intervals = append(intervals, daysBetween(sorted[i-1].Date, sorted[i].Date))
+	}
+
+	for _, candidate := range subscriptionCadences {
+		if len(sorted) < candidate.minCharges {
+			continue
+		}
+		regular := 0
+		for _, days := range intervals {
+			if days >= candidate.minDays && days <= candidate.maxDays {
+				regular++
+			}
+		}
+		if float64(regular) >= subscriptionRegularShare*float64(len(intervals)) {
+			return candidate.cadence, true
+		}
+	}
+	return "", false
+}
+
+// similarAmounts tells whether every charge is close to the median charge
+func similarAmounts(charges []models.Transaction) bool {
+	amounts := make([]float64, len(charges))
+	for i, tx := range charges {
+		amounts[i] = tx.Amount
+	}
+	sort.Float64s(amounts)
+	median := amounts[len(amounts)/2]
+	if median <= 0 {
+		return false
+	}
+	for _, amount := range amounts {
+		if math.Abs(amount-median) > median*subscriptionAmountTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// subscriptionActive tells whether the subscription was charged within its cadence, with
+// some slack for charges that come a few days late
+func subscriptionActive(subscription models.Subscription, today time.Time) bool {
+	if subscription.Status == models.SubscriptionStatusCancelled {
+		return false
+	}
+	for _, candidate := range subscriptionCadences {
+		if candidate.cadence == subscription.Cadence {
+			return daysBetween(subscription.LastChargeDate, today) <= candidate.maxDays+candidate.maxDays/4
+		}
+	}
+	return false
+}
+
+func monthlyCost(amount float64, cadence models.SubscriptionCadence) float64 {
+	switch cadence {
+	case models.SubscriptionCadenceWeekly:
+		return amount * 52 / 12
+	case models.SubscriptionCadenceYearly:
+		return amount / 12
+	}
+	return amount
+}
+
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// normalizeSubscriptionDescription keeps the letters of a description, so the reference
+// numbers and dates card statements add to a merchant's name don't split its charges
+func normalizeSubscriptionDescription(description string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, description)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupSubscriptionServiceTestDB(t *testing.T) (*gorm.DB, *models.User, *models.Account) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{}, &models.Subscription{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// Create a test account
+	account := &models.Account{Name: "Nubank", Type: models.AccountTypeCredit, UserID: user.ID}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create test account: %v", err)
+	}
+
+	return db, user, account
+}
+
+// subscriptionCharges returns expenses charged on the first date and then after every interval
+// in days, with the amount of the same position
+func subscriptionCharges(description, first string, intervals []int, amounts ...float64) []models.Transaction {
+	date := forecastDate(first)
+	charges := make([]models.Transaction, 0, len(amounts))
+	for i, amount := range amounts {
+		if i > 0 {
+			date = date.AddDate(0, 0, intervals[i-1])
+		}
+		charges = append(charges, models.Transaction{AccountID: 1, Description: description, Amount: amount, Type: models.TransactionTypeExpense, Date: date})
+	}
+	return charges
+}
+
+func TestDetectSubscriptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		expenses []models.Transaction
+		expected models.SubscriptionCadence
+	}{
+		{"weekly", subscriptionCharges("Uber Pass", "2026-09-01", []int{6, 8, 7}, 9.9, 9.9, 9.9, 9.9), models.SubscriptionCadenceWeekly},
+		{"too few weekly charges", subscriptionCharges("Uber Pass", "2026-09-01", []int{7, 7}, 9.9, 9.9, 9.9), ""},
+		{"monthly at the edges of the window", subscriptionCharges("Netflix", "2026-07-05", []int{26, 35}, 44.9, 44.9, 44.9), models.SubscriptionCadenceMonthly},
+		{"monthly interval too long", subscriptionCharges("Netflix", "2026-07-05", []int{36, 30}, 44.9, 44.9, 44.9), ""},
+		{"monthly interval too short", subscriptionCharges("Netflix", "2026-07-05", []int{25, 30}, 44.9, 44.9, 44.9), ""},
+		{"too few monthly charges", subscriptionCharges("Netflix", "2026-08-05", []int{31}, 44.9, 44.9), ""},
+		// 4 of the 5 intervals are monthly, 80%
+		{"one irregular interval in six charges", subscriptionCharges("Spotify", "2026-03-02", []int{31, 30, 50, 31, 30}, 21.9, 21.9, 21.9, 21.9, 21.9, 21.9), models.SubscriptionCadenceMonthly},
+		// 3 of the 4 intervals are monthly, 75%
+		{"one irregular interval in five charges", subscriptionCharges("Spotify", "2026-03-02", []int{31, 50, 31, 30}, 21.9, 21.9, 21.9, 21.9, 21.9), ""},
+		{"yearly", subscriptionCharges("Amazon Prime", "2025-06-10", []int{365}, 166.8, 166.8), models.SubscriptionCadenceYearly},
+		{"yearly interval too long", subscriptionCharges("Amazon Prime", "2025-06-10", []int{381}, 166.8, 166.8), ""},
+		{"amounts within the tolerance", subscriptionCharges("Luz", "2026-07-15", []int{31, 31}, 100, 125, 100), models.SubscriptionCadenceMonthly},
+		{"amounts beyond the tolerance", subscriptionCharges("Luz", "2026-07-15", []int{31, 31}, 100, 126, 100), ""},
+		{"installments with a counter", append(subscriptionCharges("Notebook 01/10", "2026-07-15", nil, 450), append(subscriptionCharges("Notebook 02/10", "2026-08-15", nil, 450), subscriptionCharges("Notebook 03/10", "2026-09-15", nil, 450)...)...), ""},
+		{"installments named as such", subscriptionCharges("Parcela Carro", "2026-07-15", []int{31, 30}, 1200, 1200, 1200), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscriptions := DetectSubscriptions(tt.expenses)
+			if tt.expected == "" {
+				if len(subscriptions) != 0 {
+					t.Errorf("Expected no subscriptions, got %+v", subscriptions)
+				}
+				return
+			}
+			if len(subscriptions) != 1 || subscriptions[0].Cadence != tt.expected {
+				t.Fatalf("Expected a %s subscription, got %+v", tt.expected, subscriptions)
+			}
+		})
+	}
+
+	// Income is never a subscription
+	income := subscriptionCharges("Salário", "2026-07-05", []int{31, 30}, 5000, 5000, 5000)
+	for i := range income {
+		income[i].Type = models.TransactionTypeIncome
+	}
+	if subscriptions := DetectSubscriptions(income); len(subscriptions) != 0 {
+		t.Errorf("Expected income to be ignored, got %+v", subscriptions)
+	}
+}
+
+func TestDetectSubscriptions_PriceChanges(t *testing.T) {
+	// Reference numbers in the descriptions don't split the charges
+	expenses := subscriptionCharges("NETFLIX.COM", "2026-06-05", []int{30, 31, 31, 30}, 39.9, 39.9, 44.9, 44.9, 49.9)
+	for i := range expenses {
+		expenses[i].Description += fmt.Sprintf(" %03d", i+1)
+	}
+
+	subscriptions := DetectSubscriptions(expenses)
+	if len(subscriptions) != 1 {
+		t.Fatalf("Expected 1 subscription, got %+v", subscriptions)
+	}
+	subscription := subscriptions[0]
+	if subscription.Key != "netflix com" || subscription.Name != "NETFLIX.COM 005" || subscription.Charges != 5 || subscription.Amount != 49.9 {
+		t.Errorf("Unexpected subscription: %+v", subscription.Subscription)
+	}
+	if !subscription.FirstChargeDate.Equal(forecastDate("2026-06-05")) || !subscription.LastChargeDate.Equal(forecastDate("2026-10-05")) {
+		t.Errorf("Unexpected charge dates: %v to %v", subscription.FirstChargeDate, subscription.LastChargeDate)
+	}
+
+	expected := []SubscriptionPriceChange{
+		{Date: "2026-08-05", From: 39.9, To: 44.9},
+		{Date: "2026-10-05", From: 44.9, To: 49.9},
+	}
+	if len(subscription.PriceChanges) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, subscription.PriceChanges)
+	}
+	for i, change := range subscription.PriceChanges {
+		if change != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], change)
+		}
+	}
+}
+
+func TestSubscriptionService_ListDoesNotStore(t *testing.T) {
+	db, user, account := setupSubscriptionServiceTestDB(t)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	service := NewSubscriptionService(subscriptionRepo, repository.NewTransactionRepository(db)).(*subscriptionService)
+	service.now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	expenses := subscriptionCharges("Netflix", "2026-07-05", []int{31, 31, 30}, 39.9, 39.9, 44.9, 44.9)
+	for i := range expenses {
+		expenses[i].AccountID = account.ID
+	}
+	if err := db.Create(&expenses).Error; err != nil {
+		t.Fatalf("Failed to create transactions: %v", err)
+	}
+
+	summaries, err := service.ListSubscriptions(ctx, user.ID, time.UTC, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(summaries) != 0 {
+		t.Errorf("Expected listing not to store the detected subscriptions, got %+v", summaries)
+	}
+	if stored, _ := subscriptionRepo.FindByUserID(ctx, user.ID); len(stored) != 0 {
+		t.Errorf("Expected no stored subscriptions, got %+v", stored)
+	}
+
+	summaries, err = service.RefreshSubscriptions(ctx, user.ID, time.UTC, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Status != models.SubscriptionStatusDetected || !summaries[0].Active || len(summaries[0].PriceChanges) != 1 {
+		t.Fatalf("Expected the refreshed subscription with its price change, got %+v", summaries)
+	}
+
+	// Listing again returns the stored subscription with the price changes of the history
+	summaries, err = service.ListSubscriptions(ctx, user.ID, time.UTC, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].MonthlyCost != 44.9 || len(summaries[0].PriceChanges) != 1 {
+		t.Errorf("Expected the stored subscription, got %+v", summaries)
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+type TagService interface {
+	ListTags(ctx context.Context, userID uint) ([]models.Tag, error)
+	GetTagByID(ctx context.Context, id uint, userID uint) (*models.Tag, error)
+	CreateTag(ctx context.Context, tag *models.Tag) error
+	UpdateTag(ctx context.Context, tag *models.Tag) error
+	DeleteTag(ctx context.Context, id uint, userID uint) error
+	// ResolveTags returns the user's tags with the given IDs, failing if any of them doesn't belong to the user
+	ResolveTags(ctx context.Context, userID uint, tagIDs []uint) ([]models.Tag, error)
+	// SetTransactionTags replaces the tags of a transaction the user has access to
+	SetTransactionTags(ctx context.Context, userID uint, transactionID uint, tagIDs []uint) error
+}
+
+type tagService struct {
+	repo            repository.TagRepository
+	transactionRepo repository.TransactionRepository
+}
+
+func NewTagService(repo repository.TagRepository, transactionRepo repository.TransactionRepository) TagService {
+	return &tagService{repo: repo, transactionRepo: transactionRepo}
+}
+
+func (s *tagService) ListTags(ctx context.Context, userID uint) ([]models.Tag, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+func (s *tagService) GetTagByID(ctx context.Context, id uint, userID uint) (*models.Tag, error) {
+	return s.repo.FindByIDAndUserID(ctx, id, userID)
+}
+
+func (s *tagService) CreateTag(ctx context.Context, tag *models.Tag) error {
+	if err := s.validateName(ctx, tag); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, tag)
+}
+
+func (s *tagService) UpdateTag(ctx context.Context, tag *models.Tag) error {
+	if err := s.validateName(ctx, tag); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, tag)
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, id uint, userID uint) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+func (s *tagService) ResolveTags(ctx context.Context, userID uint, tagIDs []uint) ([]models.Tag, error) {
+	unique := make([]uint, 0, len(tagIDs))
+	seen := make(map[uint]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	tags, err := s.repo.FindByIDsAndUserID(ctx, unique, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) != len(unique) {
+		return nil, errors.NewValidationError("tag not found")
+	}
+	return tags, nil
+}
+
+func (s *tagService) SetTransactionTags(ctx context.Context, userID uint, transactionID uint, tagIDs []uint) error {
+	if _, err := s.transactionRepo.FindByID(transactionID, userID); err != nil {
+		return err
+	}
+	if _, err := s.ResolveTags(ctx, userID, tagIDs); err != nil {
+		return err
+	}
+	return s.repo.ReplaceTransactionTags(ctx, transactionID, tagIDs)
+}
+
+// validateName trims the tag name and makes sure the user has no other tag with the same name
+func (s *tagService) validateName(ctx context.Context, tag *models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return errors.NewValidationError("tag name is required")
+	}
+
+	tags, err := s.repo.FindByUserID(ctx, tag.UserID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range tags {
+		if existing.ID != tag.ID && strings.EqualFold(existing.Name, tag.Name) {
+			return errors.NewValidationError("tag with this name already exists")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupTagServiceTestDB(t *testing.T) (*gorm.DB, *models.User, TagService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	service := NewTagService(repository.NewTagRepository(db), repository.NewTransactionRepository(db))
+	return db, user, service
+}
+
+func TestTagService_CreateAndUpdateTag(t *testing.T) {
+	_, user, service := setupTagServiceTestDB(t)
+	ctx := context.Background()
+
+	trip := &models.Tag{Name: "  viagem-2026 ", UserID: user.ID}
+	if err := service.CreateTag(ctx, trip); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if trip.Name != "viagem-2026" {
+		t.Errorf("Expected the name to be trimmed, got %q", trip.Name)
+	}
+	refundable := &models.Tag{Name: "reembolsável", UserID: user.ID}
+	if err := service.CreateTag(ctx, refundable); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tag  models.Tag
+	}{
+		{"empty name", models.Tag{Name: "   ", UserID: user.ID}},
+		{"same name", models.Tag{Name: "viagem-2026", UserID: user.ID}},
+		{"same name in another case", models.Tag{Name: "Viagem-2026", UserID: user.ID}},
+		{"rename to another tag", models.Tag{ID: refundable.ID, Name: "VIAGEM-2026", UserID: user.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag := tt.tag
+			var err error
+			if tag.ID == 0 {
+				err = service.CreateTag(ctx, &tag)
+			} else {
+				err = service.UpdateTag(ctx, &tag)
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected a validation error, got %v", err)
+			}
+		})
+	}
+
+	// Tags keep their own name in another case, and other users can use the same names
+	trip.Name = "Viagem-2026"
+	if err := service.UpdateTag(ctx, trip); err != nil {
+		t.Errorf("Expected the tag to be renamed, got %v", err)
+	}
+	if err := service.CreateTag(ctx, &models.Tag{Name: "viagem-2026", UserID: user.ID + 1}); err != nil {
+		t.Errorf("Expected another user's tag with the same name, got %v", err)
+	}
+}
+
+func TestTagService_SetTransactionTags(t *testing.T) {
+	db, user, service := setupTagServiceTestDB(t)
+	ctx := context.Background()
+
+	other := &models.User{Name: "Other User", Email: "other@example.com", Password: "hashedpassword"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	trip := &models.Tag{Name: "viagem-2026", UserID: user.ID}
+	work := &models.Tag{Name: "empresa", UserID: user.ID}
+	othersTag := &models.Tag{Name: "empresa", UserID: other.ID}
+	for _, tag := range []*models.Tag{trip, work, othersTag} {
+		if err := service.CreateTag(ctx, tag); err != nil {
+			t.Fatalf("Failed to create tag: %v", err)
+		}
+	}
+	account := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: user.ID}
+	othersAccount := &models.Account{Name: "Conta Corrente", Type: models.AccountTypeChecking, UserID: other.ID}
+	for _, account := range []*models.Account{account, othersAccount} {
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+	transaction := &models.Transaction{AccountID: account.ID, Description: "Hotel", Amount: 500, Type: models.TransactionTypeExpense, Date: time.Now()}
+	othersTransaction := &models.Transaction{AccountID: othersAccount.ID, Description: "Hotel", Amount: 500, Type: models.TransactionTypeExpense, Date: time.Now()}
+	for _, transaction := range []*models.Transaction{transaction, othersTransaction} {
+		if err := db.Create(transaction).Error; err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+	transactionTags := func() []uint {
+		var ids []uint
+		db.Table("transaction_tags").Where("transaction_id = ?", transaction.ID).Order("tag_id").Pluck("tag_id", &ids)
+		return ids
+	}
+
+	// Repeated tags are set once
+	if err := service.SetTransactionTags(ctx, user.ID, transaction.ID, []uint{work.ID, trip.ID, work.ID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ids := transactionTags(); len(ids) != 2 || ids[0] != trip.ID || ids[1] != work.ID {
+		t.Errorf("Expected both tags, got %v", ids)
+	}
+
+	// Other users' tags and transactions can't be used, and leave the tags as they were
+	if err := service.SetTransactionTags(ctx, user.ID, transaction.ID, []uint{trip.ID, othersTag.ID}); err == nil {
+		t.Error("Expected another user's tag to be refused")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+	if err := service.SetTransactionTags(ctx, user.ID, othersTransaction.ID, []uint{trip.ID}); err == nil {
+		t.Error("Expected another user's transaction to be refused")
+	}
+	if ids := transactionTags(); len(ids) != 2 {
+		t.Errorf("Expected the tags to stay, got %v", ids)
+	}
+
+	// No tags clears them
+	if err := service.SetTransactionTags(ctx, user.ID, transaction.ID, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ids := transactionTags(); len(ids) != 0 {
+		t.Errorf("Expected no tags, got %v", ids)
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/reports"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+type TaxReportService interface {
+	// TaxReport gathers the year-end balances, deductible expenses and income of the user's own
+	// accounts for the calendar year in loc. Shared accounts are declared by their owners.
+	TaxReport(ctx context.Context, userID uint, year int, loc *time.Location) (*reports.TaxReport, error)
+}
+
+type taxReportService struct {
+	accountRepo    repository.AccountRepository
+	categoryRepo   repository.CategoryRepository
+	statisticsRepo repository.StatisticsRepository
+}
+
+func NewTaxReportService(accountRepo repository.AccountRepository, categoryRepo repository.CategoryRepository, statisticsRepo repository.StatisticsRepository) TaxReportService {
+	return &taxReportService{accountRepo: accountRepo, categoryRepo: categoryRepo, statisticsRepo: statisticsRepo}
+}
+
+func (s *taxReportService) TaxReport(ctx context.Context, userID uint, year int, loc *time.Location) (*reports.TaxReport, error) {
+	if year < 1900 || year > 9999 {
+		return nil, errors.NewValidationError("invalid year")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	report := &reports.TaxReport{
+		Year:       year,
+		Accounts:   []reports.TaxAccountBalance{},
+		Deductible: []reports.TaxCategoryTotal{},
+		Income:     []reports.TaxIncomeSource{},
+	}
+
+	accounts, err := s.accountRepo.FindByUserIDIncludingDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return report, nil
+	}
+	accountIDs := make([]uint, len(accounts))
+	for i, account := range accounts {
+		accountIDs[i] = account.ID
+	}
+
+	if report.Accounts, err = s.yearEndBalances(ctx, accounts, year, loc); err != nil {
+		return nil, err
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, loc)
+	if report.Deductible, err = s.deductibleTotals(ctx, userID, accountIDs, start, end); err != nil {
+		return nil, err
+	}
+	for _, total := range report.Deductible {
+		report.DeductibleTotal += total.Amount
+	}
+	report.DeductibleTotal = roundCents(report.DeductibleTotal)
+
+	rows, err := s.statisticsRepo.AmountByMerchant(ctx, repository.StatisticsFilter{
+		UserID:           userID,
+		AccountIDs:       accountIDs,
+		StartDate:        &start,
+		EndDate:          &end,
+		Types:            []models.TransactionType{models.TransactionTypeIncome},
+		ExcludeTransfers: true,
+		Location:         loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		report.Income = append(report.Income, reports.TaxIncomeSource{Source: row.Label, Amount: roundCents(row.Amount), Count: int(row.Count)})
+		report.IncomeTotal += row.Amount
+	}
+	sort.SliceStable(report.Income, func(i, j int) bool { return report.Income[i].Amount > report.Income[j].Amount })
+	report.IncomeTotal = roundCents(report.IncomeTotal)
+
+	return report, nil
+}
+
+// yearEndBalances returns the balance of every account on 31/12 of the year and of the year
+// before. Accounts that didn't exist on either date are left out.
+func (s *taxReportService) yearEndBalances(ctx context.Context, accounts []models.Account, year int, loc *time.Location) ([]reports.TaxAccountBalance, error) {
+	yearEnd := time.Date(year, time.December, 31, 23, 59, 59, 999999999, loc)
+	previousEnd := yearEnd.AddDate(-1, 0, 0)
+	previousLabel := periodLabel(previousEnd, repository.StatisticsPeriodMonth, loc)
+
+	accountIDs := make([]uint, len(accounts))
+	for i, account := range accounts {
+		accountIDs[i] = account.ID
+	}
+	rows, err := s.statisticsRepo.BalanceChanges(ctx, accountIDs, &yearEnd, repository.StatisticsPeriodMonth, loc)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[uint]float64, len(accounts))
+	previousBalances := make(map[uint]float64, len(accounts))
+	for _, row := range rows {
+		balances[row.AccountID] += row.Amount
+		if row.Label <= previousLabel {
+			previousBalances[row.AccountID] += row.Amount
+		}
+	}
+
+	// An account exists from its first transaction or initial balance entry, which imports
+	// can date before the account was created, and a deleted account has no balance from its
+	// deletion on. Accounts without transactions exist from their creation.
+	firstDays, err := s.statisticsRepo.FirstTransactionDays(ctx, accountIDs, loc)
+	if err != nil {
+		return nil, err
+	}
+	existed := func(account models.Account, date time.Time) bool {
+		if account.DeletedAt.Valid && !account.DeletedAt.Time.After(date) {
+			return false
+		}
+		if first, ok := firstDays[account.ID]; ok {
+			return first <= periodLabel(date, repository.StatisticsPeriodDay, loc)
+		}
+		return !account.CreatedAt.After(date)
+	}
+
+	result := make([]reports.TaxAccountBalance, 0, len(accounts))
+	for _, account := range accounts {
+		existedPrevious, existedNow := existed(account, previousEnd), existed(account, yearEnd)
+		if !existedPrevious && !existedNow {
+			continue
+		}
+		balance := reports.TaxAccountBalance{AccountID: account.ID, Name: account.Name, Type: string(account.Type)}
+		if existedPrevious {
+			balance.PreviousBalance = roundCents(account.InitialBalance + previousBalances[account.ID])
+		}
+		if existedNow {
+			balance.Balance = roundCents(account.InitialBalance + balances[account.ID])
+		}
+		result = append(result, balance)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// deductibleTotals sums the expenses of every deductible category with its subcategories. A
+// transaction linked to a deductible category and one of its subcategories counts once.
+func (s *taxReportService) deductibleTotals(ctx context.Context, userID uint, accountIDs []uint, start, end time.Time) ([]reports.TaxCategoryTotal, error) {
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	// Every category is reported on its top-most deductible ancestor, itself included
+	roots := make(map[uint]uint)
+	for _, category := range categories {
+		visited := make(map[uint]bool)
+		current := category
+		for !visited[current.ID] {
+			visited[current.ID] = true
+			if current.Deductible {
+				roots[category.ID] = current.ID
+			}
+			if current.ParentID == nil {
+				break
+			}
+			parent, ok := byID[*current.ParentID]
+			if !ok {
+				break
+			}
+			current = parent
+		}
+	}
+	if len(roots) == 0 {
+		return []reports.TaxCategoryTotal{}, nil
+	}
+	categoryIDs := make([]uint, 0, len(roots))
+	for id := range roots {
+		categoryIDs = append(categoryIDs, id)
+	}
+
+	parts, err := s.statisticsRepo.CategoryParts(ctx, repository.StatisticsFilter{
+		UserID:     userID,
+		AccountIDs: accountIDs,
+		StartDate:  &start,
+		EndDate:    &end,
+		Types:      []models.TransactionType{models.TransactionTypeExpense},
+	}, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	type partKey struct{ transactionID, partID, rootID uint }
+	counted := make(map[partKey]bool)
+	transactions := make(map[uint]map[uint]bool)
+	totals := make(map[uint]*reports.TaxCategoryTotal)
+	for _, part := range parts {
+		rootID := roots[part.CategoryID]
+		key := partKey{part.TransactionID, part.PartID, rootID}
+		if counted[key] {
+			continue
+		}
+		counted[key] = true
+
+		total, ok := totals[rootID]
+		if !ok {
+			total = &reports.TaxCategoryTotal{CategoryID: rootID, Name: byID[rootID].Name}
+			totals[rootID] = total
+			transactions[rootID] = make(map[uint]bool)
+		}
+		total.Amount += part.Amount
+		transactions[rootID][part.TransactionID] = true
+	}
+
+	result := make([]reports.TaxCategoryTotal, 0, len(totals))
+	for rootID, total := range totals {
+		total.Amount = roundCents(total.Amount)
+		total.Count = len(transactions[rootID])
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupTaxReportServiceTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.AccountShare{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.Tag{}, &models.Merchant{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user
+	user := &models.User{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "hashedpassword",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestTaxReportService_AccountExistence(t *testing.T) {
+	db, user := setupTaxReportServiceTestDB(t)
+	service := NewTaxReportService(repository.NewAccountRepository(db), repository.NewCategoryRepository(db), repository.NewStatisticsRepository(db))
+	transactionRepo := repository.NewTransactionRepository(db)
+
+	createAccount := func(name string, createdAt string) *models.Account {
+		account := &models.Account{Name: name, Type: models.AccountTypeChecking, UserID: user.ID}
+		account.CreatedAt = forecastDate(createdAt)
+		if err := db.Create(account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+		return account
+	}
+	createTransactions := func(account *models.Account, transactions ...models.Transaction) {
+		for i := range transactions {
+			transactions[i].AccountID = account.ID
+			if err := transactionRepo.Create(&transactions[i]); err != nil {
+				t.Fatalf("Failed to create transaction: %v", err)
+			}
+		}
+	}
+
+	// Created in 2026 and then filled with an import of the statements since 2024
+	imported := createAccount("Importada", "2026-10-01")
+	createTransactions(imported,
+		models.Transaction{Date: forecastDate("2024-12-20"), Amount: 1000, Type: models.TransactionTypeIncome},
+		models.Transaction{Date: forecastDate("2025-03-10"), Amount: 200, Type: models.TransactionTypeExpense},
+	)
+
+	// Deleted in the middle of the year
+	closed := createAccount("Encerrada", "2024-01-01")
+	createTransactions(closed,
+		models.Transaction{Date: forecastDate("2024-01-01"), Amount: 500, Type: models.TransactionTypeInitial},
+		models.Transaction{Date: forecastDate("2024-05-01"), Amount: 100, Type: models.TransactionTypeIncome},
+	)
+	if err := db.Model(closed).Updates(map[string]interface{}{"initial_balance": 500, "deleted_at": forecastDate("2025-06-01")}).Error; err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+	if err := transactionRepo.SoftDeleteByAccountID(closed.ID); err != nil {
+		t.Fatalf("Failed to delete transactions: %v", err)
+	}
+
+	// Opened in 2025 with its initial balance, recorded at the creation
+	opened := createAccount("Aberta", "2025-08-01")
+	createTransactions(opened, models.Transaction{Date: forecastDate("2025-08-01"), Amount: 300, Type: models.TransactionTypeInitial})
+	if err := db.Model(opened).Update("initial_balance", 300).Error; err != nil {
+		t.Fatalf("Failed to update account: %v", err)
+	}
+
+	// Created before the year, but with nothing in it until after
+	createTransactions(createAccount("Nova", "2024-06-01"), models.Transaction{Date: forecastDate("2026-02-01"), Amount: 50, Type: models.TransactionTypeIncome})
+	// Without transactions, it exists from its creation, after the year
+	createAccount("Vazia", "2026-01-15")
+
+	report, err := service.TaxReport(context.Background(), user.ID, 2025, time.UTC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	type balances struct{ previous, current float64 }
+	expected := map[string]balances{
+		"Importada": {1000, 800},
+		"Encerrada": {600, 0},
+		"Aberta":    {0, 300},
+	}
+	if len(report.Accounts) != len(expected) {
+		t.Fatalf("Expected %d accounts, got %+v", len(expected), report.Accounts)
+	}
+	for _, account := range report.Accounts {
+		want, ok := expected[account.Name]
+		if !ok {
+			t.Errorf("Unexpected account %q", account.Name)
+			continue
+		}
+		if account.PreviousBalance != want.previous || account.Balance != want.current {
+			t.Errorf("Expected %s to go from %.2f to %.2f, got %+v", account.Name, want.previous, want.current, account)
+		}
+	}
+}
@@ -3,12 +3,14 @@ package service
 import (
 	"context"
 	stdErrors "errors"
+	"math"
 	"regexp"
 	"sort"
 	"strconv"
 	"time"
 
 	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/merchants"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	"github.com/LeonardsonCC/dinheiros/internal/pdfextractors"
 	repo "github.com/LeonardsonCC/dinheiros/internal/repository"
@@ -26,6 +28,8 @@ type TransactionService interface {
 		transactionTypes []models.TransactionType,
 		accountIDs []uint,
 		categoryIDs []uint,
+		tagIDs []uint,
+		merchantIDs []uint,
 		description string,
 		minAmount *float64,
 		maxAmount *float64,
@@ -42,19 +46,20 @@ type TransactionService interface {
 	UpdateTransaction(userID uint, transaction *models.Transaction) error
 	UpdateTransactionWithAttachment(userID uint, transaction *models.Transaction, attachedTransactionID *uint) error
 	DeleteTransaction(userID uint, transactionID uint) error
-	GetDashboardSummary(userID uint) (float64, float64, float64, []models.Transaction, error)
+	GetDashboardSummary(userID uint, period DashboardPeriod) (*DashboardSummary, error)
 	ExtractTransactionsFromPDF(filePath string, accountID uint) ([]models.Transaction, error)
 	ExtractTransactionsFromPDFWithExtractor(filePath string, accountID uint, extractor string) ([]models.Transaction, error)
 	ExtractTransactionsFromPDFWithExtractorAndRules(filePath string, accountID uint, userID uint, extractor string, categorizationRuleService CategorizationRuleService) ([]models.Transaction, error)
 	AssociateCategories(transactionID uint, categoryIDs []uint) error
-	GetTransactionsPerDay(userID uint) (*TransactionsPerDayData, error)
-	GetAmountByMonth(userID uint, startDate, endDate *time.Time) (*AmountByMonthData, error)
-	GetAmountByAccount(userID uint, startDate, endDate *time.Time) (*AmountByAccountData, error)
-	GetAmountByCategory(userID uint, startDate, endDate *time.Time) (*AmountByCategoryData, error)
-	GetAmountSpentByDay(userID uint) (*AmountByMonthData, error)
-	GetAmountSpentAndGainedByDay(userID uint) (map[string][]float64, []string)
-	GetTransactionsPerDayWithRange(userID uint, startDate, endDate *time.Time) (*TransactionsPerDayData, error)
-	GetAmountSpentAndGainedByDayWithRange(userID uint, startDate, endDate *time.Time) (map[string][]float64, []string)
+	ValidateSplits(userID uint, amount float64, splits []models.TransactionSplit) error
+	SetTransactionSplits(userID uint, transactionID uint, splits []models.TransactionSplit) (*models.Transaction, error)
+	GetTransactionsPerDay(userID uint, query StatisticsQuery) (*StatisticsData, error)
+	GetAmountByDay(userID uint, query StatisticsQuery) (*StatisticsData, error)
+	GetAmountByMonth(userID uint, query StatisticsQuery) (*StatisticsData, error)
+	GetAmountByAccount(userID uint, query StatisticsQuery) (*StatisticsData, error)
+	GetAmountByCategory(userID uint, query StatisticsQuery, rollup bool) (*StatisticsData, error)
+	GetAmountByTag(userID uint, query StatisticsQuery) (*StatisticsData, error)
+	GetAmountByMerchant(userID uint, query StatisticsQuery) (*StatisticsData, error)
 	ApplyCategorizationRules(transactions []models.Transaction, userID uint, categorizationRuleService CategorizationRuleService) ([]models.Transaction, error)
 }
 
@@ -62,20 +67,35 @@ type transactionService struct {
 	transactionRepo repo.TransactionRepository
 	accountRepo     repo.AccountRepository
 	categoryService CategoryService
+	statisticsRepo  repo.StatisticsRepository
+	merchantRepo    repo.MerchantRepository
 }
 
 func NewTransactionService(
 	transactionRepo repo.TransactionRepository,
 	accountRepo repo.AccountRepository,
 	categoryService CategoryService,
+	statisticsRepo repo.StatisticsRepository,
+	merchantRepo repo.MerchantRepository,
 ) TransactionService {
 	return &transactionService{
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
 		categoryService: categoryService,
+		statisticsRepo:  statisticsRepo,
+		merchantRepo:    merchantRepo,
 	}
 }
 
+// merchantID finds the merchant of a description, creating it when the user doesn't have it yet
+func (s *transactionService) merchantID(userID uint, description string) (*uint, error) {
+	assigner, err := newMerchantAssigner(context.Background(), s.merchantRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	return assigner.merchantID(context.Background(), description)
+}
+
 func (s *transactionService) CreateTransaction(
 	userID uint,
 	accountID uint,
@@ -92,6 +112,11 @@ func (s *transactionService) CreateTransaction(
 		return nil, err
 	}
 
+	merchantID, err := s.merchantID(userID, description)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the transaction
 	transaction := &models.Transaction{
 		Date:        date,
@@ -99,6 +124,7 @@ func (s *transactionService) CreateTransaction(
 		Type:        transactionType,
 		Description: description,
 		AccountID:   accountID,
+		MerchantID:  merchantID,
 	}
 
 	// Save the transaction
@@ -149,6 +175,11 @@ func (s *transactionService) CreateTransactionWithAttachment(userID uint, accoun
 		attachmentType = models.AttachmentTypeInboundTransfer
 	}
 
+	merchantID, err := s.merchantID(userID, description)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the transaction with attachment
 	transaction := &models.Transaction{
 		Date:                  date,
@@ -156,6 +187,7 @@ func (s *transactionService) CreateTransactionWithAttachment(userID uint, accoun
 		Type:                  transactionType,
 		Description:           description,
 		AccountID:             accountID,
+		MerchantID:            merchantID,
 		AttachedTransactionID: &attachedTransactionID,
 		AttachmentType:        &attachmentType,
 	}
@@ -218,6 +250,8 @@ func (s *transactionService) GetTransactionsByAccountID(userID uint, accountID u
 		nil,               // transactionTypes
 		[]uint{accountID}, // accountIDs
 		nil,               // categoryIDs
+		nil,               // tagIDs
+		nil,               // merchantIDs
 		"",                // description
 		nil,               // minAmount
 		nil,               // maxAmount
@@ -234,6 +268,8 @@ func (s *transactionService) ListTransactions(
 	transactionTypes []models.TransactionType,
 	accountIDs []uint,
 	categoryIDs []uint,
+	tagIDs []uint,
+	merchantIDs []uint,
 	description string,
 	minAmount *float64,
 	maxAmount *float64,
@@ -269,6 +305,8 @@ func (s *transactionService) ListTransactions(
 		transactionTypes,
 		accountIDs,
 		categoryIDs,
+		tagIDs,
+		merchantIDs,
 		description,
 		minAmount,
 		maxAmount,
@@ -308,6 +346,15 @@ func (s *transactionService) UpdateTransaction(userID uint, transaction *models.
 	// Calculate the difference (what needs to be added/subtracted from balance)
 	balanceAdjustment := newImpact - oldImpact
 
+	if err := checkSplitTotal(existingTx.Splits, transaction.Amount); err != nil {
+		return err
+	}
+
+	descriptionChanged, err := s.reassignMerchant(userID, existingTx, transaction)
+	if err != nil {
+		return err
+	}
+
 	// Update the categories
 	tx := s.transactionRepo.Begin()
 	defer func() {
@@ -330,8 +377,18 @@ func (s *transactionService) UpdateTransaction(userID uint, transaction *models.
 		tx.Rollback()
 		return err
 	}
+	// Updates skips nil fields, so a description without a merchant must clear it explicitly
+	if descriptionChanged {
+		if err := tx.Model(&models.Transaction{}).Where("id = ?", existingTx.ID).Update("merchant_id", transaction.MerchantID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
-	// Update categories association
+	// Update categories association. Split transactions take their categories from the splits.
+	if len(existingTx.Splits) > 0 {
+		return tx.Commit().Error
+	}
 	if len(transaction.Categories) > 0 {
 		err = tx.Model(&existingTx).Association("Categories").Replace(transaction.Categories)
 		if err != nil {
@@ -389,6 +446,17 @@ func (s *transactionService) UpdateTransactionWithAttachment(userID uint, transa
 	// Calculate the difference (what needs to be added/subtracted from balance)
 	balanceAdjustment := newImpact - oldImpact
 
+	if err := checkSplitTotal(existingTx.Splits, transaction.Amount); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	descriptionChanged, err := s.reassignMerchant(userID, existingTx, transaction)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Update account balance if there's a change
 	if balanceAdjustment != 0 {
 		if err := s.accountRepo.UpdateBalance(existingTx.AccountID, balanceAdjustment); err != nil {
@@ -505,8 +573,18 @@ func (s *transactionService) UpdateTransactionWithAttachment(userID uint, transa
 		tx.Rollback()
 		return err
 	}
+	// Updates skips nil fields, so a description without a merchant must clear it explicitly
+	if descriptionChanged {
+		if err := tx.Model(&models.Transaction{}).Where("id = ?", existingTx.ID).Update("merchant_id", transaction.MerchantID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
-	// Update categories association
+	// Update categories association. Split transactions take their categories from the splits.
+	if len(existingTx.Splits) > 0 {
+		return tx.Commit().Error
+	}
 	if len(transaction.Categories) > 0 {
 		err = tx.Model(&existingTx).Association("Categories").Replace(transaction.Categories)
 		if err != nil {
@@ -525,6 +603,20 @@ func (s *transactionService) UpdateTransactionWithAttachment(userID uint, transa
 	return tx.Commit().Error
 }
 
+// reassignMerchant finds the merchant of the updated transaction when its description changed
+func (s *transactionService) reassignMerchant(userID uint, existing *models.Transaction, updated *models.Transaction) (bool, error) {
+	if updated.Description == existing.Description {
+		return false, nil
+	}
+	merchantID, err := s.merchantID(userID, updated.Description)
+	if err != nil {
+		return false, err
+	}
+	updated.MerchantID = merchantID
+	updated.Merchant = nil
+	return true, nil
+}
+
 func (s *transactionService) DeleteTransaction(userID uint, transactionID uint) error {
 	// Verify transaction exists and user has access to the account
 	transaction, err := s.transactionRepo.FindByID(transactionID, userID)
@@ -558,10 +650,6 @@ func (s *transactionService) DeleteTransaction(userID uint, transactionID uint)
 	return s.transactionRepo.Delete(transactionID, userID)
 }
 
-func (s *transactionService) GetDashboardSummary(userID uint) (float64, float64, float64, []models.Transaction, error) {
-	return s.transactionRepo.GetDashboardSummary(userID)
-}
-
 func (s *transactionService) ExtractTransactionsFromPDF(filePath string, accountID uint) ([]models.Transaction, error) {
 	return s.ExtractTransactionsFromPDFWithExtractor(filePath, accountID, "")
 }
@@ -588,236 +676,250 @@ func (s *transactionService) AssociateCategories(transactionID uint, categoryIDs
 	return s.transactionRepo.AssociateCategories(transactionID, categoryIDs)
 }
 
-// Statistics data structures
-type TransactionsPerDayData struct {
-	Labels []string `json:"labels"`
-	Data   []int    `json:"data"`
-}
-
-type AmountByMonthData struct {
-	Labels []string  `json:"labels"`
-	Data   []float64 `json:"data"`
-}
+// ValidateSplits checks that every split uses one of the user's categories and that the
+// split amounts add up to the transaction amount
+func (s *transactionService) ValidateSplits(userID uint, amount float64, splits []models.TransactionSplit) error {
+	if len(splits) == 0 {
+		return nil
+	}
 
-type AmountByAccountData struct {
-	Labels []string  `json:"labels"`
-	Data   []float64 `json:"data"`
-}
+	for _, split := range splits {
+		if split.Amount <= 0 {
+			return errors.NewValidationError("split amounts must be greater than zero")
+		}
+		if _, err := s.categoryService.GetCategoryByID(context.Background(), strconv.FormatUint(uint64(split.CategoryID), 10), userID); err != nil {
+			return errors.NewValidationError("split category not found")
+		}
+	}
 
-type AmountByCategoryData struct {
-	Labels []string  `json:"labels"`
-	Data   []float64 `json:"data"`
+	return checkSplitTotal(splits, amount)
 }
 
-func (s *transactionService) GetTransactionsPerDay(userID uint) (*TransactionsPerDayData, error) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+// SetTransactionSplits replaces the splits of a transaction. An empty list removes the splits.
+func (s *transactionService) SetTransactionSplits(userID uint, transactionID uint, splits []models.TransactionSplit) (*models.Transaction, error) {
+	transaction, err := s.transactionRepo.FindByID(transactionID, userID)
 	if err != nil {
 		return nil, err
 	}
-	perDay := make(map[string]int)
-	for _, tx := range transactions {
-		date := tx.Date.Format("2006-01-02")
-		perDay[date]++
-	}
-	labels := make([]string, 0, len(perDay))
-	for date := range perDay {
-		labels = append(labels, date)
+
+	if err := s.ValidateSplits(userID, transaction.Amount, splits); err != nil {
+		return nil, err
 	}
-	// Sort labels
-	sort.Strings(labels)
-	data := make([]int, len(labels))
-	for i, date := range labels {
-		data[i] = perDay[date]
+
+	if err := s.transactionRepo.ReplaceSplits(transaction.ID, splits); err != nil {
+		return nil, err
 	}
-	return &TransactionsPerDayData{Labels: labels, Data: data}, nil
+
+	return s.transactionRepo.FindByID(transactionID, userID)
 }
 
-func (s *transactionService) GetAmountByMonth(userID uint, startDate, endDate *time.Time) (*AmountByMonthData, error) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, startDate, endDate, 0, 0)
-	if err != nil {
-		return nil, err
+// checkSplitTotal compares amounts in cents so floating point noise doesn't reject valid splits
+func checkSplitTotal(splits []models.TransactionSplit, amount float64) error {
+	if len(splits) == 0 {
+		return nil
 	}
-	byMonth := make(map[string]float64)
-	for _, tx := range transactions {
-		month := tx.Date.Format("2006-01")
-		byMonth[month] += tx.Amount
+
+	var totalCents int64
+	for _, split := range splits {
+		totalCents += toCents(split.Amount)
 	}
-	labels := make([]string, 0, len(byMonth))
-	for month := range byMonth {
-		labels = append(labels, month)
+	if totalCents != toCents(amount) {
+		return errors.NewValidationError("split amounts must add up to the transaction amount")
 	}
-	sort.Strings(labels)
-	data := make([]float64, len(labels))
-	for i, month := range labels {
-		data[i] = byMonth[month]
+	return nil
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// StatisticsMode selects which transactions a statistics series is built from
+type StatisticsMode string
+
+const (
+	StatisticsModeIncome  StatisticsMode = "income"
+	StatisticsModeExpense StatisticsMode = "expense"
+	// StatisticsModeNet subtracts expenses from income
+	StatisticsModeNet StatisticsMode = "net"
+	// StatisticsModeBoth returns separate income and expense series
+	StatisticsModeBoth StatisticsMode = "both"
+)
+
+// ParseStatisticsMode validates a mode, falling back to the given default when empty
+func ParseStatisticsMode(mode string, fallback StatisticsMode) (StatisticsMode, error) {
+	switch StatisticsMode(mode) {
+	case "":
+		return fallback, nil
+	case StatisticsModeIncome, StatisticsModeExpense, StatisticsModeNet, StatisticsModeBoth:
+		return StatisticsMode(mode), nil
 	}
-	return &AmountByMonthData{Labels: labels, Data: data}, nil
+	return "", errors.NewValidationError("invalid mode, expected one of income, expense, net or both")
+}
+
+// StatisticsQuery holds the options shared by all statistics. Initial balance entries and
+// transfers between accounts are left out unless explicitly included; included initial
+// balances count as income. Days and months are those of Location.
+type StatisticsQuery struct {
+	StartDate        *time.Time
+	EndDate          *time.Time
+	Mode             StatisticsMode
+	IncludeInitial   bool
+	IncludeTransfers bool
+	Location         *time.Location
+}
+
+// StatisticsSeries is one dataset of a chart, aligned with the labels of its StatisticsData
+type StatisticsSeries struct {
+	Label string    `json:"label"`
+	Data  []float64 `json:"data"`
 }
 
-func (s *transactionService) GetAmountByAccount(userID uint, startDate, endDate *time.Time) (*AmountByAccountData, error) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, startDate, endDate, 0, 0)
+// StatisticsData is the result of every statistics query. Single-series modes return one
+// series; StatisticsModeBoth returns an expense and an income series.
+type StatisticsData struct {
+	Labels []string           `json:"labels"`
+	Series []StatisticsSeries `json:"series"`
+}
+
+// GetTransactionsPerDay counts transactions per day. In net mode all transactions are counted together.
+func (s *transactionService) GetTransactionsPerDay(userID uint, query StatisticsQuery) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByPeriod(context.Background(), statisticsFilter(userID, query), repo.StatisticsPeriodDay)
 	if err != nil {
 		return nil, err
 	}
-	byAccount := make(map[string]float64)
-	for _, tx := range transactions {
-		byAccount[tx.Account.Name] += tx.Amount
-	}
-	labels := make([]string, 0, len(byAccount))
-	for acc := range byAccount {
-		labels = append(labels, acc)
-	}
-	sort.Strings(labels)
-	data := make([]float64, len(labels))
-	for i, acc := range labels {
-		data[i] = byAccount[acc]
-	}
-	return &AmountByAccountData{Labels: labels, Data: data}, nil
+	return buildStatistics(rows, query.Mode, true), nil
 }
 
-func (s *transactionService) GetAmountByCategory(userID uint, startDate, endDate *time.Time) (*AmountByCategoryData, error) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, startDate, endDate, 0, 0)
+func (s *transactionService) GetAmountByDay(userID uint, query StatisticsQuery) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByPeriod(context.Background(), statisticsFilter(userID, query), repo.StatisticsPeriodDay)
 	if err != nil {
 		return nil, err
 	}
-	byCategory := make(map[string]float64)
-	for _, tx := range transactions {
-		for _, cat := range tx.Categories {
-			byCategory[cat.Name] += tx.Amount
-		}
-	}
-	labels := make([]string, 0, len(byCategory))
-	for cat := range byCategory {
-		labels = append(labels, cat)
-	}
-	sort.Strings(labels)
-	data := make([]float64, len(labels))
-	for i, cat := range labels {
-		data[i] = byCategory[cat]
-	}
-	return &AmountByCategoryData{Labels: labels, Data: data}, nil
+	return buildStatistics(rows, query.Mode, false), nil
 }
 
-// AmountSpentByDayData for chartjs
-func (s *transactionService) GetAmountSpentByDay(userID uint) (*AmountByMonthData, error) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+func (s *transactionService) GetAmountByMonth(userID uint, query StatisticsQuery) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByPeriod(context.Background(), statisticsFilter(userID, query), repo.StatisticsPeriodMonth)
 	if err != nil {
 		return nil, err
 	}
-	byDay := make(map[string]float64)
-	for _, tx := range transactions {
-		if tx.Type == models.TransactionTypeExpense {
-			date := tx.Date.Format("2006-01-02")
-			byDay[date] += tx.Amount
-		}
-	}
-	labels := make([]string, 0, len(byDay))
-	for day := range byDay {
-		labels = append(labels, day)
-	}
-	sort.Strings(labels)
-	data := make([]float64, len(labels))
-	for i, day := range labels {
-		data[i] = byDay[day]
-	}
-	return &AmountByMonthData{Labels: labels, Data: data}, nil
+	return buildStatistics(rows, query.Mode, false), nil
 }
 
-// AmountSpentAndGainedByDayData for chartjs
-func (s *transactionService) GetAmountSpentAndGainedByDay(userID uint) (map[string][]float64, []string) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, nil, nil, 0, 0)
+func (s *transactionService) GetAmountByAccount(userID uint, query StatisticsQuery) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByAccount(context.Background(), statisticsFilter(userID, query))
 	if err != nil {
-		return map[string][]float64{"spent": {}, "gained": {}}, []string{}
-	}
-	spentByDay := make(map[string]float64)
-	gainedByDay := make(map[string]float64)
-	for _, tx := range transactions {
-		date := tx.Date.Format("2006-01-02")
-		switch tx.Type {
-		case models.TransactionTypeExpense:
-			spentByDay[date] += tx.Amount
-		case models.TransactionTypeIncome:
-			gainedByDay[date] += tx.Amount
-		}
-	}
-	labelSet := make(map[string]struct{})
-	for d := range spentByDay {
-		labelSet[d] = struct{}{}
-	}
-	for d := range gainedByDay {
-		labelSet[d] = struct{}{}
-	}
-	labels := make([]string, 0, len(labelSet))
-	for d := range labelSet {
-		labels = append(labels, d)
-	}
-	sort.Strings(labels)
-	spent := make([]float64, len(labels))
-	gained := make([]float64, len(labels))
-	for i, d := range labels {
-		spent[i] = spentByDay[d]
-		gained[i] = gainedByDay[d]
+		return nil, err
 	}
-	return map[string][]float64{"spent": spent, "gained": gained}, labels
+	return buildStatistics(rows, query.Mode, false), nil
 }
 
-func (s *transactionService) GetTransactionsPerDayWithRange(userID uint, startDate, endDate *time.Time) (*TransactionsPerDayData, error) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, startDate, endDate, 0, 0)
+// GetAmountByCategory sums transaction amounts per category. When rollup is set, amounts are
+// reported on the top-level categories only, including everything spent in their subcategories
+// at any depth. Intermediate categories aren't reported; leave rollup off for their own amounts.
+func (s *transactionService) GetAmountByCategory(userID uint, query StatisticsQuery, rollup bool) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByCategory(context.Background(), statisticsFilter(userID, query), rollup)
 	if err != nil {
 		return nil, err
 	}
-	perDay := make(map[string]int)
-	for _, tx := range transactions {
-		date := tx.Date.Format("2006-01-02")
-		perDay[date]++
-	}
-	labels := make([]string, 0, len(perDay))
-	for date := range perDay {
-		labels = append(labels, date)
-	}
-	sort.Strings(labels)
-	data := make([]int, len(labels))
-	for i, date := range labels {
-		data[i] = perDay[date]
+	return buildStatistics(rows, query.Mode, false), nil
+}
+
+func (s *transactionService) GetAmountByTag(userID uint, query StatisticsQuery) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByTag(context.Background(), statisticsFilter(userID, query))
+	if err != nil {
+		return nil, err
 	}
-	return &TransactionsPerDayData{Labels: labels, Data: data}, nil
+	return buildStatistics(rows, query.Mode, false), nil
 }
 
-func (s *transactionService) GetAmountSpentAndGainedByDayWithRange(userID uint, startDate, endDate *time.Time) (map[string][]float64, []string) {
-	transactions, _, err := s.transactionRepo.FindByUserID(userID, nil, nil, nil, "", nil, nil, startDate, endDate, 0, 0)
+func (s *transactionService) GetAmountByMerchant(userID uint, query StatisticsQuery) (*StatisticsData, error) {
+	rows, err := s.statisticsRepo.AmountByMerchant(context.Background(), statisticsFilter(userID, query))
 	if err != nil {
-		return map[string][]float64{"spent": {}, "gained": {}}, []string{}
+		return nil, err
 	}
-	spentByDay := make(map[string]float64)
-	gainedByDay := make(map[string]float64)
-	for _, tx := range transactions {
-		date := tx.Date.Format("2006-01-02")
-		switch tx.Type {
-		case models.TransactionTypeExpense:
-			spentByDay[date] += tx.Amount
-		case models.TransactionTypeIncome:
-			gainedByDay[date] += tx.Amount
-		}
+	return buildStatistics(rows, query.Mode, false), nil
+}
+
+// statisticsFilter only loads the transaction types the query's mode needs
+func statisticsFilter(userID uint, query StatisticsQuery) repo.StatisticsFilter {
+	var types []models.TransactionType
+	switch query.Mode {
+	case StatisticsModeIncome:
+		types = []models.TransactionType{models.TransactionTypeIncome}
+	case StatisticsModeExpense:
+		types = []models.TransactionType{models.TransactionTypeExpense}
+	default:
+		types = []models.TransactionType{models.TransactionTypeIncome, models.TransactionTypeExpense}
+	}
+	if query.IncludeInitial && query.Mode != StatisticsModeExpense {
+		types = append(types, models.TransactionTypeInitial)
+	}
+
+	return repo.StatisticsFilter{
+		UserID:           userID,
+		StartDate:        query.StartDate,
+		EndDate:          query.EndDate,
+		Types:            types,
+		ExcludeTransfers: !query.IncludeTransfers,
+		Location:         query.Location,
 	}
+}
+
+// buildStatistics turns aggregated rows into series for the given mode, using the
+// transaction counts instead of the amounts when count is set
+func buildStatistics(rows []repo.StatisticsRow, mode StatisticsMode, count bool) *StatisticsData {
+	income := make(map[string]float64)
+	expense := make(map[string]float64)
 	labelSet := make(map[string]struct{})
-	for d := range spentByDay {
-		labelSet[d] = struct{}{}
-	}
-	for d := range gainedByDay {
-		labelSet[d] = struct{}{}
+	for _, row := range rows {
+		value := row.Amount
+		if count {
+			value = float64(row.Count)
+		}
+		switch row.Type {
+		case models.TransactionTypeIncome, models.TransactionTypeInitial:
+			income[row.Label] += value
+		case models.TransactionTypeExpense:
+			expense[row.Label] += value
+		default:
+			continue
+		}
+		labelSet[row.Label] = struct{}{}
 	}
+
 	labels := make([]string, 0, len(labelSet))
-	for d := range labelSet {
-		labels = append(labels, d)
+	for label := range labelSet {
+		labels = append(labels, label)
 	}
 	sort.Strings(labels)
-	spent := make([]float64, len(labels))
-	gained := make([]float64, len(labels))
-	for i, d := range labels {
-		spent[i] = spentByDay[d]
-		gained[i] = gainedByDay[d]
+
+	series := func(name string, value func(label string) float64) StatisticsSeries {
+		data := make([]float64, len(labels))
+		for i, label := range labels {
+			data[i] = value(label)
+		}
+		return StatisticsSeries{Label: name, Data: data}
+	}
+	incomeSeries := series("Income", func(label string) float64 { return income[label] })
+	expenseSeries := series("Expense", func(label string) float64 { return expense[label] })
+
+	result := &StatisticsData{Labels: labels}
+	switch mode {
+	case StatisticsModeIncome:
+		result.Series = []StatisticsSeries{incomeSeries}
+	case StatisticsModeExpense:
+		result.Series = []StatisticsSeries{expenseSeries}
+	case StatisticsModeBoth:
+		result.Series = []StatisticsSeries{expenseSeries, incomeSeries}
+	default:
+		if count {
+			result.Series = []StatisticsSeries{series("Transactions", func(label string) float64 { return income[label] + expense[label] })}
+		} else {
+			result.Series = []StatisticsSeries{series("Net", func(label string) float64 { return income[label] - expense[label] })}
+		}
 	}
-	return map[string][]float64{"spent": spent, "gained": gained}, labels
+	return result
 }
 
 func (s *transactionService) ExtractTransactionsFromPDFWithExtractorAndRules(filePath string, accountID uint, userID uint, extractor string, categorizationRuleService CategorizationRuleService) ([]models.Transaction, error) {
@@ -872,6 +974,12 @@ func (s *transactionService) ApplyCategorizationRules(transactions []models.Tran
 	// Create a map to cache categories by ID to avoid multiple database queries
 	categoryCache := make(map[uint]*models.Category)
 
+	// Merchant rules compare the merchant name found in the description
+	normalizer, err := merchantNormalizer(context.Background(), s.merchantRepo, userID)
+	if err != nil {
+		return transactions, err
+	}
+
 	// Apply rules to each transaction
 	for i := range transactions {
 		transaction := &transactions[i]
@@ -885,6 +993,10 @@ func (s *transactionService) ApplyCategorizationRules(transactions []models.Tran
 			case "exact":
 				// Exact string match (case-sensitive)
 				matches = rule.Value == transaction.Description
+			case "merchant":
+				// Merchant name match, ignoring case and accents
+				key := merchants.Key(rule.Value)
+				matches = key != "" && key == merchants.Key(normalizer.Name(transaction.Description))
 			case "regex":
 				fallthrough
 			default:
@@ -900,33 +1012,50 @@ func (s *transactionService) ApplyCategorizationRules(transactions []models.Tran
 
 			// If we have a match, apply the categorization
 			if matches {
-				// Get the full category information
-				category, exists := categoryCache[rule.CategoryDst]
-				if !exists {
-					// Fetch category from database
-					category, err = s.categoryService.GetCategoryByID(context.Background(), strconv.FormatUint(uint64(rule.CategoryDst), 10), userID)
-					if err != nil {
-						// Skip if category not found
-						continue
+				// Rules without a category only add tags
+				if rule.CategoryDst != 0 {
+					// Get the full category information
+					category, exists := categoryCache[rule.CategoryDst]
+					if !exists {
+						// Fetch category from database
+						category, err = s.categoryService.GetCategoryByID(context.Background(), strconv.FormatUint(uint64(rule.CategoryDst), 10), userID)
+						if err != nil {
+							// Skip if category not found
+							continue
+						}
+						categoryCache[rule.CategoryDst] = category
+					}
+
+					// Apply the category to the transaction
+					// First, clear existing categories if this is a new categorization
+					if len(transaction.Categories) == 0 {
+						transaction.Categories = []*models.Category{category}
+					} else {
+						// Add the new category if it's not already present
+						categoryExists := false
+						for _, existingCat := range transaction.Categories {
+							if existingCat.ID == rule.CategoryDst {
+								categoryExists = true
+								break
+							}
+						}
+						if !categoryExists {
+							transaction.Categories = append(transaction.Categories, category)
+						}
 					}
-					categoryCache[rule.CategoryDst] = category
 				}
 
-				// Apply the category to the transaction
-				// First, clear existing categories if this is a new categorization
-				if len(transaction.Categories) == 0 {
-					transaction.Categories = []*models.Category{category}
-				} else {
-					// Add the new category if it's not already present
-					categoryExists := false
-					for _, existingCat := range transaction.Categories {
-						if existingCat.ID == rule.CategoryDst {
-							categoryExists = true
+				// Add the rule's tags that the transaction doesn't have yet
+				for i := range rule.Tags {
+					tagExists := false
+					for _, existingTag := range transaction.Tags {
+						if existingTag.ID == rule.Tags[i].ID {
+							tagExists = true
 							break
 						}
 					}
-					if !categoryExists {
-						transaction.Categories = append(transaction.Categories, category)
+					if !tagExists {
+						transaction.Tags = append(transaction.Tags, &rule.Tags[i])
 					}
 				}
 
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	stdErrors "errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+	"github.com/LeonardsonCC/dinheiros/internal/totp"
+)
+
+const (
+	// totpIssuer is the name authenticator apps list the account under
+	totpIssuer = "Dinheiros"
+	// recoveryCodeCount is how many recovery codes the user gets at a time
+	recoveryCodeCount = 10
+	// twoFactorChallengeDuration is how long the user has to enter the code after their password
+	twoFactorChallengeDuration = 5 * time.Minute
+)
+
+// TwoFactorSetup is the secret to add to an authenticator app, by typing it or scanning the
+// provisioning URI as a QR code
+type TwoFactorSetup struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool
+	RecoveryCodesRemaining int64
+}
+
+// TwoFactorChallenge is returned by a password login instead of tokens for users with
+// two-factor authentication. Its token is exchanged for a session along with a code.
+type TwoFactorChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+type TwoFactorService interface {
+	Status(ctx context.Context, userID uint) (*TwoFactorStatus, error)
+	// Setup generates a new secret for the user's authenticator app. Two-factor
+	// authentication is only enabled once Enable confirms a code from the app.
+	Setup(ctx context.Context, userID uint) (*TwoFactorSetup, error)
+	// Enable turns two-factor authentication on after checking a code from the app set up
+	// with Setup and returns the recovery codes, which can't be shown again. The session the
+	// code was entered in counts as recently verified.
+	Enable(ctx context.Context, userID, sessionID uint, code string) ([]string, error)
+	// Disable turns two-factor authentication off once the password is confirmed
+	Disable(ctx context.Context, userID uint, password string) error
+	// RegenerateRecoveryCodes replaces the user's recovery codes, used or not
+	RegenerateRecoveryCodes(ctx context.Context, userID uint) ([]string, error)
+	// Challenge starts the second login step for a user whose password was checked
+	Challenge(user *models.User) (*TwoFactorChallenge, error)
+	// CompleteLogin exchanges a challenge token and a code from the app, or a recovery code,
+	// for a new session. Invalid tokens and codes return errors.ErrUnauthorized. Wrong codes
+	// count towards locking the user's logins like wrong passwords, and return
+	// *errors.LockedError once they lock them and until the lock ends.
+	CompleteLogin(ctx context.Context, challengeToken, code string, client SessionClient) (*auth.TokenPair, *models.User, error)
+	// Verify checks a code in an existing session, so sensitive operations can be performed
+	// in it again. Invalid codes return errors.ErrUnauthorized, and are counted and locked
+	// like in CompleteLogin.
+	Verify(ctx context.Context, userID, sessionID uint, code string, client SessionClient) error
+}
+
+type twoFactorService struct {
+	userRepo         repository.UserRepository
+	auditLogRepo     repository.AuditLogRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	sessionRepo      repository.SessionRepository
+	sessionService   SessionService
+	jwtManager       *auth.JWTManager
+	now              func() time.Time
+}
+
+func NewTwoFactorService(userRepo repository.UserRepository, auditLogRepo repository.AuditLogRepository, recoveryCodeRepo repository.RecoveryCodeRepository, sessionRepo repository.SessionRepository, sessionService SessionService, jwtManager *auth.JWTManager) TwoFactorService {
+	return &twoFactorService{
+		userRepo:         userRepo,
+		auditLogRepo:     auditLogRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		sessionRepo:      sessionRepo,
+		sessionService:   sessionService,
+		jwtManager:       jwtManager,
+		now:              time.Now,
+	}
+}
+
+func (s *twoFactorService) Status(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: user.TwoFactorEnabled}
+	if user.TwoFactorEnabled {
+		if status.RecoveryCodesRemaining, err = s.recoveryCodeRepo.CountUnused(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (s *twoFactorService) Setup(ctx context.Context, userID uint) (*TwoFactorSetup, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.NewValidationError("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, user.Email, secret, totp.DefaultOptions),
+	}, nil
+}
+
+func (s *twoFactorService) Enable(ctx context.Context, userID, sessionID uint, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.NewValidationError("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.NewValidationError("two-factor authentication wasn't set up")
+	}
+	// Recovery codes don't exist yet, only the app's code confirms the setup worked
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.TwoFactorEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.MarkTwoFactorVerified(ctx, sessionID, s.now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userID uint, password string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := user.CheckPassword(password); err != nil {
+		return errors.ErrUnauthorized
+	}
+	if !user.TwoFactorEnabled {
+		return errors.NewValidationError("two-factor authentication isn't enabled")
+	}
+
+	user.TwoFactorEnabled = false
+	user.TOTPSecret = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.recoveryCodeRepo.DeleteByUserID(ctx, userID)
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, errors.NewValidationError("two-factor authentication isn't enabled")
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+func (s *twoFactorService) Challenge(user *models.User) (*TwoFactorChallenge, error) {
+	token, expiresAt, err := s.jwtManager.GeneratePurposeToken(user, auth.PurposeTwoFactor, twoFactorChallengeDuration)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func (s *twoFactorService) CompleteLogin(ctx context.Context, challengeToken, code string, client SessionClient) (*auth.TokenPair, *models.User, error) {
+	claims, err := s.jwtManager.VerifyPurposeToken(challengeToken, auth.PurposeTwoFactor)
+	if err != nil {
+		return nil, nil, errors.ErrUnauthorized
+	}
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, nil, errors.ErrUnauthorized
+		}
+		return nil, nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, nil, errors.ErrUnauthorized
+	}
+	if err := s.checkCode(ctx, user, code, client); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.sessionService.Start(ctx, user, client, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+func (s *twoFactorService) Verify(ctx context.Context, userID, sessionID uint, code string, client SessionClient) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return errors.NewValidationError("two-factor authentication isn't enabled")
+	}
+	if err := s.checkCode(ctx, user, code, client); err != nil {
+		return err
+	}
+	return s.sessionRepo.MarkTwoFactorVerified(ctx, sessionID, s.now())
+}
+
+// checkCode verifies a code of a user who isn't locked, counting wrong codes towards locking
+// their logins and clearing the count, wrong passwords included, on the right one
+func (s *twoFactorService) checkCode(ctx context.Context, user *models.User, code string, client SessionClient) error {
+	// Locked users can't try codes until the lock ends
+	now := s.now()
+	if user.Locked(now) {
+		return &errors.LockedError{Until: *user.LockedUntil}
+	}
+
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		if stdErrors.Is(err, errors.ErrUnauthorized) {
+			if until := recordFailedAttempt(s.userRepo, s.auditLogRepo, user.ID, client, now); until != nil {
+				return &errors.LockedError{Until: *until}
+			}
+		}
+		return err
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+			log.Printf("[TwoFactorService] Error resetting failed logins of user %d: %v", user.ID, err)
+		}
+	}
+	return nil
+}
+
+// verifyCode accepts a code from the user's authenticator app or one of their recovery codes
+func (s *twoFactorService) verifyCode(ctx context.Context, user *models.User, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) == totp.DefaultOptions.Digits && isDigits(normalized) {
+		return s.verifyTOTP(user, normalized)
+	}
+
+	err := s.recoveryCodeRepo.Use(ctx, user.ID, auth.HashToken(normalized), s.now())
+	if stdErrors.Is(err, repository.ErrNotFound) {
+		return errors.ErrUnauthorized
+	}
+	return err
+}
+
+// verifyTOTP checks a code from the user's authenticator app, which only works once
+func (s *twoFactorService) verifyTOTP(user *models.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, strings.TrimSpace(code), s.now(), totp.DefaultOptions)
+	if !ok {
+		return errors.ErrUnauthorized
+	}
+	if err := s.userRepo.AdvanceTOTPStep(user.ID, step); err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return errors.ErrUnauthorized
+		}
+		return err
+	}
+	// Keeps a later Update from writing the previous step back
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (s *twoFactorService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = auth.HashToken(normalizeRecoveryCode(code))
+	}
+	if err := s.recoveryCodeRepo.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) findUser(userID uint) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if stdErrors.Is(err, repository.ErrNotFound) {
+			return nil, errors.NewNotFoundError("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// generateRecoveryCode returns ten random base32 characters split in two groups, such as
+// "k7qbm-x2ndf"
+func generateRecoveryCode() (string, error) {
+	key := make([]byte, 7)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(key))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode drops the separators and case users may type recovery codes with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+	"github.com/LeonardsonCC/dinheiros/internal/totp"
+)
+
+func setupTwoFactorServiceTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.RecoveryCode{}, &models.AuditLog{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Create a test user with two-factor authentication
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	user := &models.User{
+		Name:             "Test User",
+		Email:            "test@example.com",
+		Password:         "hashedpassword",
+		TOTPSecret:       secret,
+		TwoFactorEnabled: true,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	return db, user
+}
+
+func TestTwoFactorService_WrongCodesLockLogins(t *testing.T) {
+	db, user := setupTwoFactorServiceTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute)
+	service := NewTwoFactorService(userRepo, repository.NewAuditLogRepository(db), repository.NewRecoveryCodeRepository(db), sessionRepo, NewSessionService(sessionRepo, userRepo, jwtManager, time.Hour), jwtManager).(*twoFactorService)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	recoveryCodes, err := service.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to create recovery codes: %v", err)
+	}
+	challenge, err := service.Challenge(user)
+	if err != nil {
+		t.Fatalf("Failed to start the challenge: %v", err)
+	}
+	codeAt := func(at time.Time) string {
+		code, err := totp.Code(user.TOTPSecret, totp.Step(at, totp.DefaultOptions), totp.DefaultOptions)
+		if err != nil {
+			t.Fatalf("Failed to generate code: %v", err)
+		}
+		return code
+	}
+	// A code from an hour ago is never accepted
+	wrongCode := codeAt(now.Add(-time.Hour))
+
+	// Wrong codes in the login and in sessions add up
+	for i := 0; i < lockoutThreshold-1; i++ {
+		if _, _, err := service.CompleteLogin(ctx, challenge.Token, wrongCode, client); !stdErrors.Is(err, errors.ErrUnauthorized) {
+			t.Fatalf("Expected wrong code %d to be unauthorized, got %v", i+1, err)
+		}
+	}
+	var locked *errors.LockedError
+	if err := service.Verify(ctx, user.ID, 1, "aaaaa-bbbbb", client); !stdErrors.As(err, &locked) {
+		t.Fatalf("Expected the last wrong code to lock the user, got %v", err)
+	}
+	if !locked.Until.Equal(now.Add(lockoutDuration)) {
+		t.Errorf("Expected a lock until %v, got %v", now.Add(lockoutDuration), locked.Until)
+	}
+
+	// While locked, even the right codes are refused
+	if _, _, err := service.CompleteLogin(ctx, challenge.Token, codeAt(now), client); !stdErrors.As(err, &locked) {
+		t.Errorf("Expected the login to be locked, got %v", err)
+	}
+	if err := service.Verify(ctx, user.ID, 1, recoveryCodes[0], client); !stdErrors.As(err, &locked) {
+		t.Errorf("Expected verifying to be locked, got %v", err)
+	}
+	var entries int64
+	db.Model(&models.AuditLog{}).Where("user_id = ? AND action = ?", user.ID, models.AuditActionAccountLocked).Count(&entries)
+	if entries != 1 {
+		t.Errorf("Expected 1 lock in the audit log, got %d", entries)
+	}
+
+	// Once the lock ends, the right code logs in and clears the count
+	now = locked.Until.Add(time.Second)
+	if _, _, err := service.CompleteLogin(ctx, challenge.Token, codeAt(now), client); err != nil {
+		t.Fatalf("Expected the login to work after the lock, got %v", err)
+	}
+	stored, err := userRepo.FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to find user: %v", err)
+	}
+	if stored.FailedLoginAttempts != 0 || stored.LockedUntil != nil {
+		t.Errorf("Expected the count to be cleared, got %d attempts locked until %v", stored.FailedLoginAttempts, stored.LockedUntil)
+	}
+}
@@ -1,37 +1,93 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"fmt"
 	"log"
-	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	appErrors "github.com/LeonardsonCC/dinheiros/internal/errors"
 	"github.com/LeonardsonCC/dinheiros/internal/models"
 	repo "github.com/LeonardsonCC/dinheiros/internal/repository"
 )
 
 // UserService defines the interface for user-related operations
 type UserService interface {
-	// Register creates a new user with the provided information and returns a JWT token
-	Register(name, email, password string) (string, *models.User, error)
-	// Login authenticates a user with the provided credentials and returns a JWT token
-	Login(email, password string) (string, *models.User, error)
+	// Register creates a new user with the provided information, seeds the chosen category
+	// template and starts a session on the client
+	Register(name, email, password, categoryTemplate string, client SessionClient) (*auth.TokenPair, *models.User, error)
+	// Login authenticates a user with the provided credentials and starts a session on the
+	// client, unless the user has two-factor authentication and must answer a challenge first.
+	// Repeated wrong passwords lock the user's logins for longer and longer, returning
+	// *errors.LockedError until the lock ends.
+	Login(email, password string, client SessionClient) (*LoginResult, error)
 	// FindByID finds a user by their ID
 	FindByID(id uint) (*models.User, error)
 	// UpdateName updates the user's name
 	UpdateName(id uint, name string) (*models.User, error)
-	// UpdatePassword updates the user's password after verifying the current password
-	UpdatePassword(id uint, currentPassword, newPassword string) error
-	// LoginOrRegisterGoogle logs in or registers a user using Google info
-	LoginOrRegisterGoogle(googleUser *GoogleUser) (string, *models.User, error)
+	// UpdatePassword updates the user's password after verifying the current password and
+	// revokes the user's sessions except the one making the change
+	UpdatePassword(id, sessionID uint, currentPassword, newPassword string) error
+	// UpdateTimezone updates the timezone the user's dates are parsed and grouped in
+	UpdateTimezone(id uint, timezone string) (*models.User, error)
+	// LoginWithIdentity logs in the user linked to the verified identity of a provider, or
+	// registers a new user linked to it when no user has its email. When a user has the email
+	// but isn't linked to the identity, the result has the link to confirm with their password
+	// instead of tokens. Like Login, users with two-factor authentication get a challenge.
+	LoginWithIdentity(identity *ExternalIdentity, categoryTemplate string, client SessionClient) (*LoginResult, error)
+	// LinkIdentity links the identity of a link token to its user once their password is
+	// confirmed, and logs them in like Login. Wrong passwords count towards locking the
+	// user's logins.
+	LinkIdentity(linkToken, password string, client SessionClient) (*LoginResult, error)
+	// ListIdentities returns the identities linked to the user
+	ListIdentities(id uint) ([]models.UserIdentity, error)
+	// UnlinkIdentity unlinks one of the user's identities, so it no longer logs into the user
+	UnlinkIdentity(id, identityID uint, client SessionClient) error
 }
 
+// LoginResult is a successful login: either the tokens of the new session, for users with
+// two-factor authentication the challenge to answer with a code, or for identities that
+// aren't linked to the user with their email yet, the link to confirm with the password
+type LoginResult struct {
+	User      *models.User
+	Tokens    *auth.TokenPair
+	Challenge *TwoFactorChallenge
+	Link      *IdentityLink
+}
+
+// IdentityLink is the link of a provider's identity to the user with the same email, which
+// the user confirms by sending the token and their password to /auth/link
+type IdentityLink struct {
+	Token     string
+	ExpiresAt time.Time
+	Provider  string
+	Email     string
+}
+
+// Logins are locked for lockoutDuration after lockoutThreshold wrong passwords or two-factor
+// codes in a row, and the lock doubles with each further wrong one up to maxLockoutDuration
+const (
+	lockoutThreshold   = 5
+	lockoutDuration    = time.Minute
+	maxLockoutDuration = time.Hour
+)
+
+// identityLinkDuration is how long users have to confirm their password to link an identity
+const identityLinkDuration = 10 * time.Minute
+
 type userService struct {
-	userRepo   repo.UserRepository
-	jwtManager *auth.JWTManager
+	userRepo         repo.UserRepository
+	auditLogRepo     repo.AuditLogRepository
+	identityRepo     repo.UserIdentityRepository
+	sessionService   SessionService
+	twoFactorService TwoFactorService
+	categoryService  CategoryService
+	jwtManager       *auth.JWTManager
+	now              func() time.Time
 }
 
 // UpdateName updates the user's name
@@ -49,8 +105,32 @@ func (s *userService) UpdateName(id uint, name string) (*models.User, error) {
 	return user, nil
 }
 
-// UpdatePassword updates the user's password after verifying the current password
-func (s *userService) UpdatePassword(id uint, currentPassword, newPassword string) error {
+// UpdateTimezone updates the user's timezone, which must be an IANA timezone name
+func (s *userService) UpdateTimezone(id uint, timezone string) (*models.User, error) {
+	// time.LoadLocation also accepts "" and "Local", which depend on the server
+	if timezone == "" || timezone == "Local" {
+		return nil, appErrors.NewValidationError("invalid timezone")
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, appErrors.NewValidationError("invalid timezone")
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Timezone = timezone
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("error updating user timezone")
+	}
+
+	return user, nil
+}
+
+// UpdatePassword updates the user's password after verifying the current password. Other
+// sessions are revoked, so whoever knew the old password is logged out.
+func (s *userService) UpdatePassword(id, sessionID uint, currentPassword, newPassword string) error {
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
 		return err
@@ -71,26 +151,42 @@ func (s *userService) UpdatePassword(id uint, currentPassword, newPassword strin
 		return errors.New("error updating password")
 	}
 
+	if _, err := s.sessionService.RevokeAll(context.Background(), id, sessionID); err != nil {
+		log.Printf("[UserService] UpdatePassword: Error revoking sessions for user %d: %v", id, err)
+		return errors.New("error revoking sessions")
+	}
+
 	return nil
 }
 
 // NewUserService creates a new instance of UserService
-func NewUserService(userRepo repo.UserRepository, jwtManager *auth.JWTManager) UserService {
+func NewUserService(userRepo repo.UserRepository, auditLogRepo repo.AuditLogRepository, identityRepo repo.UserIdentityRepository, sessionService SessionService, twoFactorService TwoFactorService, categoryService CategoryService, jwtManager *auth.JWTManager) UserService {
 	return &userService{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
+		userRepo:         userRepo,
+		auditLogRepo:     auditLogRepo,
+		identityRepo:     identityRepo,
+		sessionService:   sessionService,
+		twoFactorService: twoFactorService,
+		categoryService:  categoryService,
+		jwtManager:       jwtManager,
+		now:              time.Now,
 	}
 }
 
 // Register implements the UserService interface
-func (s *userService) Register(name, email, password string) (string, *models.User, error) {
+func (s *userService) Register(name, email, password, categoryTemplate string, client SessionClient) (*auth.TokenPair, *models.User, error) {
+	categoryTemplate, err := resolveCategoryTemplate(categoryTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.FindByEmail(email)
 	if err != nil && !errors.Is(err, repo.ErrNotFound) {
-		return "", nil, err
+		return nil, nil, err
 	}
 	if existingUser != nil {
-		return "", nil, errors.New("email already registered")
+		return nil, nil, errors.New("email already registered")
 	}
 
 	// Create new user
@@ -102,46 +198,134 @@ func (s *userService) Register(name, email, password string) (string, *models.Us
 
 	// Hash password
 	if err := user.HashPassword(); err != nil {
-		return "", nil, errors.New("error hashing password")
+		return nil, nil, errors.New("error hashing password")
 	}
 
 	// Save user to database
 	if err := s.userRepo.Create(user); err != nil {
-		return "", nil, errors.New("error creating user")
+		return nil, nil, errors.New("error creating user")
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user)
+	s.seedCategories(user.ID, categoryTemplate)
+
+	tokens, err := s.sessionService.Start(context.Background(), user, client, false)
 	if err != nil {
-		return "", nil, errors.New("error generating token")
+		return nil, nil, errors.New("error generating token")
 	}
 
-	return token, user, nil
+	return tokens, user, nil
 }
 
 // Login implements the UserService interface
-func (s *userService) Login(email, password string) (string, *models.User, error) {
+func (s *userService) Login(email, password string, client SessionClient) (*LoginResult, error) {
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
-			return "", nil, errors.New("invalid credentials")
+			return nil, errors.New("invalid credentials")
 		}
-		return "", nil, err
+		return nil, err
+	}
+
+	if err := s.checkPassword(user, password, client); err != nil {
+		return nil, err
+	}
+
+	return s.startLogin(user, client)
+}
+
+// checkPassword checks the password of a user who isn't locked, counting wrong passwords
+// towards locking their logins and clearing the count on the right one
+func (s *userService) checkPassword(user *models.User, password string, client SessionClient) error {
+	// Locked users can't try passwords until the lock ends
+	now := s.now()
+	if user.Locked(now) {
+		return &appErrors.LockedError{Until: *user.LockedUntil}
 	}
 
-	// Check password
 	if err := user.CheckPassword(password); err != nil {
-		return "", nil, errors.New("invalid credentials")
+		if until := s.recordFailedLogin(user, client, now); until != nil {
+			return &appErrors.LockedError{Until: *until}
+		}
+		return errors.New("invalid credentials")
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user)
+	// Users with two-factor authentication clear the count once they enter their code too, so
+	// the password doesn't clear the wrong codes
+	if (user.FailedLoginAttempts > 0 || user.LockedUntil != nil) && !user.TwoFactorEnabled {
+		if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+			log.Printf("[UserService] Error resetting failed logins of user %d: %v", user.ID, err)
+		}
+	}
+	return nil
+}
+
+// recordFailedLogin counts a wrong password towards locking the user's logins
+func (s *userService) recordFailedLogin(user *models.User, client SessionClient, now time.Time) *time.Time {
+	return recordFailedAttempt(s.userRepo, s.auditLogRepo, user.ID, client, now)
+}
+
+// recordFailedAttempt counts a wrong password or two-factor code and, once there are too
+// many, locks the user's logins and returns until when. Failing to record it doesn't fail the
+// attempt further.
+func recordFailedAttempt(userRepo repo.UserRepository, auditLogRepo repo.AuditLogRepository, userID uint, client SessionClient, now time.Time) *time.Time {
+	attempts, err := userRepo.RecordFailedLogin(userID)
 	if err != nil {
-		return "", nil, errors.New("error generating token")
+		log.Printf("[Lockout] Error recording failed login of user %d: %v", userID, err)
+		return nil
 	}
+	if attempts < lockoutThreshold {
+		return nil
+	}
+
+	until := now.Add(lockoutDurationAfter(attempts))
+	if err := userRepo.LockUntil(userID, until); err != nil {
+		log.Printf("[Lockout] Error locking user %d: %v", userID, err)
+		return nil
+	}
+	log.Printf("[Lockout] Locked user %d until %s after %d failed logins", userID, until.Format(time.RFC3339), attempts)
+
+	entry := &models.AuditLog{
+		UserID:    userID,
+		Action:    models.AuditActionAccountLocked,
+		Details:   fmt.Sprintf("%d failed login attempts, locked until %s", attempts, until.UTC().Format(time.RFC3339)),
+		UserAgent: truncateUserAgent(client.UserAgent),
+		IPAddress: client.IPAddress,
+	}
+	if err := auditLogRepo.Create(context.Background(), entry); err != nil {
+		log.Printf("[Lockout] Error saving %s audit entry for user %d: %v", entry.Action, userID, err)
+	}
+	return &until
+}
+
+// lockoutDurationAfter returns how long logins are locked after the number of wrong passwords
+func lockoutDurationAfter(attempts int) time.Duration {
+	duration := lockoutDuration
+	for i := lockoutThreshold; i < attempts && duration < maxLockoutDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxLockoutDuration {
+		return maxLockoutDuration
+	}
+	return duration
+}
 
-	return token, user, nil
+// startLogin starts a session for a user who proved who they are, or the two-factor
+// challenge when they have to enter a code too
+func (s *userService) startLogin(user *models.User, client SessionClient) (*LoginResult, error) {
+	if user.TwoFactorEnabled {
+		challenge, err := s.twoFactorService.Challenge(user)
+		if err != nil {
+			return nil, errors.New("error generating token")
+		}
+		return &LoginResult{User: user, Challenge: challenge}, nil
+	}
+
+	tokens, err := s.sessionService.Start(context.Background(), user, client, false)
+	if err != nil {
+		return nil, errors.New("error generating token")
+	}
+	return &LoginResult{User: user, Tokens: tokens}, nil
 }
 
 // FindByID implements the UserService interface
@@ -153,84 +337,208 @@ func (s *userService) FindByID(id uint) (*models.User, error) {
 	return user, nil
 }
 
-// GoogleUser represents the minimal user info from Google
-// You can expand this struct as needed
-// (sub = user id, email, name, picture, etc)
-type GoogleUser struct {
-	Sub     string `json:"sub"`
-	Email   string `json:"email"`
-	Name    string `json:"name"`
-	Picture string `json:"picture"`
-}
+// LoginWithIdentity implements the UserService interface
+func (s *userService) LoginWithIdentity(identity *ExternalIdentity, categoryTemplate string, client SessionClient) (*LoginResult, error) {
+	ctx := context.Background()
 
-// VerifyGoogleToken verifies the Google ID token and returns user info
-func VerifyGoogleToken(idToken string) (*GoogleUser, error) {
-	resp, err := http.Get("https://oauth2.googleapis.com/tokeninfo?id_token=" + idToken)
-	if err != nil {
-		return nil, err
+	linked, err := s.identityRepo.FindByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.identityRepo.Touch(ctx, linked.ID, s.now()); err != nil {
+			log.Printf("[UserService] LoginWithIdentity: Error recording login of identity %d: %v", linked.ID, err)
+		}
+		return s.startLogin(user, client)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	if resp.StatusCode != 200 {
+	if !errors.Is(err, repo.ErrNotFound) {
 		return nil, err
 	}
-	var gu GoogleUser
-	if err := json.NewDecoder(resp.Body).Decode(&gu); err != nil {
-		return nil, err
+
+	// Unlinked identities are matched to users by email, so it must be the user's
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, appErrors.ErrUnauthorized
 	}
-	if gu.Email == "" {
+
+	user, err := s.userRepo.FindByEmail(identity.Email)
+	if err == nil {
+		// Logging into the user with the email right away would let whoever has an account
+		// with the same email at the provider into theirs, so the user confirms the link with
+		// their password first
+		log.Printf("[UserService] LoginWithIdentity: %s identity has the email of user %d, asking to link it", identity.Provider, user.ID)
+		token, expiresAt, err := s.jwtManager.GenerateLinkToken(user, identity.Provider, identity.Subject, identity.Email, identityLinkDuration)
+		if err != nil {
+			return nil, errors.New("error generating token")
+		}
+		return &LoginResult{User: user, Link: &IdentityLink{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+		}}, nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
 		return nil, err
 	}
-	return &gu, nil
-}
 
-// LoginOrRegisterGoogle logs in or registers a user using Google info
-func (s *userService) LoginOrRegisterGoogle(googleUser *GoogleUser) (string, *models.User, error) {
-	log.Printf("[UserService] LoginOrRegisterGoogle: Starting Google login for email: %s", googleUser.Email)
+	log.Printf("[UserService] LoginWithIdentity: Registering a new user for a %s identity", identity.Provider)
 
-	// Try to find user by email
-	user, err := s.userRepo.FindByEmail(googleUser.Email)
-	if err != nil && !errors.Is(err, repo.ErrNotFound) {
-		log.Printf("[UserService] LoginOrRegisterGoogle: Error finding user by email: %v", err)
-		return "", nil, err
+	// Register new user with the identity, set a random password
+	user = &models.User{
+		Name:     identity.Name,
+		Email:    identity.Email,
+		Password: generateRandomPassword(), // Not used, but required by schema
+	}
+	if user.Name == "" {
+		user.Name = identity.Email
 	}
 
-	if user == nil {
-		log.Printf("[UserService] LoginOrRegisterGoogle: User not found, creating new user for: %s", googleUser.Email)
+	// Hash the random password
+	if err := user.HashPassword(); err != nil {
+		log.Printf("[UserService] LoginWithIdentity: Error hashing password: %v", err)
+		return nil, errors.New("error hashing password")
+	}
 
-		// Register new user with Google info, set a random password
-		user = &models.User{
-			Name:     googleUser.Name,
-			Email:    googleUser.Email,
-			Password: generateRandomPassword(), // Not used, but required by schema
-		}
+	if err := s.userRepo.Create(user); err != nil {
+		log.Printf("[UserService] LoginWithIdentity: Error creating user: %v", err)
+		return nil, errors.New("error creating user")
+	}
+
+	now := s.now()
+	if err := s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:      user.ID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		log.Printf("[UserService] LoginWithIdentity: Error linking identity to user %d: %v", user.ID, err)
+		return nil, errors.New("error creating user")
+	}
+
+	template, err := resolveCategoryTemplate(categoryTemplate)
+	if err != nil {
+		log.Printf("[UserService] LoginWithIdentity: Unknown category template %q, using default", categoryTemplate)
+		template = DefaultCategoryTemplate
+	}
+	s.seedCategories(user.ID, template)
+
+	return s.startLogin(user, client)
+}
 
-		// Hash the random password
-		if err := user.HashPassword(); err != nil {
-			log.Printf("[UserService] LoginOrRegisterGoogle: Error hashing password: %v", err)
-			return "", nil, errors.New("error hashing password")
+// LinkIdentity implements the UserService interface
+func (s *userService) LinkIdentity(linkToken, password string, client SessionClient) (*LoginResult, error) {
+	ctx := context.Background()
+
+	claims, err := s.jwtManager.VerifyLinkToken(linkToken)
+	if err != nil {
+		return nil, errors.New("invalid link token")
+	}
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, errors.New("invalid link token")
 		}
+		return nil, err
+	}
+
+	if err := s.checkPassword(user, password, client); err != nil {
+		return nil, err
+	}
 
-		if err := s.userRepo.Create(user); err != nil {
-			log.Printf("[UserService] LoginOrRegisterGoogle: Error creating user: %v", err)
-			return "", nil, errors.New("error creating user")
+	now := s.now()
+	identity := &models.UserIdentity{
+		UserID:      user.ID,
+		Provider:    claims.Provider,
+		Subject:     claims.ProviderSubject,
+		Email:       claims.ProviderEmail,
+		LastLoginAt: &now,
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		// The token may have been used before, which linked the identity already
+		existing, findErr := s.identityRepo.FindByProviderSubject(ctx, claims.Provider, claims.ProviderSubject)
+		if findErr != nil || existing.UserID != user.ID {
+			log.Printf("[UserService] LinkIdentity: Error linking %s identity to user %d: %v", claims.Provider, user.ID, err)
+			return nil, errors.New("error linking identity")
 		}
 	} else {
-		log.Printf("[UserService] LoginOrRegisterGoogle: Existing user found with ID: %d", user.ID)
+		s.audit(ctx, user.ID, models.AuditActionIdentityLinked, fmt.Sprintf("%s identity %s linked", claims.Provider, claims.ProviderEmail), client)
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user)
+	return s.startLogin(user, client)
+}
+
+// ListIdentities implements the UserService interface
+func (s *userService) ListIdentities(id uint) ([]models.UserIdentity, error) {
+	return s.identityRepo.FindByUserID(context.Background(), id)
+}
+
+// UnlinkIdentity implements the UserService interface
+func (s *userService) UnlinkIdentity(id, identityID uint, client SessionClient) error {
+	ctx := context.Background()
+	identities, err := s.identityRepo.FindByUserID(ctx, id)
 	if err != nil {
-		log.Printf("[UserService] LoginOrRegisterGoogle: Error generating token: %v", err)
-		return "", nil, errors.New("error generating token")
+		return err
+	}
+	for _, identity := range identities {
+		if identity.ID != identityID {
+			continue
+		}
+		if err := s.identityRepo.Delete(ctx, id, identityID); err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				return appErrors.NewNotFoundError("identity not found")
+			}
+			return err
+		}
+		s.audit(ctx, id, models.AuditActionIdentityUnlinked, fmt.Sprintf("%s identity %s unlinked", identity.Provider, identity.Email), client)
+		return nil
+	}
+	return appErrors.NewNotFoundError("identity not found")
+}
+
+// audit records a security event of the user's account. Failing to record it doesn't fail
+// what caused it.
+func (s *userService) audit(ctx context.Context, userID uint, action models.AuditAction, details string, client SessionClient) {
+	entry := &models.AuditLog{
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+		UserAgent: truncateUserAgent(client.UserAgent),
+		IPAddress: client.IPAddress,
+	}
+	if err := s.auditLogRepo.Create(ctx, entry); err != nil {
+		log.Printf("[UserService] Error saving %s audit entry for user %d: %v", action, userID, err)
+	}
+}
+
+// resolveCategoryTemplate returns the template to seed for a new user, falling back to the default
+func resolveCategoryTemplate(name string) (string, error) {
+	if name == "" {
+		return DefaultCategoryTemplate, nil
+	}
+	if name == NoCategoryTemplate {
+		return name, nil
+	}
+	if _, ok := FindCategoryTemplate(name); !ok {
+		return "", appErrors.NewValidationError("category template not found")
 	}
+	return name, nil
+}
 
-	return token, user, nil
+// seedCategories applies the category template to a newly created user. Failing to seed
+// categories doesn't fail the registration; the template can be applied again later.
+func (s *userService) seedCategories(userID uint, template string) {
+	if template == NoCategoryTemplate {
+		return
+	}
+	if _, err := s.categoryService.ApplyTemplate(context.Background(), userID, template); err != nil {
+		log.Printf("[UserService] Error applying category template %q for user %d: %v", template, userID, err)
+	}
 }
 
-// generateRandomPassword returns a random string (for Google users, password is not used)
+// generateRandomPassword returns a random string (for users registered by an identity
+// provider, password is not used)
 func generateRandomPassword() string {
 	return uuid.NewString()
 }
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/LeonardsonCC/dinheiros/internal/auth"
+	"github.com/LeonardsonCC/dinheiros/internal/errors"
+	"github.com/LeonardsonCC/dinheiros/internal/models"
+	"github.com/LeonardsonCC/dinheiros/internal/repository"
+)
+
+func setupUserServiceTestDB(t *testing.T) (*gorm.DB, UserService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Auto migrate the schema
+	err = db.AutoMigrate(&models.User{}, &models.Category{}, &models.Session{}, &models.RecoveryCode{}, &models.AuditLog{}, &models.UserIdentity{}, &models.Tag{}, &models.CategorizationRule{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute)
+	sessionService := NewSessionService(sessionRepo, userRepo, jwtManager, time.Hour)
+	twoFactorService := NewTwoFactorService(userRepo, auditLogRepo, repository.NewRecoveryCodeRepository(db), sessionRepo, sessionService, jwtManager)
+	service := NewUserService(userRepo, auditLogRepo, repository.NewUserIdentityRepository(db), sessionService, twoFactorService, NewCategoryService(db), jwtManager)
+
+	return db, service
+}
+
+// createIdentityTestUser creates a user with the password "secret123"
+func createIdentityTestUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	user := &models.User{Name: "Test User", Email: email, Password: "secret123"}
+	if err := user.HashPassword(); err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
+
+func googleIdentity(subject, email string) *ExternalIdentity {
+	return &ExternalIdentity{Provider: models.IdentityProviderGoogle, Subject: subject, Email: email, EmailVerified: true, Name: "Google User"}
+}
+
+func TestUserService_LoginWithIdentity_Link(t *testing.T) {
+	db, service := setupUserServiceTestDB(t)
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+	user := createIdentityTestUser(t, db, "test@example.com")
+
+	// The user with the email confirms the link with their password
+	result, err := service.LoginWithIdentity(googleIdentity("google-1", user.Email), "", client)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Link == nil || result.Tokens != nil || result.User.ID != user.ID {
+		t.Fatalf("Expected a link to confirm instead of tokens, got %+v", result)
+	}
+	if _, err := service.LinkIdentity(result.Link.Token, "wrong-password", client); err == nil {
+		t.Fatal("Expected a wrong password not to link the identity")
+	}
+	if identities, _ := service.ListIdentities(user.ID); len(identities) != 0 {
+		t.Fatalf("Expected no linked identities, got %+v", identities)
+	}
+
+	result, err = service.LinkIdentity(result.Link.Token, "secret123", client)
+	if err != nil {
+		t.Fatalf("Expected the link to work, got %v", err)
+	}
+	if result.Tokens == nil {
+		t.Errorf("Expected the link to log in, got %+v", result)
+	}
+	identities, err := service.ListIdentities(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(identities) != 1 || identities[0].Provider != models.IdentityProviderGoogle || identities[0].Subject != "google-1" {
+		t.Fatalf("Expected the Google identity to be linked, got %+v", identities)
+	}
+
+	// Later logins with the identity go straight in, even after the email changes
+	result, err = service.LoginWithIdentity(googleIdentity("google-1", "other@example.com"), "", client)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Tokens == nil || result.Link != nil || result.User.ID != user.ID {
+		t.Errorf("Expected the linked identity to log in, got %+v", result)
+	}
+
+	// Another account with the same email still has to be confirmed
+	result, err = service.LoginWithIdentity(googleIdentity("google-2", user.Email), "", client)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Link == nil || result.Tokens != nil {
+		t.Errorf("Expected another identity to need a link, got %+v", result)
+	}
+}
+
+func TestUserService_LoginWithIdentity_ExistingUsers(t *testing.T) {
+	db, service := setupUserServiceTestDB(t)
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	// A password user from before linked identities, who has logged in with their password
+	user := createIdentityTestUser(t, db, "password@example.com")
+	if _, err := service.Login(user.Email, "secret123", client); err != nil {
+		t.Fatalf("Expected the login to work, got %v", err)
+	}
+
+	// A Google account with the same email never logs in without the password
+	for i := 0; i < 2; i++ {
+		result, err := service.LoginWithIdentity(googleIdentity("google-1", user.Email), "", client)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Link == nil || result.Tokens != nil || result.Challenge != nil {
+			t.Fatalf("Expected a link to confirm instead of tokens, got %+v", result)
+		}
+	}
+	if identities, _ := service.ListIdentities(user.ID); len(identities) != 0 {
+		t.Errorf("Expected no linked identities, got %+v", identities)
+	}
+
+	// Unverified emails aren't matched at all
+	identity := googleIdentity("google-2", user.Email)
+	identity.EmailVerified = false
+	if _, err := service.LoginWithIdentity(identity, "", client); err == nil {
+		t.Error("Expected an unverified email to be refused")
+	}
+}
+
+// categoryNames returns the names of the user's categories
+func categoryNames(t *testing.T, db *gorm.DB, userID uint) map[string]bool {
+	var categories []models.Category
+	if err := db.Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		t.Fatalf("Failed to find categories: %v", err)
+	}
+	names := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		names[category.Name] = true
+	}
+	return names
+}
+
+func TestUserService_Register_CategoryTemplate(t *testing.T) {
+	db, service := setupUserServiceTestDB(t)
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	ptBR, _ := FindCategoryTemplate(DefaultCategoryTemplate)
+	en, _ := FindCategoryTemplate("en")
+	tests := []struct {
+		name     string
+		template string
+		has      string
+		count    int
+	}{
+		{"default template", "", "Mercado", len(ptBR.Categories)},
+		{"chosen template", "en", "Groceries", len(en.Categories)},
+		{"no template", NoCategoryTemplate, "", 0},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, user, err := service.Register("Test User", fmt.Sprintf("user%d@example.com", i), "secret123", tt.template, client)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			names := categoryNames(t, db, user.ID)
+			if len(names) != tt.count || (tt.has != "" && !names[tt.has]) {
+				t.Errorf("Expected %d categories with %q, got %v", tt.count, tt.has, names)
+			}
+		})
+	}
+
+	// Unknown templates don't register the user
+	var validation *errors.ValidationError
+	if _, _, err := service.Register("Test User", "unknown@example.com", "secret123", "unknown", client); !stdErrors.As(err, &validation) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+	var users int64
+	db.Model(&models.User{}).Where("email = ?", "unknown@example.com").Count(&users)
+	if users != 0 {
+		t.Errorf("Expected the user not to be registered, got %d users", users)
+	}
+}
+
+func TestUserService_LoginWithIdentity_CategoryTemplate(t *testing.T) {
+	db, service := setupUserServiceTestDB(t)
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	result, err := service.LoginWithIdentity(googleIdentity("google-1", "new@example.com"), "en", client)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if names := categoryNames(t, db, result.User.ID); !names["Groceries"] || names["Mercado"] {
+		t.Errorf("Expected the chosen template, got %v", names)
+	}
+
+	// Later logins don't seed the template again
+	if _, err := service.LoginWithIdentity(googleIdentity("google-1", "new@example.com"), "pt-BR", client); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if names := categoryNames(t, db, result.User.ID); names["Mercado"] {
+		t.Errorf("Expected only the first template, got %v", names)
+	}
+
+	// Unknown templates fall back to the default instead of failing the login
+	result, err = service.LoginWithIdentity(googleIdentity("google-2", "other@example.com"), "unknown", client)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if names := categoryNames(t, db, result.User.ID); !names["Mercado"] {
+		t.Errorf("Expected the default template, got %v", names)
+	}
+}
+
+func TestUserService_UpdatePassword_RevokesOtherSessions(t *testing.T) {
+	db, service := setupUserServiceTestDB(t)
+	client := SessionClient{UserAgent: "test", IPAddress: "127.0.0.1"}
+	user := createIdentityTestUser(t, db, "test@example.com")
+
+	var sessionIDs []uint
+	for i := 0; i < 2; i++ {
+		result, err := service.Login(user.Email, "secret123", client)
+		if err != nil {
+			t.Fatalf("Expected the login to work, got %v", err)
+		}
+		sessionIDs = append(sessionIDs, tokenSessionID(t, result.Tokens))
+	}
+	current, other := sessionIDs[0], sessionIDs[1]
+
+	if err := service.UpdatePassword(user.ID, current, "wrong-password", "new-password"); err == nil {
+		t.Fatal("Expected a wrong current password to fail")
+	}
+	sessionRepo := repository.NewSessionRepository(db)
+	if session, err := sessionRepo.FindByID(context.Background(), other); err != nil || session.RevokedAt != nil {
+		t.Fatalf("Expected a failed change to keep the sessions, got %+v and %v", session, err)
+	}
+
+	if err := service.UpdatePassword(user.ID, current, "secret123", "new-password"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if session, err := sessionRepo.FindByID(context.Background(), other); err != nil || session.RevokedAt == nil {
+		t.Errorf("Expected the other session to be revoked, got %+v and %v", session, err)
+	}
+	if session, err := sessionRepo.FindByID(context.Background(), current); err != nil || session.RevokedAt != nil {
+		t.Errorf("Expected the current session to stay active, got %+v and %v", session, err)
+	}
+}
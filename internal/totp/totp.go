@@ -0,0 +1,137 @@
+// Package totp implements time-based one-time passwords (RFC 6238), the six-digit codes
+// authenticator apps show for two-factor authentication.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSecret is returned for secrets that aren't valid base32
+var ErrInvalidSecret = errors.New("invalid TOTP secret")
+
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+type Options struct {
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+	// Skew is how many periods before and after the current one are accepted, so codes
+	// still work when the phone's clock drifts or the code is typed as it changes
+	Skew int
+}
+
+// DefaultOptions are the options every authenticator app supports
+var DefaultOptions = Options{Algorithm: SHA1, Digits: 6, Period: 30 * time.Second, Skew: 1}
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a random 160-bit secret, encoded in base32 as authenticator apps
+// expect it
+func GenerateSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(key), nil
+}
+
+// Step returns the number of periods since the Unix epoch at t, which codes are derived from
+func Step(t time.Time, opts Options) int64 {
+	return t.Unix() / int64(opts.Period/time.Second)
+}
+
+// Code returns the code for the step
+func Code(secret string, step int64, opts Options) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, step, opts), nil
+}
+
+// Validate checks the code against the steps around t allowed by the skew. It returns the
+// step the code belongs to, so callers can refuse a code that was already used.
+func Validate(secret, input string, t time.Time, opts Options) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(input) != opts.Digits {
+		return 0, false
+	}
+	current := Step(t, opts)
+	for offset := -opts.Skew; offset <= opts.Skew; offset++ {
+		step := current + int64(offset)
+		if subtle.ConstantTimeCompare([]byte(code(key, step, opts)), []byte(input)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningURI returns the otpauth:// URI authenticator apps add the account from, usually
+// shown as a QR code
+func ProvisioningURI(issuer, accountName, secret string, opts Options) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", string(opts.Algorithm))
+	params.Set("digits", strconv.Itoa(opts.Digits))
+	params.Set("period", strconv.Itoa(int(opts.Period/time.Second)))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimRight(secret, "="), " ", ""))
+	key, err := encoding.DecodeString(normalized)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// code is the HOTP value (RFC 4226) of the step
+func code(key []byte, step int64, opts Options) string {
+	mac := hmac.New(hashFunc(opts.Algorithm), key)
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < opts.Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", opts.Digits, value%modulo)
+}
+
+func hashFunc(algorithm Algorithm) func() hash.Hash {
+	switch algorithm {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
@@ -0,0 +1,113 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/LeonardsonCC/dinheiros/internal/totp"
+)
+
+// The test vectors of RFC 6238, appendix B
+func TestCode_RFC6238(t *testing.T) {
+	secrets := map[totp.Algorithm]string{
+		totp.SHA1:   base32.StdEncoding.EncodeToString([]byte("12345678901234567890")),
+		totp.SHA256: base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012")),
+		totp.SHA512: base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234")),
+	}
+	tests := []struct {
+		unix      int64
+		algorithm totp.Algorithm
+		code      string
+	}{
+		{59, totp.SHA1, "94287082"},
+		{59, totp.SHA256, "46119246"},
+		{59, totp.SHA512, "90693936"},
+		{1111111109, totp.SHA1, "07081804"},
+		{1111111109, totp.SHA256, "68084774"},
+		{1111111109, totp.SHA512, "25091201"},
+		{1111111111, totp.SHA1, "14050471"},
+		{1111111111, totp.SHA256, "67062674"},
+		{1111111111, totp.SHA512, "99943326"},
+		{1234567890, totp.SHA1, "89005924"},
+		{1234567890, totp.SHA256, "91819424"},
+		{1234567890, totp.SHA512, "93441116"},
+		{2000000000, totp.SHA1, "69279037"},
+		{2000000000, totp.SHA256, "90698825"},
+		{2000000000, totp.SHA512, "38618901"},
+		{20000000000, totp.SHA1, "65353130"},
+		{20000000000, totp.SHA256, "77737706"},
+		{20000000000, totp.SHA512, "47863826"},
+	}
+	for _, tt := range tests {
+		opts := totp.Options{Algorithm: tt.algorithm, Digits: 8, Period: 30 * time.Second}
+		step := totp.Step(time.Unix(tt.unix, 0), opts)
+		code, err := totp.Code(secrets[tt.algorithm], step, opts)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("%s at %d: expected %s, got %s", tt.algorithm, tt.unix, tt.code, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	opts := totp.DefaultOptions
+	now := time.Date(2026, 10, 18, 12, 0, 10, 0, time.UTC)
+	step := totp.Step(now, opts)
+
+	tests := []struct {
+		name  string
+		step  int64
+		valid bool
+	}{
+		{"current period", step, true},
+		{"previous period", step - 1, true},
+		{"next period", step + 1, true},
+		{"two periods ago", step - 2, false},
+		{"two periods ahead", step + 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := totp.Code(secret, tt.step, opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			matched, ok := totp.Validate(secret, code, now, opts)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid %v, got %v", tt.valid, ok)
+			}
+			if ok && matched != tt.step {
+				t.Errorf("Expected step %d, got %d", tt.step, matched)
+			}
+		})
+	}
+
+	if _, ok := totp.Validate(secret, "12345", now, opts); ok {
+		t.Error("Expected a code with the wrong length to be rejected")
+	}
+	if _, ok := totp.Validate("not base32!", "123456", now, opts); ok {
+		t.Error("Expected an invalid secret to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI("Dinheiros", "ana@example.com", "JBSWY3DPEHPK3PXP", totp.DefaultOptions)
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Expected a valid URI, got %v", err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/Dinheiros:ana@example.com" {
+		t.Errorf("Expected an otpauth://totp URI labelled with the issuer and account, got %s", uri)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Dinheiros" || query.Get("digits") != "6" || query.Get("period") != "30" || query.Get("algorithm") != "SHA1" {
+		t.Errorf("Unexpected parameters %v", query)
+	}
+}